@@ -3,6 +3,7 @@ import 'package:flutter/material.dart';
 import 'package:flutter_riverpod/flutter_riverpod.dart';
 import '../../theme/terminal_theme.dart';
 import '../../services/api_config.dart';
+import '../../services/auth_service.dart';
 import '../game/game_service.dart';
 
 class DuelScreen extends ConsumerStatefulWidget {
@@ -29,7 +30,9 @@ class _DuelScreenState extends ConsumerState<DuelScreen> {
     super.initState();
     WidgetsBinding.instance.addPostFrameCallback((_) {
       final gameService = ref.read(gameServiceProvider);
-      gameService.connect(ApiConfig.gameWebSocketUrl);
+      // Browsers can't send headers on the handshake, so the token goes in the URL
+      final token = Uri.encodeQueryComponent(AuthService.accessToken ?? '');
+      gameService.connect('${ApiConfig.gameWebSocketUrl}?access_token=$token');
 
       // Listen for updates
       gameService.gameEvents.listen((event) {
@@ -37,6 +40,8 @@ class _DuelScreenState extends ConsumerState<DuelScreen> {
           ref.read(puzzleStateProvider.notifier).state =
               event['data'] as PuzzleState;
           _startBot();
+        } else if (event['type'] == 'SOLUTION_RESULT') {
+          _handleSolutionResult(event['data'] as Map<String, dynamic>);
         }
       });
     });
@@ -76,10 +81,17 @@ class _DuelScreenState extends ConsumerState<DuelScreen> {
     final puzzleState = ref.read(puzzleStateProvider);
     if (puzzleState == null) return;
 
-    final normalizedInput = input.trim().toUpperCase();
-    final normalizedSolution = puzzleState.solution.trim().toUpperCase();
+    // The server checks the answer; the client never sees it
+    ref.read(gameServiceProvider).sendAction('SUBMIT_SOLUTION', {
+      'puzzle_id': puzzleState.id,
+      'solution': input.trim(),
+    });
+  }
+
+  void _handleSolutionResult(Map<String, dynamic> result) {
+    if (!mounted || _isGameOver) return;
 
-    if (normalizedInput == normalizedSolution) {
+    if (result['is_correct'] == true) {
       _botTimer?.cancel();
       setState(() {
         _isSuccess = true;
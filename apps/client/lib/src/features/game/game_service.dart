@@ -80,6 +80,8 @@ class GameService {
           final puzzle = PuzzleState.fromJson(payload['puzzle']);
           _eventController.add({'type': 'PUZZLE_UPDATE', 'data': puzzle});
         }
+      } else if (type == 'SOLUTION_RESULT') {
+        _eventController.add({'type': 'SOLUTION_RESULT', 'data': payload});
       }
     } catch (e) {
       debugPrint('Error parsing message: $e');
@@ -88,22 +90,22 @@ class GameService {
 }
 
 class PuzzleState {
+  final String id;
   final String encryptedText;
-  final String solution;
   final String cipherType;
   final int difficulty;
 
   PuzzleState({
+    required this.id,
     required this.encryptedText,
-    required this.solution,
     required this.cipherType,
     required this.difficulty,
   });
 
   factory PuzzleState.fromJson(Map<String, dynamic> json) {
     return PuzzleState(
+      id: json['id'] ?? '',
       encryptedText: json['encrypted_text'] ?? '',
-      solution: json['solution'] ?? '',
       cipherType: json['cipher_type'] ?? 'UNKNOWN',
       difficulty: json['difficulty'] ?? 1,
     );
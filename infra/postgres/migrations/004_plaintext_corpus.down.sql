@@ -0,0 +1,5 @@
+-- Rollback: Plaintext Corpus
+-- Version: 004
+
+DROP INDEX IF EXISTS idx_puzzles_tags;
+DROP TABLE IF EXISTS plaintext_corpus;
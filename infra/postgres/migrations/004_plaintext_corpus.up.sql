@@ -0,0 +1,20 @@
+-- Migration: Plaintext Corpus
+-- Version: 004
+-- Date: 2026-10-18
+-- Description: Curated plaintexts for puzzle generation, loaded by the puzzle engine alongside its embedded corpus
+
+CREATE TABLE IF NOT EXISTS plaintext_corpus (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    content TEXT NOT NULL,
+    language VARCHAR(8) NOT NULL DEFAULT 'en',
+    theme VARCHAR(50) NOT NULL DEFAULT 'general',
+    is_active BOOLEAN DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (language, content)
+);
+
+CREATE INDEX IF NOT EXISTS idx_plaintext_corpus_lang_theme ON plaintext_corpus(language, theme) WHERE is_active = TRUE;
+
+-- Puzzles are tagged with the corpus text they were built from (e.g. 'corpus:<id>', 'theme:science')
+CREATE INDEX IF NOT EXISTS idx_puzzles_tags ON puzzles USING GIN(tags);
@@ -0,0 +1,6 @@
+-- Rollback: Puzzle Empirical Difficulty
+-- Version: 005
+
+DROP INDEX IF EXISTS idx_puzzles_empirical_difficulty;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS solver_work;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS empirical_difficulty;
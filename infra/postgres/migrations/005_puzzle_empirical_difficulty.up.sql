@@ -0,0 +1,9 @@
+-- Migration: Puzzle Empirical Difficulty
+-- Version: 005
+-- Date: 2026-10-18
+-- Description: Stores how hard the puzzle engine's automated solvers found each generated puzzle
+
+ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS empirical_difficulty NUMERIC(4,2); -- 1-10, NULL when no solver exists for the cipher
+ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS solver_work INTEGER;               -- Candidate keys scored before the solver finished
+
+CREATE INDEX IF NOT EXISTS idx_puzzles_empirical_difficulty ON puzzles(cipher_type, empirical_difficulty);
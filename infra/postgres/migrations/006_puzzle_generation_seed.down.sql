@@ -0,0 +1,4 @@
+-- Rollback: Puzzle Generation Seed
+-- Version: 006
+
+ALTER TABLE puzzles DROP COLUMN IF EXISTS generation_seed;
@@ -0,0 +1,6 @@
+-- Migration: Puzzle Generation Seed
+-- Version: 006
+-- Date: 2026-10-18
+-- Description: Records the seed a puzzle was generated from so matches, daily puzzles and bug reports can be replayed
+
+ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS generation_seed BIGINT; -- NULL for unseeded (crypto/rand) puzzles
@@ -0,0 +1,7 @@
+-- Rollback: Puzzle Pool
+-- Version: 007
+
+DROP TABLE IF EXISTS user_seen_puzzles;
+DROP INDEX IF EXISTS idx_puzzles_pool;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS calibrated_difficulty;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS in_pool;
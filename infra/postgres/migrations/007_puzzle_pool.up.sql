@@ -0,0 +1,17 @@
+-- Migration: Puzzle Pool
+-- Version: 007
+-- Date: 2026-10-18
+-- Description: Pre-generated puzzle pool reused across players, per-user seen puzzles and calibrated difficulty
+
+ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS in_pool BOOLEAN DEFAULT FALSE;
+ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS calibrated_difficulty NUMERIC(4,2); -- NULL until enough attempts are recorded
+
+CREATE INDEX IF NOT EXISTS idx_puzzles_pool ON puzzles(cipher_type, difficulty) WHERE in_pool = TRUE AND is_active = TRUE;
+
+-- Pool puzzles already served to each user, so nobody gets the same one twice
+CREATE TABLE IF NOT EXISTS user_seen_puzzles (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    puzzle_id UUID NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    seen_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, puzzle_id)
+);
@@ -0,0 +1,4 @@
+-- Rollback: Puzzle Hint Usage
+-- Version: 008
+
+DROP TABLE IF EXISTS puzzle_hint_usage;
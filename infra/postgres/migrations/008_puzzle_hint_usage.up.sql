@@ -0,0 +1,13 @@
+-- Migration: Puzzle Hint Usage
+-- Version: 008
+-- Date: 2026-10-18
+-- Description: Server-side record of how far each user has climbed a puzzle's hint ladder
+
+CREATE TABLE IF NOT EXISTS puzzle_hint_usage (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    puzzle_id UUID NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    hints_used INTEGER NOT NULL DEFAULT 0,
+    first_hint_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_hint_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, puzzle_id)
+);
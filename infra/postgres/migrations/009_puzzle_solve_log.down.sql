@@ -0,0 +1,4 @@
+-- Rollback: Puzzle Solve Log
+-- Version: 009
+
+DROP TABLE IF EXISTS puzzle_solve_log;
@@ -0,0 +1,21 @@
+-- Migration: Puzzle Solve Log
+-- Version: 009
+-- Date: 2026-10-18
+-- Description: One row per finished puzzle attempt, so solve time percentiles can be computed.
+-- Kept apart from puzzle_attempts, which records attempts within matches.
+
+CREATE TABLE IF NOT EXISTS puzzle_solve_log (
+    id BIGSERIAL PRIMARY KEY,
+    puzzle_id UUID NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    solved BOOLEAN NOT NULL,
+    solve_time_ms INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_puzzle_solve_log_puzzle ON puzzle_solve_log(puzzle_id);
+CREATE INDEX IF NOT EXISTS idx_puzzle_solve_log_created ON puzzle_solve_log(created_at);
+
+-- Earlier versions averaged solve times over failed attempts too; failures
+-- carry no usable solve time, so the historical averages cannot be repaired
+-- and are reset until new solves arrive
+UPDATE puzzles SET avg_solve_time_ms = NULL WHERE times_used > times_solved;
@@ -0,0 +1,6 @@
+-- Rollback: Custom Puzzles
+-- Version: 010
+
+ALTER TABLE match_invitations DROP COLUMN IF EXISTS share_code;
+DROP TABLE IF EXISTS custom_puzzle_ratings;
+DROP TABLE IF EXISTS custom_puzzles;
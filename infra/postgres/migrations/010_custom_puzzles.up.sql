@@ -0,0 +1,29 @@
+-- Migration: Custom Puzzles
+-- Version: 010
+-- Date: 2026-10-18
+-- Description: Player-created puzzles with share codes, play counts and ratings, and share codes on match invites
+
+-- The puzzle itself is a regular puzzles row; this records who made it and how to find it
+CREATE TABLE IF NOT EXISTS custom_puzzles (
+    puzzle_id UUID PRIMARY KEY REFERENCES puzzles(id) ON DELETE CASCADE,
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    share_code VARCHAR(12) NOT NULL UNIQUE,
+    title VARCHAR(100),
+    play_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_custom_puzzles_owner ON custom_puzzles(owner_id, created_at DESC);
+
+-- One rating per player and puzzle; rating again replaces the earlier one
+CREATE TABLE IF NOT EXISTS custom_puzzle_ratings (
+    puzzle_id UUID NOT NULL REFERENCES custom_puzzles(puzzle_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    rating SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (puzzle_id, user_id)
+);
+
+-- Friend matches on a custom puzzle carry its share code
+ALTER TABLE match_invitations ADD COLUMN IF NOT EXISTS share_code VARCHAR(12);
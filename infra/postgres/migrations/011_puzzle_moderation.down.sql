@@ -0,0 +1,11 @@
+-- Rollback: Puzzle Moderation
+-- Version: 011
+
+DROP INDEX IF EXISTS idx_puzzle_solve_log_rating;
+ALTER TABLE puzzle_solve_log DROP COLUMN IF EXISTS player_rating;
+ALTER TABLE puzzle_solve_log DROP COLUMN IF EXISTS user_id;
+DROP INDEX IF EXISTS idx_puzzles_moderation;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS moderation_note;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS moderated_at;
+ALTER TABLE puzzles DROP COLUMN IF EXISTS moderation_status;
+DROP TABLE IF EXISTS puzzle_reports;
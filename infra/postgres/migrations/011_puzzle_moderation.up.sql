@@ -0,0 +1,35 @@
+-- Migration: Puzzle Moderation
+-- Version: 011
+-- Date: 2026-10-18
+-- Description: Player reports on puzzles, moderation status on puzzles, and the solver's rating on attempts
+
+-- A player has at most one open report per puzzle; reporting again updates it
+CREATE TABLE IF NOT EXISTS puzzle_reports (
+    id BIGSERIAL PRIMARY KEY,
+    puzzle_id UUID NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('WRONG_DECRYPTION', 'OFFENSIVE', 'UNSOLVABLE', 'DUPLICATE', 'OTHER')),
+    details TEXT,
+    status VARCHAR(10) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'RESOLVED', 'DISMISSED')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_puzzle_reports_open ON puzzle_reports(puzzle_id, user_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_puzzle_reports_status ON puzzle_reports(status, created_at);
+
+-- QUARANTINED puzzles wait for review, RETIRED ones are never served again.
+-- Both are also inactive, which keeps them out of the pool.
+ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS moderation_status VARCHAR(12) NOT NULL DEFAULT 'OK'
+    CHECK (moderation_status IN ('OK', 'QUARANTINED', 'RETIRED'));
+ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE puzzles ADD COLUMN IF NOT EXISTS moderation_note TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_puzzles_moderation ON puzzles(moderation_status) WHERE moderation_status <> 'OK';
+
+-- Who made an attempt and their rating at the time, so puzzles nobody strong
+-- has solved can be found. Older attempts stay anonymous.
+ALTER TABLE puzzle_solve_log ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE puzzle_solve_log ADD COLUMN IF NOT EXISTS player_rating INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_puzzle_solve_log_rating ON puzzle_solve_log(player_rating) WHERE player_rating IS NOT NULL;
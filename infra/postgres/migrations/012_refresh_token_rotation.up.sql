@@ -0,0 +1,22 @@
+-- Migration: Refresh Token Rotation
+-- Version: 012
+-- Date: 2026-10-18
+-- Description: Refresh token families for rotation and reuse detection, with device labels for per-device logout
+
+-- Every login starts a family; each refresh revokes the used token and adds
+-- its replacement to the same family. A revoked token coming back means it
+-- was stolen, and the whole family is revoked.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id UUID;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS device_label VARCHAR(100);
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS revoked_reason VARCHAR(20);
+
+UPDATE refresh_tokens SET family_id = id WHERE family_id IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+
+-- Tokens are looked up by hash, which must identify exactly one token
+DROP INDEX IF EXISTS idx_refresh_tokens_token_hash;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
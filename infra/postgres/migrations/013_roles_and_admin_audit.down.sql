@@ -0,0 +1,8 @@
+-- Rollback: Roles and Admin Audit Log
+-- Version: 013
+
+DROP TABLE IF EXISTS admin_audit_log;
+DROP INDEX IF EXISTS idx_users_role;
+ALTER TABLE users DROP COLUMN IF EXISTS ban_reason;
+ALTER TABLE users DROP COLUMN IF EXISTS banned_at;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,28 @@
+-- Migration: Roles and Admin Audit Log
+-- Version: 013
+-- Date: 2026-10-18
+-- Description: User roles carried in access tokens, ban details, and the audit log of privileged actions
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'player'
+    CHECK (role IN ('player', 'moderator', 'admin', 'service'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS banned_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS ban_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role) WHERE role <> 'player';
+
+-- Every service writes here when a moderator, admin or service changes
+-- something on a user's behalf. Actors and targets outlive their accounts
+-- as NULLs so the trail stays intact.
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL for the internal service token
+    actor_role VARCHAR(20) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target_user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created ON admin_audit_log(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_actor ON admin_audit_log(actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_target ON admin_audit_log(target_user_id, created_at DESC);
@@ -0,0 +1,4 @@
+-- Rollback: Account Tokens
+-- Version: 014
+
+DROP TABLE IF EXISTS account_tokens;
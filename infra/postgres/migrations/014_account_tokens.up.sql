@@ -0,0 +1,18 @@
+-- Migration: Account Tokens
+-- Version: 014
+-- Date: 2026-10-18
+-- Description: Single-use email verification and password reset tokens
+
+-- The tokens themselves are signed and emailed; only their IDs are kept, so
+-- each can be used once and outstanding ones can be invalidated
+CREATE TABLE IF NOT EXISTS account_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(30) NOT NULL CHECK (purpose IN ('email_verification', 'password_reset')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_tokens_user_purpose ON account_tokens(user_id, purpose) WHERE used_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_account_tokens_expires ON account_tokens(expires_at);
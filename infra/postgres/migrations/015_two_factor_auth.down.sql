@@ -0,0 +1,5 @@
+-- Rollback: Two-Factor Authentication
+-- Version: 015
+
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
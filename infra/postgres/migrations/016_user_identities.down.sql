@@ -0,0 +1,4 @@
+-- Rollback: User Identities
+-- Version: 016
+
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,19 @@
+-- Migration: User Identities
+-- Version: 016
+-- Date: 2026-10-18
+-- Description: Accounts at OpenID Connect providers linked to users
+
+-- subject is the provider's stable ID for the account; the email is kept
+-- only to show users which account they linked. Users created through a
+-- provider have an empty password_hash until they set a password.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT user_identities_provider_subject_key UNIQUE (provider, subject),
+    CONSTRAINT user_identities_user_provider_key UNIQUE (user_id, provider)
+);
@@ -0,0 +1,7 @@
+-- Rollback: Guest Accounts
+-- Version: 017
+
+-- Guests keep their rows, with placeholder addresses nobody can receive at
+UPDATE users SET email = 'guest-' || id || '@guest.invalid' WHERE email IS NULL;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
+ALTER TABLE users DROP COLUMN IF EXISTS is_guest;
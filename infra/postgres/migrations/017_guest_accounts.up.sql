@@ -0,0 +1,10 @@
+-- Migration: Guest Accounts
+-- Version: 017
+-- Date: 2026-10-18
+-- Description: Users who play without registering, until they add credentials
+
+-- Guests are real users, so tutorial, practice, missions and mastery
+-- progress is kept when they convert. They have no email and an empty
+-- password_hash until then, and can't play ranked.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_guest BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
@@ -0,0 +1,6 @@
+-- Rollback: Account Deletion and Data Export
+-- Version: 018
+
+DROP TABLE IF EXISTS data_exports;
+DROP TABLE IF EXISTS account_deletions;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,33 @@
+-- Migration: Account Deletion and Data Export
+-- Version: 018
+-- Date: 2026-10-18
+-- Description: Deleted user tombstones, the user.deleted outbox and data export jobs
+
+-- A deleted user's row stays, scrubbed of everything identifying, so
+-- opponents' match history still resolves to an anonymous player.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Deletions waiting to be announced to the other services. A row is
+-- written in the same transaction as the deletion and marked once the
+-- user.deleted event is published, so no deletion goes unannounced.
+CREATE TABLE IF NOT EXISTS account_deletions (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    requested_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_deletions_pending ON account_deletions(requested_at) WHERE published_at IS NULL;
+
+-- Self-service exports of a user's data, kept for download until expires_at
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, ready, failed
+    archive JSONB,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user ON data_exports(user_id, created_at DESC);
@@ -211,6 +211,18 @@ CREATE TABLE puzzle_attempts (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+-- Puzzle Solve Log (every finished attempt at a puzzle, in or out of matches,
+-- for solve time percentiles and moderation)
+CREATE TABLE puzzle_solve_log (
+    id BIGSERIAL PRIMARY KEY,
+    puzzle_id UUID NOT NULL REFERENCES puzzles(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL once the player deletes their account
+    player_rating INTEGER,
+    solved BOOLEAN NOT NULL,
+    solve_time_ms INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
 -- ============================================================================
 -- ACHIEVEMENTS & PROGRESSION
 -- ============================================================================
@@ -466,6 +478,11 @@ CREATE INDEX idx_puzzles_difficulty ON puzzles(difficulty);
 CREATE INDEX idx_puzzles_cipher_difficulty ON puzzles(cipher_type, difficulty);
 CREATE INDEX idx_puzzles_active ON puzzles(is_active);
 
+-- Puzzle Solve Log
+CREATE INDEX idx_puzzle_solve_log_puzzle ON puzzle_solve_log(puzzle_id);
+CREATE INDEX idx_puzzle_solve_log_created ON puzzle_solve_log(created_at);
+CREATE INDEX idx_puzzle_solve_log_rating ON puzzle_solve_log(player_rating) WHERE player_rating IS NOT NULL;
+
 -- Achievements
 CREATE INDEX idx_user_achievements_user_id ON user_achievements(user_id);
 CREATE INDEX idx_user_achievements_completed ON user_achievements(user_id, is_completed);
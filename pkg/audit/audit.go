@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+)
+
+// Audited actions
+const (
+	ActionUserBan           = "user.ban"
+	ActionUserUnban         = "user.unban"
+	ActionUserRole          = "user.role"
+	ActionUserRating        = "user.rating"
+	ActionCosmeticGrant     = "cosmetic.grant"
+	ActionAchievementCreate = "achievement.create"
+	ActionAchievementUpdate = "achievement.update"
+	ActionMasteryAward      = "mastery.award"
+	ActionPuzzleReview      = "puzzle.review"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// Entry is one privileged action: who took it, against whom, and with what
+type Entry struct {
+	ID           int64                  `json:"id"`
+	ActorID      *uuid.UUID             `json:"actor_id,omitempty"` // nil for the service token
+	ActorRole    auth.Role              `json:"actor_role"`
+	Action       string                 `json:"action"`
+	TargetUserID *uuid.UUID             `json:"target_user_id,omitempty"`
+	Details      map[string]interface{} `json:"details"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+// Filter narrows a listing of the audit log. Zero fields match everything.
+type Filter struct {
+	ActorID      *uuid.UUID
+	TargetUserID *uuid.UUID
+	Action       string
+	Limit        int
+	Offset       int
+}
+
+// Log records privileged actions in the admin_audit_log table, which every
+// service shares
+type Log struct {
+	db  *db.DB
+	log *logger.Logger
+}
+
+// NewLog creates an audit log
+func NewLog(database *db.DB, log *logger.Logger) *Log {
+	return &Log{db: database, log: log}
+}
+
+// Record stores an action taken by the caller in ctx. It runs after the
+// action succeeded, so a failure to record is logged rather than returned.
+func (l *Log) Record(ctx context.Context, action string, targetUserID *uuid.UUID, details map[string]interface{}) {
+	entry := &Entry{Action: action, TargetUserID: targetUserID, Details: details}
+	if caller, ok := auth.CallerFromContext(ctx); ok {
+		entry.ActorRole = caller.Role
+		if id, err := uuid.Parse(caller.UserID); err == nil {
+			entry.ActorID = &id
+		}
+	}
+	if entry.Details == nil {
+		entry.Details = map[string]interface{}{}
+	}
+
+	if err := l.insert(ctx, entry); err != nil {
+		l.log.Error("Failed to record audit entry", map[string]interface{}{
+			"action": action,
+			"error":  err.Error(),
+		})
+	}
+}
+
+func (l *Log) insert(ctx context.Context, entry *Entry) error {
+	details, err := json.Marshal(entry.Details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO admin_audit_log (actor_id, actor_role, action, target_user_id, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = l.db.ExecContext(ctx, query, entry.ActorID, string(entry.ActorRole), entry.Action, entry.TargetUserID, details)
+	return err
+}
+
+// List returns matching entries, newest first
+func (l *Log) List(ctx context.Context, filter Filter) ([]*Entry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	query := `
+		SELECT id, actor_id, actor_role, action, target_user_id, details, created_at
+		FROM admin_audit_log
+		WHERE ($1::uuid IS NULL OR actor_id = $1)
+			AND ($2::uuid IS NULL OR target_user_id = $2)
+			AND ($3 = '' OR action = $3)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := l.db.QueryContext(ctx, query, filter.ActorID, filter.TargetUserID, filter.Action, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	entries := []*Entry{}
+	for rows.Next() {
+		entry := &Entry{}
+		var actorID, targetUserID uuid.NullUUID
+		var actorRole string
+		var details []byte
+		if err := rows.Scan(&entry.ID, &actorID, &actorRole, &entry.Action, &targetUserID, &details, &entry.CreatedAt); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+
+		entry.ActorRole = auth.Role(actorRole)
+		if actorID.Valid {
+			entry.ActorID = &actorID.UUID
+		}
+		if targetUserID.Valid {
+			entry.TargetUserID = &targetUserID.UUID
+		}
+		if err := json.Unmarshal(details, &entry.Details); err != nil {
+			return nil, errors.NewInternalServerError(err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/swarit-1/cipher-clash/pkg/cache"
+)
+
+// Denylist records access tokens revoked before they expire: single tokens
+// on logout, and every token of a session when its refresh token family is
+// revoked. Entries only live as long as the tokens they deny.
+type Denylist struct {
+	cache     *cache.Cache
+	accessTTL time.Duration
+}
+
+// NewDenylist creates a denylist. Without a cache nothing can be revoked and
+// every token is accepted until it expires.
+func NewDenylist(cacheClient *cache.Cache, accessTTL time.Duration) *Denylist {
+	return &Denylist{
+		cache:     cacheClient,
+		accessTTL: accessTTL,
+	}
+}
+
+// RevokeToken denies one access token for the rest of its lifetime
+func (d *Denylist) RevokeToken(ctx context.Context, claims *Claims) error {
+	if d.cache == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return d.cache.Set(ctx, deniedTokenKey(claims.ID), true, ttl)
+}
+
+// RevokeSession denies every access token issued under a session. Tokens
+// last at most the access TTL, so the entry does too.
+func (d *Denylist) RevokeSession(ctx context.Context, sessionID string) error {
+	if d.cache == nil || sessionID == "" {
+		return nil
+	}
+	return d.cache.Set(ctx, revokedSessionKey(sessionID), true, d.accessTTL)
+}
+
+// IsRevoked reports whether an access token was revoked, by itself or with
+// its session
+func (d *Denylist) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	if d.cache == nil {
+		return false, nil
+	}
+	if claims.ID != "" {
+		denied, err := d.cache.Exists(ctx, deniedTokenKey(claims.ID))
+		if err != nil || denied {
+			return denied, err
+		}
+	}
+	if claims.SessionID != "" {
+		return d.cache.Exists(ctx, revokedSessionKey(claims.SessionID))
+	}
+	return false, nil
+}
+
+func deniedTokenKey(tokenID string) string {
+	return fmt.Sprintf("auth:denied_token:%s", tokenID)
+}
+
+func revokedSessionKey(sessionID string) string {
+	return fmt.Sprintf("auth:revoked_session:%s", sessionID)
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKSPath is where the auth service publishes its verification keys
+const JWKSPath = "/.well-known/jwks.json"
+
+// JWKS client timing. Keys are refetched when the cache goes stale, or early
+// when a token names an unknown key, which is how rotations propagate.
+const (
+	jwksCacheTTL      = 10 * time.Minute
+	jwksMinRefresh    = 30 * time.Second
+	jwksFetchTimeout  = 5 * time.Second
+	jwksMaxAgeSeconds = 300
+)
+
+// JWK is a public key in JSON Web Key form (RFC 7517). RSA keys set N and E,
+// Ed25519 keys Crv and X.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKSHandler serves a key set's public keys
+func JWKSHandler(keys *KeySet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", jwksMaxAgeSeconds))
+		json.NewEncoder(w).Encode(keys.JWKS())
+	}
+}
+
+func publicJWK(key *SigningKey) (JWK, bool) {
+	jwk := JWK{Use: "sig", Alg: key.Algorithm, Kid: key.ID}
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	default:
+		return JWK{}, false
+	}
+	return jwk, true
+}
+
+// verificationKey turns a published key back into a SigningKey that can only
+// verify
+func (j JWK) verificationKey() (*SigningKey, error) {
+	key := &SigningKey{ID: j.Kid, Algorithm: j.Alg}
+	switch {
+	case j.Kty == "RSA" && (j.Alg == AlgorithmRS256 || j.Alg == ""):
+		// Some identity providers leave alg out of RSA keys, which they
+		// only use with RS256
+		key.Algorithm = AlgorithmRS256
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil {
+			return nil, err
+		}
+		key.public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case j.Kty == "OKP" && j.Crv == "Ed25519" && j.Alg == AlgorithmEdDSA:
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %s: bad Ed25519 key size", j.Kid)
+		}
+		key.public = ed25519.PublicKey(x)
+	default:
+		return nil, fmt.Errorf("key %s: unsupported %s/%s key", j.Kid, j.Kty, j.Alg)
+	}
+	return key, nil
+}
+
+// JWKSClient fetches and caches the verification keys the auth service
+// publishes, so other services can verify tokens without its secrets
+type JWKSClient struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*SigningKey
+	fetchedAt time.Time
+}
+
+// NewJWKSClient creates a client for the key set at url
+func NewJWKSClient(url string) *JWKSClient {
+	return &JWKSClient{
+		url:    url,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+		keys:   make(map[string]*SigningKey),
+	}
+}
+
+// Lookup finds a key by ID, fetching the key set when the cache is stale or
+// doesn't know the key
+func (c *JWKSClient) Lookup(id string) (*SigningKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, known := c.keys[id]
+	age := time.Since(c.fetchedAt)
+	if (known && age < jwksCacheTTL) || (!known && age < jwksMinRefresh) {
+		if !known {
+			return nil, fmt.Errorf("unknown key ID %q", id)
+		}
+		return key, nil
+	}
+
+	if err := c.refresh(); err != nil {
+		// Keep verifying with cached keys while the auth service is unreachable
+		if known {
+			return key, nil
+		}
+		return nil, err
+	}
+	if key, ok := c.keys[id]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key ID %q", id)
+}
+
+// Keyfunc finds the key a token names and checks the token uses its
+// algorithm, for verifying tokens with jwt.Parse
+func (c *JWKSClient) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, err := c.Lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.method().Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verificationKey(), nil
+}
+
+// refresh replaces the cached keys. Called with mu held.
+func (c *JWKSClient) refresh() error {
+	c.fetchedAt = time.Now()
+
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var jwks JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]*SigningKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		key, err := jwk.verificationKey()
+		if err != nil {
+			continue // Skip key types this client doesn't know
+		}
+		keys[key.ID] = key
+	}
+	c.keys = keys
+	return nil
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/swarit-1/cipher-clash/pkg/config"
+)
+
+func TestKeySetJWKS(t *testing.T) {
+	current, err := GenerateSigningKey("current", AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	previous, err := GenerateSigningKey("previous", AlgorithmRS256)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	previous.RetiresAt = time.Now().Add(time.Hour)
+	retired, err := GenerateSigningKey("retired", AlgorithmEdDSA)
+	if err != nil {
+		t.Fatalf("GenerateSigningKey: %v", err)
+	}
+	retired.RetiresAt = time.Now().Add(-time.Minute)
+
+	keys, err := NewKeySet(current, previous, retired, NewSecretKey("secret", "secret"))
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	published := make(map[string]JWK)
+	for _, jwk := range keys.JWKS().Keys {
+		published[jwk.Kid] = jwk
+	}
+
+	tests := []struct {
+		kid     string
+		want    bool
+		wantKty string
+	}{
+		{"current", true, "OKP"},
+		{"previous", true, "RSA"},
+		{"retired", false, ""},
+		{"secret", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.kid, func(t *testing.T) {
+			jwk, ok := published[tt.kid]
+			if ok != tt.want {
+				t.Fatalf("key published = %v, want %v", ok, tt.want)
+			}
+			if ok && jwk.Kty != tt.wantKty {
+				t.Errorf("kty = %s, want %s", jwk.Kty, tt.wantKty)
+			}
+		})
+	}
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key, err := GenerateSigningKey("k1", algorithm)
+			if err != nil {
+				t.Fatalf("GenerateSigningKey: %v", err)
+			}
+			jwk, ok := publicJWK(key)
+			if !ok {
+				t.Fatal("publicJWK refused an asymmetric key")
+			}
+			public, err := jwk.verificationKey()
+			if err != nil {
+				t.Fatalf("verificationKey: %v", err)
+			}
+
+			token, err := key.Sign(&Claims{UserID: "user-1", TokenType: AccessToken})
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			keys, err := NewKeySet(public)
+			if err != nil {
+				t.Fatalf("NewKeySet: %v", err)
+			}
+			verifier := &JWTManager{keys: keys, verifier: true}
+			if _, err := verifier.ValidateToken(token, AccessToken); err != nil {
+				t.Errorf("published key doesn't verify its token: %v", err)
+			}
+		})
+	}
+
+	if _, ok := publicJWK(NewSecretKey("secret", "secret")); ok {
+		t.Error("publicJWK published a shared secret")
+	}
+}
+
+func TestJWKVerificationKeyRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		jwk  JWK
+	}{
+		{"unknown key type", JWK{Kty: "EC", Alg: "ES256", Kid: "k"}},
+		{"RSA with another algorithm", JWK{Kty: "RSA", Alg: AlgorithmHS256, Kid: "k", N: "AQAB", E: "AQAB"}},
+		{"Ed25519 without EdDSA", JWK{Kty: "OKP", Crv: "Ed25519", Alg: AlgorithmRS256, Kid: "k", X: "AAAA"}},
+		{"short Ed25519 key", JWK{Kty: "OKP", Crv: "Ed25519", Alg: AlgorithmEdDSA, Kid: "k", X: "AAAA"}},
+		{"bad encoding", JWK{Kty: "RSA", Alg: AlgorithmRS256, Kid: "k", N: "!!", E: "AQAB"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.jwk.verificationKey(); err == nil {
+				t.Error("verificationKey succeeded, want error")
+			}
+		})
+	}
+}
+
+// jwksServer serves whichever key set was set last, standing in for the auth
+// service across a rotation
+type jwksServer struct {
+	mu      sync.Mutex
+	keys    *KeySet
+	fetches int
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	keys := s.keys
+	s.fetches++
+	s.mu.Unlock()
+	JWKSHandler(keys)(w, r)
+}
+
+func (s *jwksServer) set(keys *KeySet) {
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+}
+
+func TestJWTVerifierFollowsRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := writeEd25519Key(t, dir, "old")
+	newPath := writeEd25519Key(t, dir, "new")
+
+	before := newTestManager(t, "old="+oldPath)
+	keys := &jwksServer{keys: before.KeySet()}
+	srv := httptest.NewServer(keys)
+	defer srv.Close()
+
+	verifier, err := NewJWTVerifier(config.JWTConfig{Algorithm: AlgorithmEdDSA, JWKSURL: srv.URL + JWKSPath})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+	if verifier.KeySet() != nil {
+		t.Error("a verifier exposes signing keys")
+	}
+
+	oldTokens, err := before.GenerateTokenPair("user-1", "ada", RolePlayer, false, "session-1")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	if _, err := verifier.ValidateToken(oldTokens.AccessToken, AccessToken); err != nil {
+		t.Fatalf("verifier rejected a token of the published key: %v", err)
+	}
+
+	// Rotate: the auth service now signs with "new" and still publishes "old"
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	after := newTestManager(t, "new="+newPath+",old="+oldPath+"@"+future)
+	keys.set(after.KeySet())
+	newTokens, err := after.GenerateTokenPair("user-1", "ada", RolePlayer, false, "session-1")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	// Unknown key IDs only trigger a refetch once the last fetch is old
+	// enough, so forged key IDs can't hammer the auth service
+	fetches := keys.fetches
+	if _, err := verifier.ValidateToken(newTokens.AccessToken, AccessToken); err == nil {
+		t.Error("verifier refetched the key set straight after the last fetch")
+	}
+	if keys.fetches != fetches {
+		t.Errorf("key set fetched %d more times, want none", keys.fetches-fetches)
+	}
+
+	verifier.jwks.fetchedAt = time.Now().Add(-time.Minute)
+	if _, err := verifier.ValidateToken(newTokens.AccessToken, AccessToken); err != nil {
+		t.Errorf("verifier rejected a token of the rotated key: %v", err)
+	}
+	if _, err := verifier.ValidateToken(oldTokens.AccessToken, AccessToken); err != nil {
+		t.Errorf("verifier rejected a token of the key being retired: %v", err)
+	}
+
+	// Cached keys keep working while the auth service is down
+	srv.Close()
+	verifier.jwks.fetchedAt = time.Now().Add(-2 * jwksCacheTTL)
+	if _, err := verifier.ValidateToken(newTokens.AccessToken, AccessToken); err != nil {
+		t.Errorf("verifier dropped cached keys when the refetch failed: %v", err)
+	}
+}
+
+func TestJWTVerifierRejects(t *testing.T) {
+	dir := t.TempDir()
+	manager := newTestManager(t, "main="+writeEd25519Key(t, dir, "main"))
+	srv := httptest.NewServer(JWKSHandler(manager.KeySet()))
+	defer srv.Close()
+
+	verifier, err := NewJWTVerifier(config.JWTConfig{Algorithm: AlgorithmEdDSA, JWKSURL: srv.URL + JWKSPath})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+
+	// A token signed with a shared secret but naming the published key
+	forged, err := NewSecretKey("main", "secret").Sign(&Claims{UserID: "user-1", TokenType: AccessToken})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	other := newTestManager(t, "main="+writeEd25519Key(t, dir, "other"))
+	otherTokens, err := other.GenerateTokenPair("user-1", "ada", RolePlayer, false, "session-1")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS256 token naming an asymmetric key", forged},
+		{"signed by a different key with the same ID", otherTokens.AccessToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifier.ValidateToken(tt.token, AccessToken); err == nil {
+				t.Error("ValidateToken succeeded, want error")
+			}
+		})
+	}
+
+	if _, err := NewJWTVerifier(config.JWTConfig{Algorithm: AlgorithmEdDSA}); err == nil {
+		t.Error("NewJWTVerifier accepted an asymmetric algorithm without a JWKS URL")
+	}
+}
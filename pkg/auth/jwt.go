@@ -15,13 +15,27 @@ type TokenType string
 const (
 	AccessToken  TokenType = "access"
 	RefreshToken TokenType = "refresh"
+
+	// Single-use tokens sent by email. Their IDs are stored so each works once.
+	EmailVerificationToken TokenType = "email_verification"
+	PasswordResetToken     TokenType = "password_reset"
+
+	// Issued by a login that still needs a second factor, and exchanged
+	// with a TOTP or recovery code for a token pair
+	MFAChallengeToken TokenType = "mfa_challenge"
 )
 
+// legacyKeyID names the HS256 shared secret key
+const legacyKeyID = "hs256"
+
 // Claims represents JWT claims
 type Claims struct {
-	UserID   string    `json:"user_id"`
-	Username string    `json:"username"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
 	TokenType TokenType `json:"token_type"`
+	Role      Role      `json:"role,omitempty"`
+	Guest     bool      `json:"guest,omitempty"` // Guests haven't registered, and can't play ranked
+	SessionID string    `json:"sid,omitempty"`   // Refresh token family the token was issued under
 	jwt.RegisteredClaims
 }
 
@@ -31,34 +45,112 @@ type TokenPair struct {
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresIn    int64     `json:"expires_in"` // seconds
 	ExpiresAt    time.Time `json:"expires_at"`
+
+	// RefreshTokenID and RefreshExpiresAt describe the refresh token so it
+	// can be stored; neither is sent to clients
+	RefreshTokenID   string    `json:"-"`
+	RefreshExpiresAt time.Time `json:"-"`
 }
 
-// JWTManager handles JWT token generation and validation
+// JWTManager handles JWT token generation and validation. The auth service
+// holds the signing keys; other services only verify, with the public keys
+// the auth service publishes.
 type JWTManager struct {
-	secret     string
+	keys       *KeySet     // Signing keys, or the shared secret of HS256 verifiers
+	jwks       *JWKSClient // Verifiers of asymmetric tokens only
+	verifier   bool
 	accessTTL  time.Duration
 	refreshTTL time.Duration
 }
 
-// NewJWTManager creates a new JWT manager
-func NewJWTManager(cfg config.JWTConfig) *JWTManager {
+// NewJWTManager creates the JWT manager that signs tokens. Keys come from
+// cfg.SigningKeys; without any, HS256 signs with the shared secret and the
+// asymmetric algorithms with a key generated for this process, so tokens
+// don't survive a restart.
+func NewJWTManager(cfg config.JWTConfig) (*JWTManager, error) {
+	var keys []*SigningKey
+	switch {
+	case cfg.SigningKeys != "":
+		parsed, err := ParseSigningKeys(cfg.SigningKeys)
+		if err != nil {
+			return nil, err
+		}
+		keys = parsed
+	case cfg.Algorithm == AlgorithmHS256:
+		keys = []*SigningKey{NewSecretKey(legacyKeyID, cfg.Secret)}
+	default:
+		generated, err := GenerateSigningKey("ephemeral-"+uuid.New().String()[:8], cfg.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		keys = []*SigningKey{generated}
+	}
+
+	keySet, err := NewKeySet(keys...)
+	if err != nil {
+		return nil, err
+	}
 	return &JWTManager{
-		secret:     cfg.Secret,
+		keys:       keySet,
 		accessTTL:  cfg.AccessTTL,
 		refreshTTL: cfg.RefreshTTL,
+	}, nil
+}
+
+// NewJWTVerifier creates a JWT manager for services that only verify tokens.
+// Asymmetric tokens are checked against the auth service's JWKS at
+// cfg.JWKSURL; HS256 tokens still need the shared secret.
+func NewJWTVerifier(cfg config.JWTConfig) (*JWTManager, error) {
+	verifier := &JWTManager{
+		accessTTL:  cfg.AccessTTL,
+		refreshTTL: cfg.RefreshTTL,
+		verifier:   true,
+	}
+	if cfg.Algorithm == AlgorithmHS256 {
+		keySet, err := NewKeySet(NewSecretKey(legacyKeyID, cfg.Secret))
+		if err != nil {
+			return nil, err
+		}
+		verifier.keys = keySet
+		return verifier, nil
+	}
+
+	if cfg.JWKSURL == "" {
+		return nil, fmt.Errorf("JWT_JWKS_URL is required to verify %s tokens", cfg.Algorithm)
+	}
+	verifier.jwks = NewJWKSClient(cfg.JWKSURL)
+	return verifier, nil
+}
+
+// KeySet returns the signing keys, nil for verifiers
+func (m *JWTManager) KeySet() *KeySet {
+	if m.verifier {
+		return nil
 	}
+	return m.keys
+}
+
+// AccessTTL returns how long access tokens stay valid
+func (m *JWTManager) AccessTTL() time.Duration {
+	return m.accessTTL
 }
 
-// GenerateTokenPair generates both access and refresh tokens
-func (m *JWTManager) GenerateTokenPair(userID, username string) (*TokenPair, error) {
+// GenerateTokenPair generates both access and refresh tokens for a session,
+// the refresh token family both tokens belong to
+func (m *JWTManager) GenerateTokenPair(userID, username string, role Role, guest bool, sessionID string) (*TokenPair, error) {
 	now := time.Now()
 	expiresAt := now.Add(m.accessTTL)
+	refreshExpiresAt := now.Add(m.refreshTTL)
+	refreshID := uuid.New().String()
 
 	// Generate access token
 	accessClaims := &Claims{
 		UserID:    userID,
 		Username:  username,
 		TokenType: AccessToken,
+		Role:      role,
+		Guest:     guest,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -77,11 +169,12 @@ func (m *JWTManager) GenerateTokenPair(userID, username string) (*TokenPair, err
 		UserID:    userID,
 		Username:  username,
 		TokenType: RefreshToken,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(m.refreshTTL)),
+			ExpiresAt: jwt.NewNumericDate(refreshExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
-			ID:        uuid.New().String(),
+			ID:        refreshID,
 		},
 	}
 
@@ -95,28 +188,94 @@ func (m *JWTManager) GenerateTokenPair(userID, username string) (*TokenPair, err
 		RefreshToken: refreshToken,
 		ExpiresIn:    int64(m.accessTTL.Seconds()),
 		ExpiresAt:    expiresAt,
+
+		RefreshTokenID:   refreshID,
+		RefreshExpiresAt: refreshExpiresAt,
 	}, nil
 }
 
-// generateToken creates a signed JWT token
-func (m *JWTManager) generateToken(claims *Claims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(m.secret))
+// SingleUseToken is a signed token for one account action, with the ID
+// that has to be stored to make it single-use
+type SingleUseToken struct {
+	Token     string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// GenerateSingleUseToken generates an email verification or password reset
+// token for a user
+func (m *JWTManager) GenerateSingleUseToken(userID string, tokenType TokenType, ttl time.Duration) (*SingleUseToken, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	tokenID := uuid.New().String()
+
+	claims := &Claims{
+		UserID:    userID,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ID:        tokenID,
+		},
+	}
+
+	token, err := m.generateToken(claims)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to generate %s token: %w", tokenType, err)
+	}
+
+	return &SingleUseToken{
+		Token:     token,
+		ID:        tokenID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// generateToken creates a JWT token signed with the current key
+func (m *JWTManager) generateToken(claims *Claims) (string, error) {
+	if m.verifier {
+		return "", fmt.Errorf("token verifiers cannot sign tokens")
+	}
+
+	return m.keys.Signing().Sign(claims)
+}
+
+// verificationKey finds the key a token names in its header and checks the
+// token uses that key's algorithm
+func (m *JWTManager) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	var key *SigningKey
+	switch {
+	case m.keys != nil:
+		if kid == "" && m.keys.Signing().Algorithm == AlgorithmHS256 {
+			kid = legacyKeyID // Tokens from before key IDs
+		}
+		found, ok := m.keys.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key ID %q", kid)
+		}
+		key = found
+	case m.jwks != nil:
+		found, err := m.jwks.Lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		key = found
+	default:
+		return nil, fmt.Errorf("no verification keys")
 	}
-	return signedToken, nil
+
+	if token.Method.Alg() != key.method().Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verificationKey(), nil
 }
 
 // ValidateToken validates a JWT token and returns claims
 func (m *JWTManager) ValidateToken(tokenString string, expectedType TokenType) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(m.secret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.verificationKey)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms. HS256 needs every verifier to hold the secret; the
+// asymmetric ones let services verify with keys published as a JWKS.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 2048
+
+// SigningKey is one key of a KeySet, identified in tokens by its key ID
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	// RetiresAt ends the key's overlap window after rotation: until then it
+	// still verifies tokens it signed. Zero means no end.
+	RetiresAt time.Time
+
+	private crypto.PrivateKey // *rsa.PrivateKey, ed25519.PrivateKey or the HS256 secret
+	public  crypto.PublicKey  // nil for HS256
+}
+
+// NewSecretKey wraps an HS256 shared secret
+func NewSecretKey(id, secret string) *SigningKey {
+	return &SigningKey{ID: id, Algorithm: AlgorithmHS256, private: []byte(secret)}
+}
+
+// GenerateSigningKey creates a new RS256 or EdDSA key
+func GenerateSigningKey(id, algorithm string) (*SigningKey, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: id, Algorithm: algorithm, private: private, public: &private.PublicKey}, nil
+	case AlgorithmEdDSA:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: id, Algorithm: algorithm, private: private, public: public}, nil
+	default:
+		return nil, fmt.Errorf("cannot generate %q signing keys", algorithm)
+	}
+}
+
+// LoadSigningKey reads a PEM private key (PKCS#8, or PKCS#1 for RSA). The
+// algorithm follows from the key type.
+func LoadSigningKey(id, path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+
+	var private interface{}
+	if block.Type == "RSA PRIVATE KEY" {
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: id, Algorithm: AlgorithmRS256, private: private, public: &private.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: id, Algorithm: AlgorithmEdDSA, private: private, public: private.Public()}, nil
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, private)
+	}
+}
+
+// ParseSigningKeys loads keys from "kid=path,kid=path@2026-11-01T00:00:00Z".
+// The first key signs new tokens. The others only verify, until the
+// RFC 3339 time after their @ if one is given.
+func ParseSigningKeys(spec string) ([]*SigningKey, error) {
+	var keys []*SigningKey
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, path, ok := strings.Cut(entry, "=")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("signing key %q is not KID=PATH", entry)
+		}
+		path, retires, hasRetirement := strings.Cut(path, "@")
+
+		key, err := LoadSigningKey(strings.TrimSpace(id), strings.TrimSpace(path))
+		if err != nil {
+			return nil, err
+		}
+		if hasRetirement {
+			key.RetiresAt, err = time.Parse(time.RFC3339, strings.TrimSpace(retires))
+			if err != nil {
+				return nil, fmt.Errorf("signing key %s: invalid retirement time: %w", id, err)
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// Sign signs claims with the key, naming it in the token header
+func (k *SigningKey) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method(), claims)
+	token.Header["kid"] = k.ID
+	return token.SignedString(k.private)
+}
+
+// verificationKey is what the JWT library checks signatures with
+func (k *SigningKey) verificationKey() interface{} {
+	if k.Algorithm == AlgorithmHS256 {
+		return k.private
+	}
+	return k.public
+}
+
+func (k *SigningKey) retired(now time.Time) bool {
+	return !k.RetiresAt.IsZero() && !now.Before(k.RetiresAt)
+}
+
+// KeySet holds the key new tokens are signed with and the keys still in
+// their overlap window after a rotation
+type KeySet struct {
+	keys []*SigningKey
+}
+
+// NewKeySet creates a key set. The first key signs; it must not be retired.
+func NewKeySet(keys ...*SigningKey) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("a key set needs a signing key")
+	}
+	if keys[0].retired(time.Now()) {
+		return nil, fmt.Errorf("signing key %s is retired", keys[0].ID)
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key.ID] {
+			return nil, fmt.Errorf("duplicate key ID %s", key.ID)
+		}
+		seen[key.ID] = true
+	}
+	return &KeySet{keys: keys}, nil
+}
+
+// Signing returns the key new tokens are signed with
+func (s *KeySet) Signing() *SigningKey {
+	return s.keys[0]
+}
+
+// Lookup finds an unretired key by ID
+func (s *KeySet) Lookup(id string) (*SigningKey, bool) {
+	now := time.Now()
+	for _, key := range s.keys {
+		if key.ID == id && !key.retired(now) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// JWKS returns the public halves of the unretired asymmetric keys
+func (s *KeySet) JWKS() *JWKS {
+	jwks := &JWKS{Keys: []JWK{}}
+	now := time.Now()
+	for _, key := range s.keys {
+		if key.retired(now) {
+			continue
+		}
+		if jwk, ok := publicJWK(key); ok {
+			jwks.Keys = append(jwks.Keys, jwk)
+		}
+	}
+	return jwks
+}
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/swarit-1/cipher-clash/pkg/config"
+)
+
+// writeKey saves a private key as PEM in dir and returns its path
+func writeKey(t *testing.T, dir, name string, private interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	path := filepath.Join(dir, name+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func writeEd25519Key(t *testing.T, dir, name string) string {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return writeKey(t, dir, name, private)
+}
+
+func newTestManager(t *testing.T, signingKeys string) *JWTManager {
+	t.Helper()
+	manager, err := NewJWTManager(config.JWTConfig{
+		Algorithm:   AlgorithmEdDSA,
+		SigningKeys: signingKeys,
+		AccessTTL:   time.Minute,
+		RefreshTTL:  time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("NewJWTManager(%q): %v", signingKeys, err)
+	}
+	return manager
+}
+
+func TestParseSigningKeys(t *testing.T) {
+	dir := t.TempDir()
+	edPath := writeEd25519Key(t, dir, "ed")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	rsaPath := writeKey(t, dir, "rsa", rsaKey)
+	pkcs1Path := filepath.Join(dir, "pkcs1.pem")
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	if err := os.WriteFile(pkcs1Path, pkcs1, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	notPEM := filepath.Join(dir, "plain.txt")
+	if err := os.WriteFile(notPEM, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		spec       string
+		ids        []string
+		algorithms []string
+		retires    []bool
+		wantErr    bool
+	}{
+		{"single key", "a=" + edPath, []string{"a"}, []string{AlgorithmEdDSA}, []bool{false}, false},
+		{"rotation", "new=" + rsaPath + ", old=" + edPath + "@2030-01-01T00:00:00Z", []string{"new", "old"}, []string{AlgorithmRS256, AlgorithmEdDSA}, []bool{false, true}, false},
+		{"PKCS#1", "legacy=" + pkcs1Path, []string{"legacy"}, []string{AlgorithmRS256}, []bool{false}, false},
+		{"empty entries skipped", ",a=" + edPath + ",", []string{"a"}, []string{AlgorithmEdDSA}, []bool{false}, false},
+		{"missing ID", "=" + edPath, nil, nil, nil, true},
+		{"missing path", "a", nil, nil, nil, true},
+		{"missing file", "a=" + filepath.Join(dir, "missing.pem"), nil, nil, nil, true},
+		{"not PEM", "a=" + notPEM, nil, nil, nil, true},
+		{"bad retirement time", "a=" + edPath + "@tomorrow", nil, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseSigningKeys(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ParseSigningKeys succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSigningKeys: %v", err)
+			}
+			if len(keys) != len(tt.ids) {
+				t.Fatalf("got %d keys, want %d", len(keys), len(tt.ids))
+			}
+			for i, key := range keys {
+				if key.ID != tt.ids[i] || key.Algorithm != tt.algorithms[i] || key.RetiresAt.IsZero() == tt.retires[i] {
+					t.Errorf("key %d = {%s %s retires %v}, want {%s %s retires %v}", i, key.ID, key.Algorithm, !key.RetiresAt.IsZero(), tt.ids[i], tt.algorithms[i], tt.retires[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewKeySet(t *testing.T) {
+	current := NewSecretKey("current", "secret")
+	retired := NewSecretKey("retired", "secret")
+	retired.RetiresAt = time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		keys    []*SigningKey
+		wantErr bool
+	}{
+		{"signing key only", []*SigningKey{current}, false},
+		{"retired key after the signing key", []*SigningKey{current, retired}, false},
+		{"no keys", nil, true},
+		{"retired signing key", []*SigningKey{retired, current}, true},
+		{"duplicate IDs", []*SigningKey{current, NewSecretKey("current", "other")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeySet(tt.keys...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewKeySet error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldPath := writeEd25519Key(t, dir, "old")
+	newPath := writeEd25519Key(t, dir, "new")
+
+	before := newTestManager(t, "old="+oldPath)
+	oldTokens, err := before.GenerateTokenPair("user-1", "ada", RolePlayer, false, "session-1")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	// During the overlap window the new key signs and the old one still verifies
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	during := newTestManager(t, "new="+newPath+",old="+oldPath+"@"+future)
+	if _, err := during.ValidateToken(oldTokens.AccessToken, AccessToken); err != nil {
+		t.Errorf("token signed before the rotation rejected during the overlap: %v", err)
+	}
+	newTokens, err := during.GenerateTokenPair("user-1", "ada", RolePlayer, false, "session-1")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+	if kid := tokenKeyID(t, newTokens.AccessToken); kid != "new" {
+		t.Errorf("token signed with key %q, want new", kid)
+	}
+	if _, err := before.ValidateToken(newTokens.AccessToken, AccessToken); err == nil {
+		t.Error("a manager without the new key accepted its token")
+	}
+
+	// Once the window ends, tokens of the old key are refused
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	after := newTestManager(t, "new="+newPath+",old="+oldPath+"@"+past)
+	if _, err := after.ValidateToken(oldTokens.AccessToken, AccessToken); err == nil {
+		t.Error("token of a retired key accepted")
+	}
+	if _, err := after.ValidateToken(newTokens.AccessToken, AccessToken); err != nil {
+		t.Errorf("token of the current key rejected: %v", err)
+	}
+}
+
+func TestValidateTokenRejects(t *testing.T) {
+	dir := t.TempDir()
+	manager := newTestManager(t, "main="+writeEd25519Key(t, dir, "main"))
+	tokens, err := manager.GenerateTokenPair("user-1", "ada", RolePlayer, false, "session-1")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair: %v", err)
+	}
+
+	// An HS256 token naming the Ed25519 key, signed with its public half as
+	// the secret: the classic algorithm confusion attack
+	public := manager.KeySet().Signing().public.(ed25519.PublicKey)
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "user-1", TokenType: AccessToken})
+	confused.Header["kid"] = "main"
+	confusedToken, err := confused.SignedString([]byte(public))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	unknown := NewSecretKey("stranger", "secret")
+	strangerToken, err := unknown.Sign(&Claims{UserID: "user-1", TokenType: AccessToken})
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantType TokenType
+	}{
+		{"wrong token type", tokens.RefreshToken, AccessToken},
+		{"algorithm confusion", confusedToken, AccessToken},
+		{"unknown key", strangerToken, AccessToken},
+		{"tampered", tokens.AccessToken[:len(tokens.AccessToken)-2] + "xx", AccessToken},
+		{"garbage", "not.a.token", AccessToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := manager.ValidateToken(tt.token, tt.wantType); err == nil {
+				t.Error("ValidateToken succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestLegacyHS256TokensWithoutKeyID(t *testing.T) {
+	manager, err := NewJWTManager(config.JWTConfig{Algorithm: AlgorithmHS256, Secret: "secret", AccessTTL: time.Minute, RefreshTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("NewJWTManager: %v", err)
+	}
+
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{UserID: "user-1", TokenType: AccessToken})
+	token, err := legacy.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	if _, err := manager.ValidateToken(token, AccessToken); err != nil {
+		t.Errorf("token from before key IDs rejected: %v", err)
+	}
+}
+
+func tokenKeyID(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	if err != nil {
+		t.Fatalf("ParseUnverified: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return strings.TrimSpace(kid)
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+)
+
+// Scope is what a caller may do
+type Scope string
+
+const (
+	ScopePlayer    Scope = "player"    // A logged in user, acting for themselves
+	ScopeModerator Scope = "moderator" // Staff who review reports and ban players
+	ScopeAdmin     Scope = "admin"     // Operators, who may act for any user
+	ScopeService   Scope = "service"   // Other services, holding the internal service token
+)
+
+// Caller is the authenticated identity behind a request
+type Caller struct {
+	UserID   string  // Empty for services
+	Username string  // Empty for services
+	Claims   *Claims // nil for services
+	Role     Role
+	Scopes   []Scope
+	Guest    bool // Hasn't registered yet
+}
+
+// HasScope reports whether the caller holds scope
+func (c *Caller) HasScope(scope Scope) bool {
+	for _, held := range c.Scopes {
+		if held == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Scopes returns what the holder of an access token may do. Tokens issued
+// before roles existed carry none and belong to players.
+func (c *Claims) Scopes() []Scope {
+	return c.Role.Scopes()
+}
+
+type contextKey string
+
+const callerKey contextKey = "auth_caller"
+
+// CallerFromContext returns the caller RequireAuth or RequireScope stored
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(callerKey).(*Caller)
+	return caller, ok
+}
+
+// ClaimsFromContext returns the access token claims of a user caller
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	caller, ok := CallerFromContext(ctx)
+	if !ok || caller.Claims == nil {
+		return nil, false
+	}
+	return caller.Claims, true
+}
+
+// UserIDFromContext returns the ID of a user caller
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	caller, ok := CallerFromContext(ctx)
+	if !ok || caller.UserID == "" {
+		return "", false
+	}
+	return caller.UserID, true
+}
+
+// AuthorizeUser checks the caller may act for userID: it is that user, an
+// admin, or another service
+func AuthorizeUser(ctx context.Context, userID string) error {
+	caller, ok := CallerFromContext(ctx)
+	if !ok {
+		return errors.NewUnauthorizedError("Authentication required")
+	}
+	if caller.HasScope(ScopeAdmin) || caller.HasScope(ScopeService) {
+		return nil
+	}
+	if caller.UserID == "" || !strings.EqualFold(caller.UserID, userID) {
+		return errors.NewForbiddenError("Cannot act for another user")
+	}
+	return nil
+}
+
+// Middleware authenticates requests with access tokens or the internal
+// service token. Every service wraps its protected routes with it.
+type Middleware struct {
+	verifier     *JWTManager
+	denylist     *Denylist
+	serviceToken string
+	log          *logger.Logger
+}
+
+// NewMiddleware creates an auth middleware. A nil denylist skips revocation
+// checks; an empty service token disables service callers.
+func NewMiddleware(verifier *JWTManager, denylist *Denylist, serviceToken string, log *logger.Logger) *Middleware {
+	return &Middleware{
+		verifier:     verifier,
+		denylist:     denylist,
+		serviceToken: serviceToken,
+		log:          log,
+	}
+}
+
+// RequireAuth admits any authenticated caller, player or service, and adds
+// it to the request context
+func (m *Middleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, appErr := m.authenticate(r)
+		if appErr != nil {
+			m.respondError(w, appErr)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), callerKey, caller)))
+	}
+}
+
+// OptionalAuth adds the caller to the request context when it sent
+// credentials, for public routes that show callers more. Bad credentials are
+// still rejected.
+func (m *Middleware) OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" && r.Header.Get(ServiceTokenHeader) == "" {
+			next(w, r)
+			return
+		}
+		m.RequireAuth(next)(w, r)
+	}
+}
+
+// RequireScope admits callers holding scope
+func (m *Middleware) RequireScope(scope Scope, next http.HandlerFunc) http.HandlerFunc {
+	return m.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		caller, _ := CallerFromContext(r.Context())
+		if !caller.HasScope(scope) {
+			m.respondError(w, errors.NewForbiddenError("Insufficient permissions"))
+			return
+		}
+		next(w, r)
+	})
+}
+
+// authenticate identifies the caller. Services are trusted with admin
+// operations, as they were when internal-only routes checked the token alone.
+func (m *Middleware) authenticate(r *http.Request) (*Caller, *errors.AppError) {
+	if IsInternalRequest(r, m.serviceToken) {
+		return &Caller{Role: RoleService, Scopes: RoleService.Scopes()}, nil
+	}
+
+	token, appErr := bearerToken(r)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	claims, err := m.verifier.ValidateToken(token, AccessToken)
+	if err != nil {
+		return nil, errors.NewUnauthorizedError("Invalid or expired token")
+	}
+
+	// Reject tokens revoked by logout. A denylist outage fails open:
+	// tokens are short-lived and refresh tokens are checked in the database.
+	if m.denylist != nil {
+		revoked, err := m.denylist.IsRevoked(r.Context(), claims)
+		if err != nil {
+			m.log.Error("Denylist check failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		if revoked {
+			return nil, errors.NewUnauthorizedError("Token has been revoked")
+		}
+	}
+
+	return &Caller{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		Claims:   claims,
+		Role:     claims.Role,
+		Scopes:   claims.Scopes(),
+		Guest:    claims.Guest,
+	}, nil
+}
+
+// bearerToken extracts the access token from the Authorization header.
+// Browsers can't set headers on WebSocket handshakes, so those may pass it
+// as the access_token query parameter instead.
+func bearerToken(r *http.Request) (string, *errors.AppError) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			if token := r.URL.Query().Get("access_token"); token != "" {
+				return token, nil
+			}
+		}
+		return "", errors.NewUnauthorizedError("Missing authorization header")
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", errors.NewUnauthorizedError("Invalid authorization header format")
+	}
+	return parts[1], nil
+}
+
+func (m *Middleware) respondError(w http.ResponseWriter, appErr *errors.AppError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(appErr.HTTPStatus)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    appErr.Code,
+			"message": appErr.Message,
+		},
+	})
+}
@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"sync"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -25,11 +27,34 @@ func HashPassword(password string) (string, error) {
 	return string(hashedBytes), nil
 }
 
-// ComparePassword compares a password with its hash
+// ErrNoPassword is returned for accounts without a password, such as those
+// created by logging in with an identity provider
+var ErrNoPassword = errors.New("account has no password")
+
+// ComparePassword compares a password with its hash. An empty hash never
+// matches, and takes as long to reject as a wrong password.
 func ComparePassword(hashedPassword, password string) error {
+	if hashedPassword == "" {
+		SimulatePasswordCompare(password)
+		return ErrNoPassword
+	}
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// SimulatePasswordCompare spends as long as ComparePassword does, for logins
+// to unknown accounts, so response times don't reveal which emails exist
+func SimulatePasswordCompare(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("cipher-clash-dummy-password"), bcryptCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
 // ValidatePasswordStrength validates password strength
 func ValidatePasswordStrength(password string) error {
 	if len(password) < 8 {
@@ -0,0 +1,50 @@
+package auth
+
+// Role is what a user account is allowed to do. Access tokens carry it, so a
+// role change only reaches other services once the user's tokens are
+// reissued.
+type Role string
+
+const (
+	RolePlayer    Role = "player"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+	RoleService   Role = "service" // Accounts used by automation, trusted like the service token
+)
+
+// ParseRole validates a role name
+func ParseRole(name string) (Role, bool) {
+	switch role := Role(name); role {
+	case RolePlayer, RoleModerator, RoleAdmin, RoleService:
+		return role, true
+	}
+	return "", false
+}
+
+// Scopes returns what holders of the role may do. Each staff role includes
+// the scopes of the roles below it.
+func (r Role) Scopes() []Scope {
+	switch r {
+	case RoleModerator:
+		return []Scope{ScopePlayer, ScopeModerator}
+	case RoleAdmin:
+		return []Scope{ScopePlayer, ScopeModerator, ScopeAdmin}
+	case RoleService:
+		return []Scope{ScopeService, ScopeModerator, ScopeAdmin}
+	default:
+		return []Scope{ScopePlayer}
+	}
+}
+
+// Outranks reports whether r may manage accounts holding other, e.g. ban
+// them. Moderators manage players; admins and services manage everyone.
+func (r Role) Outranks(other Role) bool {
+	switch r {
+	case RoleAdmin, RoleService:
+		return true
+	case RoleModerator:
+		return other == RolePlayer || other == ""
+	default:
+		return false
+	}
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// ServiceTokenHeader carries the shared secret that identifies calls between
+// services
+const ServiceTokenHeader = "X-Service-Token"
+
+// IsInternalRequest reports whether r was made by another service. An empty
+// service token trusts nobody, so unconfigured deployments fail closed.
+func IsInternalRequest(r *http.Request, serviceToken string) bool {
+	if serviceToken == "" {
+		return false
+	}
+	provided := r.Header.Get(ServiceTokenHeader)
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(serviceToken)) == 1
+}
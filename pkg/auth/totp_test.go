@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of RFC 6238 appendix B, "12345678901234567890",
+// in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B (SHA-1), truncated to the last six of the eight digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+			if !ok {
+				t.Fatalf("code %s rejected at %d", tt.code, tt.unix)
+			}
+			if want := tt.unix / 30; step != want {
+				t.Errorf("step = %d, want %d", step, want)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		unix     int64
+		wantOK   bool
+		wantStep int64
+	}{
+		{"previous step within skew", rfc6238Secret, "287082", 89, true, 1},
+		{"next step within skew", rfc6238Secret, "287082", 29, true, 1},
+		{"two steps late", rfc6238Secret, "287082", 120, false, 0},
+		{"surrounding spaces", rfc6238Secret, " 287082 ", 59, true, 1},
+		{"lowercase secret", strings.ToLower(rfc6238Secret), "287082", 59, true, 1},
+		{"wrong code", rfc6238Secret, "287083", 59, false, 0},
+		{"too short", rfc6238Secret, "28708", 59, false, 0},
+		{"eight digits", rfc6238Secret, "94287082", 59, false, 0},
+		{"invalid secret", "not base32!", "287082", 59, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(tt.secret, tt.code, time.Unix(tt.unix, 0))
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(key) != totpSecretSize {
+		t.Fatalf("secret %q decodes to %d bytes (%v), want %d", secret, len(key), err, totpSecretSize)
+	}
+
+	now := time.Now()
+	code := totpCode(key, now.Unix()/30)
+	if _, ok := ValidateTOTP(secret, code, now); !ok {
+		t.Error("a fresh secret rejects its own current code")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 14 || code[4] != '-' || code[9] != '-' {
+			t.Errorf("code %q isn't formatted xxxx-xxxx-xxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+
+	tests := []struct {
+		typed string
+		want  string
+	}{
+		{"k7qm-2xfp-9bnc", "k7qm2xfp9bnc"},
+		{" K7QM-2XFP-9BNC ", "k7qm2xfp9bnc"},
+		{"k7qm 2xfp 9bnc", "k7qm2xfp9bnc"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRecoveryCode(tt.typed); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.typed, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("Cipher Clash", "ada@example.com", rfc6238Secret)
+	for _, part := range []string{"otpauth://totp/", "secret=" + rfc6238Secret, "issuer=Cipher+Clash", "digits=6", "period=30"} {
+		if !strings.Contains(uri, part) {
+			t.Errorf("URI %q lacks %q", uri, part)
+		}
+	}
+}
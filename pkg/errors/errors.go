@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // AppError represents application-specific errors
@@ -34,6 +35,7 @@ const (
 	ErrInvalidCredentials = "INVALID_CREDENTIALS"
 	ErrTokenExpired       = "TOKEN_EXPIRED"
 	ErrTokenInvalid       = "TOKEN_INVALID"
+	ErrAccountLocked      = "ACCOUNT_LOCKED"
 
 	// User Management
 	ErrUserNotFound      = "USER_NOT_FOUND"
@@ -156,6 +158,28 @@ func NewRateLimitError() *AppError {
 	}
 }
 
+// NewTooManyAttemptsError rejects a retry that comes before retryAfter has passed
+func NewTooManyAttemptsError(retryAfter time.Duration) *AppError {
+	seconds := int(retryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &AppError{
+		Code:       ErrRateLimitExceeded,
+		Message:    fmt.Sprintf("Too many failed attempts. Try again in %d seconds", seconds),
+		HTTPStatus: http.StatusTooManyRequests,
+	}
+}
+
+// NewAccountLockedError rejects logins to a temporarily locked account
+func NewAccountLockedError() *AppError {
+	return &AppError{
+		Code:       ErrAccountLocked,
+		Message:    "Account temporarily locked after repeated failed logins. Try again later or reset your password",
+		HTTPStatus: http.StatusLocked,
+	}
+}
+
 // NewInternalError creates a generic internal server error
 func NewInternalError(message string) *AppError {
 	return &AppError{
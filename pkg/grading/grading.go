@@ -0,0 +1,177 @@
+package grading
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultThreshold is the accuracy needed to pass in modes without their own
+// threshold: the solution must be exact
+const DefaultThreshold = 1.0
+
+// maxGradedRunes bounds the alignment work a single submission can cause
+const maxGradedRunes = 2000
+
+// Thresholds map a game mode to the accuracy (0-1) a solution needs to pass
+type Thresholds map[string]float64
+
+// DefaultThresholds allows partial credit only in modes built around it
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		"ACCURACY":  0.8,  // Practice accuracy mode scores how close you got
+		"SPEED_RUN": 0.95, // A typo under time pressure still counts
+		"BLITZ":     0.95,
+	}
+}
+
+// ParseThresholds overlays "MODE=0.9,OTHER=1" style overrides on the defaults
+func ParseThresholds(raw string) (Thresholds, error) {
+	thresholds := DefaultThresholds()
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		mode, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("threshold %q is not MODE=VALUE", entry)
+		}
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			return nil, fmt.Errorf("threshold for %s must be in (0, 1]", mode)
+		}
+		thresholds[strings.TrimSpace(mode)] = threshold
+	}
+	return thresholds, nil
+}
+
+// For returns the pass threshold of a game mode
+func (t Thresholds) For(mode string) float64 {
+	if threshold, ok := t[mode]; ok {
+		return threshold
+	}
+	return DefaultThreshold
+}
+
+// Result is a submission graded against the expected plaintext. The masks
+// follow the submission rather than the answer so they reveal nothing the
+// player didn't type.
+type Result struct {
+	Accuracy     float64 `json:"accuracy"` // 0-1, from the edit distance over the longer text
+	Exact        bool    `json:"exact"`
+	Passed       bool    `json:"passed"`
+	EditDistance int     `json:"edit_distance"`
+	CharMask     []bool  `json:"char_mask"` // Per submitted character: lines up with the answer (whitespace always true)
+	WordMask     []bool  `json:"word_mask"` // Per submitted word: correct and in place
+	WordsCorrect int     `json:"words_correct"`
+	WordsTotal   int     `json:"words_total"` // Words in the expected answer
+}
+
+// Grade aligns a submission with the expected plaintext, ignoring case and
+// whitespace, and passes it if its accuracy reaches threshold
+func Grade(submitted, expected string, threshold float64) *Result {
+	submittedRunes := truncate([]rune(submitted))
+
+	// Compare letters only, remembering where each sits in the submission
+	var positions []int
+	var submittedLetters []rune
+	for i, char := range submittedRunes {
+		if !unicode.IsSpace(char) {
+			positions = append(positions, i)
+			submittedLetters = append(submittedLetters, unicode.ToUpper(char))
+		}
+	}
+	var expectedLetters []rune
+	for _, char := range truncate([]rune(expected)) {
+		if !unicode.IsSpace(char) {
+			expectedLetters = append(expectedLetters, unicode.ToUpper(char))
+		}
+	}
+
+	distance, matched := align(submittedLetters, expectedLetters)
+
+	result := &Result{
+		EditDistance: distance,
+		CharMask:     make([]bool, len(submittedRunes)),
+	}
+	for i, char := range submittedRunes {
+		result.CharMask[i] = unicode.IsSpace(char)
+	}
+	for i, ok := range matched {
+		result.CharMask[positions[i]] = ok
+	}
+
+	longest := len(expectedLetters)
+	if len(submittedLetters) > longest {
+		longest = len(submittedLetters)
+	}
+	if len(expectedLetters) > 0 {
+		result.Accuracy = 1 - float64(distance)/float64(longest)
+	}
+	result.Exact = len(expectedLetters) > 0 && distance == 0
+	result.Passed = result.Exact || (len(expectedLetters) > 0 && result.Accuracy >= threshold)
+
+	submittedWords := strings.Fields(strings.ToUpper(string(submittedRunes)))
+	expectedWords := strings.Fields(strings.ToUpper(expected))
+	_, result.WordMask = align(submittedWords, expectedWords)
+	for _, ok := range result.WordMask {
+		if ok {
+			result.WordsCorrect++
+		}
+	}
+	result.WordsTotal = len(expectedWords)
+
+	return result
+}
+
+// align computes the Levenshtein distance between a and b and marks the
+// elements of a that the cheapest alignment keeps unchanged
+func align[T comparable](a, b []T) (int, []bool) {
+	costs := make([][]int, len(a)+1)
+	for i := range costs {
+		costs[i] = make([]int, len(b)+1)
+		costs[i][0] = i
+	}
+	for j := range costs[0] {
+		costs[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			substitution := costs[i-1][j-1]
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			costs[i][j] = min(substitution, costs[i-1][j]+1, costs[i][j-1]+1)
+		}
+	}
+
+	// Walk back along the cheapest path, preferring matches
+	matched := make([]bool, len(a))
+	i, j := len(a), len(b)
+	for i > 0 && j > 0 {
+		switch {
+		case a[i-1] == b[j-1] && costs[i][j] == costs[i-1][j-1]:
+			matched[i-1] = true
+			i, j = i-1, j-1
+		case costs[i][j] == costs[i-1][j-1]+1:
+			i, j = i-1, j-1
+		case costs[i][j] == costs[i-1][j]+1:
+			i--
+		default:
+			j--
+		}
+	}
+
+	return costs[len(a)][len(b)], matched
+}
+
+func truncate(runes []rune) []rune {
+	if len(runes) > maxGradedRunes {
+		return runes[:maxGradedRunes]
+	}
+	return runes
+}
@@ -0,0 +1,150 @@
+package grading
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestGrade(t *testing.T) {
+	tests := []struct {
+		name         string
+		submitted    string
+		expected     string
+		threshold    float64
+		accuracy     float64
+		exact        bool
+		passed       bool
+		distance     int
+		wordsCorrect int
+		wordsTotal   int
+	}{
+		{"exact", "hello world", "HELLO WORLD", 1, 1, true, true, 0, 2, 2},
+		{"whitespace ignored", "HELLOWORLD", "HELLO WORLD", 1, 1, true, true, 0, 0, 2},
+		{"typo fails exact mode", "HELLO WORLE", "HELLO WORLD", 1, 0.9, false, false, 1, 1, 2},
+		{"typo passes partial credit", "HELLO WORLE", "HELLO WORLD", 0.8, 0.9, false, true, 1, 1, 2},
+		{"extra letter", "HELLOO", "HELLO", 0.8, 1 - 1.0/6, false, true, 1, 0, 1},
+		{"empty submission", "", "ABC", 0.5, 0, false, false, 3, 0, 1},
+		{"empty answer", "", "", 0.5, 0, false, false, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Grade(tt.submitted, tt.expected, tt.threshold)
+			if math.Abs(result.Accuracy-tt.accuracy) > 1e-9 {
+				t.Errorf("Accuracy = %v, want %v", result.Accuracy, tt.accuracy)
+			}
+			if result.Exact != tt.exact {
+				t.Errorf("Exact = %v, want %v", result.Exact, tt.exact)
+			}
+			if result.Passed != tt.passed {
+				t.Errorf("Passed = %v, want %v", result.Passed, tt.passed)
+			}
+			if result.EditDistance != tt.distance {
+				t.Errorf("EditDistance = %d, want %d", result.EditDistance, tt.distance)
+			}
+			if result.WordsCorrect != tt.wordsCorrect || result.WordsTotal != tt.wordsTotal {
+				t.Errorf("words = %d/%d, want %d/%d", result.WordsCorrect, result.WordsTotal, tt.wordsCorrect, tt.wordsTotal)
+			}
+			if len(result.CharMask) != len([]rune(tt.submitted)) {
+				t.Errorf("CharMask has %d entries for %d submitted characters", len(result.CharMask), len([]rune(tt.submitted)))
+			}
+		})
+	}
+}
+
+func TestGradeMasksFollowSubmission(t *testing.T) {
+	tests := []struct {
+		name      string
+		submitted string
+		expected  string
+		charMask  []bool
+		wordMask  []bool
+	}{
+		{
+			name:      "wrong last letter",
+			submitted: "HI THERE",
+			expected:  "HI THERF",
+			charMask:  []bool{true, true, true, true, true, true, true, false},
+			wordMask:  []bool{true, false},
+		},
+		{
+			name:      "missing letter",
+			submitted: "CAT",
+			expected:  "CART",
+			charMask:  []bool{true, true, true},
+			wordMask:  []bool{false},
+		},
+		{
+			name:      "extra word",
+			submitted: "A B C",
+			expected:  "A C",
+			charMask:  []bool{true, true, false, true, true},
+			wordMask:  []bool{true, false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Grade(tt.submitted, tt.expected, 1)
+			if !reflect.DeepEqual(result.CharMask, tt.charMask) {
+				t.Errorf("CharMask = %v, want %v", result.CharMask, tt.charMask)
+			}
+			if !reflect.DeepEqual(result.WordMask, tt.wordMask) {
+				t.Errorf("WordMask = %v, want %v", result.WordMask, tt.wordMask)
+			}
+		})
+	}
+}
+
+func TestGradeBoundsWork(t *testing.T) {
+	long := make([]rune, maxGradedRunes+500)
+	for i := range long {
+		long[i] = 'A'
+	}
+
+	result := Grade(string(long), string(long), 1)
+	if !result.Exact {
+		t.Error("identical long texts should grade as exact")
+	}
+	if len(result.CharMask) != maxGradedRunes {
+		t.Errorf("CharMask has %d entries, want %d", len(result.CharMask), maxGradedRunes)
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{"empty keeps defaults", "", map[string]float64{"ACCURACY": 0.8, "SPEED_RUN": 0.95, "TIMED": 1}, false},
+		{"overrides and adds", "ACCURACY=0.5, TIMED = 0.9", map[string]float64{"ACCURACY": 0.5, "TIMED": 0.9, "BLITZ": 0.95}, false},
+		{"trailing comma", "RANKED=1,", map[string]float64{"RANKED": 1}, false},
+		{"missing value", "ACCURACY", nil, true},
+		{"zero", "ACCURACY=0", nil, true},
+		{"above one", "ACCURACY=1.5", nil, true},
+		{"not a number", "ACCURACY=high", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thresholds, err := ParseThresholds(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseThresholds(%q) succeeded, want error", tt.raw)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseThresholds(%q): %v", tt.raw, err)
+			}
+			for mode, want := range tt.want {
+				if got := thresholds.For(mode); got != want {
+					t.Errorf("For(%s) = %v, want %v", mode, got, want)
+				}
+			}
+		})
+	}
+}
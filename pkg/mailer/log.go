@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+)
+
+// LogMailer stands in for a mail server in development and tests. Messages
+// are logged, and appended as JSON lines to an outbox file when one is set,
+// where links in them can be picked up.
+type LogMailer struct {
+	path string
+	log  *logger.Logger
+	mu   sync.Mutex
+}
+
+// NewLogMailer creates a log mailer. An empty path only logs.
+func NewLogMailer(path string, log *logger.Logger) *LogMailer {
+	return &LogMailer{path: path, log: log}
+}
+
+// Send records a message
+func (m *LogMailer) Send(ctx context.Context, msg *Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	m.log.Info("Mail sent", map[string]interface{}{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+	if m.path == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(struct {
+		*Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now()})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,89 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swarit-1/cipher-clash/pkg/config"
+)
+
+// SMTPMailer sends mail through an SMTP relay. net/smtp upgrades to TLS
+// when the server offers STARTTLS, and only sends credentials over TLS or
+// to localhost.
+type SMTPMailer struct {
+	addr string
+	host string
+	from *mail.Address
+	auth smtp.Auth
+}
+
+// NewSMTPMailer creates an SMTP mailer. Credentials are optional, for
+// relays that trust the sender's network.
+func NewSMTPMailer(cfg config.MailConfig) (*SMTPMailer, error) {
+	if cfg.SMTPHost == "" {
+		return nil, fmt.Errorf("SMTP_HOST is required for the smtp mail driver")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+
+	mailer := &SMTPMailer{
+		addr: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		host: cfg.SMTPHost,
+		from: from,
+	}
+	if cfg.SMTPUsername != "" {
+		mailer.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return mailer, nil
+}
+
+// Send delivers a message. net/smtp can't be cancelled, so ctx only stops
+// messages that haven't started sending.
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var data strings.Builder
+	fmt.Fprintf(&data, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&data, "To: %s\r\n", to.String())
+	fmt.Fprintf(&data, "Subject: %s\r\n", mimeHeader(msg.Subject))
+	fmt.Fprintf(&data, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	data.WriteString("MIME-Version: 1.0\r\n")
+	data.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	data.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	data.WriteString("\r\n")
+	body := strings.ReplaceAll(msg.Body, "\r\n", "\n")
+	data.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from.Address, []string{to.Address}, []byte(data.String())); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// mimeHeader encodes non-ASCII header text (RFC 2047)
+func mimeHeader(text string) string {
+	for _, r := range text {
+		if r > 127 {
+			return mime.QEncoding.Encode("UTF-8", text)
+		}
+	}
+	return text
+}
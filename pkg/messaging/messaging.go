@@ -20,6 +20,7 @@ const (
 	EventAchievementUnlocked EventType = "achievement.unlocked"
 	EventPlayerJoinedQueue EventType = "queue.player_joined"
 	EventPlayerLeftQueue  EventType = "queue.player_left"
+	EventUserDeleted      EventType = "user.deleted"
 )
 
 // Event represents a message event
@@ -230,6 +231,7 @@ const (
 	ExchangeMatches      = "matches"
 	ExchangeAchievements = "achievements"
 	ExchangeQueue        = "matchmaking"
+	ExchangeUsers        = "users"
 )
 
 // InitializeExchanges sets up common exchanges
@@ -238,6 +240,7 @@ func InitializeExchanges(publisher *Publisher) error {
 		ExchangeMatches:      "topic",
 		ExchangeAchievements: "fanout",
 		ExchangeQueue:        "topic",
+		ExchangeUsers:        "topic",
 	}
 
 	for name, kind := range exchanges {
@@ -0,0 +1,87 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/config"
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+)
+
+// userDeletedTimeout bounds one run of a UserDeletedHandler
+const userDeletedTimeout = 30 * time.Second
+
+// UserDeletedHandler erases what a service keeps about a user who deleted
+// their account. Failed events are redelivered, so it must be safe to run
+// more than once for the same user.
+type UserDeletedHandler func(ctx context.Context, userID uuid.UUID) error
+
+// UserDeletedEvent is published by the auth service once an account is
+// deleted
+func UserDeletedEvent(userID uuid.UUID) Event {
+	return Event{
+		Type: EventUserDeleted,
+		Data: map[string]interface{}{
+			"user_id": userID.String(),
+		},
+	}
+}
+
+// ListenUserDeleted runs handler for every user.deleted event. Each service
+// consumes from its own durable queue, so events published while it is down
+// wait for it to come back.
+func ListenUserDeleted(cfg config.RabbitMQConfig, service string, handler UserDeletedHandler, log *logger.Logger) (*Subscriber, error) {
+	sub, err := NewSubscriber(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	queueName := service + ".user_deleted"
+	if err := sub.channel.ExchangeDeclare(ExchangeUsers, "topic", true, false, false, false, nil); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("failed to declare exchange %s: %w", ExchangeUsers, err)
+	}
+	if _, err := sub.DeclareQueue(queueName); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("failed to declare queue %s: %w", queueName, err)
+	}
+	if err := sub.BindQueue(queueName, ExchangeUsers, string(EventUserDeleted)); err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("failed to bind queue %s: %w", queueName, err)
+	}
+
+	err = sub.Subscribe(queueName, func(event Event) error {
+		if event.Type != EventUserDeleted {
+			return nil
+		}
+
+		raw, _ := event.Data["user_id"].(string)
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			// Redelivering a malformed event would never help
+			log.Error("Dropping user.deleted event without a valid user ID", map[string]interface{}{
+				"user_id": raw,
+			})
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), userDeletedTimeout)
+		defer cancel()
+		if err := handler(ctx, userID); err != nil {
+			return err
+		}
+
+		log.Info("Erased data of deleted user", map[string]interface{}{
+			"user_id": userID.String(),
+		})
+		return nil
+	})
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	return sub, nil
+}
@@ -0,0 +1,343 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/swarit-1/cipher-clash/pkg/config"
+	"github.com/swarit-1/cipher-clash/pkg/oidc"
+	"github.com/swarit-1/cipher-clash/pkg/oidc/mockoidc"
+)
+
+const (
+	testClientID     = "cipher-clash"
+	testClientSecret = "s3cret"
+	testRedirectURL  = "https://app.example.com/oidc/callback"
+)
+
+// startProvider serves a mock provider at an httptest server's address
+func startProvider(t *testing.T) (*mockoidc.Provider, *httptest.Server) {
+	t.Helper()
+	var handler http.Handler
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	mock, err := mockoidc.New(srv.URL, testClientID, testClientSecret)
+	if err != nil {
+		t.Fatalf("mockoidc.New: %v", err)
+	}
+	handler = mock.Handler()
+	return mock, srv
+}
+
+func newProvider(t *testing.T, issuer string) *oidc.Provider {
+	t.Helper()
+	provider, err := oidc.NewProvider(config.OIDCProviderConfig{
+		Name:         "mock",
+		Issuer:       issuer,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+	})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	return provider
+}
+
+// authorize follows the authorization URL as the browser would and returns
+// the code and state the provider redirects back with
+func authorize(t *testing.T, provider *oidc.Provider, state, nonce, challenge string) (code, returnedState string) {
+	t.Helper()
+	authURL, err := provider.AuthorizationURL(context.Background(), state, nonce, challenge)
+	if err != nil {
+		t.Fatalf("AuthorizationURL: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("GET %s: %v", authURL, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("bad redirect: %v", err)
+	}
+	if !strings.HasPrefix(location.String(), testRedirectURL+"?") {
+		t.Fatalf("redirected to %s, want %s", location, testRedirectURL)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestPKCE(t *testing.T) {
+	// RFC 7636 appendix B
+	if got := oidc.S256Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("S256Challenge = %s, want the RFC 7636 challenge", got)
+	}
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE: %v", err)
+	}
+	if challenge != oidc.S256Challenge(verifier) {
+		t.Error("NewPKCE's challenge doesn't match its verifier")
+	}
+	// RFC 7636 4.1: 43 to 128 characters
+	if len(verifier) < 43 || len(verifier) > 128 {
+		t.Errorf("verifier is %d characters long", len(verifier))
+	}
+	if other, _, _ := oidc.NewPKCE(); other == verifier {
+		t.Error("NewPKCE returned the same verifier twice")
+	}
+}
+
+func TestAuthorizationURL(t *testing.T) {
+	_, srv := startProvider(t)
+	provider := newProvider(t, srv.URL)
+
+	authURL, err := provider.AuthorizationURL(context.Background(), "the-state", "the-nonce", "the-challenge")
+	if err != nil {
+		t.Fatalf("AuthorizationURL: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	if want := srv.URL + "/authorize"; !strings.HasPrefix(authURL, want+"?") {
+		t.Errorf("URL %s isn't at %s", authURL, want)
+	}
+
+	tests := []struct {
+		param string
+		want  string
+	}{
+		{"response_type", "code"},
+		{"client_id", testClientID},
+		{"redirect_uri", testRedirectURL},
+		{"scope", "openid email profile"},
+		{"state", "the-state"},
+		{"nonce", "the-nonce"},
+		{"code_challenge", "the-challenge"},
+		{"code_challenge_method", "S256"},
+	}
+	for _, tt := range tests {
+		if got := parsed.Query().Get(tt.param); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.param, got, tt.want)
+		}
+	}
+}
+
+func TestExchange(t *testing.T) {
+	mock, srv := startProvider(t)
+	mock.SetUser(mockoidc.User{
+		Subject:           "user-42",
+		Email:             "ada@example.com",
+		EmailVerified:     true,
+		Name:              "Ada Lovelace",
+		PreferredUsername: "ada",
+	})
+	provider := newProvider(t, srv.URL)
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE: %v", err)
+	}
+	code, state := authorize(t, provider, "the-state", "the-nonce", challenge)
+	if state != "the-state" {
+		t.Errorf("state came back as %q", state)
+	}
+
+	identity, err := provider.Exchange(context.Background(), code, verifier, "the-nonce")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := oidc.Identity{
+		Subject:           "user-42",
+		Email:             "ada@example.com",
+		EmailVerified:     true,
+		Name:              "Ada Lovelace",
+		PreferredUsername: "ada",
+	}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+
+	// Codes are single use
+	if _, err := provider.Exchange(context.Background(), code, verifier, "the-nonce"); err == nil {
+		t.Error("a redeemed code was accepted again")
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		nonce    string // Sent with the authorization request
+		verifier func(verifier string) string
+		code     func(code string) string
+		expected string // Nonce the callback expects
+	}{
+		{
+			name:     "wrong verifier",
+			nonce:    "n",
+			verifier: func(string) string { v, _, _ := oidc.NewPKCE(); return v },
+			expected: "n",
+		},
+		{
+			name:     "no verifier",
+			nonce:    "n",
+			verifier: func(string) string { return "" },
+			expected: "n",
+		},
+		{
+			name:     "unknown code",
+			nonce:    "n",
+			code:     func(string) string { return "made-up" },
+			expected: "n",
+		},
+		{
+			name:     "nonce of another attempt",
+			nonce:    "n",
+			expected: "other",
+		},
+		{
+			name:     "no nonce",
+			nonce:    "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, srv := startProvider(t)
+			provider := newProvider(t, srv.URL)
+
+			verifier, challenge, err := oidc.NewPKCE()
+			if err != nil {
+				t.Fatalf("NewPKCE: %v", err)
+			}
+			code, _ := authorize(t, provider, "s", tt.nonce, challenge)
+			if tt.verifier != nil {
+				verifier = tt.verifier(verifier)
+			}
+			if tt.code != nil {
+				code = tt.code(code)
+			}
+
+			if identity, err := provider.Exchange(context.Background(), code, verifier, tt.expected); err == nil {
+				t.Errorf("Exchange succeeded with %+v, want error", identity)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsForeignIDTokens(t *testing.T) {
+	// The impostor claims the real provider's issuer and client but signs
+	// with its own key, which the real provider's JWKS doesn't publish
+	var real, impostor *mockoidc.Provider
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/authorize" || r.URL.Path == "/token" {
+			impostor.Handler().ServeHTTP(w, r)
+			return
+		}
+		real.Handler().ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	var err error
+	if real, err = mockoidc.New(srv.URL, testClientID, testClientSecret); err != nil {
+		t.Fatalf("mockoidc.New: %v", err)
+	}
+	if impostor, err = mockoidc.New(srv.URL, testClientID, testClientSecret); err != nil {
+		t.Fatalf("mockoidc.New: %v", err)
+	}
+	provider := newProvider(t, srv.URL)
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE: %v", err)
+	}
+	code, _ := authorize(t, provider, "s", "n", challenge)
+	if _, err := provider.Exchange(context.Background(), code, verifier, "n"); err == nil {
+		t.Error("ID token signed by an unpublished key accepted")
+	}
+}
+
+func TestDiscoveryRejects(t *testing.T) {
+	mock, srv := startProvider(t)
+
+	tests := []struct {
+		name   string
+		issuer string
+	}{
+		// Discovery must name exactly the configured issuer
+		{"issuer with trailing slash", srv.URL + "/"},
+		{"issuer at another path", srv.URL + "/tenant"},
+		{"unreachable provider", "http://127.0.0.1:1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newProvider(t, tt.issuer)
+			if _, err := provider.AuthorizationURL(context.Background(), "s", "n", "c"); err == nil {
+				t.Error("AuthorizationURL succeeded, want a discovery error")
+			}
+		})
+	}
+
+	// A client the provider doesn't know can't redeem codes
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatalf("NewPKCE: %v", err)
+	}
+	code, _ := authorize(t, newProvider(t, srv.URL), "s", "n", challenge)
+	wrongSecret, err := oidc.NewProvider(config.OIDCProviderConfig{
+		Name:         "mock",
+		Issuer:       mock.Issuer,
+		ClientID:     testClientID,
+		ClientSecret: "wrong",
+		RedirectURL:  testRedirectURL,
+	})
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	if _, err := wrongSecret.Exchange(context.Background(), code, verifier, "n"); err == nil {
+		t.Error("code redeemed with the wrong client secret")
+	}
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.OIDCProviderConfig
+		wantErr bool
+	}{
+		{"complete", config.OIDCProviderConfig{Name: "google", Issuer: "https://accounts.google.com", ClientID: "id", RedirectURL: testRedirectURL}, false},
+		{"no name", config.OIDCProviderConfig{Issuer: "https://accounts.google.com", ClientID: "id", RedirectURL: testRedirectURL}, true},
+		{"no issuer", config.OIDCProviderConfig{Name: "google", ClientID: "id", RedirectURL: testRedirectURL}, true},
+		{"no client", config.OIDCProviderConfig{Name: "google", Issuer: "https://accounts.google.com", RedirectURL: testRedirectURL}, true},
+		{"no redirect URL", config.OIDCProviderConfig{Name: "google", Issuer: "https://accounts.google.com", ClientID: "id"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := oidc.NewProvider(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewProvider error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
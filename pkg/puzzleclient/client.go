@@ -0,0 +1,283 @@
+package puzzleclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/grading"
+)
+
+// defaultTimeout bounds every call; puzzle generation grades candidates with
+// solvers, so it is the slowest endpoint
+const defaultTimeout = 10 * time.Second
+
+// Client calls the puzzle engine on behalf of other services. Every request
+// carries the shared service token, which unlocks internal-only fields such
+// as the correct answer after a submission.
+type Client struct {
+	baseURL      string
+	serviceToken string
+	httpClient   *http.Client
+}
+
+// New creates a puzzle engine client
+func New(baseURL, serviceToken string) *Client {
+	return &Client{
+		baseURL:      baseURL,
+		serviceToken: serviceToken,
+		httpClient:   &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Puzzle is a puzzle as served to players, without its answer or key
+type Puzzle struct {
+	ID                  string  `json:"id"`
+	CipherType          string  `json:"cipher_type"`
+	Difficulty          int     `json:"difficulty"`
+	EncryptedText       string  `json:"encrypted_text"`
+	Language            string  `json:"language"`
+	Hint                string  `json:"hint,omitempty"`
+	Layers              int     `json:"layers,omitempty"`
+	HintsAvailable      int     `json:"hints_available"`
+	EmpiricalDifficulty float64 `json:"empirical_difficulty,omitempty"`
+}
+
+// GenerateRequest asks for a new puzzle
+type GenerateRequest struct {
+	CipherType string `json:"cipher_type,omitempty"` // Empty for random
+	Difficulty int    `json:"difficulty"`
+	PlayerELO  int    `json:"player_elo,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	Theme      string `json:"theme,omitempty"`
+	Language   string `json:"language,omitempty"`
+}
+
+// MatchRequest asks for the ordered puzzle set of a match
+type MatchRequest struct {
+	Count         int      `json:"count"`
+	MinDifficulty int      `json:"min_difficulty,omitempty"` // 0 derives the range from AvgPlayerELO
+	MaxDifficulty int      `json:"max_difficulty,omitempty"`
+	CipherTypes   []string `json:"cipher_types,omitempty"`
+	AvgPlayerELO  int      `json:"avg_player_elo,omitempty"`
+	PlayerIDs     []string `json:"player_ids,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	MatchSeed     *int64   `json:"match_seed,omitempty"` // Must be secret; never take one from a player
+}
+
+type matchResponse struct {
+	Puzzles []*Puzzle `json:"puzzles"`
+}
+
+// CustomPuzzle is a player-made puzzle found by its share code
+type CustomPuzzle struct {
+	Puzzle
+	ShareCode   string    `json:"share_code"`
+	OwnerID     string    `json:"owner_id"`
+	Title       string    `json:"title,omitempty"`
+	PlayCount   int       `json:"play_count"`
+	RatingCount int       `json:"rating_count"`
+	AvgRating   float64   `json:"avg_rating"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type customPuzzleRequest struct {
+	ShareCode string `json:"share_code"`
+	UserID    string `json:"user_id"`
+}
+
+// ValidateRequest submits a solution
+type ValidateRequest struct {
+	PuzzleID    string `json:"puzzle_id"`
+	Solution    string `json:"solution"`
+	SolveTimeMs int64  `json:"solve_time_ms"`
+	Stage       int    `json:"stage,omitempty"`
+	UserID      string `json:"user_id,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+
+	// RevealAnswer returns the correct answer with the result, for showing
+	// players what they missed once their submission is final
+	RevealAnswer bool `json:"reveal_answer,omitempty"`
+	// IncludeFeedback returns the per-character alignment with the answer.
+	// Only modes that teach rather than compete should ask for it.
+	IncludeFeedback bool `json:"include_feedback,omitempty"`
+}
+
+// ValidateResult is the graded outcome of a submission
+type ValidateResult struct {
+	IsCorrect       bool            `json:"is_correct"`
+	Score           int             `json:"score"`
+	Accuracy        float64         `json:"accuracy"` // 0-1
+	LayersRemaining int             `json:"layers_remaining,omitempty"`
+	HintsUsed       int             `json:"hints_used"`
+	Feedback        *grading.Result `json:"feedback,omitempty"`       // Only when IncludeFeedback was set
+	CorrectAnswer   string          `json:"correct_answer,omitempty"` // Only when RevealAnswer was set
+}
+
+// AnalyzeRequest asks for cryptanalysis of a ciphertext in a game mode
+type AnalyzeRequest struct {
+	Ciphertext   string   `json:"ciphertext"`
+	Mode         string   `json:"mode"`
+	Language     string   `json:"language,omitempty"`
+	Tools        []string `json:"tools,omitempty"` // Defaults to every tool the mode allows
+	Candidates   []string `json:"candidates,omitempty"`
+	MaxKeyLength int      `json:"max_key_length,omitempty"`
+}
+
+// Hint is one rung of a puzzle's hint ladder
+type Hint struct {
+	Level int    `json:"level"`
+	Kind  string `json:"kind"`
+	Text  string `json:"text"`
+}
+
+type hintRequest struct {
+	PuzzleID string `json:"puzzle_id"`
+	UserID   string `json:"user_id"`
+}
+
+// HintResult is the part of a hint ladder a user has unlocked
+type HintResult struct {
+	PuzzleID       string `json:"puzzle_id"`
+	Hints          []Hint `json:"hints"`
+	HintsUsed      int    `json:"hints_used"`
+	HintsRemaining int    `json:"hints_remaining"`
+}
+
+// Error is an error response from the puzzle engine
+type Error struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("puzzle engine returned %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Generate creates a puzzle
+func (c *Client) Generate(ctx context.Context, req *GenerateRequest) (*Puzzle, error) {
+	var puzzle Puzzle
+	if err := c.do(ctx, http.MethodPost, "/api/v1/puzzle/generate", req, &puzzle); err != nil {
+		return nil, err
+	}
+	return &puzzle, nil
+}
+
+// GenerateMatch creates a match's puzzle set in play order
+func (c *Client) GenerateMatch(ctx context.Context, req *MatchRequest) ([]*Puzzle, error) {
+	var result matchResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/puzzle/match", req, &result); err != nil {
+		return nil, err
+	}
+	return result.Puzzles, nil
+}
+
+// GetPuzzle fetches a puzzle by ID
+func (c *Client) GetPuzzle(ctx context.Context, puzzleID string) (*Puzzle, error) {
+	var puzzle Puzzle
+	path := "/api/v1/puzzle/get?id=" + url.QueryEscape(puzzleID)
+	if err := c.do(ctx, http.MethodGet, path, nil, &puzzle); err != nil {
+		return nil, err
+	}
+	return &puzzle, nil
+}
+
+// GetCustomPuzzle looks up a custom puzzle by share code without counting a play
+func (c *Client) GetCustomPuzzle(ctx context.Context, shareCode string) (*CustomPuzzle, error) {
+	var puzzle CustomPuzzle
+	path := "/api/v1/puzzle/custom?code=" + url.QueryEscape(shareCode)
+	if err := c.do(ctx, http.MethodGet, path, nil, &puzzle); err != nil {
+		return nil, err
+	}
+	return &puzzle, nil
+}
+
+// PlayCustomPuzzle fetches a custom puzzle for a player about to solve it,
+// counting the play
+func (c *Client) PlayCustomPuzzle(ctx context.Context, shareCode, userID string) (*CustomPuzzle, error) {
+	req := &customPuzzleRequest{ShareCode: shareCode, UserID: userID}
+	var puzzle CustomPuzzle
+	if err := c.do(ctx, http.MethodPost, "/api/v1/puzzle/custom/play", req, &puzzle); err != nil {
+		return nil, err
+	}
+	return &puzzle, nil
+}
+
+// Validate grades a solution
+func (c *Client) Validate(ctx context.Context, req *ValidateRequest) (*ValidateResult, error) {
+	var result ValidateResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/puzzle/validate", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RequestHint unlocks the next hint of a puzzle for a user
+func (c *Client) RequestHint(ctx context.Context, puzzleID, userID string) (*HintResult, error) {
+	req := &hintRequest{PuzzleID: puzzleID, UserID: userID}
+	var result HintResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/puzzle/hint", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Analyze runs the cryptanalysis toolkit. The report is passed through as
+// the engine wrote it.
+func (c *Client) Analyze(ctx context.Context, req *AnalyzeRequest) (json.RawMessage, error) {
+	var report json.RawMessage
+	if err := c.do(ctx, http.MethodPost, "/api/v1/puzzle/toolkit", req, &report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set(auth.ServiceTokenHeader, c.serviceToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call puzzle engine: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		var envelope struct {
+			Error *Error `json:"error"`
+		}
+		envelope.Error = apiErr
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil || apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		return apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode puzzle engine response: %w", err)
+	}
+	return nil
+}
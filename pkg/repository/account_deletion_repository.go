@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// AccountDeletionRepository reads the outbox of deletions the other
+// services haven't been told about. UserRepository.Delete fills it.
+type AccountDeletionRepository struct {
+	db *db.DB
+}
+
+// NewAccountDeletionRepository creates a new account deletion repository
+func NewAccountDeletionRepository(database *db.DB) *AccountDeletionRepository {
+	return &AccountDeletionRepository{db: database}
+}
+
+// Pending returns the oldest deleted users whose user.deleted event hasn't
+// been published
+func (r *AccountDeletionRepository) Pending(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT user_id
+		FROM account_deletions
+		WHERE published_at IS NULL
+		ORDER BY requested_at
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	userIDs := []uuid.UUID{}
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return userIDs, nil
+}
+
+// MarkPublished records that a deletion's event went out
+func (r *AccountDeletionRepository) MarkPublished(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE account_deletions SET published_at = NOW() WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
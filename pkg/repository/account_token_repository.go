@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// AccountToken records an emailed single-use token. The token is signed and
+// never stored; its ID is enough to use it up.
+type AccountToken struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	Purpose   string       `json:"purpose"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// AccountTokenRepository handles account token database operations
+type AccountTokenRepository struct {
+	db *db.DB
+}
+
+// NewAccountTokenRepository creates a new account token repository
+func NewAccountTokenRepository(database *db.DB) *AccountTokenRepository {
+	return &AccountTokenRepository{db: database}
+}
+
+// Create stores a token
+func (r *AccountTokenRepository) Create(ctx context.Context, token *AccountToken) error {
+	query := `
+		INSERT INTO account_tokens (id, user_id, purpose, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query, token.ID, token.UserID, token.Purpose, token.ExpiresAt).Scan(&token.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// Consume uses up a live token of a user. It returns false when the token
+// is unknown, already used, invalidated or expired.
+func (r *AccountTokenRepository) Consume(ctx context.Context, id, userID uuid.UUID, purpose string) (bool, error) {
+	query := `
+		UPDATE account_tokens
+		SET used_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND purpose = $3
+			AND used_at IS NULL AND expires_at > NOW()
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID, purpose)
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// InvalidateAll uses up every live token of a user for a purpose, e.g. the
+// reset links still in their inbox once the password changed
+func (r *AccountTokenRepository) InvalidateAll(ctx context.Context, userID uuid.UUID, purpose string) error {
+	query := `
+		UPDATE account_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, purpose)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
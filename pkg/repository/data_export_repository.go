@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// Data export statuses
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// DataExport is a job exporting a user's data. The archive is loaded only
+// for downloads.
+type DataExport struct {
+	ID          uuid.UUID       `json:"id"`
+	UserID      uuid.UUID       `json:"user_id"`
+	Status      string          `json:"status"`
+	Archive     json.RawMessage `json:"-"`
+	Error       sql.NullString  `json:"error"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt sql.NullTime    `json:"completed_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+}
+
+// DataExportRepository handles data export database operations
+type DataExportRepository struct {
+	db *db.DB
+}
+
+// NewDataExportRepository creates a new data export repository
+func NewDataExportRepository(database *db.DB) *DataExportRepository {
+	return &DataExportRepository{db: database}
+}
+
+// Create starts a pending export, downloadable until ttl after it starts
+func (r *DataExportRepository) Create(ctx context.Context, userID uuid.UUID, ttl time.Duration) (*DataExport, error) {
+	query := `
+		INSERT INTO data_exports (user_id, status, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	export := &DataExport{
+		UserID:    userID,
+		Status:    ExportPending,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	err := r.db.QueryRowContext(ctx, query, userID, export.Status, export.ExpiresAt).Scan(&export.ID, &export.CreatedAt)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return export, nil
+}
+
+// Find returns one of a user's unexpired exports, with its archive when
+// withArchive is set
+func (r *DataExportRepository) Find(ctx context.Context, id, userID uuid.UUID, withArchive bool) (*DataExport, error) {
+	query := `
+		SELECT id, user_id, status, CASE WHEN $3 THEN archive END, error, created_at, completed_at, expires_at
+		FROM data_exports
+		WHERE id = $1 AND user_id = $2 AND expires_at > NOW()
+	`
+
+	export := &DataExport{}
+	var archive []byte
+	err := r.db.QueryRowContext(ctx, query, id, userID, withArchive).Scan(
+		&export.ID,
+		&export.UserID,
+		&export.Status,
+		&archive,
+		&export.Error,
+		&export.CreatedAt,
+		&export.CompletedAt,
+		&export.ExpiresAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Export not found")
+		}
+		return nil, errors.NewDatabaseError(err)
+	}
+	export.Archive = archive
+	return export, nil
+}
+
+// ListByUser returns a user's unexpired exports, newest first
+func (r *DataExportRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*DataExport, error) {
+	query := `
+		SELECT id, user_id, status, error, created_at, completed_at, expires_at
+		FROM data_exports
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	exports := []*DataExport{}
+	for rows.Next() {
+		export := &DataExport{}
+		err := rows.Scan(
+			&export.ID,
+			&export.UserID,
+			&export.Status,
+			&export.Error,
+			&export.CreatedAt,
+			&export.CompletedAt,
+			&export.ExpiresAt,
+		)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		exports = append(exports, export)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return exports, nil
+}
+
+// Complete stores a finished export's archive
+func (r *DataExportRepository) Complete(ctx context.Context, id uuid.UUID, archive []byte) error {
+	query := `
+		UPDATE data_exports
+		SET status = $2, archive = $3, completed_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, ExportReady, archive); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// Fail records why an export couldn't be built
+func (r *DataExportRepository) Fail(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `
+		UPDATE data_exports
+		SET status = $2, error = $3, completed_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, ExportFailed, reason); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// DeleteExpired removes exports past their download window, returning how
+// many went
+func (r *DataExportRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM data_exports WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, errors.NewDatabaseError(err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError(err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// UserIdentity links a user to their account at an OpenID Connect provider
+type UserIdentity struct {
+	ID          uuid.UUID      `json:"id"`
+	UserID      uuid.UUID      `json:"user_id"`
+	Provider    string         `json:"provider"`
+	Subject     string         `json:"-"`
+	Email       sql.NullString `json:"email"`
+	CreatedAt   time.Time      `json:"created_at"`
+	LastLoginAt sql.NullTime   `json:"last_login_at"`
+}
+
+// IdentityRepository handles linked identity database operations
+type IdentityRepository struct {
+	db *db.DB
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(database *db.DB) *IdentityRepository {
+	return &IdentityRepository{db: database}
+}
+
+// Find returns the identity of a provider's account
+func (r *IdentityRepository) Find(ctx context.Context, provider, subject string) (*UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+
+	identity := &UserIdentity{}
+	err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+		&identity.LastLoginAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Identity not linked")
+		}
+		return nil, errors.NewDatabaseError(err)
+	}
+	return identity, nil
+}
+
+// ListByUser returns a user's linked identities
+func (r *IdentityRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	identities := []*UserIdentity{}
+	for rows.Next() {
+		identity := &UserIdentity{}
+		err := rows.Scan(
+			&identity.ID,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+			&identity.LastLoginAt,
+		)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return identities, nil
+}
+
+// Link stores an identity. A provider account links to one user, and a user
+// links one account per provider.
+func (r *IdentityRepository) Link(ctx context.Context, identity *UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at, last_login_at
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		identity.UserID, identity.Provider, identity.Subject, identity.Email,
+	).Scan(&identity.ID, &identity.CreatedAt, &identity.LastLoginAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			if pqErr.Constraint == "user_identities_user_provider_key" {
+				return errors.NewInvalidInputError("An account at this provider is already linked")
+			}
+			return errors.NewInvalidInputError("This account is linked to another user")
+		}
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// Unlink removes a user's identity at a provider
+func (r *IdentityRepository) Unlink(ctx context.Context, userID uuid.UUID, provider string) error {
+	query := `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`
+
+	result, err := r.db.ExecContext(ctx, query, userID, provider)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	if rowsAffected == 0 {
+		return errors.NewNotFoundError("No account at this provider is linked")
+	}
+	return nil
+}
+
+// TouchLogin records a login through an identity
+func (r *IdentityRepository) TouchLogin(ctx context.Context, id uuid.UUID, email string) error {
+	query := `
+		UPDATE user_identities
+		SET last_login_at = NOW(), email = COALESCE(NULLIF($2, ''), email)
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, id, email); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
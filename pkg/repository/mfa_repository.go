@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// UserMFA is a user's TOTP enrollment. It protects logins once confirmed.
+type UserMFA struct {
+	UserID       uuid.UUID     `json:"user_id"`
+	Secret       string        `json:"-"`
+	ConfirmedAt  sql.NullTime  `json:"confirmed_at"`
+	LastUsedStep sql.NullInt64 `json:"-"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// Enabled reports whether the enrollment was confirmed
+func (m *UserMFA) Enabled() bool {
+	return m.ConfirmedAt.Valid
+}
+
+// MFARepository handles two-factor authentication database operations
+type MFARepository struct {
+	db *db.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(database *db.DB) *MFARepository {
+	return &MFARepository{db: database}
+}
+
+// Find returns a user's enrollment
+func (r *MFARepository) Find(ctx context.Context, userID uuid.UUID) (*UserMFA, error) {
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step, created_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+
+	mfa := &UserMFA{}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.Secret,
+		&mfa.ConfirmedAt,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Two-factor authentication is not set up")
+		}
+		return nil, errors.NewDatabaseError(err)
+	}
+	return mfa, nil
+}
+
+// Enroll stores a new, unconfirmed secret, replacing an earlier unconfirmed
+// one. It returns false if 2FA is already on.
+func (r *MFARepository) Enroll(ctx context.Context, userID uuid.UUID, secret string) (bool, error) {
+	query := `
+		INSERT INTO user_mfa (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = NULL, created_at = NOW()
+		WHERE user_mfa.confirmed_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// Confirm turns 2FA on with the first code's time step and the hashes of
+// the recovery codes shown to the user. It returns false if it was on
+// already.
+func (r *MFARepository) Confirm(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string) (bool, error) {
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE user_mfa
+			SET confirmed_at = NOW(), last_used_step = $2
+			WHERE user_id = $1 AND confirmed_at IS NULL
+		`, userID, step)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+	return true, nil
+}
+
+// UseStep records a code's time step as used. It returns false when a code
+// of that step or a later one was accepted already.
+func (r *MFARepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE user_mfa
+		SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL
+			AND (last_used_step IS NULL OR last_used_step < $2)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// UseRecoveryCode uses up a recovery code by its hash. It returns false when
+// the user has no unused code with that hash.
+func (r *MFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// ReplaceRecoveryCodes swaps a user's recovery codes for new ones
+func (r *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codeHashes []string) error {
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (r *MFARepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, errors.NewDatabaseError(err)
+	}
+	return count, nil
+}
+
+// Disable turns 2FA off, deleting the secret and recovery codes
+func (r *MFARepository) Disable(ctx context.Context, userID uuid.UUID) error {
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+		return err
+	})
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// Reasons a refresh token was revoked
+const (
+	RevokedRotated   = "rotated"    // Exchanged for its replacement
+	RevokedLogout    = "logout"     // The device logged out
+	RevokedLogoutAll = "logout_all" // The user logged out everywhere
+	RevokedReuse     = "reuse"      // A rotated token of the family was used again
+)
+
+// RefreshToken is a stored refresh token. Only its hash is kept; the family
+// groups a login's token with every token it was rotated into.
+type RefreshToken struct {
+	ID            uuid.UUID      `json:"id"`
+	UserID        uuid.UUID      `json:"user_id"`
+	FamilyID      uuid.UUID      `json:"family_id"`
+	TokenHash     string         `json:"-"`
+	DeviceLabel   sql.NullString `json:"device_label"`
+	UserAgent     sql.NullString `json:"user_agent"`
+	IPAddress     sql.NullString `json:"ip_address"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	RevokedAt     sql.NullTime   `json:"revoked_at"`
+	RevokedReason sql.NullString `json:"revoked_reason"`
+	ReplacedBy    uuid.NullUUID  `json:"replaced_by"`
+}
+
+// Session is a live refresh token family, one per logged in device
+type Session struct {
+	ID          uuid.UUID      `json:"id"` // The family ID
+	DeviceLabel sql.NullString `json:"device_label"`
+	UserAgent   sql.NullString `json:"user_agent"`
+	IPAddress   sql.NullString `json:"ip_address"`
+	StartedAt   time.Time      `json:"started_at"`
+	LastUsedAt  time.Time      `json:"last_used_at"`
+	ExpiresAt   time.Time      `json:"expires_at"`
+}
+
+// RefreshTokenRepository handles refresh token database operations
+type RefreshTokenRepository struct {
+	db *db.DB
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(database *db.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: database}
+}
+
+// Create stores a refresh token
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *RefreshToken) error {
+	err := insertRefreshToken(ctx, r.db.QueryRowContext, token)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// FindByHash retrieves a refresh token, revoked or not, by its hash
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, device_label, user_agent, ip_address,
+			expires_at, created_at, revoked_at, revoked_reason, replaced_by
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	token := &RefreshToken{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.DeviceLabel,
+		&token.UserAgent,
+		&token.IPAddress,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&token.RevokedAt,
+		&token.RevokedReason,
+		&token.ReplacedBy,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.NewNotFoundError("Refresh token not found")
+		}
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return token, nil
+}
+
+// Rotate revokes a token in favour of its replacement. It returns false,
+// storing nothing, when the token was already revoked, e.g. by a concurrent
+// refresh with the same token.
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, used *RefreshToken, next *RefreshToken) (bool, error) {
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := insertRefreshToken(ctx, tx.QueryRowContext, next); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `
+			UPDATE refresh_tokens
+			SET revoked_at = NOW(), revoked_reason = $2, replaced_by = $3, last_used_at = NOW()
+			WHERE id = $1 AND revoked_at IS NULL
+		`, used.ID, RevokedRotated, next.ID)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows // Rolls back the replacement
+		}
+		return nil
+	})
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.NewDatabaseError(err)
+	}
+	return true, nil
+}
+
+// RevokeFamily revokes a user's live tokens in one family, returning how
+// many were revoked
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, userID, familyID uuid.UUID, reason string) (int64, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW(), revoked_reason = $3
+		WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, familyID, reason)
+	if err != nil {
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.NewDatabaseError(err)
+	}
+	return rowsAffected, nil
+}
+
+// RevokeAll revokes every live token of a user, returning the families that
+// were still live
+func (r *RefreshTokenRepository) RevokeAll(ctx context.Context, userID uuid.UUID, reason string) ([]uuid.UUID, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW(), revoked_reason = $2
+		WHERE user_id = $1 AND revoked_at IS NULL
+		RETURNING family_id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, reason)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	families := []uuid.UUID{}
+	seen := make(map[uuid.UUID]bool)
+	for rows.Next() {
+		var familyID uuid.UUID
+		if err := rows.Scan(&familyID); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		if !seen[familyID] {
+			seen[familyID] = true
+			families = append(families, familyID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return families, nil
+}
+
+// SessionStartedAt returns when a user logged in to start a session, which
+// refreshes don't move
+func (r *RefreshTokenRepository) SessionStartedAt(ctx context.Context, userID, familyID uuid.UUID) (time.Time, error) {
+	query := `
+		SELECT MIN(created_at)
+		FROM refresh_tokens
+		WHERE user_id = $1 AND family_id = $2
+	`
+
+	var startedAt sql.NullTime
+	if err := r.db.QueryRowContext(ctx, query, userID, familyID).Scan(&startedAt); err != nil {
+		return time.Time{}, errors.NewDatabaseError(err)
+	}
+	if !startedAt.Valid {
+		return time.Time{}, errors.NewNotFoundError("Session not found")
+	}
+	return startedAt.Time, nil
+}
+
+// ListSessions returns a user's live sessions, most recently used first
+func (r *RefreshTokenRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	query := `
+		SELECT family_id, device_label, user_agent, ip_address, started_at, created_at, expires_at
+		FROM (
+			SELECT DISTINCT ON (t.family_id)
+				t.family_id, t.device_label, t.user_agent, t.ip_address, t.created_at, t.expires_at,
+				(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id) AS started_at
+			FROM refresh_tokens t
+			WHERE t.user_id = $1 AND t.revoked_at IS NULL AND t.expires_at > NOW()
+			ORDER BY t.family_id, t.created_at DESC
+		) live
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		session := &Session{}
+		err := rows.Scan(
+			&session.ID,
+			&session.DeviceLabel,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.StartedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return sessions, nil
+}
+
+type queryRowFunc func(ctx context.Context, query string, args ...interface{}) *sql.Row
+
+func insertRefreshToken(ctx context.Context, queryRow queryRowFunc, token *RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (
+			id, user_id, family_id, token_hash, device_label, user_agent, ip_address, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at
+	`
+
+	return queryRow(
+		ctx,
+		query,
+		token.ID,
+		token.UserID,
+		token.FamilyID,
+		token.TokenHash,
+		token.DeviceLabel,
+		token.UserAgent,
+		token.IPAddress,
+		token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+}
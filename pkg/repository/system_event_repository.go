@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// System event types and severities
+const (
+	EventTypeSecurity = "SECURITY"
+
+	SeverityInfo    = "INFO"
+	SeverityWarning = "WARNING"
+)
+
+// SystemEvent is an operational or security event worth keeping beyond the
+// service logs
+type SystemEvent struct {
+	ID          uuid.UUID              `json:"id"`
+	EventType   string                 `json:"event_type"`
+	ServiceName string                 `json:"service_name"`
+	Message     string                 `json:"message"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Severity    string                 `json:"severity"`
+	UserID      *uuid.UUID             `json:"user_id,omitempty"`
+	MatchID     *uuid.UUID             `json:"match_id,omitempty"`
+	IPAddress   string                 `json:"ip_address,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+// SystemEventRepository handles system event database operations
+type SystemEventRepository struct {
+	db *db.DB
+}
+
+// NewSystemEventRepository creates a new system event repository
+func NewSystemEventRepository(database *db.DB) *SystemEventRepository {
+	return &SystemEventRepository{db: database}
+}
+
+// Record stores an event
+func (r *SystemEventRepository) Record(ctx context.Context, event *SystemEvent) error {
+	if event.Severity == "" {
+		event.Severity = SeverityInfo
+	}
+	metadata, err := json.Marshal(event.Metadata)
+	if err != nil {
+		return errors.NewInternalServerError(err)
+	}
+
+	query := `
+		INSERT INTO system_events (event_type, service_name, message, metadata, severity, user_id, match_id, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING id, created_at
+	`
+
+	err = r.db.QueryRowContext(ctx, query,
+		event.EventType, event.ServiceName, event.Message, metadata, event.Severity,
+		event.UserID, event.MatchID, event.IPAddress,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
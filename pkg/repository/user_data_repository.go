@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// UserDataArchive is everything stored about a user, as handed to them by
+// a data export. Each section is a JSON array of rows.
+type UserDataArchive struct {
+	UserID           uuid.UUID       `json:"user_id"`
+	ExportedAt       time.Time       `json:"exported_at"`
+	Profile          json.RawMessage `json:"profile"`
+	Matches          json.RawMessage `json:"matches"`
+	PracticeSessions json.RawMessage `json:"practice_sessions"`
+	Achievements     json.RawMessage `json:"achievements"`
+	Missions         json.RawMessage `json:"missions"`
+	Mastery          json.RawMessage `json:"mastery"`
+	MasteryPoints    json.RawMessage `json:"mastery_points"`
+	Cosmetics        json.RawMessage `json:"cosmetics"`
+	Friendships      json.RawMessage `json:"friendships"`
+}
+
+// UserDataRepository reads a user's rows across every service's tables for
+// data exports. It never writes.
+type UserDataRepository struct {
+	db *db.DB
+}
+
+// NewUserDataRepository creates a new user data repository
+func NewUserDataRepository(database *db.DB) *UserDataRepository {
+	return &UserDataRepository{db: database}
+}
+
+// Other users appear in matches and friendships by username only
+const (
+	exportProfileQuery = `
+		SELECT id, username, email, display_name, avatar_url, title, region,
+			level, xp, total_games, wins, losses, win_streak, best_win_streak,
+			elo_rating, rank_tier, puzzles_solved, fastest_solve_ms,
+			is_verified, is_guest, role, last_login_at, created_at
+		FROM users
+		WHERE id = $1
+	`
+	exportMatchesQuery = `
+		SELECT m.id, gm.name AS game_mode, m.status, m.started_at, m.ended_at, m.duration_ms,
+			opponent.username AS opponent,
+			m.winner_id = $1 AS won,
+			CASE WHEN m.player1_id = $1 THEN m.elo_change_p1 ELSE m.elo_change_p2 END AS elo_change
+		FROM matches m
+		JOIN game_modes gm ON gm.id = m.game_mode_id
+		LEFT JOIN users opponent
+			ON opponent.id = CASE WHEN m.player1_id = $1 THEN m.player2_id ELSE m.player1_id END
+		WHERE m.player1_id = $1 OR m.player2_id = $1
+		ORDER BY m.started_at
+	`
+	exportPracticeQuery = `
+		SELECT * FROM practice_sessions WHERE user_id = $1 ORDER BY started_at
+	`
+	exportAchievementsQuery = `
+		SELECT a.name, a.description, ua.progress, a.total, ua.unlocked, ua.unlocked_at
+		FROM user_achievements ua
+		JOIN achievements a ON a.id = ua.achievement_id
+		WHERE ua.user_id = $1
+		ORDER BY ua.created_at
+	`
+	exportMissionsQuery = `
+		SELECT * FROM user_missions WHERE user_id = $1 ORDER BY assigned_date
+	`
+	exportMasteryQuery = `
+		SELECT * FROM user_mastery WHERE user_id = $1 ORDER BY unlocked_at
+	`
+	exportMasteryPointsQuery = `
+		SELECT * FROM cipher_mastery_points WHERE user_id = $1 ORDER BY cipher_type
+	`
+	exportCosmeticsQuery = `
+		SELECT c.name, c.category, c.rarity, uc.source, uc.is_equipped, uc.acquired_at
+		FROM user_cosmetics uc
+		JOIN cosmetics c ON c.id = uc.cosmetic_id
+		WHERE uc.user_id = $1
+		ORDER BY uc.acquired_at
+	`
+	exportFriendshipsQuery = `
+		SELECT friend.username AS friend, f.status, f.requester_id = $1 AS requested_by_you,
+			f.created_at, f.accepted_at
+		FROM friendships f
+		JOIN users friend
+			ON friend.id = CASE WHEN f.user_id = $1 THEN f.friend_id ELSE f.user_id END
+		WHERE f.user_id = $1 OR f.friend_id = $1
+		ORDER BY f.created_at
+	`
+)
+
+// Collect gathers a user's data into an archive
+func (r *UserDataRepository) Collect(ctx context.Context, userID uuid.UUID) (*UserDataArchive, error) {
+	archive := &UserDataArchive{UserID: userID, ExportedAt: time.Now().UTC()}
+
+	sections := []struct {
+		dest  *json.RawMessage
+		query string
+	}{
+		{&archive.Profile, exportProfileQuery},
+		{&archive.Matches, exportMatchesQuery},
+		{&archive.PracticeSessions, exportPracticeQuery},
+		{&archive.Achievements, exportAchievementsQuery},
+		{&archive.Missions, exportMissionsQuery},
+		{&archive.Mastery, exportMasteryQuery},
+		{&archive.MasteryPoints, exportMasteryPointsQuery},
+		{&archive.Cosmetics, exportCosmeticsQuery},
+		{&archive.Friendships, exportFriendshipsQuery},
+	}
+
+	for _, section := range sections {
+		rows, err := r.rowsAsJSON(ctx, section.query, userID)
+		if err != nil {
+			return nil, err
+		}
+		*section.dest = rows
+	}
+
+	return archive, nil
+}
+
+// rowsAsJSON runs a query and returns its rows as a JSON array
+func (r *UserDataRepository) rowsAsJSON(ctx context.Context, query string, userID uuid.UUID) (json.RawMessage, error) {
+	var rows []byte
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(json_agg(t), '[]'::json) FROM (`+query+`) t`, userID,
+	).Scan(&rows)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return json.RawMessage(rows), nil
+}
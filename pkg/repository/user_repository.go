@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
 )
@@ -35,6 +36,11 @@ type User struct {
 	FastestSolveMS  sql.NullInt32  `json:"fastest_solve_ms"`
 	IsVerified      bool           `json:"is_verified"`
 	IsBanned        bool           `json:"is_banned"`
+	BannedAt        sql.NullTime   `json:"banned_at"`
+	BanReason       sql.NullString `json:"ban_reason"`
+	Role            string         `json:"role"`
+	IsGuest         bool           `json:"is_guest"` // Played without registering; Email is empty
+	DeletedAt       sql.NullTime   `json:"deleted_at"`
 	CreatedAt       time.Time      `json:"created_at"`
 	UpdatedAt       time.Time      `json:"updated_at"`
 }
@@ -49,13 +55,13 @@ func NewUserRepository(database *db.DB) *UserRepository {
 	return &UserRepository{db: database}
 }
 
-// Create creates a new user
+// Create creates a new user. Guests are created without an email.
 func (r *UserRepository) Create(ctx context.Context, user *User) error {
 	query := `
 		INSERT INTO users (
-			username, email, password_hash, region, display_name
-		) VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, created_at, updated_at, level, xp, elo_rating, rank_tier
+			username, email, password_hash, region, display_name, is_guest
+		) VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at, level, xp, elo_rating, rank_tier, role
 	`
 
 	err := r.db.QueryRowContext(
@@ -66,6 +72,7 @@ func (r *UserRepository) Create(ctx context.Context, user *User) error {
 		user.PasswordHash,
 		user.Region,
 		user.DisplayName,
+		user.IsGuest,
 	).Scan(
 		&user.ID,
 		&user.CreatedAt,
@@ -74,6 +81,7 @@ func (r *UserRepository) Create(ctx context.Context, user *User) error {
 		&user.XP,
 		&user.EloRating,
 		&user.RankTier,
+		&user.Role,
 	)
 
 	if err != nil {
@@ -95,10 +103,11 @@ func (r *UserRepository) FindByID(ctx context.Context, id uuid.UUID) (*User, err
 	user := &User{}
 	query := `
 		SELECT
-			id, username, email, password_hash, display_name, avatar_url, title, region,
+			id, username, COALESCE(email, ''), password_hash, display_name, avatar_url, title, region,
 			level, xp, total_games, wins, losses, win_streak, best_win_streak,
 			elo_rating, rating_deviation, volatility, rank_tier, puzzles_solved,
-			fastest_solve_ms, is_verified, is_banned, created_at, updated_at
+			fastest_solve_ms, is_verified, is_banned, banned_at, ban_reason, role,
+			is_guest, deleted_at, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -110,7 +119,8 @@ func (r *UserRepository) FindByID(ctx context.Context, id uuid.UUID) (*User, err
 		&user.WinStreak, &user.BestWinStreak, &user.EloRating,
 		&user.RatingDeviation, &user.Volatility, &user.RankTier,
 		&user.PuzzlesSolved, &user.FastestSolveMS, &user.IsVerified,
-		&user.IsBanned, &user.CreatedAt, &user.UpdatedAt,
+		&user.IsBanned, &user.BannedAt, &user.BanReason, &user.Role,
+		&user.IsGuest, &user.DeletedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
@@ -128,10 +138,11 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*User,
 	user := &User{}
 	query := `
 		SELECT
-			id, username, email, password_hash, display_name, avatar_url, title, region,
+			id, username, COALESCE(email, ''), password_hash, display_name, avatar_url, title, region,
 			level, xp, total_games, wins, losses, win_streak, best_win_streak,
 			elo_rating, rating_deviation, volatility, rank_tier, puzzles_solved,
-			fastest_solve_ms, is_verified, is_banned, created_at, updated_at
+			fastest_solve_ms, is_verified, is_banned, banned_at, ban_reason, role,
+			is_guest, deleted_at, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -143,7 +154,8 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*User,
 		&user.WinStreak, &user.BestWinStreak, &user.EloRating,
 		&user.RatingDeviation, &user.Volatility, &user.RankTier,
 		&user.PuzzlesSolved, &user.FastestSolveMS, &user.IsVerified,
-		&user.IsBanned, &user.CreatedAt, &user.UpdatedAt,
+		&user.IsBanned, &user.BannedAt, &user.BanReason, &user.Role,
+		&user.IsGuest, &user.DeletedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
@@ -161,10 +173,11 @@ func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*
 	user := &User{}
 	query := `
 		SELECT
-			id, username, email, password_hash, display_name, avatar_url, title, region,
+			id, username, COALESCE(email, ''), password_hash, display_name, avatar_url, title, region,
 			level, xp, total_games, wins, losses, win_streak, best_win_streak,
 			elo_rating, rating_deviation, volatility, rank_tier, puzzles_solved,
-			fastest_solve_ms, is_verified, is_banned, created_at, updated_at
+			fastest_solve_ms, is_verified, is_banned, banned_at, ban_reason, role,
+			is_guest, deleted_at, created_at, updated_at
 		FROM users
 		WHERE username = $1
 	`
@@ -176,7 +189,8 @@ func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*
 		&user.WinStreak, &user.BestWinStreak, &user.EloRating,
 		&user.RatingDeviation, &user.Volatility, &user.RankTier,
 		&user.PuzzlesSolved, &user.FastestSolveMS, &user.IsVerified,
-		&user.IsBanned, &user.CreatedAt, &user.UpdatedAt,
+		&user.IsBanned, &user.BannedAt, &user.BanReason, &user.Role,
+		&user.IsGuest, &user.DeletedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
@@ -268,6 +282,158 @@ func (r *UserRepository) UpdateELO(ctx context.Context, userID uuid.UUID, newELO
 	return nil
 }
 
+// AdjustELO moves a user's ELO rating by delta, never below zero, and
+// returns the new rating
+func (r *UserRepository) AdjustELO(ctx context.Context, userID uuid.UUID, delta int) (int, error) {
+	query := `
+		UPDATE users
+		SET elo_rating = GREATEST(elo_rating + $2, 0), updated_at = NOW()
+		WHERE id = $1
+		RETURNING elo_rating
+	`
+
+	var rating int
+	err := r.db.QueryRowContext(ctx, query, userID, delta).Scan(&rating)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errors.NewUserNotFoundError()
+		}
+		return 0, errors.NewDatabaseError(err)
+	}
+
+	return rating, nil
+}
+
+// SetBanned bans a user with a reason, or lifts their ban
+func (r *UserRepository) SetBanned(ctx context.Context, userID uuid.UUID, banned bool, reason string) error {
+	query := `
+		UPDATE users SET
+			is_banned = $2,
+			banned_at = CASE WHEN $2 THEN NOW() ELSE NULL END,
+			ban_reason = CASE WHEN $2 THEN NULLIF($3, '') ELSE NULL END,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, banned, reason)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewUserNotFoundError()
+	}
+
+	return nil
+}
+
+// MarkVerified records that a user confirmed their email address
+func (r *UserRepository) MarkVerified(ctx context.Context, userID uuid.UUID) error {
+	query := `UPDATE users SET is_verified = TRUE, updated_at = NOW() WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewUserNotFoundError()
+	}
+
+	return nil
+}
+
+// UpdatePassword replaces a user's password hash
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, userID, passwordHash)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewUserNotFoundError()
+	}
+
+	return nil
+}
+
+// ConvertGuest gives a guest credentials, making them a full user. Their ID
+// and everything recorded under it stay the same. An empty display name
+// keeps the current one.
+func (r *UserRepository) ConvertGuest(ctx context.Context, userID uuid.UUID, username, email, passwordHash, displayName string) error {
+	query := `
+		UPDATE users SET
+			username = $2,
+			email = $3,
+			password_hash = $4,
+			display_name = COALESCE(NULLIF($5, ''), display_name),
+			is_guest = FALSE,
+			is_verified = FALSE,
+			updated_at = NOW()
+		WHERE id = $1 AND is_guest
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, username, email, passwordHash, displayName)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			if pqErr.Constraint == "users_username_key" {
+				return errors.NewUserAlreadyExistsError("username")
+			}
+			return errors.NewUserAlreadyExistsError("email")
+		}
+		return errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewInvalidInputError("Account is not a guest account")
+	}
+
+	return nil
+}
+
+// SetRole changes a user's role
+func (r *UserRepository) SetRole(ctx context.Context, userID uuid.UUID, role string) error {
+	query := `UPDATE users SET role = $2, updated_at = NOW() WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, userID, role)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	if rowsAffected == 0 {
+		return errors.NewUserNotFoundError()
+	}
+
+	return nil
+}
+
 // UpdateStats updates user game statistics
 func (r *UserRepository) UpdateStats(ctx context.Context, userID uuid.UUID, won bool) error {
 	query := `
@@ -300,7 +466,7 @@ func (r *UserRepository) GetLeaderboard(ctx context.Context, limit, offset int)
 			id, username, display_name, avatar_url, level, elo_rating, rank_tier,
 			total_games, wins, losses, win_streak, best_win_streak
 		FROM users
-		WHERE is_banned = FALSE AND total_games >= 10
+		WHERE is_banned = FALSE AND is_guest = FALSE AND total_games >= 10
 		ORDER BY elo_rating DESC
 		LIMIT $1 OFFSET $2
 	`
@@ -328,10 +494,62 @@ func (r *UserRepository) GetLeaderboard(ctx context.Context, limit, offset int)
 	return users, nil
 }
 
-// Delete deletes a user (soft delete by marking as banned)
+// Delete deletes a user. The row stays behind as an anonymous tombstone, so
+// opponents' matches still resolve, but everything identifying goes with
+// the user's credentials, sessions, linked identities, 2FA and exports. The
+// deletion is queued in account_deletions in the same transaction, for the
+// user.deleted event that has the other services erase their data.
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `UPDATE users SET is_banned = TRUE WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
+	err := r.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `
+			UPDATE users SET
+				username = 'deleted_' || substr(replace(id::text, '-', ''), 1, 12),
+				email = NULL,
+				password_hash = '',
+				display_name = 'Deleted player',
+				avatar_url = NULL,
+				title = NULL,
+				is_verified = FALSE,
+				is_guest = FALSE,
+				is_banned = TRUE,
+				banned_at = NULL,
+				ban_reason = NULL,
+				last_login_at = NULL,
+				deleted_at = NOW(),
+				updated_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL
+		`, id)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return sql.ErrNoRows
+		}
+
+		for _, query := range []string{
+			`DELETE FROM refresh_tokens WHERE user_id = $1`,
+			`DELETE FROM account_tokens WHERE user_id = $1`,
+			`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+			`DELETE FROM user_mfa WHERE user_id = $1`,
+			`DELETE FROM user_identities WHERE user_id = $1`,
+			`DELETE FROM data_exports WHERE user_id = $1`,
+			`INSERT INTO account_deletions (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`,
+		} {
+			if _, err := tx.ExecContext(ctx, query, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err == sql.ErrNoRows {
+		return errors.NewUserNotFoundError()
+	}
 	if err != nil {
 		return errors.NewDatabaseError(err)
 	}
@@ -0,0 +1,103 @@
+package toycrypto
+
+import "errors"
+
+// DefaultLFSRTaps is a maximal-length feedback polynomial for a 16-bit
+// Galois LFSR (x^16 + x^14 + x^13 + x^11 + 1): any nonzero seed cycles
+// through all 65535 nonzero states before repeating
+const DefaultLFSRTaps uint16 = 0xB400
+
+// LFSR errors
+var (
+	ErrZeroSeed = errors.New("LFSR seed must be nonzero")
+	ErrBadTaps  = errors.New("LFSR taps must include the top bit")
+)
+
+// LFSR is a 16-bit Galois linear feedback shift register
+type LFSR struct {
+	state uint16
+	taps  uint16
+}
+
+// LFSRStep is one keystream byte and the register states that produced it
+type LFSRStep struct {
+	States []uint16 `json:"states"` // State before each of the eight shifts
+	Bits   []int    `json:"bits"`   // Output bit of each shift, most significant first
+	Byte   byte     `json:"byte"`
+}
+
+// NewLFSR creates a register from a seed and feedback taps, zero taps meaning
+// DefaultLFSRTaps. The all-zero seed is rejected because the register never
+// leaves it, and taps without the top bit because the register would lose
+// states instead of cycling through them.
+func NewLFSR(seed, taps uint16) (*LFSR, error) {
+	if seed == 0 {
+		return nil, ErrZeroSeed
+	}
+	if taps == 0 {
+		taps = DefaultLFSRTaps
+	}
+	if taps&0x8000 == 0 {
+		return nil, ErrBadTaps
+	}
+	return &LFSR{state: seed, taps: taps}, nil
+}
+
+// State returns the current register contents
+func (l *LFSR) State() uint16 {
+	return l.state
+}
+
+// Bit shifts the register once and returns the bit shifted out
+func (l *LFSR) Bit() uint8 {
+	bit := uint8(l.state & 1)
+	l.state >>= 1
+	if bit == 1 {
+		l.state ^= l.taps
+	}
+	return bit
+}
+
+// NextByte collects eight output bits, most significant first
+func (l *LFSR) NextByte() LFSRStep {
+	step := LFSRStep{States: make([]uint16, 8), Bits: make([]int, 8)}
+	for i := 0; i < 8; i++ {
+		step.States[i] = l.state
+		bit := l.Bit()
+		step.Bits[i] = int(bit)
+		step.Byte = step.Byte<<1 | bit
+	}
+	return step
+}
+
+// Keystream returns the next n keystream bytes with how each was produced
+func (l *LFSR) Keystream(n int) []LFSRStep {
+	steps := make([]LFSRStep, n)
+	for i := range steps {
+		steps[i] = l.NextByte()
+	}
+	return steps
+}
+
+// XORKeyStream XORs data with the keystream. Encrypting and decrypting are
+// the same operation, starting from the same seed.
+func (l *LFSR) XORKeyStream(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = b ^ l.NextByte().Byte
+	}
+	return out
+}
+
+// Period counts the shifts until the register returns to its current state.
+// It is at most 65535, reached only with maximal-length taps.
+func (l *LFSR) Period() int {
+	probe := *l
+	start := probe.state
+	for n := 1; ; n++ {
+		probe.Bit()
+		if probe.state == start {
+			return n
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package toycrypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewLFSR(t *testing.T) {
+	tests := []struct {
+		name     string
+		seed     uint16
+		taps     uint16
+		wantTaps uint16
+		wantErr  error
+	}{
+		{"default taps", 0xACE1, 0, DefaultLFSRTaps, nil},
+		{"custom taps", 1, 0x8016, 0x8016, nil},
+		{"zero seed", 0, 0, 0, ErrZeroSeed},
+		{"taps without top bit", 1, 0x0016, 0, ErrBadTaps},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lfsr, err := NewLFSR(tt.seed, tt.taps)
+			if err != tt.wantErr {
+				t.Fatalf("NewLFSR error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if lfsr.taps != tt.wantTaps || lfsr.State() != tt.seed {
+				t.Errorf("register = {state %#04x, taps %#04x}, want {%#04x, %#04x}", lfsr.State(), lfsr.taps, tt.seed, tt.wantTaps)
+			}
+		})
+	}
+}
+
+func TestLFSRBit(t *testing.T) {
+	// A set low bit is shifted out and the taps are XORed in
+	lfsr, _ := NewLFSR(0x0001, DefaultLFSRTaps)
+	if bit := lfsr.Bit(); bit != 1 || lfsr.State() != DefaultLFSRTaps {
+		t.Errorf("Bit() = %d with state %#04x, want 1 with %#04x", bit, lfsr.State(), DefaultLFSRTaps)
+	}
+
+	// A clear low bit is a plain shift
+	lfsr, _ = NewLFSR(0x0002, DefaultLFSRTaps)
+	if bit := lfsr.Bit(); bit != 0 || lfsr.State() != 0x0001 {
+		t.Errorf("Bit() = %d with state %#04x, want 0 with 0x0001", bit, lfsr.State())
+	}
+}
+
+func TestLFSRPeriod(t *testing.T) {
+	tests := []struct {
+		name string
+		seed uint16
+		taps uint16
+		want int
+	}{
+		{"maximal length", 0xACE1, DefaultLFSRTaps, 65535},
+		{"maximal length from another seed", 1, DefaultLFSRTaps, 65535},
+		{"single tap", 1, 0x8000, 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lfsr, _ := NewLFSR(tt.seed, tt.taps)
+			if got := lfsr.Period(); got != tt.want {
+				t.Errorf("Period() = %d, want %d", got, tt.want)
+			}
+			if lfsr.State() != tt.seed {
+				t.Error("Period() moved the register")
+			}
+		})
+	}
+}
+
+func TestLFSRKeystream(t *testing.T) {
+	lfsr, _ := NewLFSR(0xACE1, 0)
+	for _, step := range lfsr.Keystream(4) {
+		var b byte
+		for _, bit := range step.Bits {
+			b = b<<1 | byte(bit)
+		}
+		if b != step.Byte {
+			t.Errorf("bits %v make %#02x, step reports %#02x", step.Bits, b, step.Byte)
+		}
+		if len(step.States) != 8 {
+			t.Errorf("step has %d states, want 8", len(step.States))
+		}
+	}
+}
+
+func TestLFSRXORKeyStream(t *testing.T) {
+	tests := []struct {
+		name string
+		seed uint16
+		data []byte
+	}{
+		{"empty", 0xACE1, []byte{}},
+		{"text", 0xACE1, []byte("ATTACK AT DAWN")},
+		{"zero bytes show the keystream", 0x1234, make([]byte, 8)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypt, _ := NewLFSR(tt.seed, 0)
+			decrypt, _ := NewLFSR(tt.seed, 0)
+
+			ciphertext := encrypt.XORKeyStream(tt.data)
+			if got := decrypt.XORKeyStream(ciphertext); !bytes.Equal(got, tt.data) {
+				t.Errorf("round trip gave %q, want %q", got, tt.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package toycrypto
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+)
+
+// RSA modulus sizes. Keys up to FactoringChallengeMaxBits are small enough
+// for players to factor n themselves and recover the private key.
+const (
+	MinRSABits                = 16
+	MaxRSABits                = 512
+	FactoringChallengeMaxBits = 64
+)
+
+// primeRounds is the number of Miller-Rabin rounds; ProbablyPrime also runs
+// a Baillie-PSW test, which has no known counterexamples
+const primeRounds = 20
+
+// publicExponents are tried in order until one is coprime with φ(n)
+var publicExponents = []int64{65537, 257, 17, 5, 3}
+
+var big1 = big.NewInt(1)
+
+// RSAKey is a textbook RSA key pair along with the primes it was built from
+type RSAKey struct {
+	N    *big.Int
+	E    *big.Int
+	D    *big.Int
+	P    *big.Int
+	Q    *big.Int
+	Phi  *big.Int
+	Bits int
+}
+
+// RSABlock is one message block and its encryption
+type RSABlock struct {
+	Plain []byte
+	M     *big.Int
+	C     *big.Int
+}
+
+// GenerateRSAKey builds a key with an n of exactly bits bits from two primes
+// of half that size. All randomness comes from rng, so a seeded generator
+// reproduces the same key.
+func GenerateRSAKey(rng *rand.Rand, bits int) (*RSAKey, error) {
+	if bits < MinRSABits || bits > MaxRSABits || bits%2 != 0 {
+		return nil, fmt.Errorf("RSA modulus must be an even number of bits from %d to %d", MinRSABits, MaxRSABits)
+	}
+
+	for {
+		p := randomPrime(rng, bits/2)
+		q := randomPrime(rng, bits/2)
+		if p.Cmp(q) == 0 {
+			continue
+		}
+		key, err := NewRSAKey(p, q)
+		if err == nil {
+			return key, nil
+		}
+	}
+}
+
+// NewRSAKey builds a key from two chosen primes, picking the public exponent
+// and working out the private one
+func NewRSAKey(p, q *big.Int) (*RSAKey, error) {
+	if !p.ProbablyPrime(primeRounds) || !q.ProbablyPrime(primeRounds) {
+		return nil, errors.New("p and q must both be prime")
+	}
+	if p.Cmp(q) == 0 {
+		return nil, errors.New("p and q must be different primes")
+	}
+
+	n := new(big.Int).Mul(p, q)
+	if n.BitLen() < MinRSABits || n.BitLen() > MaxRSABits {
+		return nil, fmt.Errorf("n must be from %d to %d bits", MinRSABits, MaxRSABits)
+	}
+	phi := new(big.Int).Mul(new(big.Int).Sub(p, big1), new(big.Int).Sub(q, big1))
+
+	for _, candidate := range publicExponents {
+		e := big.NewInt(candidate)
+		if e.Cmp(phi) >= 0 {
+			continue
+		}
+		d := new(big.Int).ModInverse(e, phi)
+		if d == nil {
+			continue
+		}
+		return &RSAKey{
+			N:    n,
+			E:    e,
+			D:    d,
+			P:    new(big.Int).Set(p),
+			Q:    new(big.Int).Set(q),
+			Phi:  phi,
+			Bits: n.BitLen(),
+		}, nil
+	}
+	return nil, errors.New("no public exponent is coprime with φ(n)")
+}
+
+// NewRSAPrivateKey rebuilds a key from its stored parts without the primes
+func NewRSAPrivateKey(n, e, d *big.Int) *RSAKey {
+	return &RSAKey{N: n, E: e, D: d, Bits: n.BitLen()}
+}
+
+// BlockSize is the number of message bytes per block: one byte less than n,
+// so every block is smaller than n
+func (k *RSAKey) BlockSize() int {
+	return (k.N.BitLen()+7)/8 - 1
+}
+
+// Encrypt splits a message into blocks and raises each to e mod n, with no
+// padding. The last block is filled out with zero bytes, which Decrypt
+// strips again.
+func (k *RSAKey) Encrypt(message []byte) []RSABlock {
+	size := k.BlockSize()
+	blocks := make([]RSABlock, 0, (len(message)+size-1)/size)
+	for start := 0; start < len(message); start += size {
+		plain := make([]byte, size)
+		copy(plain, message[start:])
+		m := new(big.Int).SetBytes(plain)
+		blocks = append(blocks, RSABlock{
+			Plain: plain,
+			M:     m,
+			C:     new(big.Int).Exp(m, k.E, k.N),
+		})
+	}
+	return blocks
+}
+
+// Decrypt raises each block to d mod n and joins the results
+func (k *RSAKey) Decrypt(blocks []*big.Int) ([]byte, error) {
+	size := k.BlockSize()
+	var message []byte
+	for _, c := range blocks {
+		if c.Sign() < 0 || c.Cmp(k.N) >= 0 {
+			return nil, errors.New("ciphertext block out of range")
+		}
+		m := new(big.Int).Exp(c, k.D, k.N)
+		if m.BitLen() > size*8 {
+			return nil, errors.New("ciphertext block does not decrypt with this key")
+		}
+		message = append(message, m.FillBytes(make([]byte, size))...)
+	}
+	return []byte(strings.TrimRight(string(message), "\x00")), nil
+}
+
+// EncodeBlocks writes ciphertext blocks as space-separated hex, each padded
+// to the width of n
+func (k *RSAKey) EncodeBlocks(blocks []RSABlock) string {
+	width := (k.N.BitLen() + 3) / 4
+	encoded := make([]string, len(blocks))
+	for i, block := range blocks {
+		encoded[i] = fmt.Sprintf("%0*X", width, block.C)
+	}
+	return strings.Join(encoded, " ")
+}
+
+// DecodeBlocks parses the output of EncodeBlocks
+func DecodeBlocks(ciphertext string) ([]*big.Int, error) {
+	fields := strings.Fields(ciphertext)
+	blocks := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		c, ok := new(big.Int).SetString(field, 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid ciphertext block %q", field)
+		}
+		blocks[i] = c
+	}
+	return blocks, nil
+}
+
+// Factor looks for a nontrivial factor of n with Pollard's rho method, giving
+// up after maxSteps iterations. It finds the factors of a
+// FactoringChallengeMaxBits modulus in well under a million steps.
+func Factor(n *big.Int, maxSteps int) (p, q *big.Int, ok bool) {
+	if n.Cmp(big.NewInt(4)) < 0 || n.ProbablyPrime(primeRounds) {
+		return nil, nil, false
+	}
+	if n.Bit(0) == 0 {
+		return big.NewInt(2), new(big.Int).Rsh(n, 1), true
+	}
+
+	steps := 0
+	for c := int64(1); steps < maxSteps; c++ {
+		x, y, d := big.NewInt(2), big.NewInt(2), big.NewInt(1)
+		increment := big.NewInt(c)
+		f := func(v *big.Int) {
+			v.Mul(v, v).Add(v, increment).Mod(v, n)
+		}
+		diff := new(big.Int)
+		for d.Cmp(big1) == 0 && steps < maxSteps {
+			f(x)
+			f(y)
+			f(y)
+			d.GCD(nil, nil, diff.Sub(x, y).Abs(diff), n)
+			steps++
+		}
+		if d.Cmp(big1) != 0 && d.Cmp(n) != 0 {
+			return d, new(big.Int).Div(n, d), true
+		}
+	}
+	return nil, nil, false
+}
+
+// randomPrime returns a prime of exactly bits bits with its top two bits set,
+// so the product of two such primes has exactly twice as many bits
+func randomPrime(rng *rand.Rand, bits int) *big.Int {
+	limit := new(big.Int).Lsh(big1, uint(bits))
+	for {
+		candidate := new(big.Int).Rand(rng, limit)
+		candidate.SetBit(candidate, bits-1, 1)
+		candidate.SetBit(candidate, bits-2, 1)
+		candidate.SetBit(candidate, 0, 1)
+		for candidate.BitLen() == bits {
+			if candidate.ProbablyPrime(primeRounds) {
+				return candidate
+			}
+			candidate.Add(candidate, big.NewInt(2))
+		}
+	}
+}
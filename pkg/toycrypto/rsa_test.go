@@ -0,0 +1,155 @@
+package toycrypto
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+func TestNewRSAKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		p, q    int64
+		wantE   int64
+		wantErr bool
+	}{
+		{"small exponent fallback", 251, 241, 257, false},
+		{"65537", 65521, 65519, 65537, false},
+		{"p not prime", 250, 241, 0, true},
+		{"same prime", 251, 251, 0, true},
+		{"n too small", 61, 53, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := NewRSAKey(big.NewInt(tt.p), big.NewInt(tt.q))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewRSAKey succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRSAKey: %v", err)
+			}
+			if key.E.Int64() != tt.wantE {
+				t.Errorf("e = %s, want %d", key.E, tt.wantE)
+			}
+			if key.N.Int64() != tt.p*tt.q {
+				t.Errorf("n = %s, want %d", key.N, tt.p*tt.q)
+			}
+			ed := new(big.Int).Mul(key.E, key.D)
+			if ed.Mod(ed, key.Phi).Cmp(big1) != 0 {
+				t.Errorf("e*d mod φ(n) = %s, want 1", ed)
+			}
+		})
+	}
+}
+
+func TestGenerateRSAKey(t *testing.T) {
+	for _, bits := range []int{MinRSABits, 24, 40, FactoringChallengeMaxBits} {
+		key, err := GenerateRSAKey(rand.New(rand.NewSource(int64(bits))), bits)
+		if err != nil {
+			t.Fatalf("GenerateRSAKey(%d): %v", bits, err)
+		}
+		if key.N.BitLen() != bits {
+			t.Errorf("GenerateRSAKey(%d) made a %d-bit n", bits, key.N.BitLen())
+		}
+
+		again, _ := GenerateRSAKey(rand.New(rand.NewSource(int64(bits))), bits)
+		if again.N.Cmp(key.N) != 0 {
+			t.Errorf("GenerateRSAKey(%d) isn't reproducible from its seed", bits)
+		}
+	}
+
+	for _, bits := range []int{MinRSABits - 2, 33, MaxRSABits + 2} {
+		if _, err := GenerateRSAKey(rand.New(rand.NewSource(1)), bits); err == nil {
+			t.Errorf("GenerateRSAKey(%d) succeeded, want error", bits)
+		}
+	}
+}
+
+func TestRSARoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		bits    int
+		message string
+	}{
+		{"one byte blocks", 16, "HI"},
+		{"short message", 32, "ATTACK"},
+		{"several blocks", 48, "THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := GenerateRSAKey(rand.New(rand.NewSource(7)), tt.bits)
+			if err != nil {
+				t.Fatalf("GenerateRSAKey: %v", err)
+			}
+
+			encoded := key.EncodeBlocks(key.Encrypt([]byte(tt.message)))
+			blocks, err := DecodeBlocks(encoded)
+			if err != nil {
+				t.Fatalf("DecodeBlocks(%q): %v", encoded, err)
+			}
+
+			// Only the public values survive storage, as in a puzzle config
+			private := NewRSAPrivateKey(key.N, key.E, key.D)
+			message, err := private.Decrypt(blocks)
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if !bytes.Equal(message, []byte(tt.message)) {
+				t.Errorf("round trip gave %q, want %q", message, tt.message)
+			}
+		})
+	}
+}
+
+func TestRSADecryptRejectsOutOfRange(t *testing.T) {
+	key, _ := NewRSAKey(big.NewInt(251), big.NewInt(241))
+	if _, err := key.Decrypt([]*big.Int{new(big.Int).Set(key.N)}); err == nil {
+		t.Error("Decrypt accepted a block equal to n")
+	}
+	if _, err := DecodeBlocks("12 XYZ"); err == nil {
+		t.Error("DecodeBlocks accepted a non-hex block")
+	}
+}
+
+func TestFactor(t *testing.T) {
+	tests := []struct {
+		name   string
+		n      *big.Int
+		wantOK bool
+	}{
+		{"even", big.NewInt(2 * 65521), true},
+		{"prime", big.NewInt(65521), false},
+		{"too small", big.NewInt(3), false},
+		{"semiprime", big.NewInt(251 * 241), true},
+	}
+
+	for _, bits := range []int{32, 48, FactoringChallengeMaxBits} {
+		key, _ := GenerateRSAKey(rand.New(rand.NewSource(int64(bits))), bits)
+		tests = append(tests, struct {
+			name   string
+			n      *big.Int
+			wantOK bool
+		}{"generated challenge", key.N, true})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, q, ok := Factor(tt.n, 1_000_000)
+			if ok != tt.wantOK {
+				t.Fatalf("Factor(%s) ok = %v, want %v", tt.n, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if p.Cmp(big1) == 0 || q.Cmp(big1) == 0 || new(big.Int).Mul(p, q).Cmp(tt.n) != 0 {
+				t.Errorf("Factor(%s) = %s × %s", tt.n, p, q)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Package toycrypto implements deliberately small versions of modern ciphers
+// for teaching: a 16-bit substitution-permutation network, an LFSR stream
+// cipher and textbook RSA. None of them is secure. Besides encrypting, each
+// can report its intermediate values so tutorials can show it at work.
+package toycrypto
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SPN parameters, following the classic teaching network used to introduce
+// linear and differential cryptanalysis: 16-bit blocks, a 32-bit key, four
+// rounds of 4-bit S-boxes and a bit transposition
+const (
+	SPNBlockSize = 2 // bytes
+	SPNRounds    = 4
+)
+
+// Block cipher modes
+const (
+	ModeECB = "ECB"
+	ModeCBC = "CBC"
+)
+
+var errBadPadding = errors.New("invalid padding")
+
+// spnSBox substitutes each nibble; spnInverseSBox undoes it
+var (
+	spnSBox        = [16]uint16{0xE, 0x4, 0xD, 0x1, 0x2, 0xF, 0xB, 0x8, 0x3, 0xA, 0x6, 0xC, 0x5, 0x9, 0x0, 0x7}
+	spnInverseSBox = invertSBox(spnSBox)
+)
+
+// SPN is the toy block cipher with its round keys expanded
+type SPN struct {
+	subkeys [SPNRounds + 1]uint16
+}
+
+// SPNRound is one round of encrypting a block
+type SPNRound struct {
+	Round       int    `json:"round"`
+	Input       uint16 `json:"input"`
+	Subkey      uint16 `json:"subkey"`
+	KeyMixed    uint16 `json:"key_mixed"`
+	Substituted uint16 `json:"substituted"`
+	Output      uint16 `json:"output"` // After the permutation, or the final key mix in the last round
+}
+
+// NewSPN expands a 32-bit key into the five round keys: round key i is the
+// 16 bits starting at bit 4i of the key, counted from the most significant
+func NewSPN(key uint32) *SPN {
+	s := &SPN{}
+	for i := range s.subkeys {
+		s.subkeys[i] = uint16(key >> (16 - 4*i))
+	}
+	return s
+}
+
+// Subkeys returns the round keys, the last one being the final whitening key
+func (s *SPN) Subkeys() []uint16 {
+	return append([]uint16(nil), s.subkeys[:]...)
+}
+
+// EncryptBlock encrypts one 16-bit block
+func (s *SPN) EncryptBlock(block uint16) uint16 {
+	rounds := s.TraceBlock(block)
+	return rounds[len(rounds)-1].Output
+}
+
+// TraceBlock encrypts one block and returns every round. Every round but the
+// last mixes in its key, substitutes and permutes; the last substitutes and
+// then mixes in the final key instead of permuting.
+func (s *SPN) TraceBlock(block uint16) []SPNRound {
+	rounds := make([]SPNRound, SPNRounds)
+	w := block
+	for r := 0; r < SPNRounds; r++ {
+		round := SPNRound{Round: r + 1, Input: w, Subkey: s.subkeys[r]}
+		round.KeyMixed = w ^ s.subkeys[r]
+		round.Substituted = substitute(round.KeyMixed, &spnSBox)
+		if r < SPNRounds-1 {
+			round.Output = permute(round.Substituted)
+		} else {
+			round.Output = round.Substituted ^ s.subkeys[SPNRounds]
+		}
+		rounds[r] = round
+		w = round.Output
+	}
+	return rounds
+}
+
+// DecryptBlock decrypts one 16-bit block
+func (s *SPN) DecryptBlock(block uint16) uint16 {
+	w := substitute(block^s.subkeys[SPNRounds], &spnInverseSBox) ^ s.subkeys[SPNRounds-1]
+	for r := SPNRounds - 2; r >= 0; r-- {
+		w = substitute(permute(w), &spnInverseSBox) ^ s.subkeys[r]
+	}
+	return w
+}
+
+// Encrypt pads data to whole blocks (PKCS#7) and encrypts it in ECB or CBC
+// mode. The IV is only used by CBC.
+func (s *SPN) Encrypt(data []byte, mode string, iv uint16) ([]byte, error) {
+	if mode != ModeECB && mode != ModeCBC {
+		return nil, fmt.Errorf("unknown block cipher mode: %s", mode)
+	}
+
+	padded := Pad(data)
+	out := make([]byte, len(padded))
+	previous := iv
+	for i := 0; i < len(padded); i += SPNBlockSize {
+		block := uint16(padded[i])<<8 | uint16(padded[i+1])
+		if mode == ModeCBC {
+			block ^= previous
+		}
+		c := s.EncryptBlock(block)
+		out[i], out[i+1] = byte(c>>8), byte(c)
+		previous = c
+	}
+	return out, nil
+}
+
+// Decrypt reverses Encrypt, removing the padding
+func (s *SPN) Decrypt(data []byte, mode string, iv uint16) ([]byte, error) {
+	if mode != ModeECB && mode != ModeCBC {
+		return nil, fmt.Errorf("unknown block cipher mode: %s", mode)
+	}
+	if len(data) == 0 || len(data)%SPNBlockSize != 0 {
+		return nil, fmt.Errorf("ciphertext must be a non-empty multiple of %d bytes", SPNBlockSize)
+	}
+
+	out := make([]byte, len(data))
+	previous := iv
+	for i := 0; i < len(data); i += SPNBlockSize {
+		c := uint16(data[i])<<8 | uint16(data[i+1])
+		block := s.DecryptBlock(c)
+		if mode == ModeCBC {
+			block ^= previous
+		}
+		out[i], out[i+1] = byte(block>>8), byte(block)
+		previous = c
+	}
+
+	padding := int(out[len(out)-1])
+	if padding < 1 || padding > SPNBlockSize {
+		return nil, errBadPadding
+	}
+	for _, b := range out[len(out)-padding:] {
+		if int(b) != padding {
+			return nil, errBadPadding
+		}
+	}
+	return out[:len(out)-padding], nil
+}
+
+// Pad fills data out to whole blocks PKCS#7 style: n bytes of value n, always
+// at least one so the padding can be told apart from the data
+func Pad(data []byte) []byte {
+	padding := SPNBlockSize - len(data)%SPNBlockSize
+	padded := make([]byte, len(data), len(data)+padding)
+	copy(padded, data)
+	for i := 0; i < padding; i++ {
+		padded = append(padded, byte(padding))
+	}
+	return padded
+}
+
+// substitute runs every nibble of a block through an S-box
+func substitute(block uint16, sbox *[16]uint16) uint16 {
+	var out uint16
+	for shift := 0; shift < 16; shift += 4 {
+		out |= sbox[(block>>shift)&0xF] << shift
+	}
+	return out
+}
+
+// permute transposes the block as a 4x4 bit matrix: bit j of nibble i moves
+// to bit i of nibble j. The permutation is its own inverse.
+func permute(block uint16) uint16 {
+	var out uint16
+	for i := 0; i < 16; i++ {
+		if block&(1<<(15-i)) != 0 {
+			j := (i%4)*4 + i/4
+			out |= 1 << (15 - j)
+		}
+	}
+	return out
+}
+
+func invertSBox(sbox [16]uint16) [16]uint16 {
+	var inverse [16]uint16
+	for i, v := range sbox {
+		inverse[v] = uint16(i)
+	}
+	return inverse
+}
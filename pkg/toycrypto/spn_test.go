@@ -0,0 +1,165 @@
+package toycrypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewSPNSubkeys(t *testing.T) {
+	spn := NewSPN(0x3A94D63F)
+	want := []uint16{0x3A94, 0xA94D, 0x94D6, 0x4D63, 0xD63F}
+
+	got := spn.Subkeys()
+	if len(got) != len(want) {
+		t.Fatalf("got %d subkeys, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("subkey %d = %#04x, want %#04x", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSPNBlockRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   uint32
+		block uint16
+	}{
+		{"zero key and block", 0, 0},
+		{"all ones", 0xFFFFFFFF, 0xFFFF},
+		{"teaching key", 0x3A94D63F, 0x26B7},
+		{"single bit", 0x00000001, 0x8000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spn := NewSPN(tt.key)
+			c := spn.EncryptBlock(tt.block)
+			if got := spn.DecryptBlock(c); got != tt.block {
+				t.Errorf("DecryptBlock(EncryptBlock(%#04x)) = %#04x", tt.block, got)
+			}
+
+			rounds := spn.TraceBlock(tt.block)
+			if len(rounds) != SPNRounds {
+				t.Fatalf("TraceBlock returned %d rounds, want %d", len(rounds), SPNRounds)
+			}
+			if rounds[0].Input != tt.block || rounds[len(rounds)-1].Output != c {
+				t.Error("trace doesn't start at the block and end at its encryption")
+			}
+			for i := 1; i < len(rounds); i++ {
+				if rounds[i].Input != rounds[i-1].Output {
+					t.Errorf("round %d input %#04x doesn't follow round %d output %#04x", i+1, rounds[i].Input, i, rounds[i-1].Output)
+				}
+			}
+		})
+	}
+}
+
+func TestSPNModes(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		mode string
+		iv   uint16
+	}{
+		{"ECB empty", []byte{}, ModeECB, 0},
+		{"ECB odd length", []byte("HELLO"), ModeECB, 0},
+		{"ECB whole blocks", []byte("ABCD"), ModeECB, 0},
+		{"CBC odd length", []byte("ATTACK AT DAWN!"), ModeCBC, 0x1234},
+		{"CBC zero IV", []byte("ABABABAB"), ModeCBC, 0},
+	}
+
+	spn := NewSPN(0x3A94D63F)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciphertext, err := spn.Encrypt(tt.data, tt.mode, tt.iv)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if len(ciphertext)%SPNBlockSize != 0 || len(ciphertext) <= len(tt.data) {
+				t.Fatalf("ciphertext of %d bytes for %d bytes of data", len(ciphertext), len(tt.data))
+			}
+			plaintext, err := spn.Decrypt(ciphertext, tt.mode, tt.iv)
+			if err != nil {
+				t.Fatalf("Decrypt: %v", err)
+			}
+			if !bytes.Equal(plaintext, tt.data) {
+				t.Errorf("round trip gave %q, want %q", plaintext, tt.data)
+			}
+		})
+	}
+}
+
+func TestSPNRepeatedBlocks(t *testing.T) {
+	spn := NewSPN(0x3A94D63F)
+	data := []byte("ABABAB")
+
+	ecb, _ := spn.Encrypt(data, ModeECB, 0)
+	if !bytes.Equal(ecb[0:2], ecb[2:4]) {
+		t.Error("ECB should encrypt equal blocks alike")
+	}
+	cbc, _ := spn.Encrypt(data, ModeCBC, 0x1234)
+	if bytes.Equal(cbc[0:2], cbc[2:4]) {
+		t.Error("CBC should chain equal blocks into different ciphertext")
+	}
+}
+
+func TestSPNDecryptErrors(t *testing.T) {
+	spn := NewSPN(0x3A94D63F)
+	valid, _ := spn.Encrypt([]byte("HI"), ModeECB, 0)
+
+	tests := []struct {
+		name string
+		data []byte
+		mode string
+	}{
+		{"unknown mode", valid, "CTR"},
+		{"empty", nil, ModeECB},
+		{"partial block", valid[:3], ModeECB},
+		{"wrong key", mustEncrypt(t, NewSPN(1), []byte("HI")), ModeECB},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := spn.Decrypt(tt.data, tt.mode, 0); err == nil {
+				t.Error("Decrypt succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want []byte
+	}{
+		{[]byte{}, []byte{2, 2}},
+		{[]byte{'A'}, []byte{'A', 1}},
+		{[]byte{'A', 'B'}, []byte{'A', 'B', 2, 2}},
+	}
+
+	for _, tt := range tests {
+		if got := Pad(tt.data); !bytes.Equal(got, tt.want) {
+			t.Errorf("Pad(%v) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestPermuteIsInvolution(t *testing.T) {
+	for block := 0; block <= 0xFFFF; block++ {
+		if got := permute(permute(uint16(block))); got != uint16(block) {
+			t.Fatalf("permute(permute(%#04x)) = %#04x", block, got)
+		}
+	}
+}
+
+// mustEncrypt encrypts data in ECB mode
+func mustEncrypt(t *testing.T, spn *SPN, data []byte) []byte {
+	t.Helper()
+	ciphertext, err := spn.Encrypt(data, ModeECB, 0)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	return ciphertext
+}
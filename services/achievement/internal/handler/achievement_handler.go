@@ -5,20 +5,23 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/swarit-1/cipher-clash/pkg/audit"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 	"github.com/swarit-1/cipher-clash/services/achievement/internal"
-	"github.com/swarit-1/cipher-clash/services/achievement/internal/middleware"
 	"github.com/swarit-1/cipher-clash/services/achievement/internal/service"
 )
 
 type AchievementHandler struct {
 	service service.AchievementService
+	audit   *audit.Log
 	log     *logger.Logger
 }
 
-func NewAchievementHandler(service service.AchievementService, log *logger.Logger) *AchievementHandler {
+func NewAchievementHandler(service service.AchievementService, auditLog *audit.Log, log *logger.Logger) *AchievementHandler {
 	return &AchievementHandler{
 		service: service,
+		audit:   auditLog,
 		log:     log,
 	}
 }
@@ -98,7 +101,7 @@ func (h *AchievementHandler) GetUserAchievements(w http.ResponseWriter, r *http.
 		return
 	}
 
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	userID, ok := auth.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, `{"error":"User ID not found in context"}`, http.StatusUnauthorized)
 		return
@@ -127,7 +130,7 @@ func (h *AchievementHandler) GetUserProgress(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	userID, ok := auth.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, `{"error":"User ID not found in context"}`, http.StatusUnauthorized)
 		return
@@ -172,7 +175,7 @@ func (h *AchievementHandler) GetUserStats(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	userID, ok := auth.UserIDFromContext(r.Context())
 	if !ok {
 		http.Error(w, `{"error":"User ID not found in context"}`, http.StatusUnauthorized)
 		return
@@ -213,6 +216,11 @@ func (h *AchievementHandler) CreateAchievement(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	h.audit.Record(r.Context(), audit.ActionAchievementCreate, nil, map[string]interface{}{
+		"achievement_id": achievement.ID,
+		"name":           achievement.Name,
+	})
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(achievement)
@@ -240,6 +248,11 @@ func (h *AchievementHandler) UpdateAchievement(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	h.audit.Record(r.Context(), audit.ActionAchievementUpdate, nil, map[string]interface{}{
+		"achievement_id": achievement.ID,
+		"changes":        req,
+	})
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(achievement)
 }
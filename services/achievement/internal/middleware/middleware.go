@@ -1,27 +1,20 @@
 package middleware
 
 import (
-	"context"
 	"net/http"
-	"strings"
 
-	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 )
 
-type contextKey string
-
-const UserIDKey contextKey = "user_id"
-
+// AuthMiddleware adds CORS headers and logs requests; tokens are checked by
+// auth.Middleware
 type AuthMiddleware struct {
-	jwtManager *auth.JWTManager
-	log        *logger.Logger
+	log *logger.Logger
 }
 
-func NewAuthMiddleware(jwtManager *auth.JWTManager, log *logger.Logger) *AuthMiddleware {
+func NewAuthMiddleware(log *logger.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtManager: jwtManager,
-		log:        log,
+		log: log,
 	}
 }
 
@@ -50,35 +43,3 @@ func (m *AuthMiddleware) Logging(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 	}
 }
-
-func (m *AuthMiddleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, `{"error":"Authorization header required"}`, http.StatusUnauthorized)
-			return
-		}
-
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := m.jwtManager.ValidateToken(tokenString, auth.AccessToken)
-		if err != nil {
-			m.log.Error("Invalid token", map[string]interface{}{
-				"error": err.Error(),
-			})
-			http.Error(w, `{"error":"Invalid token"}`, http.StatusUnauthorized)
-			return
-		}
-
-		// Add user info to context
-		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-
-		next(w, r.WithContext(ctx))
-	}
-}
-
-
-// Helper to get user ID from context
-func GetUserIDFromContext(ctx context.Context) (string, bool) {
-	userID, ok := ctx.Value(UserIDKey).(string)
-	return userID, ok
-}
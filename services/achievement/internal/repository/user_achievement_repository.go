@@ -19,6 +19,7 @@ type UserAchievementRepository interface {
 	UnlockAchievement(ctx context.Context, userID, achievementID string) error
 	GetUserStats(ctx context.Context, userID string) (*internal.UserAchievementStats, error)
 	GetUserAchievementsWithProgress(ctx context.Context, userID string) ([]*internal.AchievementWithProgress, error)
+	DeleteByUserID(ctx context.Context, userID string) error
 }
 
 type userAchievementRepository struct {
@@ -332,3 +333,17 @@ func (r *userAchievementRepository) GetUserAchievementsWithProgress(ctx context.
 
 	return achievements, nil
 }
+
+func (r *userAchievementRepository) DeleteByUserID(ctx context.Context, userID string) error {
+	query := `DELETE FROM user_achievements WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		r.log.Error("Failed to delete user achievements", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		return fmt.Errorf("failed to delete user achievements: %w", err)
+	}
+
+	return nil
+}
@@ -23,6 +23,7 @@ type AchievementService interface {
 	GetUserStats(ctx context.Context, userID string) (*internal.UserAchievementStats, error)
 	UpdateProgress(ctx context.Context, userID, achievementID string, increment int) error
 	InitializeUserAchievements(ctx context.Context, userID string) error
+	DeleteUserData(ctx context.Context, userID string) error
 }
 
 type achievementService struct {
@@ -306,3 +307,18 @@ func (s *achievementService) InitializeUserAchievements(ctx context.Context, use
 
 	return nil
 }
+
+// DeleteUserData erases the achievement progress of a user who deleted
+// their account
+func (s *achievementService) DeleteUserData(ctx context.Context, userID string) error {
+	if err := s.userAchievementRepo.DeleteByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	s.cache.Delete(ctx,
+		fmt.Sprintf("user:%s:achievements", userID),
+		fmt.Sprintf("user:%s:achievement_stats", userID),
+	)
+
+	return nil
+}
@@ -8,12 +8,15 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	"github.com/swarit-1/cipher-clash/pkg/audit"
 	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
 	"github.com/swarit-1/cipher-clash/services/achievement/internal/handler"
 	"github.com/swarit-1/cipher-clash/services/achievement/internal/middleware"
 	"github.com/swarit-1/cipher-clash/services/achievement/internal/repository"
@@ -65,8 +68,13 @@ func main() {
 	}
 	defer cacheClient.Close()
 
-	// Initialize JWT manager
-	jwtManager := auth.NewJWTManager(cfg.JWT)
+	// Initialize JWT verifier, which checks tokens against the auth service's keys
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT verifier", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 
 	// Initialize repositories
 	achievementRepo := repository.NewAchievementRepository(database, log)
@@ -80,11 +88,25 @@ func main() {
 		log,
 	)
 
+	// Erase what this service keeps about users who delete their account
+	// (optional - warn but continue if RabbitMQ unavailable)
+	userDeletions, err := messaging.ListenUserDeleted(cfg.RabbitMQ, "achievement", func(ctx context.Context, userID uuid.UUID) error {
+		return achievementService.DeleteUserData(ctx, userID.String())
+	}, log)
+	if err != nil {
+		log.Warn("RabbitMQ unavailable - deleted users' achievement progress won't be erased", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else {
+		defer userDeletions.Close()
+	}
+
 	// Initialize handlers
-	achievementHandler := handler.NewAchievementHandler(achievementService, log)
+	achievementHandler := handler.NewAchievementHandler(achievementService, audit.NewLog(database, log), log)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtManager, log)
+	authMiddleware := middleware.NewAuthMiddleware(log)
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, log)
 
 	// Setup HTTP router
 	mux := http.NewServeMux()
@@ -97,9 +119,13 @@ func main() {
 	mux.HandleFunc("/api/v1/achievements/", authMiddleware.CORS(authMiddleware.Logging(achievementHandler.GetAchievement)))
 
 	// Protected routes (user-specific)
-	mux.HandleFunc("/api/v1/user/achievements", authMiddleware.CORS(authMiddleware.Logging(authMiddleware.RequireAuth(achievementHandler.GetUserAchievements))))
-	mux.HandleFunc("/api/v1/user/achievements/progress", authMiddleware.CORS(authMiddleware.Logging(authMiddleware.RequireAuth(achievementHandler.GetUserProgress))))
-	mux.HandleFunc("/api/v1/user/achievements/stats", authMiddleware.CORS(authMiddleware.Logging(authMiddleware.RequireAuth(achievementHandler.GetUserStats))))
+	mux.HandleFunc("/api/v1/user/achievements", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, achievementHandler.GetUserAchievements))))
+	mux.HandleFunc("/api/v1/user/achievements/progress", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, achievementHandler.GetUserProgress))))
+	mux.HandleFunc("/api/v1/user/achievements/stats", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, achievementHandler.GetUserStats))))
+
+	// Admin routes
+	mux.HandleFunc("/api/v1/admin/achievements/create", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopeAdmin, achievementHandler.CreateAchievement))))
+	mux.HandleFunc("/api/v1/admin/achievements/update", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopeAdmin, achievementHandler.UpdateAchievement))))
 
 	// Create HTTP server
 	addr := "0.0.0.0:" + port
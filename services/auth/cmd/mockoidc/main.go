@@ -0,0 +1,36 @@
+// Command mockoidc runs a mock OpenID Connect provider for trying social
+// login locally. Configure the auth service with OIDC_PROVIDERS=mock and
+// OIDC_MOCK_ISSUER, OIDC_MOCK_CLIENT_ID and OIDC_MOCK_CLIENT_SECRET matching
+// the flags here.
+package main
+
+import (
+	"flag"
+	"net/http"
+
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/oidc/mockoidc"
+)
+
+func main() {
+	addr := flag.String("addr", ":9400", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9400", "issuer URL the provider is reached at")
+	clientID := flag.String("client-id", "cipher-clash", "client ID the auth service uses")
+	clientSecret := flag.String("client-secret", "mock-secret", "client secret the auth service uses")
+	flag.Parse()
+
+	log := logger.New("mock-oidc")
+
+	provider, err := mockoidc.New(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal("Failed to create mock OIDC provider", map[string]interface{}{"error": err.Error()})
+	}
+
+	log.Info("Mock OIDC provider listening", map[string]interface{}{
+		"addr":   *addr,
+		"issuer": *issuer,
+	})
+	if err := http.ListenAndServe(*addr, provider.Handler()); err != nil {
+		log.Fatal("Mock OIDC provider stopped", map[string]interface{}{"error": err.Error()})
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/auth/internal/service"
+)
+
+// RequestDataExport starts an export of the authenticated user's data
+func (h *AuthHandler) RequestDataExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := h.accountDataService.RequestDataExport(r.Context(), uid)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusAccepted, export)
+}
+
+// ListDataExports lists the authenticated user's exports
+func (h *AuthHandler) ListDataExports(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	exports, err := h.accountDataService.ListDataExports(r.Context(), uid)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"exports": exports,
+	})
+}
+
+// DownloadDataExport sends the archive of the export given by the id query
+// parameter as a JSON file
+func (h *AuthHandler) DownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+	exportID, err := uuid.Parse(r.URL.Query().Get("id"))
+	if err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid export ID"))
+		return
+	}
+
+	export, err := h.accountDataService.DownloadDataExport(r.Context(), uid, exportID)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="cipher-clash-export-%s.json"`, export.ID.String()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(export.Archive); err != nil {
+		h.log.Error("Failed to write data export", map[string]interface{}{
+			"export_id": export.ID.String(),
+			"error":     err.Error(),
+		})
+	}
+}
+
+// DeleteAccount deletes the authenticated user's account
+func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	if claims, ok := auth.ClaimsFromContext(r.Context()); ok {
+		req.SessionID = claims.SessionID
+	}
+
+	if err := h.accountDataService.DeleteAccount(r.Context(), uid, &req); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Account deleted",
+	})
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/auth/internal/service"
+)
+
+// VerifyEmail confirms an email address with the token from a verification
+// link
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	user, err := h.authService.VerifyEmail(r.Context(), req.Token)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, user)
+}
+
+// ResendVerification emails the authenticated user a new verification link
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	userID, _ := auth.UserIDFromContext(r.Context())
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		h.respondError(w, errors.NewUnauthorizedError("Invalid user ID"))
+		return
+	}
+
+	if err := h.authService.ResendVerification(r.Context(), uid); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Verification email sent",
+	})
+}
+
+// RequestPasswordReset emails a password reset link. It answers the same
+// whether or not the address has an account.
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(r.Context(), &req, clientInfo(r)); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "If an account uses that address, a reset link is on its way",
+	})
+}
+
+// ResetPassword sets a new password with the token from a reset link
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), &req); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Password reset, log in with your new password",
+	})
+}
+
+// ChangePassword replaces the authenticated user's password, ending their
+// other sessions
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	userID, _ := auth.UserIDFromContext(r.Context())
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		h.respondError(w, errors.NewUnauthorizedError("Invalid user ID"))
+		return
+	}
+
+	var req service.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	response, err := h.authService.ChangePassword(r.Context(), uid, &req, clientInfo(r))
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/audit"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/auth/internal/service"
+)
+
+// BanUser bans a user. Moderators and admins.
+func (h *AuthHandler) BanUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	user, err := h.adminService.BanUser(r.Context(), &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, user)
+}
+
+// UnbanUser lifts a user's ban. Moderators and admins.
+func (h *AuthHandler) UnbanUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	user, err := h.adminService.UnbanUser(r.Context(), &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, user)
+}
+
+// SetUserRole changes a user's role. Admins only.
+func (h *AuthHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	user, err := h.adminService.SetRole(r.Context(), &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, user)
+}
+
+// AdjustUserRating moves a user's rating. Admins only.
+func (h *AuthHandler) AdjustUserRating(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.RatingAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	user, err := h.adminService.AdjustRating(r.Context(), &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, user)
+}
+
+// ListAuditLog lists privileged actions across all services, filtered by
+// the actor_id, target_user_id and action query parameters. Admins only.
+func (h *AuthHandler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := audit.Filter{Action: query.Get("action")}
+
+	if raw := query.Get("actor_id"); raw != "" {
+		actorID, err := uuid.Parse(raw)
+		if err != nil {
+			h.respondError(w, errors.NewInvalidInputError("Invalid actor ID"))
+			return
+		}
+		filter.ActorID = &actorID
+	}
+	if raw := query.Get("target_user_id"); raw != "" {
+		targetUserID, err := uuid.Parse(raw)
+		if err != nil {
+			h.respondError(w, errors.NewInvalidInputError("Invalid target user ID"))
+			return
+		}
+		filter.TargetUserID = &targetUserID
+	}
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil {
+		filter.Limit = limit
+	}
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil {
+		filter.Offset = offset
+	}
+
+	entries, err := h.adminService.ListAuditLog(r.Context(), filter)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"entries": entries,
+	})
+}
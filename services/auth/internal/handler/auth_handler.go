@@ -2,9 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 
 	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 	"github.com/swarit-1/cipher-clash/services/auth/internal/service"
@@ -12,15 +14,21 @@ import (
 
 // AuthHandler handles HTTP requests for authentication
 type AuthHandler struct {
-	authService *service.AuthService
-	log         *logger.Logger
+	authService        *service.AuthService
+	adminService       *service.AdminService
+	oidcService        *service.OIDCService
+	accountDataService *service.AccountDataService
+	log                *logger.Logger
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(authService *service.AuthService, log *logger.Logger) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, adminService *service.AdminService, oidcService *service.OIDCService, accountDataService *service.AccountDataService, log *logger.Logger) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		log:         log,
+		authService:        authService,
+		adminService:       adminService,
+		oidcService:        oidcService,
+		accountDataService: accountDataService,
+		log:                log,
 	}
 }
 
@@ -32,7 +40,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.authService.Register(r.Context(), &req)
+	response, err := h.authService.Register(r.Context(), &req, clientInfo(r))
 	if err != nil {
 		h.respondError(w, err)
 		return
@@ -49,7 +57,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response, err := h.authService.Login(r.Context(), &req)
+	response, err := h.authService.Login(r.Context(), &req, clientInfo(r))
 	if err != nil {
 		h.respondError(w, err)
 		return
@@ -68,7 +76,7 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.authService.RefreshToken(r.Context(), req.RefreshToken)
+	tokens, err := h.authService.RefreshToken(r.Context(), req.RefreshToken, clientInfo(r))
 	if err != nil {
 		h.respondError(w, err)
 		return
@@ -79,7 +87,7 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 
 // GetProfile retrieves the authenticated user's profile
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
+	userID, _ := auth.UserIDFromContext(r.Context())
 	uid, err := uuid.Parse(userID)
 	if err != nil {
 		h.respondError(w, errors.NewUnauthorizedError("Invalid user ID"))
@@ -97,7 +105,7 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 
 // UpdateProfile updates user profile
 func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
+	userID, _ := auth.UserIDFromContext(r.Context())
 	uid, err := uuid.Parse(userID)
 	if err != nil {
 		h.respondError(w, errors.NewUnauthorizedError("Invalid user ID"))
@@ -123,22 +131,103 @@ func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusOK, user)
 }
 
-// Logout handles user logout
+// Logout ends the session of the access token used
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value("user_id").(string)
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.respondError(w, errors.NewUnauthorizedError("Missing token claims"))
+		return
+	}
+
+	if err := h.authService.Logout(r.Context(), claims); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Logged out successfully",
+	})
+}
+
+// LogoutAll ends every session of the authenticated user
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	userID, _ := auth.UserIDFromContext(r.Context())
 	uid, err := uuid.Parse(userID)
 	if err != nil {
 		h.respondError(w, errors.NewUnauthorizedError("Invalid user ID"))
 		return
 	}
 
-	if err := h.authService.Logout(r.Context(), uid); err != nil {
+	sessions, err := h.authService.LogoutAll(r.Context(), uid)
+	if err != nil {
 		h.respondError(w, err)
 		return
 	}
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Logged out successfully",
+		"message":  "Logged out everywhere",
+		"sessions": sessions,
+	})
+}
+
+// ListSessions lists the authenticated user's logged in devices
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		h.respondError(w, errors.NewUnauthorizedError("Missing token claims"))
+		return
+	}
+	uid, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		h.respondError(w, errors.NewUnauthorizedError("Invalid user ID"))
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(r.Context(), uid, claims.SessionID)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession logs one of the authenticated user's devices out
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	userID, _ := auth.UserIDFromContext(r.Context())
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		h.respondError(w, errors.NewUnauthorizedError("Invalid user ID"))
+		return
+	}
+
+	var req struct {
+		SessionID string `json:"session_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), uid, req.SessionID); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Session revoked",
 	})
 }
 
@@ -152,6 +241,19 @@ func (h *AuthHandler) Health(w http.ResponseWriter, r *http.Request) {
 
 // Helper methods
 
+// clientInfo identifies the device making a request. The device label comes
+// from the request body instead.
+func clientInfo(r *http.Request) service.ClientInfo {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	return service.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IPAddress: ip,
+	}
+}
+
 func (h *AuthHandler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/auth/internal/service"
+)
+
+// CreateGuest starts a guest account for playing without registering
+func (h *AuthHandler) CreateGuest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	// Everything in the body is optional, so it may be empty
+	var req service.GuestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	response, err := h.authService.CreateGuest(r.Context(), &req, clientInfo(r))
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, response)
+}
+
+// UpgradeGuest registers the authenticated guest with credentials, keeping
+// their progress
+func (h *AuthHandler) UpgradeGuest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.UpgradeGuestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	response, err := h.authService.UpgradeGuest(r.Context(), uid, &req, clientInfo(r))
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/auth/internal/service"
+)
+
+// LoginMFA completes a login to an account with 2FA on, exchanging its
+// challenge token and a code for tokens
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	response, err := h.authService.CompleteMFALogin(r.Context(), &req, clientInfo(r))
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// MFAStatus reports whether the authenticated user has 2FA on
+func (h *AuthHandler) MFAStatus(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.authService.MFAStatus(r.Context(), uid)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, status)
+}
+
+// EnrollMFA creates a TOTP secret for the authenticated user
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.MFAEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	enrollment, err := h.authService.EnrollMFA(r.Context(), uid, &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, enrollment)
+}
+
+// ConfirmMFA turns 2FA on with a first code, returning recovery codes
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	codes, err := h.authService.ConfirmMFA(r.Context(), uid, &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, codes)
+}
+
+// DisableMFA turns 2FA off for the authenticated user
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.MFADisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	if err := h.authService.DisableMFA(r.Context(), uid, &req); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the authenticated user's recovery codes
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), uid, &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, codes)
+}
+
+// callerID returns the authenticated user's ID, answering the request if
+// there is none
+func (h *AuthHandler) callerID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, _ := auth.UserIDFromContext(r.Context())
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		h.respondError(w, errors.NewUnauthorizedError("Invalid user ID"))
+		return uuid.Nil, false
+	}
+	return uid, true
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/auth/internal/service"
+)
+
+// OIDCProviders lists the providers users can log in with
+func (h *AuthHandler) OIDCProviders(w http.ResponseWriter, r *http.Request) {
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"providers": h.oidcService.Providers(),
+	})
+}
+
+// StartOIDCLogin returns the provider page to send the user to
+func (h *AuthHandler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.OIDCStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	authorization, err := h.oidcService.StartLogin(r.Context(), &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, authorization)
+}
+
+// OIDCCallback completes a login with the code the provider redirected
+// back with
+func (h *AuthHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	response, err := h.oidcService.CompleteLogin(r.Context(), &req, clientInfo(r))
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, response)
+}
+
+// ListIdentities lists the provider accounts linked to the authenticated user
+func (h *AuthHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	identities, err := h.oidcService.ListIdentities(r.Context(), uid)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"identities": identities,
+	})
+}
+
+// StartLinkIdentity starts linking a provider account to the authenticated
+// user
+func (h *AuthHandler) StartLinkIdentity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.OIDCStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	authorization, err := h.oidcService.StartLink(r.Context(), uid, &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, authorization)
+}
+
+// CompleteLinkIdentity links the provider account the code belongs to
+func (h *AuthHandler) CompleteLinkIdentity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.OIDCCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	identities, err := h.oidcService.CompleteLink(r.Context(), uid, &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"identities": identities,
+	})
+}
+
+// UnlinkIdentity removes a provider account from the authenticated user
+func (h *AuthHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+	uid, ok := h.callerID(w, r)
+	if !ok {
+		return
+	}
+
+	var req service.OIDCStartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	if err := h.oidcService.Unlink(r.Context(), uid, &req); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Account unlinked",
+	})
+}
@@ -1,61 +1,21 @@
 package middleware
 
 import (
-	"context"
 	"net/http"
-	"strings"
 
-	"github.com/swarit-1/cipher-clash/pkg/auth"
-	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 )
 
-// AuthMiddleware validates JWT tokens
+// AuthMiddleware provides CORS and request logging. Authentication is
+// auth.Middleware, shared by every service.
 type AuthMiddleware struct {
-	jwtManager *auth.JWTManager
-	log        *logger.Logger
+	log *logger.Logger
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(jwtManager *auth.JWTManager, log *logger.Logger) *AuthMiddleware {
+func NewAuthMiddleware(log *logger.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtManager: jwtManager,
-		log:        log,
-	}
-}
-
-// RequireAuth validates JWT and adds user context
-func (m *AuthMiddleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Extract token from Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			m.respondError(w, errors.NewUnauthorizedError("Missing authorization header"))
-			return
-		}
-
-		// Check Bearer prefix
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			m.respondError(w, errors.NewUnauthorizedError("Invalid authorization header format"))
-			return
-		}
-
-		token := parts[1]
-
-		// Validate token
-		claims, err := m.jwtManager.ValidateToken(token, auth.AccessToken)
-		if err != nil {
-			m.respondError(w, errors.NewUnauthorizedError("Invalid or expired token"))
-			return
-		}
-
-		// Add user info to context
-		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
-		ctx = context.WithValue(ctx, "username", claims.Username)
-
-		// Call next handler
-		next.ServeHTTP(w, r.WithContext(ctx))
+		log: log,
 	}
 }
 
@@ -86,14 +46,3 @@ func (m *AuthMiddleware) Logging(next http.HandlerFunc) http.HandlerFunc {
 		next.ServeHTTP(w, r)
 	}
 }
-
-func (m *AuthMiddleware) respondError(w http.ResponseWriter, err error) {
-	appErr, ok := err.(*errors.AppError)
-	if !ok {
-		appErr = errors.NewInternalServerError(err)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(appErr.HTTPStatus)
-	w.Write([]byte(`{"error":{"code":"` + appErr.Code + `","message":"` + appErr.Message + `"}}`))
-}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/mailer"
+	"github.com/swarit-1/cipher-clash/pkg/repository"
+)
+
+// Account email limits. Reset requests are limited per address and per
+// client so nobody can flood an inbox or probe many addresses.
+const (
+	accountEmailWindow         = time.Hour
+	verificationResendsPerHour = 3
+	passwordResetsPerEmail     = 3
+	passwordResetsPerIP        = 10
+	passwordChangesPerHour     = 5
+)
+
+// PasswordResetRequest asks for a reset link
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest sets a new password with a reset link's token
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePasswordRequest sets a new password while logged in
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	DeviceLabel     string `json:"device_label,omitempty"`
+}
+
+// ResendVerification emails a new verification link, replacing any sent
+// before
+func (s *AuthService) ResendVerification(ctx context.Context, userID uuid.UUID) error {
+	if err := s.rateLimit(ctx, fmt.Sprintf("verify_email:%s", userID.String()), verificationResendsPerHour); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsVerified {
+		return errors.NewInvalidInputError("Email is already verified")
+	}
+	if user.IsGuest {
+		return errors.NewInvalidInputError("Guest accounts have no email to verify")
+	}
+
+	if err := s.accountTokens.InvalidateAll(ctx, user.ID, string(auth.EmailVerificationToken)); err != nil {
+		return err
+	}
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// VerifyEmail confirms a user's address with the token from their
+// verification link
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) (*UserDTO, error) {
+	userID, err := s.consumeAccountToken(ctx, token, auth.EmailVerificationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.MarkVerified(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.accountTokens.InvalidateAll(ctx, userID, string(auth.EmailVerificationToken)); err != nil {
+		return nil, err
+	}
+	s.cache.Delete(ctx, fmt.Sprintf("user:%s", userID.String()))
+
+	s.log.Info("Email verified", map[string]interface{}{
+		"user_id": userID.String(),
+	})
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toUserDTO(user), nil
+}
+
+// RequestPasswordReset emails a reset link if an account uses the address.
+// The outcome is the same either way, so it can't be used to find accounts.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, req *PasswordResetRequest, client ClientInfo) error {
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		return errors.NewInvalidInputError("Email is required")
+	}
+
+	if err := s.rateLimit(ctx, fmt.Sprintf("password_reset:%s", strings.ToLower(email)), passwordResetsPerEmail); err != nil {
+		return err
+	}
+	if err := s.rateLimit(ctx, fmt.Sprintf("password_reset_ip:%s", client.IPAddress), passwordResetsPerIP); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrDatabaseError {
+			return err
+		}
+		return nil
+	}
+	if user.IsBanned {
+		return nil
+	}
+
+	// Only the newest link works
+	if err := s.accountTokens.InvalidateAll(ctx, user.ID, string(auth.PasswordResetToken)); err != nil {
+		return err
+	}
+
+	link, err := s.issueAccountToken(ctx, user, auth.PasswordResetToken, s.accountCfg.PasswordResetTTL, "/reset-password")
+	if err != nil {
+		return err
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your Cipher Clash password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your Cipher Clash account. If it was you, choose a new password here:\n\n%s\n\nThe link works once and expires in %s. If you didn't ask for this, ignore this email; your password stays the same.\n",
+			user.Username, link, s.accountCfg.PasswordResetTTL,
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		// Failing here would tell the caller the address has an account
+		s.log.Error("Failed to send password reset email", map[string]interface{}{
+			"user_id": user.ID.String(),
+			"error":   err.Error(),
+		})
+		return nil
+	}
+
+	s.log.Info("Password reset requested", map[string]interface{}{
+		"user_id": user.ID.String(),
+	})
+	return nil
+}
+
+// ResetPassword sets a new password with the token from a reset link. Every
+// session of the account ends, in case the old password was compromised, and
+// a login lockout is lifted.
+func (s *AuthService) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+	if err := auth.ValidatePasswordStrength(req.NewPassword); err != nil {
+		return errors.NewInvalidInputError(err.Error())
+	}
+
+	userID, err := s.consumeAccountToken(ctx, req.Token, auth.PasswordResetToken)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsBanned {
+		return errors.NewForbiddenError("Account is banned")
+	}
+
+	if _, err := s.setPassword(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+	s.unlockLogin(ctx, user.Email)
+
+	s.log.Info("Password reset", map[string]interface{}{
+		"user_id": user.ID.String(),
+	})
+	return nil
+}
+
+// ChangePassword replaces the password of a logged in user. Every session
+// ends, and the device making the request gets a new one.
+func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, req *ChangePasswordRequest, client ClientInfo) (*AuthResponse, error) {
+	if err := s.rateLimit(ctx, fmt.Sprintf("change_password:%s", userID.String()), passwordChangesPerHour); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.ComparePassword(user.PasswordHash, req.CurrentPassword); err != nil {
+		return nil, errors.NewInvalidCredentialsError()
+	}
+	if err := auth.ValidatePasswordStrength(req.NewPassword); err != nil {
+		return nil, errors.NewInvalidInputError(err.Error())
+	}
+
+	sessions, err := s.setPassword(ctx, user, req.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	client.DeviceLabel = req.DeviceLabel
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Password changed", map[string]interface{}{
+		"user_id":        user.ID.String(),
+		"sessions_ended": sessions,
+	})
+
+	return &AuthResponse{
+		User:         s.toUserDTO(user),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// rateLimit allows limit requests per hour under key
+func (s *AuthService) rateLimit(ctx context.Context, key string, limit int64) error {
+	allowed, err := s.cache.RateLimitCheck(ctx, key, limit, accountEmailWindow)
+	if err != nil {
+		s.log.Error("Rate limit check failed", map[string]interface{}{"error": err.Error()})
+	}
+	if !allowed {
+		return errors.NewRateLimitError()
+	}
+	return nil
+}
+
+// sendVerificationEmail emails a user a link confirming their address
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *repository.User) error {
+	link, err := s.issueAccountToken(ctx, user, auth.EmailVerificationToken, s.accountCfg.VerificationTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your Cipher Clash email address",
+		Body: fmt.Sprintf(
+			"Welcome to Cipher Clash, %s!\n\nConfirm this is your email address by opening:\n\n%s\n\nThe link expires in %s.\n",
+			user.Username, link, s.accountCfg.VerificationTTL,
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return errors.NewInternalServerError(err)
+	}
+	return nil
+}
+
+// issueAccountToken stores a new single-use token and returns the app link
+// at path that carries it
+func (s *AuthService) issueAccountToken(ctx context.Context, user *repository.User, tokenType auth.TokenType, ttl time.Duration, path string) (string, error) {
+	token, err := s.jwtManager.GenerateSingleUseToken(user.ID.String(), tokenType, ttl)
+	if err != nil {
+		return "", errors.NewInternalServerError(err)
+	}
+	tokenID, err := uuid.Parse(token.ID)
+	if err != nil {
+		return "", errors.NewInternalServerError(err)
+	}
+
+	err = s.accountTokens.Create(ctx, &repository.AccountToken{
+		ID:        tokenID,
+		UserID:    user.ID,
+		Purpose:   string(tokenType),
+		ExpiresAt: token.ExpiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(s.accountCfg.AppURL, "/") + path + "?token=" + url.QueryEscape(token.Token), nil
+}
+
+// consumeAccountToken checks a single-use token's signature and uses it up,
+// returning its user
+func (s *AuthService) consumeAccountToken(ctx context.Context, token string, tokenType auth.TokenType) (uuid.UUID, error) {
+	invalid := errors.NewInvalidInputError("Link is invalid or has expired")
+
+	claims, err := s.jwtManager.ValidateToken(token, tokenType)
+	if err != nil {
+		return uuid.Nil, invalid
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, invalid
+	}
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, invalid
+	}
+
+	consumed, err := s.accountTokens.Consume(ctx, tokenID, userID, string(tokenType))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !consumed {
+		return uuid.Nil, invalid
+	}
+	return userID, nil
+}
+
+// setPassword stores a new password, voids outstanding reset links and ends
+// every session, returning how many ended
+func (s *AuthService) setPassword(ctx context.Context, user *repository.User, password string) (int, error) {
+	passwordHash, err := auth.HashPassword(password)
+	if err != nil {
+		return 0, errors.NewInternalServerError(err)
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, passwordHash); err != nil {
+		return 0, err
+	}
+	user.PasswordHash = passwordHash
+
+	if err := s.accountTokens.InvalidateAll(ctx, user.ID, string(auth.PasswordResetToken)); err != nil {
+		return 0, err
+	}
+	return s.LogoutAll(ctx, user.ID)
+}
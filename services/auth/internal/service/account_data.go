@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
+	"github.com/swarit-1/cipher-clash/pkg/repository"
+)
+
+const (
+	dataExportTTL         = 7 * 24 * time.Hour // How long a finished export can be downloaded
+	dataExportTimeout     = 2 * time.Minute
+	dataExportsPerHour    = 2
+	accountDeletesPerHour = 5
+	// accountReauthWindow is how recently an account without a password must
+	// have logged in to delete itself
+	accountReauthWindow = 5 * time.Minute
+	// Deletions the other services haven't heard about are published in
+	// batches, right after each deletion and then on an interval in case
+	// RabbitMQ was down
+	deletionBatchSize       = 100
+	deletionPublishInterval = time.Minute
+)
+
+// DeleteAccountRequest confirms an account deletion. The password is needed
+// if the account has one, or else a session that has just logged in, and a
+// code if it has 2FA on.
+type DeleteAccountRequest struct {
+	Password  string `json:"password"`
+	Code      string `json:"code"`
+	SessionID string `json:"-"` // Set from the caller's token, never the body
+}
+
+// AccountDataService exports a user's data and deletes accounts
+type AccountDataService struct {
+	exports     *repository.DataExportRepository
+	userData    *repository.UserDataRepository
+	deletions   *repository.AccountDeletionRepository
+	userRepo    *repository.UserRepository
+	authService *AuthService
+	publisher   *messaging.Publisher // nil when RabbitMQ is unavailable
+	cache       *cache.Cache
+	log         *logger.Logger
+	wake        chan struct{}
+}
+
+// NewAccountDataService creates a new account data service
+func NewAccountDataService(
+	exports *repository.DataExportRepository,
+	userData *repository.UserDataRepository,
+	deletions *repository.AccountDeletionRepository,
+	userRepo *repository.UserRepository,
+	authService *AuthService,
+	publisher *messaging.Publisher,
+	cache *cache.Cache,
+	log *logger.Logger,
+) *AccountDataService {
+	return &AccountDataService{
+		exports:     exports,
+		userData:    userData,
+		deletions:   deletions,
+		userRepo:    userRepo,
+		authService: authService,
+		publisher:   publisher,
+		cache:       cache,
+		log:         log,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// RequestDataExport starts building an archive of a user's data. It is
+// built in the background; the export is listed as ready once it can be
+// downloaded.
+func (s *AccountDataService) RequestDataExport(ctx context.Context, userID uuid.UUID) (*repository.DataExport, error) {
+	if err := s.authService.rateLimit(ctx, fmt.Sprintf("data_export:%s", userID.String()), dataExportsPerHour); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.exports.DeleteExpired(ctx); err != nil {
+		return nil, err
+	}
+	export, err := s.exports.Create(ctx, userID, dataExportTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Data export requested", map[string]interface{}{
+		"user_id":   userID.String(),
+		"export_id": export.ID.String(),
+	})
+
+	go s.buildExport(export.ID, userID)
+	return export, nil
+}
+
+// ListDataExports returns a user's exports that haven't expired
+func (s *AccountDataService) ListDataExports(ctx context.Context, userID uuid.UUID) ([]*repository.DataExport, error) {
+	return s.exports.ListByUser(ctx, userID)
+}
+
+// DownloadDataExport returns a finished export with its archive
+func (s *AccountDataService) DownloadDataExport(ctx context.Context, userID, exportID uuid.UUID) (*repository.DataExport, error) {
+	export, err := s.exports.Find(ctx, exportID, userID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	switch export.Status {
+	case repository.ExportReady:
+		return export, nil
+	case repository.ExportFailed:
+		return nil, errors.NewInvalidInputError("Export failed, request a new one")
+	default:
+		return nil, errors.NewInvalidInputError("Export is not ready yet")
+	}
+}
+
+// DeleteAccount deletes a user's account. Their sessions end at once and
+// the other services are told to erase their data; matches stay, showing
+// an anonymous player to their opponents.
+func (s *AccountDataService) DeleteAccount(ctx context.Context, userID uuid.UUID, req *DeleteAccountRequest) error {
+	if err := s.authService.rateLimit(ctx, fmt.Sprintf("delete_account:%s", userID.String()), accountDeletesPerHour); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// Social login users have no password to confirm with, so they log in
+	// with their provider again. Guests have no credential but their session.
+	switch {
+	case user.PasswordHash != "":
+		if err := auth.ComparePassword(user.PasswordHash, req.Password); err != nil {
+			return errors.NewInvalidCredentialsError()
+		}
+	case !user.IsGuest:
+		if err := s.requireRecentLogin(ctx, userID, req.SessionID); err != nil {
+			return err
+		}
+	}
+
+	mfaEnabled, err := s.authService.mfaEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if mfaEnabled {
+		mfa, err := s.authService.enabledMFA(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.authService.verifySecondFactor(ctx, mfa, req.Code, true); err != nil {
+			return err
+		}
+	}
+
+	// Access tokens are denylisted by session, so sessions end before the
+	// delete removes them
+	sessions, err := s.authService.LogoutAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	if s.cache != nil {
+		s.cache.Delete(ctx, fmt.Sprintf("user:%s", userID.String()))
+	}
+
+	s.log.Info("Account deleted", map[string]interface{}{
+		"user_id":        userID.String(),
+		"sessions_ended": sessions,
+	})
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// requireRecentLogin checks a session was started by a login within
+// accountReauthWindow
+func (s *AccountDataService) requireRecentLogin(ctx context.Context, userID uuid.UUID, sessionID string) error {
+	reauth := errors.NewForbiddenError("Log in again to confirm it's you, then retry")
+
+	familyID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return reauth
+	}
+	startedAt, err := s.authService.refreshTokens.SessionStartedAt(ctx, userID, familyID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.HTTPStatus == http.StatusNotFound {
+			return reauth
+		}
+		return err
+	}
+	if time.Since(startedAt) > accountReauthWindow {
+		return reauth
+	}
+	return nil
+}
+
+// RunDeletionPublisher publishes user.deleted for every deleted account
+// until the context is cancelled. Without a publisher, deletions wait in
+// the outbox for a restart that has one.
+func (s *AccountDataService) RunDeletionPublisher(ctx context.Context) {
+	if s.publisher == nil {
+		return
+	}
+
+	ticker := time.NewTicker(deletionPublishInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.publishDeletions(ctx); err != nil {
+			s.log.Error("Failed to publish account deletions", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// publishDeletions publishes the pending deletions, oldest first
+func (s *AccountDataService) publishDeletions(ctx context.Context) error {
+	for {
+		pending, err := s.deletions.Pending(ctx, deletionBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, userID := range pending {
+			err := s.publisher.Publish(ctx, messaging.ExchangeUsers, string(messaging.EventUserDeleted), messaging.UserDeletedEvent(userID))
+			if err != nil {
+				return err
+			}
+			if err := s.deletions.MarkPublished(ctx, userID); err != nil {
+				return err
+			}
+		}
+
+		if len(pending) < deletionBatchSize {
+			return nil
+		}
+	}
+}
+
+// buildExport collects a user's data into an export's archive
+func (s *AccountDataService) buildExport(exportID, userID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), dataExportTimeout)
+	defer cancel()
+
+	archive, err := s.userData.Collect(ctx, userID)
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(archive)
+	}
+	if err == nil {
+		err = s.exports.Complete(ctx, exportID, data)
+	}
+	if err == nil {
+		s.log.Info("Data export ready", map[string]interface{}{
+			"user_id":   userID.String(),
+			"export_id": exportID.String(),
+			"bytes":     len(data),
+		})
+		return
+	}
+
+	s.log.Error("Failed to build data export", map[string]interface{}{
+		"user_id":   userID.String(),
+		"export_id": exportID.String(),
+		"error":     err.Error(),
+	})
+	// The build may have failed by running out of time, so recording it
+	// gets a context of its own
+	failCtx, cancelFail := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFail()
+	if err := s.exports.Fail(failCtx, exportID, "Export could not be built"); err != nil {
+		s.log.Error("Failed to record data export failure", map[string]interface{}{
+			"export_id": exportID.String(),
+			"error":     err.Error(),
+		})
+	}
+}
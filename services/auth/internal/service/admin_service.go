@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/audit"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/repository"
+)
+
+const (
+	maxAdminReasonLength = 500
+	// maxRatingAdjustment bounds one manual rating change, so a typo can't
+	// send a player to the top of the leaderboard
+	maxRatingAdjustment = 1000
+)
+
+// AdminService handles moderator and admin actions on user accounts. Every
+// action is written to the audit log.
+type AdminService struct {
+	userRepo    *repository.UserRepository
+	authService *AuthService
+	audit       *audit.Log
+	cache       *cache.Cache
+	log         *logger.Logger
+}
+
+// NewAdminService creates a new admin service
+func NewAdminService(
+	userRepo *repository.UserRepository,
+	authService *AuthService,
+	auditLog *audit.Log,
+	cache *cache.Cache,
+	log *logger.Logger,
+) *AdminService {
+	return &AdminService{
+		userRepo:    userRepo,
+		authService: authService,
+		audit:       auditLog,
+		cache:       cache,
+		log:         log,
+	}
+}
+
+// BanRequest bans or unbans a user
+type BanRequest struct {
+	UserID string `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+// RoleRequest changes a user's role
+type RoleRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// RatingAdjustmentRequest moves a user's rating, e.g. to undo a boosted
+// match
+type RatingAdjustmentRequest struct {
+	UserID string `json:"user_id"`
+	Delta  int    `json:"delta"`
+	Reason string `json:"reason"`
+}
+
+// AdminUserDTO is a user as staff see it
+type AdminUserDTO struct {
+	*UserDTO
+	IsBanned  bool   `json:"is_banned"`
+	BanReason string `json:"ban_reason,omitempty"`
+}
+
+// BanUser bans a user and ends all their sessions. Moderators may only ban
+// players; nobody may ban themselves.
+func (s *AdminService) BanUser(ctx context.Context, req *BanRequest) (*AdminUserDTO, error) {
+	reason, err := validateReason(req.Reason, true)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.manageableUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsBanned {
+		return nil, errors.NewInvalidInputError("User is already banned")
+	}
+
+	if err := s.userRepo.SetBanned(ctx, user.ID, true, reason); err != nil {
+		return nil, err
+	}
+	user.IsBanned = true
+	user.BanReason.String = reason
+
+	sessions, err := s.authService.LogoutAll(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateUser(ctx, user.ID)
+
+	s.audit.Record(ctx, audit.ActionUserBan, &user.ID, map[string]interface{}{
+		"reason":         reason,
+		"sessions_ended": sessions,
+	})
+
+	return s.toAdminUserDTO(user), nil
+}
+
+// UnbanUser lifts a user's ban
+func (s *AdminService) UnbanUser(ctx context.Context, req *BanRequest) (*AdminUserDTO, error) {
+	reason, err := validateReason(req.Reason, false)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.manageableUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsBanned {
+		return nil, errors.NewInvalidInputError("User is not banned")
+	}
+
+	banReason := user.BanReason.String
+	if err := s.userRepo.SetBanned(ctx, user.ID, false, ""); err != nil {
+		return nil, err
+	}
+	user.IsBanned = false
+	s.invalidateUser(ctx, user.ID)
+
+	s.audit.Record(ctx, audit.ActionUserUnban, &user.ID, map[string]interface{}{
+		"reason":     reason,
+		"ban_reason": banReason,
+	})
+
+	return s.toAdminUserDTO(user), nil
+}
+
+// SetRole changes a user's role. Their sessions end, so the tokens they log
+// in with next carry the new role.
+func (s *AdminService) SetRole(ctx context.Context, req *RoleRequest) (*AdminUserDTO, error) {
+	role, ok := auth.ParseRole(req.Role)
+	if !ok {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Invalid role: %s", req.Role))
+	}
+	user, err := s.manageableUser(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	previous := user.Role
+	if previous == string(role) {
+		return s.toAdminUserDTO(user), nil
+	}
+
+	if err := s.userRepo.SetRole(ctx, user.ID, string(role)); err != nil {
+		return nil, err
+	}
+	user.Role = string(role)
+
+	sessions, err := s.authService.LogoutAll(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateUser(ctx, user.ID)
+
+	s.audit.Record(ctx, audit.ActionUserRole, &user.ID, map[string]interface{}{
+		"from":           previous,
+		"to":             user.Role,
+		"sessions_ended": sessions,
+	})
+
+	return s.toAdminUserDTO(user), nil
+}
+
+// AdjustRating moves a user's rating by a bounded amount
+func (s *AdminService) AdjustRating(ctx context.Context, req *RatingAdjustmentRequest) (*AdminUserDTO, error) {
+	if req.Delta == 0 || req.Delta > maxRatingAdjustment || req.Delta < -maxRatingAdjustment {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Delta must be non-zero and at most %d either way", maxRatingAdjustment))
+	}
+	reason, err := validateReason(req.Reason, true)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, errors.NewInvalidInputError("Invalid user ID")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	previous := user.EloRating
+
+	rating, err := s.userRepo.AdjustELO(ctx, user.ID, req.Delta)
+	if err != nil {
+		return nil, err
+	}
+	user.EloRating = rating
+	s.invalidateUser(ctx, user.ID)
+
+	s.audit.Record(ctx, audit.ActionUserRating, &user.ID, map[string]interface{}{
+		"delta":  req.Delta,
+		"from":   previous,
+		"to":     rating,
+		"reason": reason,
+	})
+
+	return s.toAdminUserDTO(user), nil
+}
+
+// ListAuditLog returns audit entries, newest first
+func (s *AdminService) ListAuditLog(ctx context.Context, filter audit.Filter) ([]*audit.Entry, error) {
+	return s.audit.List(ctx, filter)
+}
+
+// manageableUser loads a user the caller may ban or change the role of
+func (s *AdminService) manageableUser(ctx context.Context, rawUserID string) (*repository.User, error) {
+	userID, err := uuid.Parse(rawUserID)
+	if err != nil {
+		return nil, errors.NewInvalidInputError("Invalid user ID")
+	}
+
+	caller, ok := auth.CallerFromContext(ctx)
+	if !ok {
+		return nil, errors.NewUnauthorizedError("Authentication required")
+	}
+	if strings.EqualFold(caller.UserID, userID.String()) {
+		return nil, errors.NewForbiddenError("Cannot change your own account")
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Deleted accounts stay banned; there is nobody left to manage
+	if user.DeletedAt.Valid {
+		return nil, errors.NewUserNotFoundError()
+	}
+	if !caller.Role.Outranks(auth.Role(user.Role)) {
+		return nil, errors.NewForbiddenError("Cannot manage a user with this role")
+	}
+
+	return user, nil
+}
+
+// invalidateUser drops the cached profile, which shows the role and rating
+func (s *AdminService) invalidateUser(ctx context.Context, userID uuid.UUID) {
+	if s.cache == nil {
+		return
+	}
+	s.cache.Delete(ctx, fmt.Sprintf("user:%s", userID.String()))
+}
+
+// validateReason trims a reason and checks its length
+func validateReason(reason string, required bool) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if required && reason == "" {
+		return "", errors.NewInvalidInputError("A reason is required")
+	}
+	if utf8.RuneCountInString(reason) > maxAdminReasonLength {
+		return "", errors.NewInvalidInputError(fmt.Sprintf("Reason must be at most %d characters", maxAdminReasonLength))
+	}
+	return reason, nil
+}
+
+func (s *AdminService) toAdminUserDTO(user *repository.User) *AdminUserDTO {
+	dto := &AdminUserDTO{
+		UserDTO:  s.authService.toUserDTO(user),
+		IsBanned: user.IsBanned,
+	}
+	if user.IsBanned {
+		dto.BanReason = user.BanReason.String
+	}
+	return dto
+}
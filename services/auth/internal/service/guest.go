@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/repository"
+)
+
+const (
+	guestsPerIP         = 10
+	guestDisplayName    = "Guest"
+	guestUsernamePrefix = "guest_"
+	// guestUsernameTries bounds retries when a random guest username is taken
+	guestUsernameTries = 3
+)
+
+// GuestRequest starts playing without registering
+type GuestRequest struct {
+	Region      string `json:"region"`
+	DeviceLabel string `json:"device_label,omitempty"`
+}
+
+// UpgradeGuestRequest adds credentials to a guest account. The username
+// defaults to the generated one.
+type UpgradeGuestRequest struct {
+	Username    string `json:"username,omitempty"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	DeviceLabel string `json:"device_label,omitempty"`
+}
+
+// CreateGuest creates a guest account and logs it in. Guests are real users,
+// so everything they do is kept if they register later, but they have no
+// way to log in again once their session ends.
+func (s *AuthService) CreateGuest(ctx context.Context, req *GuestRequest, client ClientInfo) (*AuthResponse, error) {
+	if err := s.rateLimit(ctx, fmt.Sprintf("guest:%s", client.IPAddress), guestsPerIP); err != nil {
+		return nil, err
+	}
+
+	region := req.Region
+	if region == "" {
+		region = "US"
+	}
+
+	user := &repository.User{
+		Region:      region,
+		DisplayName: sql.NullString{String: guestDisplayName, Valid: true},
+		IsGuest:     true,
+	}
+
+	var err error
+	for attempt := 0; attempt < guestUsernameTries; attempt++ {
+		user.Username, err = guestUsername()
+		if err != nil {
+			return nil, errors.NewInternalServerError(err)
+		}
+		err = s.userRepo.Create(ctx, user)
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrUserAlreadyExists {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Guest account created", map[string]interface{}{
+		"user_id":  user.ID.String(),
+		"username": user.Username,
+	})
+
+	client.DeviceLabel = req.DeviceLabel
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		User:         s.toUserDTO(user),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// UpgradeGuest turns a guest into a full account with the credentials
+// given, keeping their ID and history. The guest's sessions end, since
+// their tokens say guest, and the device making the request gets a new one.
+func (s *AuthService) UpgradeGuest(ctx context.Context, userID uuid.UUID, req *UpgradeGuestRequest, client ClientInfo) (*AuthResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsGuest {
+		return nil, errors.NewInvalidInputError("Account is already registered")
+	}
+
+	if req.Username == "" {
+		req.Username = user.Username
+	}
+	if err := s.validateRegisterRequest(&RegisterRequest{Username: req.Username, Email: req.Email, Password: req.Password}); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	// Guests are all called Guest; registering gives them their own name
+	displayName := ""
+	if user.DisplayName.String == guestDisplayName {
+		displayName = req.Username
+	}
+	if err := s.userRepo.ConvertGuest(ctx, user.ID, req.Username, req.Email, passwordHash, displayName); err != nil {
+		return nil, err
+	}
+
+	user.Username = req.Username
+	user.Email = req.Email
+	user.PasswordHash = passwordHash
+	user.IsGuest = false
+	user.IsVerified = false
+	if displayName != "" {
+		user.DisplayName = sql.NullString{String: displayName, Valid: true}
+	}
+	s.cache.Delete(ctx, fmt.Sprintf("user:%s", user.ID.String()))
+
+	sessions, err := s.LogoutAll(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Guest account upgraded", map[string]interface{}{
+		"user_id":        user.ID.String(),
+		"username":       user.Username,
+		"sessions_ended": sessions,
+	})
+
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		s.log.Error("Failed to send verification email", map[string]interface{}{
+			"user_id": user.ID.String(),
+			"error":   err.Error(),
+		})
+	}
+
+	client.DeviceLabel = req.DeviceLabel
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		User:         s.toUserDTO(user),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// guestUsername returns a random username for a guest
+func guestUsername() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return guestUsernamePrefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/repository"
+)
+
+// Failed login limits. An account gets a few free failures, then each one
+// doubles the wait before the next try, and enough of them lock it until
+// the lockout passes or the owner resets their password. Clients are
+// limited the same way with more room, as many players can share an IP.
+const (
+	loginFailureWindow     = time.Hour
+	loginBackoffBase       = time.Second
+	accountFreeFailures    = 3
+	accountBackoffCap      = 5 * time.Minute
+	accountLockoutFailures = 10
+	accountLockoutDuration = 30 * time.Minute
+	clientFreeFailures     = 20
+	clientBackoffCap       = 15 * time.Minute
+)
+
+// checkLoginAllowed rejects a login while its account is locked or either
+// the account or the client is waiting out a backoff. Keys are by email,
+// whether or not an account uses it, so lockouts don't reveal accounts.
+func (s *AuthService) checkLoginAllowed(ctx context.Context, email, ipAddress string) error {
+	email = normalizeEmail(email)
+
+	if _, locked := s.blockedUntil(ctx, loginLockoutKey(email)); locked {
+		return errors.NewAccountLockedError()
+	}
+	if until, blocked := s.blockedUntil(ctx, loginBackoffKey(email)); blocked {
+		return errors.NewTooManyAttemptsError(time.Until(until))
+	}
+	if ipAddress != "" {
+		if until, blocked := s.blockedUntil(ctx, clientBackoffKey(ipAddress)); blocked {
+			return errors.NewTooManyAttemptsError(time.Until(until))
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed login against the email and client,
+// starting backoffs and locking the account once it has failed too often.
+// user is nil when no account uses the email.
+func (s *AuthService) recordLoginFailure(ctx context.Context, email string, user *repository.User, client ClientInfo) {
+	email = normalizeEmail(email)
+
+	failures, err := s.cache.IncrementWithExpiry(ctx, loginFailuresKey(email), loginFailureWindow)
+	if err != nil {
+		s.log.Error("Failed to count login failure", map[string]interface{}{"error": err.Error()})
+	} else if failures >= accountLockoutFailures {
+		s.lockAccount(ctx, email, user, client, failures)
+	} else if failures > accountFreeFailures {
+		s.block(ctx, loginBackoffKey(email), backoffDelay(failures-accountFreeFailures, accountBackoffCap))
+	}
+
+	if client.IPAddress == "" {
+		return
+	}
+	failures, err = s.cache.IncrementWithExpiry(ctx, clientFailuresKey(client.IPAddress), loginFailureWindow)
+	if err != nil {
+		s.log.Error("Failed to count login failure", map[string]interface{}{"error": err.Error()})
+	} else if failures > clientFreeFailures {
+		s.block(ctx, clientBackoffKey(client.IPAddress), backoffDelay(failures-clientFreeFailures, clientBackoffCap))
+	}
+}
+
+// clearLoginFailures forgets an account's failures after a successful login.
+// The client's count stays, or logging into an account of one's own would
+// reset it between guesses at others.
+func (s *AuthService) clearLoginFailures(ctx context.Context, email string) {
+	email = normalizeEmail(email)
+	s.cache.Delete(ctx, loginFailuresKey(email), loginBackoffKey(email))
+}
+
+// unlockLogin lifts an account's lockout and forgets its failures
+func (s *AuthService) unlockLogin(ctx context.Context, email string) {
+	email = normalizeEmail(email)
+	s.cache.Delete(ctx, loginLockoutKey(email), loginFailuresKey(email), loginBackoffKey(email))
+}
+
+// lockAccount locks logins by email and records the lockout. The failure
+// count restarts, so the next lockout takes as many failures.
+func (s *AuthService) lockAccount(ctx context.Context, email string, user *repository.User, client ClientInfo, failures int64) {
+	until := s.block(ctx, loginLockoutKey(email), accountLockoutDuration)
+	s.cache.Delete(ctx, loginFailuresKey(email), loginBackoffKey(email))
+
+	event := &repository.SystemEvent{
+		EventType:   repository.EventTypeSecurity,
+		ServiceName: "auth",
+		Message:     "Account locked after repeated failed logins",
+		Severity:    repository.SeverityWarning,
+		IPAddress:   client.IPAddress,
+		Metadata: map[string]interface{}{
+			"email":        email,
+			"failures":     failures,
+			"locked_until": until,
+			"user_agent":   client.UserAgent,
+		},
+	}
+	if user != nil {
+		event.UserID = &user.ID
+	}
+	if err := s.systemEvents.Record(ctx, event); err != nil {
+		s.log.Error("Failed to record lockout event", map[string]interface{}{"error": err.Error()})
+	}
+
+	s.log.Warn("Account locked after repeated failed logins", map[string]interface{}{
+		"email":      email,
+		"ip_address": client.IPAddress,
+		"failures":   failures,
+	})
+}
+
+// block stores when a backoff or lockout ends, so a rejected login can say
+// how long to wait
+func (s *AuthService) block(ctx context.Context, key string, d time.Duration) time.Time {
+	until := time.Now().Add(d)
+	if err := s.cache.Set(ctx, key, until.Unix(), d); err != nil {
+		s.log.Error("Failed to store login backoff", map[string]interface{}{"error": err.Error()})
+	}
+	return until
+}
+
+// blockedUntil reports whether the backoff or lockout at key is running. A
+// cache outage lets logins through, as the other cache-backed limits do.
+func (s *AuthService) blockedUntil(ctx context.Context, key string) (time.Time, bool) {
+	var unix int64
+	if err := s.cache.Get(ctx, key, &unix); err != nil {
+		return time.Time{}, false
+	}
+	until := time.Unix(unix, 0)
+	return until, time.Now().Before(until)
+}
+
+// backoffDelay is the wait after the nth failure past the free ones
+func backoffDelay(n int64, limit time.Duration) time.Duration {
+	if n > 30 {
+		return limit
+	}
+	if d := loginBackoffBase << (n - 1); d < limit {
+		return d
+	}
+	return limit
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginFailuresKey(email string) string { return fmt.Sprintf("login_failures:%s", email) }
+func loginBackoffKey(email string) string  { return fmt.Sprintf("login_backoff:%s", email) }
+func loginLockoutKey(email string) string  { return fmt.Sprintf("login_lockout:%s", email) }
+func clientFailuresKey(ip string) string   { return fmt.Sprintf("login_failures_ip:%s", ip) }
+func clientBackoffKey(ip string) string    { return fmt.Sprintf("login_backoff_ip:%s", ip) }
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/repository"
+)
+
+const (
+	mfaIssuer            = "Cipher Clash" // Shown next to the code in authenticator apps
+	mfaRecoveryCodeCount = 10
+	mfaChallengeTTL      = 5 * time.Minute
+	// mfaChallengeAttempts bounds the codes tried with one challenge; the
+	// login failure limits bound them across challenges
+	mfaChallengeAttempts = 5
+	mfaChangesPerHour    = 10
+)
+
+// MFAEnrollRequest starts 2FA setup
+type MFAEnrollRequest struct {
+	Password string `json:"password"`
+}
+
+// MFAEnrollment is a new secret to add to an authenticator app
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// MFACodeRequest carries a code from the authenticator app, or a recovery
+// code where one is accepted
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// MFADisableRequest turns 2FA off
+type MFADisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// MFALoginRequest completes a login with the challenge token it returned
+type MFALoginRequest struct {
+	MFAToken    string `json:"mfa_token"`
+	Code        string `json:"code"`
+	DeviceLabel string `json:"device_label,omitempty"`
+}
+
+// MFAStatusDTO describes a user's 2FA
+type MFAStatusDTO struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+// RecoveryCodesDTO lists recovery codes. They are shown once and only their
+// hashes are kept.
+type RecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAStatus reports whether a user has 2FA on
+func (s *AuthService) MFAStatus(ctx context.Context, userID uuid.UUID) (*MFAStatusDTO, error) {
+	enabled, err := s.mfaEnabled(ctx, userID)
+	if err != nil || !enabled {
+		return &MFAStatusDTO{}, err
+	}
+
+	remaining, err := s.mfa.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &MFAStatusDTO{Enabled: true, RecoveryCodesRemaining: remaining}, nil
+}
+
+// EnrollMFA creates a TOTP secret for a user. 2FA stays off until
+// ConfirmMFA sees a code generated from it.
+func (s *AuthService) EnrollMFA(ctx context.Context, userID uuid.UUID, req *MFAEnrollRequest) (*MFAEnrollment, error) {
+	user, err := s.mfaUser(ctx, userID, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	enrolled, err := s.mfa.Enroll(ctx, user.ID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !enrolled {
+		return nil, errors.NewInvalidInputError("Two-factor authentication is already enabled")
+	}
+
+	return &MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURI: auth.TOTPURI(mfaIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmMFA turns 2FA on once the user proves their app generates codes,
+// returning their recovery codes
+func (s *AuthService) ConfirmMFA(ctx context.Context, userID uuid.UUID, req *MFACodeRequest) (*RecoveryCodesDTO, error) {
+	if err := s.rateLimit(ctx, fmt.Sprintf("mfa:%s", userID.String()), mfaChangesPerHour); err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.mfa.Find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled() {
+		return nil, errors.NewInvalidInputError("Two-factor authentication is already enabled")
+	}
+
+	step, ok := auth.ValidateTOTP(mfa.Secret, req.Code, time.Now())
+	if !ok {
+		return nil, errors.NewInvalidInputError("Invalid authentication code")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	confirmed, err := s.mfa.Confirm(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, errors.NewInvalidInputError("Two-factor authentication is already enabled")
+	}
+
+	s.log.Info("Two-factor authentication enabled", map[string]interface{}{
+		"user_id": userID.String(),
+	})
+	return &RecoveryCodesDTO{RecoveryCodes: codes}, nil
+}
+
+// DisableMFA turns 2FA off. It takes the password and a current or
+// recovery code, so neither a stolen session nor a stolen password is
+// enough.
+func (s *AuthService) DisableMFA(ctx context.Context, userID uuid.UUID, req *MFADisableRequest) error {
+	if _, err := s.mfaUser(ctx, userID, req.Password); err != nil {
+		return err
+	}
+
+	mfa, err := s.enabledMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.verifySecondFactor(ctx, mfa, req.Code, true); err != nil {
+		return err
+	}
+
+	if err := s.mfa.Disable(ctx, userID); err != nil {
+		return err
+	}
+
+	s.log.Info("Two-factor authentication disabled", map[string]interface{}{
+		"user_id": userID.String(),
+	})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes, e.g. when they
+// have used most of them. It takes a code from the authenticator app.
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, req *MFACodeRequest) (*RecoveryCodesDTO, error) {
+	if err := s.rateLimit(ctx, fmt.Sprintf("mfa:%s", userID.String()), mfaChangesPerHour); err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.enabledMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.verifySecondFactor(ctx, mfa, req.Code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfa.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Recovery codes regenerated", map[string]interface{}{
+		"user_id": userID.String(),
+	})
+	return &RecoveryCodesDTO{RecoveryCodes: codes}, nil
+}
+
+// CompleteMFALogin exchanges the challenge token of a login plus a TOTP or
+// recovery code for a session. Wrong codes count as failed logins.
+func (s *AuthService) CompleteMFALogin(ctx context.Context, req *MFALoginRequest, client ClientInfo) (*AuthResponse, error) {
+	expired := errors.NewUnauthorizedError("Invalid or expired login, log in again")
+
+	claims, err := s.jwtManager.ValidateToken(req.MFAToken, auth.MFAChallengeToken)
+	if err != nil {
+		return nil, expired
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, expired
+	}
+
+	attempts, err := s.cache.IncrementWithExpiry(ctx, fmt.Sprintf("mfa_challenge_attempts:%s", claims.ID), mfaChallengeTTL)
+	if err != nil {
+		s.log.Error("Failed to count MFA attempt", map[string]interface{}{"error": err.Error()})
+	}
+	if attempts > mfaChallengeAttempts {
+		return nil, expired
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, expired
+	}
+	if user.IsBanned {
+		return nil, errors.NewForbiddenError("Account is banned")
+	}
+	if err := s.checkLoginAllowed(ctx, user.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	mfa, err := s.enabledMFA(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The challenge works once, however many sessions its code could start.
+	// It is claimed before the code is used up, so replaying a challenge
+	// can't burn a recovery code, and released again if the code is wrong.
+	usedKey := fmt.Sprintf("mfa_challenge_used:%s", claims.ID)
+	claimed, err := s.cache.SetNX(ctx, usedKey, true, mfaChallengeTTL)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	if !claimed {
+		return nil, expired
+	}
+
+	method, err := s.verifySecondFactor(ctx, mfa, req.Code, true)
+	if err != nil {
+		if delErr := s.cache.Delete(ctx, usedKey); delErr != nil {
+			s.log.Error("Failed to release MFA challenge", map[string]interface{}{"error": delErr.Error()})
+		}
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrDatabaseError {
+			s.recordLoginFailure(ctx, user.Email, user, client)
+		}
+		return nil, err
+	}
+	s.clearLoginFailures(ctx, user.Email)
+
+	s.userRepo.UpdateLastLogin(ctx, user.ID)
+
+	s.log.Info("User logged in successfully", map[string]interface{}{
+		"user_id":  user.ID.String(),
+		"username": user.Username,
+		"mfa":      method,
+	})
+
+	client.DeviceLabel = req.DeviceLabel
+	tokens, err := s.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		User:         s.toUserDTO(user),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// mfaChallenge answers a correct password on an account with 2FA on
+func (s *AuthService) mfaChallenge(user *repository.User) (*AuthResponse, error) {
+	challenge, err := s.jwtManager.GenerateSingleUseToken(user.ID.String(), auth.MFAChallengeToken, mfaChallengeTTL)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	return &AuthResponse{MFARequired: true, MFAToken: challenge.Token}, nil
+}
+
+// mfaEnabled reports whether a user has confirmed 2FA
+func (s *AuthService) mfaEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	mfa, err := s.mfa.Find(ctx, userID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.HTTPStatus == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return mfa.Enabled(), nil
+}
+
+// enabledMFA loads a user's confirmed enrollment
+func (s *AuthService) enabledMFA(ctx context.Context, userID uuid.UUID) (*repository.UserMFA, error) {
+	mfa, err := s.mfa.Find(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.Enabled() {
+		return nil, errors.NewInvalidInputError("Two-factor authentication is not enabled")
+	}
+	return mfa, nil
+}
+
+// mfaUser loads a user changing their 2FA, checking their password
+func (s *AuthService) mfaUser(ctx context.Context, userID uuid.UUID, password string) (*repository.User, error) {
+	if err := s.rateLimit(ctx, fmt.Sprintf("mfa:%s", userID.String()), mfaChangesPerHour); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := auth.ComparePassword(user.PasswordHash, password); err != nil {
+		return nil, errors.NewInvalidCredentialsError()
+	}
+	return user, nil
+}
+
+// verifySecondFactor accepts a TOTP code not used before or, if
+// allowRecovery, an unused recovery code, which it uses up. It returns which
+// of the two it was.
+func (s *AuthService) verifySecondFactor(ctx context.Context, mfa *repository.UserMFA, code string, allowRecovery bool) (string, error) {
+	invalid := errors.NewUnauthorizedError("Invalid authentication code")
+
+	if step, ok := auth.ValidateTOTP(mfa.Secret, code, time.Now()); ok {
+		used, err := s.mfa.UseStep(ctx, mfa.UserID, step)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			return "", invalid
+		}
+		return "totp", nil
+	}
+
+	if !allowRecovery {
+		return "", invalid
+	}
+	used, err := s.mfa.UseRecoveryCode(ctx, mfa.UserID, hashToken(auth.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return "", err
+	}
+	if !used {
+		return "", invalid
+	}
+
+	s.log.Info("Recovery code used", map[string]interface{}{
+		"user_id": mfa.UserID.String(),
+	})
+	return "recovery_code", nil
+}
+
+// newRecoveryCodes returns recovery codes to show the user and the hashes
+// to store
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(mfaRecoveryCodeCount)
+	if err != nil {
+		return nil, nil, errors.NewInternalServerError(err)
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashToken(auth.NormalizeRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,501 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/oidc"
+	"github.com/swarit-1/cipher-clash/pkg/repository"
+)
+
+const (
+	// Generated usernames are a cleaned up name from the provider plus, if
+	// taken, a random suffix
+	maxUsernameBase   = 20
+	usernameAttempts  = 10
+	defaultUsername   = "player"
+	usernameSuffixMax = 10000
+)
+
+// OIDCService logs users in with OpenID Connect providers and manages the
+// identities linked to their accounts
+type OIDCService struct {
+	providers   map[string]*oidc.Provider
+	identities  *repository.IdentityRepository
+	userRepo    *repository.UserRepository
+	authService *AuthService
+	stateTTL    time.Duration
+	cache       *cache.Cache
+	log         *logger.Logger
+}
+
+// NewOIDCService creates a new OIDC service
+func NewOIDCService(
+	providers []*oidc.Provider,
+	identities *repository.IdentityRepository,
+	userRepo *repository.UserRepository,
+	authService *AuthService,
+	stateTTL time.Duration,
+	cache *cache.Cache,
+	log *logger.Logger,
+) *OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return &OIDCService{
+		providers:   byName,
+		identities:  identities,
+		userRepo:    userRepo,
+		authService: authService,
+		stateTTL:    stateTTL,
+		cache:       cache,
+		log:         log,
+	}
+}
+
+// OIDCStartRequest names the provider to log in or link with
+type OIDCStartRequest struct {
+	Provider string `json:"provider"`
+}
+
+// OIDCAuthorization is where to send the user to log in at the provider
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+// OIDCCallbackRequest carries what the provider redirected back with
+type OIDCCallbackRequest struct {
+	State       string `json:"state"`
+	Code        string `json:"code"`
+	DeviceLabel string `json:"device_label,omitempty"`
+}
+
+// IdentityDTO is a provider account linked to a user
+type IdentityDTO struct {
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email,omitempty"`
+	LinkedAt    time.Time  `json:"linked_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// oidcState is what a login attempt needs once the provider redirects back.
+// It stays on the server, so the PKCE verifier never reaches the browser.
+type oidcState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	UserID   string `json:"user_id,omitempty"` // Set when linking to a logged in user
+}
+
+// Providers returns the names of the configured providers
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartLogin begins a login with a provider
+func (s *OIDCService) StartLogin(ctx context.Context, req *OIDCStartRequest) (*OIDCAuthorization, error) {
+	return s.start(ctx, req.Provider, "")
+}
+
+// StartLink begins linking a provider account to a logged in user. Guests
+// register first, so the account they link has an email to log in with.
+func (s *OIDCService) StartLink(ctx context.Context, userID uuid.UUID, req *OIDCStartRequest) (*OIDCAuthorization, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsGuest {
+		return nil, errors.NewInvalidInputError("Create an account before linking a login provider")
+	}
+	return s.start(ctx, req.Provider, userID.String())
+}
+
+// CompleteLogin finishes a login with the code the provider redirected back
+// with. The provider account's user is logged in: the one it is linked to,
+// the one with its verified email, or a new one.
+func (s *OIDCService) CompleteLogin(ctx context.Context, req *OIDCCallbackRequest, client ClientInfo) (*AuthResponse, error) {
+	state, err := s.consumeState(ctx, req.State)
+	if err != nil {
+		return nil, err
+	}
+	if state.UserID != "" {
+		return nil, errors.NewInvalidInputError("This link was started to link an account, not to log in")
+	}
+
+	provider, identity, err := s.exchange(ctx, state, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userForIdentity(ctx, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsBanned {
+		return nil, errors.NewForbiddenError("Account is banned")
+	}
+
+	mfaEnabled, err := s.authService.mfaEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaEnabled {
+		return s.authService.mfaChallenge(user)
+	}
+
+	s.userRepo.UpdateLastLogin(ctx, user.ID)
+
+	s.log.Info("User logged in successfully", map[string]interface{}{
+		"user_id":  user.ID.String(),
+		"username": user.Username,
+		"provider": provider,
+	})
+
+	client.DeviceLabel = req.DeviceLabel
+	tokens, err := s.authService.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthResponse{
+		User:         s.authService.toUserDTO(user),
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// CompleteLink finishes linking a provider account to the user who started
+// it, returning their identities
+func (s *OIDCService) CompleteLink(ctx context.Context, userID uuid.UUID, req *OIDCCallbackRequest) ([]*IdentityDTO, error) {
+	state, err := s.consumeState(ctx, req.State)
+	if err != nil {
+		return nil, err
+	}
+	if state.UserID != userID.String() {
+		return nil, errors.NewForbiddenError("This link was started by another user")
+	}
+
+	provider, identity, err := s.exchange(ctx, state, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.identities.Link(ctx, &repository.UserIdentity{
+		UserID:   userID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    sql.NullString{String: identity.Email, Valid: identity.Email != ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Identity linked", map[string]interface{}{
+		"user_id":  userID.String(),
+		"provider": provider,
+	})
+	return s.ListIdentities(ctx, userID)
+}
+
+// ListIdentities returns the provider accounts linked to a user
+func (s *OIDCService) ListIdentities(ctx context.Context, userID uuid.UUID) ([]*IdentityDTO, error) {
+	identities, err := s.identities.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := make([]*IdentityDTO, len(identities))
+	for i, identity := range identities {
+		dtos[i] = &IdentityDTO{
+			Provider: identity.Provider,
+			Email:    identity.Email.String,
+			LinkedAt: identity.CreatedAt,
+		}
+		if identity.LastLoginAt.Valid {
+			dtos[i].LastLoginAt = &identity.LastLoginAt.Time
+		}
+	}
+	return dtos, nil
+}
+
+// Unlink removes a provider account from a user. The last one can't go
+// while the user has no password, or they couldn't log in again.
+func (s *OIDCService) Unlink(ctx context.Context, userID uuid.UUID, req *OIDCStartRequest) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" {
+		identities, err := s.identities.ListByUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) <= 1 {
+			return errors.NewInvalidInputError("Set a password before unlinking your only way to log in")
+		}
+	}
+
+	if err := s.identities.Unlink(ctx, userID, req.Provider); err != nil {
+		return err
+	}
+
+	s.log.Info("Identity unlinked", map[string]interface{}{
+		"user_id":  userID.String(),
+		"provider": req.Provider,
+	})
+	return nil
+}
+
+// start stores a login attempt and returns the provider's login page
+func (s *OIDCService) start(ctx context.Context, providerName, userID string) (*OIDCAuthorization, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Unknown login provider: %s", providerName))
+	}
+
+	stateID, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+
+	authorizationURL, err := provider.AuthorizationURL(ctx, stateID, nonce, challenge)
+	if err != nil {
+		s.log.Error("OIDC provider unavailable", map[string]interface{}{
+			"provider": providerName,
+			"error":    err.Error(),
+		})
+		return nil, errors.NewInternalError(fmt.Sprintf("Login with %s is unavailable right now", providerName))
+	}
+
+	state := &oidcState{Provider: providerName, Verifier: verifier, Nonce: nonce, UserID: userID}
+	if err := s.cache.Set(ctx, oidcStateKey(stateID), state, s.stateTTL); err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+
+	return &OIDCAuthorization{AuthorizationURL: authorizationURL, State: stateID}, nil
+}
+
+// consumeState loads a login attempt and ends it, so each works once
+func (s *OIDCService) consumeState(ctx context.Context, stateID string) (*oidcState, error) {
+	if stateID == "" {
+		return nil, errors.NewInvalidInputError("State is required")
+	}
+
+	var state oidcState
+	if err := s.cache.Get(ctx, oidcStateKey(stateID), &state); err != nil {
+		return nil, errors.NewInvalidInputError("Login expired or was already used, start again")
+	}
+	s.cache.Delete(ctx, oidcStateKey(stateID))
+	return &state, nil
+}
+
+// exchange redeems a code at the provider an attempt was started with
+func (s *OIDCService) exchange(ctx context.Context, state *oidcState, code string) (string, *oidc.Identity, error) {
+	provider, ok := s.providers[state.Provider]
+	if !ok {
+		return "", nil, errors.NewInvalidInputError(fmt.Sprintf("Unknown login provider: %s", state.Provider))
+	}
+	if code == "" {
+		return "", nil, errors.NewInvalidInputError("Code is required")
+	}
+
+	identity, err := provider.Exchange(ctx, code, state.Verifier, state.Nonce)
+	if err != nil {
+		s.log.Warn("OIDC code exchange failed", map[string]interface{}{
+			"provider": state.Provider,
+			"error":    err.Error(),
+		})
+		return "", nil, errors.NewUnauthorizedError(fmt.Sprintf("Login with %s failed", state.Provider))
+	}
+	return state.Provider, identity, nil
+}
+
+// userForIdentity finds or creates the user a provider account logs in.
+// An account is linked to an existing user by email only when both sides
+// verified the address; otherwise the user must link it while logged in.
+func (s *OIDCService) userForIdentity(ctx context.Context, provider string, identity *oidc.Identity) (*repository.User, error) {
+	linked, err := s.identities.Find(ctx, provider, identity.Subject)
+	if err == nil {
+		if err := s.identities.TouchLogin(ctx, linked.ID, identity.Email); err != nil {
+			return nil, err
+		}
+		return s.userRepo.FindByID(ctx, linked.UserID)
+	}
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.HTTPStatus != http.StatusNotFound {
+		return nil, err
+	}
+
+	if identity.Email == "" {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("%s did not share an email address", provider))
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, identity.Email)
+	switch {
+	case err == nil:
+		if !identity.EmailVerified || !user.IsVerified {
+			return nil, errors.NewInvalidInputError(fmt.Sprintf(
+				"An account already uses this email. Log in to it and link %s from your profile", provider))
+		}
+	case isUserNotFound(err):
+		user, err = s.createUser(ctx, identity)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	err = s.identities.Link(ctx, &repository.UserIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    sql.NullString{String: identity.Email, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Identity linked", map[string]interface{}{
+		"user_id":  user.ID.String(),
+		"provider": provider,
+	})
+	return user, nil
+}
+
+// createUser registers a user for a provider account. They have no password
+// until they set one through a password reset.
+func (s *OIDCService) createUser(ctx context.Context, identity *oidc.Identity) (*repository.User, error) {
+	base := usernameBase(identity)
+
+	var lastErr error
+	for attempt := 0; attempt < usernameAttempts; attempt++ {
+		username := base
+		if attempt > 0 {
+			suffix, err := rand.Int(rand.Reader, big.NewInt(usernameSuffixMax))
+			if err != nil {
+				return nil, errors.NewInternalServerError(err)
+			}
+			username = fmt.Sprintf("%s_%04d", base, suffix.Int64())
+		}
+
+		if _, err := s.userRepo.FindByUsername(ctx, username); err == nil {
+			continue
+		}
+
+		user := &repository.User{
+			Username:    username,
+			Email:       identity.Email,
+			Region:      "US",
+			DisplayName: sql.NullString{String: displayName(identity, username), Valid: true},
+		}
+		err := s.userRepo.Create(ctx, user)
+		if err == nil {
+			return s.finishCreatedUser(ctx, user, identity)
+		}
+		// Someone else took the name between the check and the insert
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Code == errors.ErrUserAlreadyExists && strings.Contains(appErr.Message, "username") {
+			lastErr = err
+			continue
+		}
+		return nil, err
+	}
+
+	if lastErr == nil {
+		lastErr = errors.NewInternalError("Could not find a free username")
+	}
+	return nil, lastErr
+}
+
+func (s *OIDCService) finishCreatedUser(ctx context.Context, user *repository.User, identity *oidc.Identity) (*repository.User, error) {
+	if identity.EmailVerified {
+		if err := s.userRepo.MarkVerified(ctx, user.ID); err != nil {
+			return nil, err
+		}
+		user.IsVerified = true
+	} else if err := s.authService.sendVerificationEmail(ctx, user); err != nil {
+		s.log.Error("Failed to send verification email", map[string]interface{}{
+			"user_id": user.ID.String(),
+			"error":   err.Error(),
+		})
+	}
+
+	s.log.Info("User registered successfully", map[string]interface{}{
+		"user_id":  user.ID.String(),
+		"username": user.Username,
+	})
+	return user, nil
+}
+
+// usernameBase turns the name a provider gives into a username: lowercase
+// letters, digits and underscores
+func usernameBase(identity *oidc.Identity) string {
+	candidates := []string{identity.PreferredUsername, identity.Name}
+	if at := strings.IndexByte(identity.Email, '@'); at > 0 {
+		candidates = append(candidates, identity.Email[:at])
+	}
+
+	for _, candidate := range candidates {
+		var b strings.Builder
+		for _, r := range strings.ToLower(candidate) {
+			switch {
+			case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+				b.WriteRune(r)
+			case r == '_' || r == '.' || r == '-' || r == ' ':
+				if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+					b.WriteByte('_')
+				}
+			}
+		}
+		name := strings.Trim(b.String(), "_")
+		if len(name) > maxUsernameBase {
+			name = strings.TrimRight(name[:maxUsernameBase], "_")
+		}
+		if len(name) >= 3 {
+			return name
+		}
+	}
+	return defaultUsername
+}
+
+func displayName(identity *oidc.Identity, username string) string {
+	if name := strings.TrimSpace(identity.Name); name != "" && len(name) <= 100 {
+		return name
+	}
+	return username
+}
+
+func isUserNotFound(err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Code == errors.ErrUserNotFound
+}
+
+func oidcStateKey(state string) string {
+	return fmt.Sprintf("oidc_state:%s", state)
+}
@@ -9,11 +9,15 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/swarit-1/cipher-clash/pkg/audit"
 	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/mailer"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
+	"github.com/swarit-1/cipher-clash/pkg/oidc"
 	"github.com/swarit-1/cipher-clash/pkg/repository"
 	"github.com/swarit-1/cipher-clash/services/auth/internal/handler"
 	"github.com/swarit-1/cipher-clash/services/auth/internal/middleware"
@@ -67,34 +71,132 @@ func main() {
 		defer cacheClient.Close()
 	}
 
-	// Initialize JWT manager
-	jwtManager := auth.NewJWTManager(cfg.JWT)
+	// Initialize JWT manager and the denylist of revoked access tokens
+	jwtManager, err := auth.NewJWTManager(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	if cfg.JWT.SigningKeys == "" && cfg.JWT.Algorithm != auth.AlgorithmHS256 {
+		log.Warn("JWT_SIGNING_KEYS not set - signing with a generated key, tokens won't survive a restart", map[string]interface{}{
+			"kid": jwtManager.KeySet().Signing().ID,
+		})
+	}
+	denylist := auth.NewDenylist(cacheClient, jwtManager.AccessTTL())
+
+	// Initialize mailer for verification and password reset emails
+	mail, err := mailer.New(cfg.Mail, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mailer", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	// Initialize messaging publisher for user.deleted events (optional -
+	// deletions wait in the outbox until RabbitMQ is back)
+	publisher, err := messaging.NewPublisher(cfg.RabbitMQ, log)
+	if err == nil {
+		err = publisher.DeclareExchange(messaging.ExchangeUsers, "topic")
+		defer publisher.Close()
+	}
+	if err != nil {
+		log.Warn("RabbitMQ unavailable - deleted accounts won't be erased by other services until restart", map[string]interface{}{
+			"error": err.Error(),
+		})
+		publisher = nil
+	}
+
+	// Initialize OpenID Connect providers for social login. Their discovery
+	// documents are fetched on first use.
+	oidcProviders := make([]*oidc.Provider, 0, len(cfg.OIDC.Providers))
+	for _, providerCfg := range cfg.OIDC.Providers {
+		provider, err := oidc.NewProvider(providerCfg)
+		if err != nil {
+			log.Fatal("Invalid OIDC provider configuration", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		oidcProviders = append(oidcProviders, provider)
+	}
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(database)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(database)
+	accountTokenRepo := repository.NewAccountTokenRepository(database)
+	systemEventRepo := repository.NewSystemEventRepository(database)
+	mfaRepo := repository.NewMFARepository(database)
+	identityRepo := repository.NewIdentityRepository(database)
+	dataExportRepo := repository.NewDataExportRepository(database)
+	userDataRepo := repository.NewUserDataRepository(database)
+	accountDeletionRepo := repository.NewAccountDeletionRepository(database)
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, jwtManager, cacheClient, log)
+	authService := service.NewAuthService(userRepo, refreshTokenRepo, accountTokenRepo, systemEventRepo, mfaRepo, jwtManager, denylist, mail, cfg.Account, cacheClient, log)
+	adminService := service.NewAdminService(userRepo, authService, audit.NewLog(database, log), cacheClient, log)
+	oidcService := service.NewOIDCService(oidcProviders, identityRepo, userRepo, authService, cfg.OIDC.StateTTL, cacheClient, log)
+	accountDataService := service.NewAccountDataService(dataExportRepo, userDataRepo, accountDeletionRepo, userRepo, authService, publisher, cacheClient, log)
+
+	// Start publishing account deletions to the other services
+	deletionsCtx, stopDeletions := context.WithCancel(context.Background())
+	defer stopDeletions()
+	go accountDataService.RunDeletionPublisher(deletionsCtx)
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authService, log)
+	authHandler := handler.NewAuthHandler(authService, adminService, oidcService, accountDataService, log)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(jwtManager, log)
+	authMiddleware := middleware.NewAuthMiddleware(log)
+	authGuard := auth.NewMiddleware(jwtManager, denylist, cfg.Internal.ServiceToken, log)
 
 	// Setup HTTP router
 	mux := http.NewServeMux()
 
 	// Public routes
 	mux.HandleFunc("/health", authMiddleware.CORS(authMiddleware.Logging(authHandler.Health)))
+	mux.HandleFunc(auth.JWKSPath, authMiddleware.CORS(authMiddleware.Logging(auth.JWKSHandler(jwtManager.KeySet()))))
 	mux.HandleFunc("/api/v1/auth/register", authMiddleware.CORS(authMiddleware.Logging(authHandler.Register)))
+	mux.HandleFunc("/api/v1/auth/guest", authMiddleware.CORS(authMiddleware.Logging(authHandler.CreateGuest)))
 	mux.HandleFunc("/api/v1/auth/login", authMiddleware.CORS(authMiddleware.Logging(authHandler.Login)))
 	mux.HandleFunc("/api/v1/auth/refresh", authMiddleware.CORS(authMiddleware.Logging(authHandler.RefreshToken)))
+	mux.HandleFunc("/api/v1/auth/verify-email", authMiddleware.CORS(authMiddleware.Logging(authHandler.VerifyEmail)))
+	mux.HandleFunc("/api/v1/auth/password/forgot", authMiddleware.CORS(authMiddleware.Logging(authHandler.RequestPasswordReset)))
+	mux.HandleFunc("/api/v1/auth/password/reset", authMiddleware.CORS(authMiddleware.Logging(authHandler.ResetPassword)))
+	mux.HandleFunc("/api/v1/auth/login/mfa", authMiddleware.CORS(authMiddleware.Logging(authHandler.LoginMFA)))
+	mux.HandleFunc("/api/v1/auth/oidc/providers", authMiddleware.CORS(authMiddleware.Logging(authHandler.OIDCProviders)))
+	mux.HandleFunc("/api/v1/auth/oidc/start", authMiddleware.CORS(authMiddleware.Logging(authHandler.StartOIDCLogin)))
+	mux.HandleFunc("/api/v1/auth/oidc/callback", authMiddleware.CORS(authMiddleware.Logging(authHandler.OIDCCallback)))
 
 	// Protected routes
-	mux.HandleFunc("/api/v1/auth/profile", authMiddleware.CORS(authMiddleware.Logging(authMiddleware.RequireAuth(authHandler.GetProfile))))
-	mux.HandleFunc("/api/v1/auth/profile/update", authMiddleware.CORS(authMiddleware.Logging(authMiddleware.RequireAuth(authHandler.UpdateProfile))))
-	mux.HandleFunc("/api/v1/auth/logout", authMiddleware.CORS(authMiddleware.Logging(authMiddleware.RequireAuth(authHandler.Logout))))
+	mux.HandleFunc("/api/v1/auth/profile", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.GetProfile))))
+	mux.HandleFunc("/api/v1/auth/profile/update", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.UpdateProfile))))
+	mux.HandleFunc("/api/v1/auth/logout", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.Logout))))
+	mux.HandleFunc("/api/v1/auth/logout/all", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.LogoutAll))))
+	mux.HandleFunc("/api/v1/auth/sessions", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.ListSessions))))
+	mux.HandleFunc("/api/v1/auth/sessions/revoke", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.RevokeSession))))
+	mux.HandleFunc("/api/v1/auth/verify-email/resend", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.ResendVerification))))
+	mux.HandleFunc("/api/v1/auth/guest/upgrade", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.UpgradeGuest))))
+	mux.HandleFunc("/api/v1/auth/password/change", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.ChangePassword))))
+	mux.HandleFunc("/api/v1/auth/mfa", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.MFAStatus))))
+	mux.HandleFunc("/api/v1/auth/mfa/enroll", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.EnrollMFA))))
+	mux.HandleFunc("/api/v1/auth/mfa/confirm", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.ConfirmMFA))))
+	mux.HandleFunc("/api/v1/auth/mfa/disable", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.DisableMFA))))
+	mux.HandleFunc("/api/v1/auth/mfa/recovery-codes", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.RegenerateRecoveryCodes))))
+	mux.HandleFunc("/api/v1/auth/identities", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.ListIdentities))))
+	mux.HandleFunc("/api/v1/auth/identities/link", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.StartLinkIdentity))))
+	mux.HandleFunc("/api/v1/auth/identities/link/complete", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.CompleteLinkIdentity))))
+	mux.HandleFunc("/api/v1/auth/identities/unlink", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.UnlinkIdentity))))
+	mux.HandleFunc("/api/v1/auth/account/export", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.RequestDataExport))))
+	mux.HandleFunc("/api/v1/auth/account/exports", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.ListDataExports))))
+	mux.HandleFunc("/api/v1/auth/account/export/download", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.DownloadDataExport))))
+	mux.HandleFunc("/api/v1/auth/account/delete", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopePlayer, authHandler.DeleteAccount))))
+
+	// Admin routes
+	mux.HandleFunc("/api/v1/admin/users/ban", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopeModerator, authHandler.BanUser))))
+	mux.HandleFunc("/api/v1/admin/users/unban", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopeModerator, authHandler.UnbanUser))))
+	mux.HandleFunc("/api/v1/admin/users/role", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopeAdmin, authHandler.SetUserRole))))
+	mux.HandleFunc("/api/v1/admin/users/rating", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopeAdmin, authHandler.AdjustUserRating))))
+	mux.HandleFunc("/api/v1/admin/audit", authMiddleware.CORS(authMiddleware.Logging(authGuard.RequireScope(auth.ScopeAdmin, authHandler.ListAuditLog))))
 
 	// Create HTTP server
 	addr := "0.0.0.0:" + port
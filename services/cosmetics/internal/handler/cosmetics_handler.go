@@ -6,6 +6,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/swarit-1/cipher-clash/pkg/audit"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 	"github.com/swarit-1/cipher-clash/services/cosmetics/internal/models"
@@ -14,12 +16,14 @@ import (
 
 type CosmeticsHandler struct {
 	cosmeticsService *service.CosmeticsService
+	audit            *audit.Log
 	log              *logger.Logger
 }
 
-func NewCosmeticsHandler(cosmeticsService *service.CosmeticsService, log *logger.Logger) *CosmeticsHandler {
+func NewCosmeticsHandler(cosmeticsService *service.CosmeticsService, auditLog *audit.Log, log *logger.Logger) *CosmeticsHandler {
 	return &CosmeticsHandler{
 		cosmeticsService: cosmeticsService,
+		audit:            auditLog,
 		log:              log,
 	}
 }
@@ -65,6 +69,11 @@ func (h *CosmeticsHandler) GetInventory(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	category := r.URL.Query().Get("category")
 
 	inventory, totalOwned, totalEquipped, err := h.cosmeticsService.GetInventory(r.Context(), userID, category)
@@ -89,6 +98,11 @@ func (h *CosmeticsHandler) PurchaseCosmetic(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	userCosmetic, newBalance, err := h.cosmeticsService.PurchaseCosmetic(r.Context(), req.UserID, req.CosmeticID)
 	if err != nil {
 		h.respondError(w, err)
@@ -112,6 +126,11 @@ func (h *CosmeticsHandler) GetLoadout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	loadout, err := h.cosmeticsService.GetLoadout(r.Context(), userID)
 	if err != nil {
 		h.respondError(w, err)
@@ -132,6 +151,11 @@ func (h *CosmeticsHandler) EquipCosmetic(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	loadout, err := h.cosmeticsService.EquipCosmetic(r.Context(), req.UserID, req.CosmeticID)
 	if err != nil {
 		h.respondError(w, err)
@@ -154,6 +178,11 @@ func (h *CosmeticsHandler) UnequipCosmetic(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	loadout, err := h.cosmeticsService.UnequipCosmetic(r.Context(), req.UserID, req.Category)
 	if err != nil {
 		h.respondError(w, err)
@@ -176,6 +205,11 @@ func (h *CosmeticsHandler) UpdateLoadout(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	loadout, err := h.cosmeticsService.UpdateLoadout(
 		r.Context(),
 		req.UserID,
@@ -196,7 +230,8 @@ func (h *CosmeticsHandler) UpdateLoadout(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// GrantCosmetic handles granting a cosmetic (admin/system function)
+// GrantCosmetic handles granting a cosmetic. Admins and services only;
+// every grant is audited.
 func (h *CosmeticsHandler) GrantCosmetic(w http.ResponseWriter, r *http.Request) {
 	var req models.GrantCosmeticRequest
 
@@ -205,12 +240,25 @@ func (h *CosmeticsHandler) GrantCosmetic(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if req.UserID == uuid.Nil || req.CosmeticID == "" {
+		h.respondError(w, errors.NewInvalidInputError("User ID and cosmetic ID required"))
+		return
+	}
+	if req.Source == "" {
+		req.Source = "admin_grant"
+	}
+
 	userCosmetic, err := h.cosmeticsService.GrantCosmetic(r.Context(), req.UserID, req.CosmeticID, req.Source)
 	if err != nil {
 		h.respondError(w, err)
 		return
 	}
 
+	h.audit.Record(r.Context(), audit.ActionCosmeticGrant, &req.UserID, map[string]interface{}{
+		"cosmetic_id": req.CosmeticID,
+		"source":      req.Source,
+	})
+
 	h.respondJSON(w, http.StatusCreated, map[string]interface{}{
 		"success":  true,
 		"cosmetic": userCosmetic,
@@ -97,7 +97,7 @@ type UnequipCosmeticResponse struct {
 type GrantCosmeticRequest struct {
 	UserID     uuid.UUID `json:"user_id"`
 	CosmeticID string    `json:"cosmetic_id"`
-	Source     string    `json:"source"` // mission_reward, achievement_unlock, gift
+	Source     string    `json:"source"` // mission_reward, achievement_unlock, gift, admin_grant
 }
 
 type GrantCosmeticResponse struct {
@@ -18,6 +18,7 @@ type InventoryRepository interface {
 	GetEquippedCosmetics(ctx context.Context, userID uuid.UUID) ([]*models.UserCosmetic, error)
 	UnequipAllInCategory(ctx context.Context, userID uuid.UUID, category string) error
 	GetInventoryStats(ctx context.Context, userID uuid.UUID) (totalOwned, totalEquipped int, err error)
+	DeleteUserInventory(ctx context.Context, userID uuid.UUID) error
 }
 
 type inventoryRepository struct {
@@ -135,6 +136,12 @@ func (r *inventoryRepository) RemoveCosmetic(ctx context.Context, id uuid.UUID)
 	return err
 }
 
+func (r *inventoryRepository) DeleteUserInventory(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM user_cosmetics WHERE user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
 func (r *inventoryRepository) UpdateEquippedStatus(ctx context.Context, id uuid.UUID, isEquipped bool) error {
 	query := `UPDATE user_cosmetics SET is_equipped = $2 WHERE id = $1`
 	_, err := r.db.ExecContext(ctx, query, id, isEquipped)
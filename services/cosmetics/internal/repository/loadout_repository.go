@@ -14,6 +14,7 @@ type LoadoutRepository interface {
 	UpdateLoadout(ctx context.Context, loadout *models.UserLoadout) error
 	EquipCosmetic(ctx context.Context, userID uuid.UUID, category, cosmeticID string) error
 	UnequipCosmetic(ctx context.Context, userID uuid.UUID, category string) error
+	DeleteLoadout(ctx context.Context, userID uuid.UUID) error
 }
 
 type loadoutRepository struct {
@@ -145,3 +146,9 @@ func (r *loadoutRepository) UnequipCosmetic(ctx context.Context, userID uuid.UUI
 	_, err := r.db.ExecContext(ctx, query, userID)
 	return err
 }
+
+func (r *loadoutRepository) DeleteLoadout(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM user_loadouts WHERE user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
@@ -362,3 +362,18 @@ func (s *CosmeticsService) UpdateLoadout(ctx context.Context, userID uuid.UUID,
 	// Return updated loadout with details
 	return s.GetLoadout(ctx, userID)
 }
+
+// DeleteUserData erases the inventory and loadout of a user who deleted
+// their account
+func (s *CosmeticsService) DeleteUserData(ctx context.Context, userID uuid.UUID) error {
+	if err := s.loadoutRepo.DeleteLoadout(ctx, userID); err != nil {
+		s.log.LogError("Failed to delete user loadout", "user_id", userID, "error", err)
+		return errors.NewInternalError("Failed to delete user loadout")
+	}
+	if err := s.inventoryRepo.DeleteUserInventory(ctx, userID); err != nil {
+		s.log.LogError("Failed to delete user inventory", "user_id", userID, "error", err)
+		return errors.NewInternalError("Failed to delete user inventory")
+	}
+
+	return nil
+}
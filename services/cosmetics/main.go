@@ -10,9 +10,13 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
+	"github.com/swarit-1/cipher-clash/pkg/audit"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
 	"github.com/swarit-1/cipher-clash/services/cosmetics/internal/handler"
 	"github.com/swarit-1/cipher-clash/services/cosmetics/internal/repository"
 	"github.com/swarit-1/cipher-clash/services/cosmetics/internal/service"
@@ -37,14 +41,40 @@ func main() {
 
 	log.LogInfo("Connected to database successfully")
 
+	// Initialize cache
+	cacheClient, err := cache.New(cfg.Redis, log)
+	if err != nil {
+		log.Fatal("Failed to connect to Redis", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	defer cacheClient.Close()
+
 	catalogRepo := repository.NewCatalogRepository(database.DB)
 	inventoryRepo := repository.NewInventoryRepository(database.DB)
 	loadoutRepo := repository.NewLoadoutRepository(database.DB)
 
 	cosmeticsService := service.NewCosmeticsService(catalogRepo, inventoryRepo, loadoutRepo, log)
-	cosmeticsHandler := handler.NewCosmeticsHandler(cosmeticsService, log)
 
-	router := setupRouter(cosmeticsHandler)
+	// Erase what this service keeps about users who delete their account
+	// (optional - warn but continue if RabbitMQ unavailable)
+	userDeletions, err := messaging.ListenUserDeleted(cfg.RabbitMQ, "cosmetics", cosmeticsService.DeleteUserData, log)
+	if err != nil {
+		log.LogWarn("RabbitMQ unavailable - deleted users' cosmetics won't be erased", "error", err)
+	} else {
+		defer userDeletions.Close()
+	}
+
+	cosmeticsHandler := handler.NewCosmeticsHandler(cosmeticsService, audit.NewLog(database, log), log)
+
+	// Tokens are checked against the auth service's published keys
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT verifier", map[string]interface{}{"error": err})
+	}
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, log)
+
+	router := setupRouter(cosmeticsHandler, authGuard)
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
@@ -87,7 +117,7 @@ func main() {
 	log.LogInfo("Cosmetics Service stopped")
 }
 
-func setupRouter(h *handler.CosmeticsHandler) *mux.Router {
+func setupRouter(h *handler.CosmeticsHandler, authGuard *auth.Middleware) *mux.Router {
 	r := mux.NewRouter()
 	api := r.PathPrefix("/api/v1").Subrouter()
 
@@ -96,13 +126,16 @@ func setupRouter(h *handler.CosmeticsHandler) *mux.Router {
 	api.HandleFunc("/cosmetics/catalog/{id}", h.GetCosmeticItem).Methods("GET")
 
 	// Inventory
-	api.HandleFunc("/cosmetics/inventory/{user_id}", h.GetInventory).Methods("GET")
-	api.HandleFunc("/cosmetics/purchase", h.PurchaseCosmetic).Methods("POST")
+	api.HandleFunc("/cosmetics/inventory/{user_id}", authGuard.RequireAuth(h.GetInventory)).Methods("GET")
+	api.HandleFunc("/cosmetics/purchase", authGuard.RequireAuth(h.PurchaseCosmetic)).Methods("POST")
 
 	// Loadout
-	api.HandleFunc("/cosmetics/loadout/{user_id}", h.GetLoadout).Methods("GET")
-	api.HandleFunc("/cosmetics/loadout/equip", h.EquipCosmetic).Methods("POST")
-	api.HandleFunc("/cosmetics/loadout/unequip", h.UnequipCosmetic).Methods("POST")
+	api.HandleFunc("/cosmetics/loadout/{user_id}", authGuard.RequireAuth(h.GetLoadout)).Methods("GET")
+	api.HandleFunc("/cosmetics/loadout/equip", authGuard.RequireAuth(h.EquipCosmetic)).Methods("POST")
+	api.HandleFunc("/cosmetics/loadout/unequip", authGuard.RequireAuth(h.UnequipCosmetic)).Methods("POST")
+
+	// Admin
+	api.HandleFunc("/admin/cosmetics/grant", authGuard.RequireScope(auth.ScopeAdmin, h.GrantCosmetic)).Methods("POST")
 
 	r.HandleFunc("/health", healthCheck).Methods("GET")
 	return r
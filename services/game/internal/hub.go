@@ -1,14 +1,16 @@
 package game
 
 import (
+	"context"
 	"encoding/json"
 	"log"
-	"math/rand"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/puzzleclient"
 )
 
 var upgrader = websocket.Upgrader{
@@ -19,20 +21,28 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// puzzleTimeout bounds each call to the puzzle engine
+const puzzleTimeout = 15 * time.Second
+
+// matchPuzzleCount is how many rounds a match is played over
+const matchPuzzleCount = 3
+
 type Hub struct {
 	clients    map[*Client]bool
 	broadcast  chan []byte
 	register   chan *Client
 	unregister chan *Client
+	puzzles    *puzzleclient.Client
 	mu         sync.Mutex
 }
 
-func NewHub() *Hub {
+func NewHub(puzzles *puzzleclient.Client) *Hub {
 	return &Hub{
 		broadcast:  make(chan []byte),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		clients:    make(map[*Client]bool),
+		puzzles:    puzzles,
 	}
 }
 
@@ -44,51 +54,42 @@ func (h *Hub) Run() {
 			h.clients[client] = true
 			h.mu.Unlock()
 
-			// MOCK: Send a random puzzle immediately upon connection
+			// Start a match with its puzzle set from the puzzle engine, one
+			// call per match. The answers never leave the engine; solutions
+			// are checked through SUBMIT_SOLUTION.
 			go func(c *Client) {
-				time.Sleep(500 * time.Millisecond) // Wait a bit
-
-				puzzles := []map[string]interface{}{
-					{
-						"encrypted_text": "WKH TXLFN EURZQ IRA MXPSV RYHU WKH ODCB GRJ",
-						"solution":       "THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG",
-						"cipher_type":    "CAESAR",
-						"difficulty":     1,
-					},
-					{
-						"encrypted_text": "LIPPS ASVPH",
-						"solution":       "HELLO WORLD",
-						"cipher_type":    "CAESAR",
-						"difficulty":     1,
-					},
-					{
-						"encrypted_text": "D OLWWOH ELW KDUGHU",
-						"solution":       "A LITTLE BIT HARDER",
-						"cipher_type":    "CAESAR",
-						"difficulty":     2,
-					},
-					{
-						"encrypted_text": "LXFOPVEFRNHR",
-						"solution":       "CRYPTOGRAPHY",
-						"cipher_type":    "VIGENERE",
-						"difficulty":     3,
-					},
+				ctx, cancel := context.WithTimeout(context.Background(), puzzleTimeout)
+				defer cancel()
+
+				puzzles, err := h.puzzles.GenerateMatch(ctx, &puzzleclient.MatchRequest{
+					Count:     matchPuzzleCount,
+					PlayerIDs: []string{c.userID},
+				})
+				if err != nil || len(puzzles) == 0 {
+					log.Printf("Failed to generate match puzzles: %v", err)
+					return
 				}
 
-				// Simple random selection (using time as seed is not strictly necessary for this mock but good practice)
-				randomIndex := rand.Intn(len(puzzles))
-				selectedPuzzle := puzzles[randomIndex]
+				rounds := make([]PuzzleState, 0, len(puzzles))
+				for _, puzzle := range puzzles {
+					rounds = append(rounds, PuzzleState{
+						ID:            puzzle.ID,
+						EncryptedText: puzzle.EncryptedText,
+						CipherType:    puzzle.CipherType,
+						Difficulty:    puzzle.Difficulty,
+					})
+				}
 
-				mockPuzzle := map[string]interface{}{
-					"type": "MATCH_STARTED",
-					"payload": map[string]interface{}{
+				msg, _ := json.Marshal(GameEvent{
+					Type: "MATCH_STARTED",
+					Payload: map[string]interface{}{
 						"match_id":          "mock-match-123",
 						"opponent_id":       "bot-1",
 						"opponent_username": "NEMESIS_X",
-						"puzzle":            selectedPuzzle,
+						"puzzle":            rounds[0],
+						"puzzles":           rounds,
 					},
-				}
-				msg, _ := json.Marshal(mockPuzzle)
+				})
 				c.send <- msg
 			}(client)
 
@@ -115,9 +116,10 @@ func (h *Hub) Run() {
 }
 
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte
+	hub    *Hub
+	conn   *websocket.Conn
+	send   chan []byte
+	userID string
 }
 
 func (c *Client) readPump() {
@@ -130,12 +132,50 @@ func (c *Client) readPump() {
 		if err != nil {
 			break
 		}
-		// Echo back for now (or handle actions)
+		var action ClientAction
+		if err := json.Unmarshal(message, &action); err == nil && action.Action == "SUBMIT_SOLUTION" {
+			c.submitSolution(action.Payload)
+			continue
+		}
+		// Echo everything else back for now
 		// In a real game, we'd parse the action and update state
 		c.hub.broadcast <- message
 	}
 }
 
+// submitSolution checks a solution with the puzzle engine and reports the
+// result to the submitting client only
+func (c *Client) submitSolution(payload json.RawMessage) {
+	var submission SubmitSolutionPayload
+	if err := json.Unmarshal(payload, &submission); err != nil || submission.PuzzleID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), puzzleTimeout)
+	defer cancel()
+
+	result, err := c.hub.puzzles.Validate(ctx, &puzzleclient.ValidateRequest{
+		PuzzleID:    submission.PuzzleID,
+		Solution:    submission.Solution,
+		SolveTimeMs: submission.SolveTimeMs,
+		UserID:      c.userID,
+	})
+	if err != nil {
+		log.Printf("Failed to validate solution: %v", err)
+		return
+	}
+
+	msg, _ := json.Marshal(GameEvent{
+		Type: "SOLUTION_RESULT",
+		Payload: SolutionResultPayload{
+			IsCorrect: result.IsCorrect,
+			Score:     result.Score,
+			Accuracy:  result.Accuracy,
+		},
+	})
+	c.send <- msg
+}
+
 func (c *Client) writePump() {
 	defer func() {
 		c.conn.Close()
@@ -152,7 +192,8 @@ func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
 		log.Println(err)
 		return
 	}
-	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256)}
+	userID, _ := auth.UserIDFromContext(r.Context())
+	client := &Client{hub: hub, conn: conn, send: make(chan []byte, 256), userID: userID}
 	client.hub.register <- client
 
 	go client.writePump()
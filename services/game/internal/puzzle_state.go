@@ -1,6 +1,9 @@
 package game
 
+import "encoding/json"
+
 type PuzzleState struct {
+	ID            string `json:"id"`
 	EncryptedText string `json:"encrypted_text"`
 	CipherType    string `json:"cipher_type"`
 	Difficulty    int    `json:"difficulty"`
@@ -8,10 +11,30 @@ type PuzzleState struct {
 }
 
 type GameEvent struct {
-	Type    string      `json:"type"` // "MATCH_STARTED", "OPPONENT_PROGRESS", "GAME_RESULT"
+	Type    string      `json:"type"` // "MATCH_STARTED", "OPPONENT_PROGRESS", "SOLUTION_RESULT", "GAME_RESULT"
 	Payload interface{} `json:"payload"`
 }
 
+// ClientAction is a message sent by a player
+type ClientAction struct {
+	Action  string          `json:"action"` // "SUBMIT_SOLUTION", "KEYSTROKE", "SURRENDER", ...
+	Payload json.RawMessage `json:"payload"`
+}
+
+type SubmitSolutionPayload struct {
+	PuzzleID    string `json:"puzzle_id"`
+	Solution    string `json:"solution"`
+	SolveTimeMs int64  `json:"solve_time_ms"`
+}
+
+// SolutionResultPayload grades a submission. Competitive matches get no
+// per-character feedback, which would reveal the answer.
+type SolutionResultPayload struct {
+	IsCorrect bool    `json:"is_correct"`
+	Score     int     `json:"score"`
+	Accuracy  float64 `json:"accuracy"`
+}
+
 type OpponentProgressPayload struct {
 	Progress float64 `json:"progress"` // 0.0 to 1.0
 }
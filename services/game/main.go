@@ -6,6 +6,11 @@ import (
 	"os"
 
 	"github.com/joho/godotenv"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
+	"github.com/swarit-1/cipher-clash/pkg/config"
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/puzzleclient"
 	game "github.com/swarit-1/cipher-clash/services/game/internal"
 )
 
@@ -26,12 +31,33 @@ func main() {
 		port = "8088"
 	}
 
-	hub := game.NewHub()
+	// Load configuration
+	cfg := config.LoadConfig()
+
+	// Puzzles come from the puzzle engine, which keeps the answers
+	puzzles := puzzleclient.New(cfg.Internal.PuzzleEngineURL, cfg.Internal.ServiceToken)
+
+	hub := game.NewHub(puzzles)
 	go hub.Run()
 
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+	// Players connect with their access token, as the access_token query
+	// parameter since browsers can't set headers on WebSocket handshakes
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("JWT verifier: ", err)
+	}
+	// Revoked sessions are refused through the auth service's denylist
+	serviceLog := logger.New("game-service")
+	cacheClient, err := cache.New(cfg.Redis, serviceLog)
+	if err != nil {
+		log.Fatal("Redis: ", err)
+	}
+	defer cacheClient.Close()
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, serviceLog)
+
+	http.HandleFunc("/ws", authGuard.RequireScope(auth.ScopePlayer, func(w http.ResponseWriter, r *http.Request) {
 		game.ServeWs(hub, w, r)
-	})
+	}))
 
 	addr := "0.0.0.0:" + port
 	log.Printf("Game Service listening on port %s\n", port)
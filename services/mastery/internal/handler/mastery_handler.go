@@ -7,6 +7,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/swarit-1/cipher-clash/pkg/audit"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 	"github.com/swarit-1/cipher-clash/services/mastery/internal/service"
@@ -14,12 +16,14 @@ import (
 
 type MasteryHandler struct {
 	masteryService *service.MasteryService
+	audit          *audit.Log
 	log            *logger.Logger
 }
 
-func NewMasteryHandler(masteryService *service.MasteryService, log *logger.Logger) *MasteryHandler {
+func NewMasteryHandler(masteryService *service.MasteryService, auditLog *audit.Log, log *logger.Logger) *MasteryHandler {
 	return &MasteryHandler{
 		masteryService: masteryService,
+		audit:          auditLog,
 		log:            log,
 	}
 }
@@ -83,6 +87,11 @@ func (h *MasteryHandler) GetUserMastery(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	mastery, err := h.masteryService.GetUserMastery(r.Context(), userID)
 	if err != nil {
 		h.respondError(w, err)
@@ -104,6 +113,11 @@ func (h *MasteryHandler) GetUserCipherMastery(w http.ResponseWriter, r *http.Req
 		h.respondError(w, errors.NewInvalidInputError("Invalid user ID"))
 		return
 	}
+
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
 	cipherType := vars["cipher_type"]
 
 	mastery, err := h.masteryService.GetUserCipherMastery(r.Context(), userID, cipherType)
@@ -131,6 +145,11 @@ func (h *MasteryHandler) UnlockNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	if req.UserID == uuid.Nil || req.NodeID == "" {
 		h.respondError(w, errors.NewInvalidInputError("User ID and Node ID required"))
 		return
@@ -157,6 +176,11 @@ func (h *MasteryHandler) GetUserMasteryPoints(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	points, err := h.masteryService.GetUserMasteryPoints(r.Context(), userID)
 	if err != nil {
 		h.respondError(w, err)
@@ -169,7 +193,8 @@ func (h *MasteryHandler) GetUserMasteryPoints(w http.ResponseWriter, r *http.Req
 	})
 }
 
-// AwardMasteryPoints awards mastery points to a user for a cipher
+// AwardMasteryPoints awards mastery points to a user for a cipher. Admins
+// and services only; every award is audited.
 func (h *MasteryHandler) AwardMasteryPoints(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserID     uuid.UUID `json:"user_id"`
@@ -194,6 +219,12 @@ func (h *MasteryHandler) AwardMasteryPoints(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	h.audit.Record(r.Context(), audit.ActionMasteryAward, &req.UserID, map[string]interface{}{
+		"cipher_type": req.CipherType,
+		"points":      req.Points,
+		"reason":      req.Reason,
+	})
+
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"cipher_points": result,
 		"message":       "Mastery points awarded",
@@ -14,6 +14,7 @@ type CipherMasteryPointsRepository interface {
 	GetAllUserPoints(ctx context.Context, userID uuid.UUID) ([]*models.CipherMasteryPoints, error)
 	UpdateCipherPoints(ctx context.Context, points *models.CipherMasteryPoints) error
 	GetLeaderboard(ctx context.Context, cipherType string, limit int) ([]*models.LeaderboardEntry, error)
+	DeleteUserPoints(ctx context.Context, userID uuid.UUID) error
 }
 
 type cipherMasteryPointsRepository struct {
@@ -152,6 +153,11 @@ func (r *cipherMasteryPointsRepository) UpdateCipherPoints(ctx context.Context,
 	return err
 }
 
+func (r *cipherMasteryPointsRepository) DeleteUserPoints(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM cipher_mastery_points WHERE user_id = $1`, userID)
+	return err
+}
+
 func (r *cipherMasteryPointsRepository) GetLeaderboard(ctx context.Context, cipherType string, limit int) ([]*models.LeaderboardEntry, error) {
 	query := `
 		SELECT cmp.user_id, u.username, cmp.total_points, cmp.level
@@ -13,6 +13,7 @@ type UserMasteryRepository interface {
 	GetUserMastery(ctx context.Context, userID uuid.UUID) ([]*models.UserMasteryNode, error)
 	GetUserCipherMastery(ctx context.Context, userID uuid.UUID, cipherType string) ([]*models.UserMasteryNode, error)
 	GetUserNode(ctx context.Context, userID uuid.UUID, nodeID string) (*models.UserMasteryNode, error)
+	DeleteUserMastery(ctx context.Context, userID uuid.UUID) error
 }
 
 type userMasteryRepository struct {
@@ -75,6 +76,11 @@ func (r *userMasteryRepository) GetUserCipherMastery(ctx context.Context, userID
 	return r.scanUserMastery(rows)
 }
 
+func (r *userMasteryRepository) DeleteUserMastery(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_mastery WHERE user_id = $1`, userID)
+	return err
+}
+
 func (r *userMasteryRepository) GetUserNode(ctx context.Context, userID uuid.UUID, nodeID string) (*models.UserMasteryNode, error) {
 	query := `
 		SELECT id, user_id, node_id, unlocked_at, points_spent
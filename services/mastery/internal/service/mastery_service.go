@@ -263,3 +263,18 @@ func (s *MasteryService) GetMasteryLeaderboard(ctx context.Context, cipherType s
 
 	return entries, nil
 }
+
+// DeleteUserData erases the unlocked nodes and mastery points of a user who
+// deleted their account
+func (s *MasteryService) DeleteUserData(ctx context.Context, userID uuid.UUID) error {
+	if err := s.userMasteryRepo.DeleteUserMastery(ctx, userID); err != nil {
+		s.log.LogError("Failed to delete user mastery", "user_id", userID, "error", err)
+		return errors.NewInternalError("Failed to delete user mastery")
+	}
+	if err := s.cipherPointsRepo.DeleteUserPoints(ctx, userID); err != nil {
+		s.log.LogError("Failed to delete mastery points", "user_id", userID, "error", err)
+		return errors.NewInternalError("Failed to delete mastery points")
+	}
+
+	return nil
+}
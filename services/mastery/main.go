@@ -10,9 +10,13 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
+	"github.com/swarit-1/cipher-clash/pkg/audit"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
 	"github.com/swarit-1/cipher-clash/services/mastery/internal/handler"
 	"github.com/swarit-1/cipher-clash/services/mastery/internal/repository"
 	"github.com/swarit-1/cipher-clash/services/mastery/internal/service"
@@ -38,6 +42,15 @@ func main() {
 
 	log.LogInfo("Connected to database successfully")
 
+	// Initialize cache
+	cacheClient, err := cache.New(cfg.Redis, log)
+	if err != nil {
+		log.Fatal("Failed to connect to Redis", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	defer cacheClient.Close()
+
 	// Initialize repositories
 	masteryNodesRepo := repository.NewMasteryNodesRepository(database.DB)
 	userMasteryRepo := repository.NewUserMasteryRepository(database.DB)
@@ -46,11 +59,27 @@ func main() {
 	// Initialize service
 	masteryService := service.NewMasteryService(masteryNodesRepo, userMasteryRepo, cipherPointsRepo, log)
 
+	// Erase what this service keeps about users who delete their account
+	// (optional - warn but continue if RabbitMQ unavailable)
+	userDeletions, err := messaging.ListenUserDeleted(cfg.RabbitMQ, "mastery", masteryService.DeleteUserData, log)
+	if err != nil {
+		log.LogWarn("RabbitMQ unavailable - deleted users' mastery progress won't be erased", "error", err)
+	} else {
+		defer userDeletions.Close()
+	}
+
 	// Initialize handler
-	masteryHandler := handler.NewMasteryHandler(masteryService, log)
+	masteryHandler := handler.NewMasteryHandler(masteryService, audit.NewLog(database, log), log)
+
+	// Tokens are checked against the auth service's published keys
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT verifier", map[string]interface{}{"error": err})
+	}
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, log)
 
 	// Setup router
-	router := setupRouter(masteryHandler)
+	router := setupRouter(masteryHandler, authGuard)
 
 	// Setup CORS
 	corsHandler := cors.New(cors.Options{
@@ -96,7 +125,7 @@ func main() {
 	log.LogInfo("Mastery Service stopped")
 }
 
-func setupRouter(h *handler.MasteryHandler) *mux.Router {
+func setupRouter(h *handler.MasteryHandler, authGuard *auth.Middleware) *mux.Router {
 	r := mux.NewRouter()
 	api := r.PathPrefix("/api/v1").Subrouter()
 
@@ -106,13 +135,13 @@ func setupRouter(h *handler.MasteryHandler) *mux.Router {
 	api.HandleFunc("/mastery/node/{node_id}", h.GetNode).Methods("GET")
 
 	// User mastery
-	api.HandleFunc("/mastery/user/{user_id}", h.GetUserMastery).Methods("GET")
-	api.HandleFunc("/mastery/user/{user_id}/cipher/{cipher_type}", h.GetUserCipherMastery).Methods("GET")
-	api.HandleFunc("/mastery/unlock", h.UnlockNode).Methods("POST")
+	api.HandleFunc("/mastery/user/{user_id}", authGuard.RequireAuth(h.GetUserMastery)).Methods("GET")
+	api.HandleFunc("/mastery/user/{user_id}/cipher/{cipher_type}", authGuard.RequireAuth(h.GetUserCipherMastery)).Methods("GET")
+	api.HandleFunc("/mastery/unlock", authGuard.RequireAuth(h.UnlockNode)).Methods("POST")
 
 	// Mastery points
-	api.HandleFunc("/mastery/points/{user_id}", h.GetUserMasteryPoints).Methods("GET")
-	api.HandleFunc("/mastery/points/award", h.AwardMasteryPoints).Methods("POST")
+	api.HandleFunc("/mastery/points/{user_id}", authGuard.RequireAuth(h.GetUserMasteryPoints)).Methods("GET")
+	api.HandleFunc("/mastery/points/award", authGuard.RequireScope(auth.ScopeAdmin, h.AwardMasteryPoints)).Methods("POST")
 
 	// Leaderboard
 	api.HandleFunc("/mastery/leaderboard/{cipher_type}", h.GetMasteryLeaderboard).Methods("GET")
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 	"github.com/swarit-1/cipher-clash/services/matchmaker/internal/service"
@@ -32,6 +33,14 @@ func (h *MatchmakerHandler) JoinQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID); err != nil {
+		h.respondError(w, err)
+		return
+	}
+	if caller, ok := auth.CallerFromContext(r.Context()); ok {
+		req.Guest = caller.Guest
+	}
+
 	response, err := h.matchmakerService.JoinQueue(r.Context(), &req)
 	if err != nil {
 		h.respondError(w, err)
@@ -51,6 +60,11 @@ func (h *MatchmakerHandler) LeaveQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	if err := h.matchmakerService.LeaveQueue(r.Context(), req.UserID); err != nil {
 		h.respondError(w, err)
 		return
@@ -68,6 +82,10 @@ func (h *MatchmakerHandler) GetQueueStatus(w http.ResponseWriter, r *http.Reques
 		h.respondError(w, errors.NewInvalidInputError("User ID is required"))
 		return
 	}
+	if err := auth.AuthorizeUser(r.Context(), userID); err != nil {
+		h.respondError(w, err)
+		return
+	}
 
 	status, err := h.matchmakerService.GetQueueStatus(r.Context(), userID)
 	if err != nil {
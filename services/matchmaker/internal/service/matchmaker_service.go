@@ -52,6 +52,7 @@ type JoinQueueRequest struct {
 	ELO      int    `json:"elo"`
 	Region   string `json:"region"`
 	GameMode string `json:"game_mode"`
+	Guest    bool   `json:"-"` // Set from the caller's token, never the body
 }
 
 // JoinQueueResponse represents queue join result
@@ -85,6 +86,17 @@ func (ms *MatchmakerService) JoinQueue(ctx context.Context, req *JoinQueueReques
 		req.GameMode = "RANKED_1V1"
 	}
 
+	// Guests may queue for casual modes only
+	if req.Guest {
+		ranked, err := ms.isRankedMode(ctx, req.GameMode)
+		if err != nil {
+			return nil, err
+		}
+		if ranked {
+			return nil, errors.NewForbiddenError("Create an account to play ranked")
+		}
+	}
+
 	// Create queue entry
 	entry := &queue.QueueEntry{
 		UserID:   req.UserID,
@@ -151,6 +163,17 @@ func (ms *MatchmakerService) LeaveQueue(ctx context.Context, userID string) erro
 	return nil
 }
 
+// DeleteUserData takes a user who deleted their account out of the queue
+// and erases their queue history
+func (ms *MatchmakerService) DeleteUserData(ctx context.Context, userID uuid.UUID) error {
+	ms.queue.RemovePlayer(userID.String())
+
+	if _, err := ms.db.ExecContext(ctx, `DELETE FROM queue_metrics WHERE user_id = $1`, userID); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
 // GetQueueStatus returns current queue status for a player
 func (ms *MatchmakerService) GetQueueStatus(ctx context.Context, userID string) (map[string]interface{}, error) {
 	entry, playersInQueue, err := ms.queue.GetQueueStatus(userID)
@@ -186,7 +209,7 @@ func (ms *MatchmakerService) GetLeaderboard(ctx context.Context, region string,
 			total_games, wins, losses, win_streak,
 			CASE WHEN total_games > 0 THEN ROUND((wins::FLOAT / total_games::FLOAT) * 100, 2) ELSE 0 END as win_rate
 		FROM users
-		WHERE is_banned = FALSE AND total_games >= 10
+		WHERE is_banned = FALSE AND is_guest = FALSE AND total_games >= 10
 	`
 
 	// Add region filter if specified
@@ -386,3 +409,17 @@ func pow10(x float64) float64 {
 	}
 	return result
 }
+
+// isRankedMode reports whether a game mode is ranked. Unknown modes count
+// as ranked, so guests can't slip past with a misspelt name.
+func (ms *MatchmakerService) isRankedMode(ctx context.Context, gameMode string) (bool, error) {
+	var ranked bool
+	err := ms.db.QueryRowContext(ctx, `SELECT is_ranked FROM game_modes WHERE name = $1`, gameMode).Scan(&ranked)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return true, nil
+		}
+		return false, errors.NewDatabaseError(err)
+	}
+	return ranked, nil
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
@@ -87,19 +88,40 @@ func main() {
 	// Initialize services
 	matchmakerService := service.NewMatchmakerService(database, cacheClient, matchmakingQueue, publisher, log)
 
+	// Take users who delete their account out of the queue and erase their
+	// queue history
+	userDeletions, err := messaging.ListenUserDeleted(cfg.RabbitMQ, "matchmaker", matchmakerService.DeleteUserData, log)
+	if err != nil {
+		log.Fatal("Failed to subscribe to user deletions", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	defer userDeletions.Close()
+
 	// Initialize handlers
 	matchmakerHandler := handler.NewMatchmakerHandler(matchmakerService, log)
 
+	// Initialize JWT verifier, which checks tokens against the auth service's keys
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT verifier", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, log)
+
 	// Setup HTTP router
 	mux := http.NewServeMux()
 
 	// Public routes
 	mux.HandleFunc("/health", matchmakerHandler.Health)
-	mux.HandleFunc("/api/v1/matchmaker/join", matchmakerHandler.JoinQueue)
-	mux.HandleFunc("/api/v1/matchmaker/leave", matchmakerHandler.LeaveQueue)
-	mux.HandleFunc("/api/v1/matchmaker/status", matchmakerHandler.GetQueueStatus)
 	mux.HandleFunc("/api/v1/matchmaker/leaderboard", matchmakerHandler.GetLeaderboard)
 
+	// Protected routes, for the user_id they name
+	mux.HandleFunc("/api/v1/matchmaker/join", authGuard.RequireAuth(matchmakerHandler.JoinQueue))
+	mux.HandleFunc("/api/v1/matchmaker/leave", authGuard.RequireAuth(matchmakerHandler.LeaveQueue))
+	mux.HandleFunc("/api/v1/matchmaker/status", authGuard.RequireAuth(matchmakerHandler.GetQueueStatus))
+
 	// Create HTTP server
 	addr := "0.0.0.0:" + port
 	server := &http.Server{
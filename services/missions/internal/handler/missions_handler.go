@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 	"github.com/swarit-1/cipher-clash/services/missions/internal/service"
@@ -62,6 +63,11 @@ func (h *MissionsHandler) GetUserMissions(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	missions, err := h.missionsService.GetUserMissions(r.Context(), userID)
 	if err != nil {
 		h.respondError(w, err)
@@ -82,6 +88,11 @@ func (h *MissionsHandler) GetActiveMissions(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	missions, err := h.missionsService.GetActiveMissions(r.Context(), userID)
 	if err != nil {
 		h.respondError(w, err)
@@ -105,6 +116,11 @@ func (h *MissionsHandler) AssignDailyMissions(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	missions, err := h.missionsService.AssignDailyMissions(r.Context(), req.UserID)
 	if err != nil {
 		h.respondError(w, err)
@@ -178,6 +194,11 @@ func (h *MissionsHandler) ClaimMissionReward(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	rewards, err := h.missionsService.ClaimMissionReward(r.Context(), req.UserID, req.TemplateID)
 	if err != nil {
 		h.respondError(w, err)
@@ -201,6 +222,11 @@ func (h *MissionsHandler) RefreshMissions(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	missions, err := h.missionsService.RefreshExpiredMissions(r.Context(), req.UserID)
 	if err != nil {
 		h.respondError(w, err)
@@ -222,6 +248,11 @@ func (h *MissionsHandler) GetMissionStats(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	stats, err := h.missionsService.GetMissionStats(r.Context(), userID)
 	if err != nil {
 		h.respondError(w, err)
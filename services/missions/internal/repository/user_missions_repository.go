@@ -16,6 +16,7 @@ type UserMissionsRepository interface {
 	UpdateUserMission(ctx context.Context, mission *models.UserMission) error
 	MarkExpiredMissions(ctx context.Context, userID uuid.UUID) error
 	GetMissionStats(ctx context.Context, userID uuid.UUID) (*models.MissionStats, error)
+	DeleteUserMissions(ctx context.Context, userID uuid.UUID) error
 }
 
 type userMissionsRepository struct {
@@ -157,6 +158,11 @@ func (r *userMissionsRepository) MarkExpiredMissions(ctx context.Context, userID
 	return err
 }
 
+func (r *userMissionsRepository) DeleteUserMissions(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_missions WHERE user_id = $1`, userID)
+	return err
+}
+
 func (r *userMissionsRepository) GetMissionStats(ctx context.Context, userID uuid.UUID) (*models.MissionStats, error) {
 	query := `
 		SELECT
@@ -282,3 +282,13 @@ func (s *MissionsService) GetMissionStats(ctx context.Context, userID uuid.UUID)
 
 	return stats, nil
 }
+
+// DeleteUserData erases the missions of a user who deleted their account
+func (s *MissionsService) DeleteUserData(ctx context.Context, userID uuid.UUID) error {
+	if err := s.userMissionsRepo.DeleteUserMissions(ctx, userID); err != nil {
+		s.log.LogError("Failed to delete user missions", "user_id", userID, "error", err)
+		return errors.NewInternalError("Failed to delete user missions")
+	}
+
+	return nil
+}
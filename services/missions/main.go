@@ -10,9 +10,12 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
 	"github.com/swarit-1/cipher-clash/services/missions/internal/handler"
 	"github.com/swarit-1/cipher-clash/services/missions/internal/repository"
 	"github.com/swarit-1/cipher-clash/services/missions/internal/service"
@@ -41,6 +44,15 @@ func main() {
 
 	log.LogInfo("Connected to database successfully")
 
+	// Initialize cache
+	cacheClient, err := cache.New(cfg.Redis, log)
+	if err != nil {
+		log.Fatal("Failed to connect to Redis", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	defer cacheClient.Close()
+
 	// Initialize repositories
 	missionsRepo := repository.NewMissionsRepository(database.DB)
 	userMissionsRepo := repository.NewUserMissionsRepository(database.DB)
@@ -48,11 +60,27 @@ func main() {
 	// Initialize service
 	missionsService := service.NewMissionsService(missionsRepo, userMissionsRepo, log)
 
+	// Erase what this service keeps about users who delete their account
+	// (optional - warn but continue if RabbitMQ unavailable)
+	userDeletions, err := messaging.ListenUserDeleted(cfg.RabbitMQ, "missions", missionsService.DeleteUserData, log)
+	if err != nil {
+		log.LogWarn("RabbitMQ unavailable - deleted users' missions won't be erased", "error", err)
+	} else {
+		defer userDeletions.Close()
+	}
+
 	// Initialize handler
 	missionsHandler := handler.NewMissionsHandler(missionsService, log)
 
+	// Tokens are checked against the auth service's published keys
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT verifier", map[string]interface{}{"error": err})
+	}
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, log)
+
 	// Setup router
-	router := setupRouter(missionsHandler)
+	router := setupRouter(missionsHandler, authGuard)
 
 	// Setup CORS
 	corsHandler := cors.New(cors.Options{
@@ -103,7 +131,7 @@ func main() {
 	log.LogInfo("Missions Service stopped")
 }
 
-func setupRouter(h *handler.MissionsHandler) *mux.Router {
+func setupRouter(h *handler.MissionsHandler, authGuard *auth.Middleware) *mux.Router {
 	r := mux.NewRouter()
 
 	// API v1 routes
@@ -114,16 +142,16 @@ func setupRouter(h *handler.MissionsHandler) *mux.Router {
 	api.HandleFunc("/missions/templates/{id}", h.GetMissionTemplate).Methods("GET")
 
 	// User missions
-	api.HandleFunc("/missions/user/{user_id}", h.GetUserMissions).Methods("GET")
-	api.HandleFunc("/missions/user/{user_id}/active", h.GetActiveMissions).Methods("GET")
-	api.HandleFunc("/missions/assign", h.AssignDailyMissions).Methods("POST")
-	api.HandleFunc("/missions/progress", h.UpdateMissionProgress).Methods("POST")
-	api.HandleFunc("/missions/complete", h.CompleteMission).Methods("POST")
-	api.HandleFunc("/missions/claim", h.ClaimMissionReward).Methods("POST")
-	api.HandleFunc("/missions/refresh", h.RefreshMissions).Methods("POST")
+	api.HandleFunc("/missions/user/{user_id}", authGuard.RequireAuth(h.GetUserMissions)).Methods("GET")
+	api.HandleFunc("/missions/user/{user_id}/active", authGuard.RequireAuth(h.GetActiveMissions)).Methods("GET")
+	api.HandleFunc("/missions/assign", authGuard.RequireAuth(h.AssignDailyMissions)).Methods("POST")
+	api.HandleFunc("/missions/progress", authGuard.RequireScope(auth.ScopeService, h.UpdateMissionProgress)).Methods("POST")
+	api.HandleFunc("/missions/complete", authGuard.RequireScope(auth.ScopeService, h.CompleteMission)).Methods("POST")
+	api.HandleFunc("/missions/claim", authGuard.RequireAuth(h.ClaimMissionReward)).Methods("POST")
+	api.HandleFunc("/missions/refresh", authGuard.RequireAuth(h.RefreshMissions)).Methods("POST")
 
 	// Stats
-	api.HandleFunc("/missions/stats/{user_id}", h.GetMissionStats).Methods("GET")
+	api.HandleFunc("/missions/stats/{user_id}", authGuard.RequireAuth(h.GetMissionStats)).Methods("GET")
 
 	// Health check
 	r.HandleFunc("/health", healthCheck).Methods("GET")
@@ -2,31 +2,30 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/puzzleclient"
 	"github.com/swarit-1/cipher-clash/services/practice/internal"
 	"github.com/swarit-1/cipher-clash/services/practice/internal/service"
 )
 
 type PracticeHandler struct {
-	service    *service.PracticeService
-	jwtManager *auth.JWTManager
-	log        *logger.Logger
+	service *service.PracticeService
+	log     *logger.Logger
 }
 
 func NewPracticeHandler(
 	service *service.PracticeService,
-	jwtManager *auth.JWTManager,
 	log *logger.Logger,
 ) *PracticeHandler {
 	return &PracticeHandler{
-		service:    service,
-		jwtManager: jwtManager,
-		log:        log,
+		service: service,
+		log:     log,
 	}
 }
 
@@ -41,8 +40,7 @@ func (h *PracticeHandler) Health(w http.ResponseWriter, r *http.Request) {
 
 // GeneratePuzzle handles POST /api/v1/practice/generate
 func (h *PracticeHandler) GeneratePuzzle(w http.ResponseWriter, r *http.Request) {
-	// Extract user ID from JWT
-	userID, err := h.getUserIDFromToken(r)
+	userID, err := h.getUserID(r)
 	if err != nil {
 		h.respondError(w, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -55,14 +53,16 @@ func (h *PracticeHandler) GeneratePuzzle(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Validate request
-	if req.CipherType == "" {
-		h.respondError(w, http.StatusBadRequest, "cipher_type is required")
-		return
-	}
-	if req.Difficulty < 1 || req.Difficulty > 10 {
-		h.respondError(w, http.StatusBadRequest, "difficulty must be between 1 and 10")
-		return
+	// Validate request. Custom puzzles bring their own cipher and difficulty.
+	if req.ShareCode == "" {
+		if req.CipherType == "" {
+			h.respondError(w, http.StatusBadRequest, "cipher_type is required")
+			return
+		}
+		if req.Difficulty < 1 || req.Difficulty > 10 {
+			h.respondError(w, http.StatusBadRequest, "difficulty must be between 1 and 10")
+			return
+		}
 	}
 	if req.Mode == "" {
 		req.Mode = "UNTIMED"
@@ -71,6 +71,11 @@ func (h *PracticeHandler) GeneratePuzzle(w http.ResponseWriter, r *http.Request)
 	// Generate puzzle
 	result, err := h.service.GeneratePuzzle(r.Context(), userID, &req)
 	if err != nil {
+		var engineErr *puzzleclient.Error
+		if errors.As(err, &engineErr) && engineErr.StatusCode == http.StatusNotFound {
+			h.respondError(w, http.StatusNotFound, "Custom puzzle not found")
+			return
+		}
 		h.log.Error("Failed to generate puzzle", map[string]interface{}{
 			"error":   err.Error(),
 			"user_id": userID,
@@ -84,8 +89,7 @@ func (h *PracticeHandler) GeneratePuzzle(w http.ResponseWriter, r *http.Request)
 
 // SubmitSolution handles POST /api/v1/practice/submit
 func (h *PracticeHandler) SubmitSolution(w http.ResponseWriter, r *http.Request) {
-	// Extract user ID from JWT
-	userID, err := h.getUserIDFromToken(r)
+	userID, err := h.getUserID(r)
 	if err != nil {
 		h.respondError(w, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -122,10 +126,75 @@ func (h *PracticeHandler) SubmitSolution(w http.ResponseWriter, r *http.Request)
 	h.respondSuccess(w, http.StatusOK, result)
 }
 
+// RequestHint handles POST /api/v1/practice/hint
+func (h *PracticeHandler) RequestHint(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserID(r)
+	if err != nil {
+		h.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse request
+	var req internal.HintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.SessionID == "" {
+		h.respondError(w, http.StatusBadRequest, "session_id is required")
+		return
+	}
+
+	result, err := h.service.RequestHint(r.Context(), userID, &req)
+	if err != nil {
+		h.log.Error("Failed to request hint", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondSuccess(w, http.StatusOK, result)
+}
+
+// RunToolkit handles POST /api/v1/practice/toolkit
+func (h *PracticeHandler) RunToolkit(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserID(r)
+	if err != nil {
+		h.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Parse request
+	var req internal.ToolkitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.SessionID == "" {
+		h.respondError(w, http.StatusBadRequest, "session_id is required")
+		return
+	}
+
+	result, err := h.service.RunToolkit(r.Context(), userID, &req)
+	if err != nil {
+		h.log.Error("Failed to run toolkit", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		h.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.respondSuccess(w, http.StatusOK, result)
+}
+
 // GetHistory handles GET /api/v1/practice/history
 func (h *PracticeHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
-	// Extract user ID from JWT
-	userID, err := h.getUserIDFromToken(r)
+	userID, err := h.getUserID(r)
 	if err != nil {
 		h.respondError(w, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -168,8 +237,7 @@ func (h *PracticeHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 
 // GetPersonalBests handles GET /api/v1/practice/leaderboard/:cipher_type
 func (h *PracticeHandler) GetPersonalBests(w http.ResponseWriter, r *http.Request) {
-	// Extract user ID from JWT
-	userID, err := h.getUserIDFromToken(r)
+	userID, err := h.getUserID(r)
 	if err != nil {
 		h.respondError(w, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -207,19 +275,13 @@ func (h *PracticeHandler) GetPersonalBests(w http.ResponseWriter, r *http.Reques
 
 // Helper functions
 
-func (h *PracticeHandler) getUserIDFromToken(r *http.Request) (string, error) {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return "", http.ErrNoCookie
+// getUserID returns the player the auth middleware authenticated
+func (h *PracticeHandler) getUserID(r *http.Request) (string, error) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		return "", errors.New("no authenticated user")
 	}
-
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-	claims, err := h.jwtManager.ValidateToken(token, auth.AccessToken)
-	if err != nil {
-		return "", err
-	}
-
-	return claims.UserID, nil
+	return userID, nil
 }
 
 func (h *PracticeHandler) respondSuccess(w http.ResponseWriter, status int, data interface{}) {
@@ -56,6 +56,7 @@ func (r *PracticeRepository) UpdateSessionWithSolution(
 	accuracyPct float64,
 	score int,
 	perfectSolve bool,
+	hintsUsed int,
 ) error {
 	query := `
 		UPDATE practice_sessions
@@ -65,7 +66,8 @@ func (r *PracticeRepository) UpdateSessionWithSolution(
 		    is_correct = $4,
 		    accuracy_percentage = $5,
 		    score = $6,
-		    perfect_solve = $7
+		    perfect_solve = $7,
+		    hints_used = $8
 		WHERE id = $1
 	`
 
@@ -77,10 +79,23 @@ func (r *PracticeRepository) UpdateSessionWithSolution(
 		accuracyPct,
 		score,
 		perfectSolve,
+		hintsUsed,
 	)
 	return err
 }
 
+// UpdateSessionHintsUsed records how many hints a session has taken so far
+func (r *PracticeRepository) UpdateSessionHintsUsed(ctx context.Context, sessionID string, hintsUsed int) error {
+	query := `
+		UPDATE practice_sessions
+		SET hints_used = $2
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, sessionID, hintsUsed)
+	return err
+}
+
 // GetSessionByID retrieves a practice session by ID
 func (r *PracticeRepository) GetSessionByID(ctx context.Context, sessionID string) (*internal.PracticeSession, error) {
 	query := `
@@ -266,3 +281,17 @@ func (r *PracticeRepository) GetPersonalBests(
 
 	return records, nil
 }
+
+// DeleteUserData removes a user's practice sessions and personal bests
+func (r *PracticeRepository) DeleteUserData(ctx context.Context, userID string) error {
+	// Personal bests point at sessions, so they go first
+	for _, query := range []string{
+		`DELETE FROM practice_leaderboards WHERE user_id = $1`,
+		`DELETE FROM practice_sessions WHERE user_id = $1`,
+	} {
+		if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
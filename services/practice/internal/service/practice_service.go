@@ -1,14 +1,12 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 
+	"github.com/google/uuid"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/puzzleclient"
 	"github.com/swarit-1/cipher-clash/services/practice/internal"
 	"github.com/swarit-1/cipher-clash/services/practice/internal/repository"
 )
@@ -16,64 +14,47 @@ import (
 type PracticeService struct {
 	repo           *repository.PracticeRepository
 	scoringService *ScoringService
-	puzzleEngineURL string
-	log             *logger.Logger
+	puzzles        *puzzleclient.Client
+	log            *logger.Logger
 }
 
 func NewPracticeService(
 	repo *repository.PracticeRepository,
 	scoringService *ScoringService,
-	puzzleEngineURL string,
+	puzzles *puzzleclient.Client,
 	log *logger.Logger,
 ) *PracticeService {
 	return &PracticeService{
 		repo:           repo,
 		scoringService: scoringService,
-		puzzleEngineURL: puzzleEngineURL,
+		puzzles:        puzzles,
 		log:            log,
 	}
 }
 
 // GeneratePuzzle generates a practice puzzle
 func (s *PracticeService) GeneratePuzzle(ctx context.Context, userID string, req *internal.GeneratePuzzleRequest) (map[string]interface{}, error) {
-	// Call puzzle engine to generate puzzle
-	puzzleReq := map[string]interface{}{
-		"cipher_type": req.CipherType,
-		"difficulty":  req.Difficulty,
-	}
-
-	jsonData, err := json.Marshal(puzzleReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	resp, err := http.Post(
-		s.puzzleEngineURL+"/api/v1/puzzle/generate",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call puzzle engine: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("puzzle engine returned error: %s", string(body))
-	}
-
-	var puzzleResp map[string]interface{}
-	if err := json.Unmarshal(body, &puzzleResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	puzzleData, ok := puzzleResp["data"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid puzzle response format")
+	// Call puzzle engine to generate puzzle, or to fetch the custom puzzle a
+	// share code points at
+	var puzzleData *puzzleclient.Puzzle
+	if req.ShareCode != "" {
+		custom, err := s.puzzles.PlayCustomPuzzle(ctx, req.ShareCode, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load custom puzzle: %w", err)
+		}
+		puzzleData = &custom.Puzzle
+		req.CipherType = custom.CipherType
+		req.Difficulty = custom.Difficulty
+	} else {
+		generated, err := s.puzzles.Generate(ctx, &puzzleclient.GenerateRequest{
+			CipherType: req.CipherType,
+			Difficulty: req.Difficulty,
+			UserID:     userID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate puzzle: %w", err)
+		}
+		puzzleData = generated
 	}
 
 	// Create practice session
@@ -85,7 +66,7 @@ func (s *PracticeService) GeneratePuzzle(ctx context.Context, userID string, req
 
 	session := &internal.PracticeSession{
 		UserID:      userID,
-		PuzzleID:    puzzleData["id"].(string),
+		PuzzleID:    puzzleData.ID,
 		CipherType:  req.CipherType,
 		Difficulty:  req.Difficulty,
 		Mode:        internal.PracticeMode(req.Mode),
@@ -99,12 +80,11 @@ func (s *PracticeService) GeneratePuzzle(ctx context.Context, userID string, req
 
 	// Build response
 	puzzle := &internal.PracticePuzzle{
-		ID:             puzzleData["id"].(string),
-		CipherType:     req.CipherType,
-		Difficulty:     req.Difficulty,
-		EncryptedText:  puzzleData["encrypted_text"].(string),
-		Config:         puzzleData["config"].(map[string]interface{}),
-		HintsAvailable: 3, // Default hints
+		ID:             puzzleData.ID,
+		CipherType:     puzzleData.CipherType,
+		Difficulty:     puzzleData.Difficulty,
+		EncryptedText:  puzzleData.EncryptedText,
+		HintsAvailable: puzzleData.HintsAvailable,
 	}
 
 	result := map[string]interface{}{
@@ -119,12 +99,16 @@ func (s *PracticeService) GeneratePuzzle(ctx context.Context, userID string, req
 	} else {
 		result["time_limit_ms"] = 0
 	}
+	if req.ShareCode != "" {
+		result["share_code"] = req.ShareCode
+	}
 
 	s.log.Info("Practice puzzle generated", map[string]interface{}{
 		"user_id":     userID,
 		"session_id":  session.ID,
 		"cipher_type": req.CipherType,
 		"difficulty":  req.Difficulty,
+		"share_code":  req.ShareCode,
 	})
 
 	return result, nil
@@ -146,47 +130,27 @@ func (s *PracticeService) SubmitSolution(ctx context.Context, userID string, req
 		return nil, fmt.Errorf("session already completed")
 	}
 
-	// Validate solution with puzzle engine
-	validateReq := map[string]interface{}{
-		"puzzle_id": session.PuzzleID,
-		"solution":  req.Solution,
-	}
-
-	jsonData, err := json.Marshal(validateReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	resp, err := http.Post(
-		s.puzzleEngineURL+"/api/v1/puzzle/validate",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	// Validate solution with puzzle engine. The answer is only revealed to
+	// this service, and only now that the session is being closed.
+	validation, err := s.puzzles.Validate(ctx, &puzzleclient.ValidateRequest{
+		PuzzleID:        session.PuzzleID,
+		Solution:        req.Solution,
+		SolveTimeMs:     req.SolveTimeMs,
+		UserID:          userID,
+		Mode:            string(session.Mode),
+		RevealAnswer:    true,
+		IncludeFeedback: true,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate solution: %w", err)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
 
-	var validateResp map[string]interface{}
-	if err := json.Unmarshal(body, &validateResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	validationData, ok := validateResp["data"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid validation response format")
-	}
+	isCorrect := validation.IsCorrect
+	correctAnswer := validation.CorrectAnswer
+	accuracy := validation.Accuracy * 100
 
-	isCorrect := validationData["is_correct"].(bool)
-	correctAnswer := validationData["plaintext"].(string)
-
-	// Calculate accuracy
-	accuracy, charDiff := s.scoringService.CalculateAccuracy(correctAnswer, req.Solution)
+	// The puzzle engine's record of hints taken is authoritative
+	hintsUsed := validation.HintsUsed
 
 	// Calculate score
 	var timeLimitMs int64
@@ -197,14 +161,14 @@ func (s *PracticeService) SubmitSolution(ctx context.Context, userID string, req
 	score := s.scoringService.CalculateScore(
 		req.SolveTimeMs,
 		session.Difficulty,
-		req.HintsUsed,
+		hintsUsed,
 		accuracy,
 		timeLimitMs,
 	)
 
 	// Check if perfect solve
 	perfectSolve := s.scoringService.IsPerfectSolve(
-		req.HintsUsed,
+		hintsUsed,
 		accuracy,
 		req.SolveTimeMs,
 		session.Difficulty,
@@ -220,6 +184,7 @@ func (s *PracticeService) SubmitSolution(ctx context.Context, userID string, req
 		accuracy,
 		score,
 		perfectSolve,
+		hintsUsed,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update session: %w", err)
@@ -228,24 +193,29 @@ func (s *PracticeService) SubmitSolution(ctx context.Context, userID string, req
 	// Build feedback
 	feedback := &internal.SolutionFeedback{
 		TimeRating:  s.scoringService.GetTimeRating(req.SolveTimeMs, session.Difficulty, timeLimitMs),
-		HintsRating: s.scoringService.GetHintsRating(req.HintsUsed),
+		HintsRating: s.scoringService.GetHintsRating(hintsUsed),
+	}
+	if grade := validation.Feedback; grade != nil {
+		feedback.CharMask = grade.CharMask
+		feedback.WordMask = grade.WordMask
+		feedback.WordsCorrect = grade.WordsCorrect
+		feedback.WordsTotal = grade.WordsTotal
+		feedback.CharacterDiff = grade.EditDistance
 	}
 
 	if isCorrect {
 		feedback.Message = "Correct! Great job!"
-		feedback.CharacterDiff = 0
 	} else {
 		feedback.Message = "Not quite right. Keep trying!"
 		feedback.CorrectAnswer = &correctAnswer
 		feedback.YourAnswer = &req.Solution
-		feedback.CharacterDiff = charDiff
 	}
 
 	// Get personal best update
 	personalBest := s.getPersonalBestUpdate(ctx, userID, session.CipherType, session.Difficulty, req.SolveTimeMs, score)
 
 	// Calculate mastery XP
-	masteryXP := s.calculateMasteryXP(session.Difficulty, req.SolveTimeMs, accuracy, req.HintsUsed, perfectSolve)
+	masteryXP := s.calculateMasteryXP(session.Difficulty, req.SolveTimeMs, accuracy, hintsUsed, perfectSolve)
 
 	// Build response
 	result := map[string]interface{}{
@@ -253,6 +223,7 @@ func (s *PracticeService) SubmitSolution(ctx context.Context, userID string, req
 		"accuracy_percentage": accuracy,
 		"score":               score,
 		"perfect_solve":       perfectSolve,
+		"hints_used":          hintsUsed,
 		"feedback":            feedback,
 		"personal_best":       personalBest,
 		"mastery_update":      masteryXP,
@@ -269,6 +240,89 @@ func (s *PracticeService) SubmitSolution(ctx context.Context, userID string, req
 	return result, nil
 }
 
+// RequestHint unlocks the next hint for a practice session through the puzzle engine
+func (s *PracticeService) RequestHint(ctx context.Context, userID string, req *internal.HintRequest) (map[string]interface{}, error) {
+	session, err := s.repo.GetSessionByID(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if session.UserID != userID {
+		return nil, fmt.Errorf("session does not belong to user")
+	}
+
+	if session.SubmittedAt != nil {
+		return nil, fmt.Errorf("session already completed")
+	}
+
+	hint, err := s.puzzles.RequestHint(ctx, session.PuzzleID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request hint: %w", err)
+	}
+
+	if err := s.repo.UpdateSessionHintsUsed(ctx, req.SessionID, hint.HintsUsed); err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	s.log.Info("Practice hint served", map[string]interface{}{
+		"user_id":    userID,
+		"session_id": req.SessionID,
+		"hints_used": hint.HintsUsed,
+	})
+
+	return map[string]interface{}{
+		"session_id":      req.SessionID,
+		"hints":           hint.Hints,
+		"hints_used":      hint.HintsUsed,
+		"hints_remaining": hint.HintsRemaining,
+	}, nil
+}
+
+// RunToolkit runs the puzzle engine's cryptanalysis toolkit for an open
+// practice session. The mode comes from the session, so the tools the mode
+// allows can't be widened by the client.
+func (s *PracticeService) RunToolkit(ctx context.Context, userID string, req *internal.ToolkitRequest) (map[string]interface{}, error) {
+	session, err := s.repo.GetSessionByID(ctx, req.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if session.UserID != userID {
+		return nil, fmt.Errorf("session does not belong to user")
+	}
+
+	if session.SubmittedAt != nil {
+		return nil, fmt.Errorf("session already completed")
+	}
+
+	puzzle, err := s.puzzles.GetPuzzle(ctx, session.PuzzleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get puzzle: %w", err)
+	}
+
+	ciphertext := req.Ciphertext
+	if ciphertext == "" {
+		ciphertext = puzzle.EncryptedText
+	}
+
+	report, err := s.puzzles.Analyze(ctx, &puzzleclient.AnalyzeRequest{
+		Ciphertext:   ciphertext,
+		Mode:         string(session.Mode),
+		Language:     puzzle.Language,
+		Tools:        req.Tools,
+		Candidates:   req.Candidates,
+		MaxKeyLength: req.MaxKeyLength,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run toolkit: %w", err)
+	}
+
+	return map[string]interface{}{
+		"session_id": req.SessionID,
+		"report":     report,
+	}, nil
+}
+
 // GetHistory retrieves practice history
 func (s *PracticeService) GetHistory(ctx context.Context, userID string, cipherType *string, limit, offset int) (map[string]interface{}, error) {
 	sessions, total, err := s.repo.GetUserHistory(ctx, userID, cipherType, limit, offset)
@@ -320,6 +374,15 @@ func (s *PracticeService) GetPersonalBests(ctx context.Context, userID string, c
 	return result, nil
 }
 
+// DeleteUserData erases the practice history of a user who deleted their
+// account
+func (s *PracticeService) DeleteUserData(ctx context.Context, userID uuid.UUID) error {
+	if err := s.repo.DeleteUserData(ctx, userID.String()); err != nil {
+		return fmt.Errorf("failed to delete practice data: %w", err)
+	}
+	return nil
+}
+
 // Helper function to get personal best update
 func (s *PracticeService) getPersonalBestUpdate(ctx context.Context, userID, cipherType string, difficulty int, solveTimeMs int64, score int) *internal.PersonalBestUpdate {
 	records, err := s.repo.GetPersonalBests(ctx, userID, &cipherType, &difficulty)
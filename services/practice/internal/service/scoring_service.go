@@ -2,7 +2,6 @@ package service
 
 import (
 	"math"
-	"strings"
 )
 
 type ScoringService struct{}
@@ -74,75 +73,3 @@ func (s *ScoringService) IsPerfectSolve(hintsUsed int, accuracyPct float64, solv
 	targetTime := int64(difficulty * 30000)
 	return hintsUsed == 0 && accuracyPct == 100.0 && solveTimeMs <= targetTime
 }
-
-// CalculateAccuracy calculates accuracy percentage between two strings
-func (s *ScoringService) CalculateAccuracy(expected, actual string) (float64, int) {
-	expected = strings.ToUpper(strings.TrimSpace(expected))
-	actual = strings.ToUpper(strings.TrimSpace(actual))
-
-	if expected == actual {
-		return 100.0, 0
-	}
-
-	// Calculate Levenshtein distance
-	diff := s.levenshteinDistance(expected, actual)
-	maxLen := math.Max(float64(len(expected)), float64(len(actual)))
-
-	if maxLen == 0 {
-		return 0.0, 0
-	}
-
-	accuracy := (1.0 - float64(diff)/maxLen) * 100.0
-	accuracy = math.Max(0, accuracy)
-
-	return accuracy, diff
-}
-
-// levenshteinDistance calculates the edit distance between two strings
-func (s *ScoringService) levenshteinDistance(s1, s2 string) int {
-	if len(s1) == 0 {
-		return len(s2)
-	}
-	if len(s2) == 0 {
-		return len(s1)
-	}
-
-	matrix := make([][]int, len(s1)+1)
-	for i := range matrix {
-		matrix[i] = make([]int, len(s2)+1)
-		matrix[i][0] = i
-	}
-	for j := range matrix[0] {
-		matrix[0][j] = j
-	}
-
-	for i := 1; i <= len(s1); i++ {
-		for j := 1; j <= len(s2); j++ {
-			cost := 0
-			if s1[i-1] != s2[j-1] {
-				cost = 1
-			}
-
-			matrix[i][j] = min(
-				matrix[i-1][j]+1,      // deletion
-				matrix[i][j-1]+1,      // insertion
-				matrix[i-1][j-1]+cost, // substitution
-			)
-		}
-	}
-
-	return matrix[len(s1)][len(s2)]
-}
-
-func min(a, b, c int) int {
-	if a < b {
-		if a < c {
-			return a
-		}
-		return c
-	}
-	if b < c {
-		return b
-	}
-	return c
-}
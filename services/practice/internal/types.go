@@ -72,6 +72,10 @@ type SolutionFeedback struct {
 	CorrectAnswer *string `json:"correct_answer,omitempty"`
 	YourAnswer    *string `json:"your_answer,omitempty"`
 	CharacterDiff int     `json:"character_diff"`
+	CharMask      []bool  `json:"char_mask"` // Per submitted character: matches the answer
+	WordMask      []bool  `json:"word_mask"` // Per submitted word: correct and in place
+	WordsCorrect  int     `json:"words_correct"`
+	WordsTotal    int     `json:"words_total"`
 }
 
 // PersonalBestUpdate represents whether a new record was achieved
@@ -101,6 +105,7 @@ type GeneratePuzzleRequest struct {
 	Difficulty       int    `json:"difficulty"`
 	Mode             string `json:"mode"`
 	TimeLimitSeconds *int   `json:"time_limit_seconds,omitempty"`
+	ShareCode        string `json:"share_code,omitempty"` // Plays a custom puzzle; cipher type and difficulty come from it
 }
 
 // SubmitSolutionRequest is the request for submitting a solution
@@ -108,5 +113,20 @@ type SubmitSolutionRequest struct {
 	SessionID   string `json:"session_id"`
 	Solution    string `json:"solution"`
 	SolveTimeMs int64  `json:"solve_time_ms"`
-	HintsUsed   int    `json:"hints_used"`
+}
+
+// HintRequest is the request for the next hint of a practice session. Hint
+// usage is recorded by the puzzle engine, not reported by the client.
+type HintRequest struct {
+	SessionID string `json:"session_id"`
+}
+
+// ToolkitRequest runs the cryptanalysis toolkit for a practice session, in the
+// session's mode
+type ToolkitRequest struct {
+	SessionID    string   `json:"session_id"`
+	Ciphertext   string   `json:"ciphertext,omitempty"` // Defaults to the puzzle's; set it to analyze a partly decrypted text
+	Tools        []string `json:"tools,omitempty"`
+	Candidates   []string `json:"candidates,omitempty"`
+	MaxKeyLength int      `json:"max_key_length,omitempty"`
 }
@@ -10,9 +10,12 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
+	"github.com/swarit-1/cipher-clash/pkg/puzzleclient"
 	"github.com/swarit-1/cipher-clash/services/practice/internal/handler"
 	"github.com/swarit-1/cipher-clash/services/practice/internal/repository"
 	"github.com/swarit-1/cipher-clash/services/practice/internal/service"
@@ -49,24 +52,49 @@ func main() {
 	}
 	defer database.Close()
 
-	// Initialize JWT manager
-	jwtManager := auth.NewJWTManager(cfg.JWT)
+	// Initialize cache
+	cacheClient, err := cache.New(cfg.Redis, log)
+	if err != nil {
+		log.Fatal("Failed to connect to Redis", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	defer cacheClient.Close()
 
-	// Get puzzle engine URL
-	puzzleEngineURL := os.Getenv("PUZZLE_ENGINE_URL")
-	if puzzleEngineURL == "" {
-		puzzleEngineURL = "http://localhost:8087"
+	// Initialize JWT verifier, which checks tokens against the auth service's keys
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT verifier", map[string]interface{}{
+			"error": err.Error(),
+		})
 	}
 
+	// Puzzle engine client, authenticated as an internal service
+	puzzles := puzzleclient.New(cfg.Internal.PuzzleEngineURL, cfg.Internal.ServiceToken)
+
 	// Initialize repositories
 	practiceRepo := repository.NewPracticeRepository(database.DB)
 
 	// Initialize services
 	scoringService := service.NewScoringService()
-	practiceService := service.NewPracticeService(practiceRepo, scoringService, puzzleEngineURL, log)
+	practiceService := service.NewPracticeService(practiceRepo, scoringService, puzzles, log)
+
+	// Erase what this service keeps about users who delete their account
+	// (optional - warn but continue if RabbitMQ unavailable)
+	userDeletions, err := messaging.ListenUserDeleted(cfg.RabbitMQ, "practice", practiceService.DeleteUserData, log)
+	if err != nil {
+		log.Warn("RabbitMQ unavailable - deleted users' practice history won't be erased", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else {
+		defer userDeletions.Close()
+	}
 
 	// Initialize handlers
-	practiceHandler := handler.NewPracticeHandler(practiceService, jwtManager, log)
+	practiceHandler := handler.NewPracticeHandler(practiceService, log)
+
+	// Every practice route acts for the logged in player
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, log)
 
 	// Setup HTTP router
 	mux := http.NewServeMux()
@@ -75,31 +103,47 @@ func main() {
 	mux.HandleFunc("/health", practiceHandler.Health)
 
 	// Practice routes
-	mux.HandleFunc("/api/v1/practice/generate", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/v1/practice/generate", authGuard.RequireScope(auth.ScopePlayer, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			practiceHandler.GeneratePuzzle(w, r)
 		} else {
 			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
 		}
-	})
+	}))
 
-	mux.HandleFunc("/api/v1/practice/submit", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/v1/practice/submit", authGuard.RequireScope(auth.ScopePlayer, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			practiceHandler.SubmitSolution(w, r)
 		} else {
 			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
 		}
-	})
+	}))
+
+	mux.HandleFunc("/api/v1/practice/hint", authGuard.RequireScope(auth.ScopePlayer, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			practiceHandler.RequestHint(w, r)
+		} else {
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/v1/practice/toolkit", authGuard.RequireScope(auth.ScopePlayer, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			practiceHandler.RunToolkit(w, r)
+		} else {
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
 
-	mux.HandleFunc("/api/v1/practice/history", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/v1/practice/history", authGuard.RequireScope(auth.ScopePlayer, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			practiceHandler.GetHistory(w, r)
 		} else {
 			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
 		}
-	})
+	}))
 
-	mux.HandleFunc("/api/v1/practice/leaderboard/", practiceHandler.GetPersonalBests)
+	mux.HandleFunc("/api/v1/practice/leaderboard/", authGuard.RequireScope(auth.ScopePlayer, practiceHandler.GetPersonalBests))
 
 	// CORS middleware
 	corsMiddleware := func(next http.Handler) http.Handler {
@@ -1,13 +1,13 @@
 package ciphers
 
 import (
-	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
-	"math/big"
+	"math/rand"
 	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
 // ============================================================================
@@ -19,35 +19,29 @@ type CaesarCipher struct{}
 func (c *CaesarCipher) Name() string { return TypeCaesar }
 
 func (c *CaesarCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
-	shift := int(config["shift"].(float64))
-	return caesarShift(plaintext, shift), nil
+	shift := configInt(config, "shift")
+	return caesarShift(plaintext, shift, AlphabetOf(config)), nil
 }
 
 func (c *CaesarCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
-	shift := int(config["shift"].(float64))
-	return caesarShift(ciphertext, -shift), nil
+	shift := configInt(config, "shift")
+	return caesarShift(ciphertext, -shift, AlphabetOf(config)), nil
 }
 
-func (c *CaesarCipher) GenerateKey(difficulty int) map[string]interface{} {
-	shift := (difficulty * 3) % 26
+func (c *CaesarCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return c.GenerateLocalizedKey(difficulty, Latin, rng)
+}
+
+func (c *CaesarCipher) GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{} {
+	shift := (difficulty * 3) % alphabet.Size()
 	if shift == 0 {
 		shift = 3
 	}
 	return map[string]interface{}{"shift": shift}
 }
 
-func caesarShift(text string, shift int) string {
-	result := ""
-	for _, char := range text {
-		if char >= 'A' && char <= 'Z' {
-			result += string((int(char-'A')+shift+26)%26 + 'A')
-		} else if char >= 'a' && char <= 'z' {
-			result += string((int(char-'a')+shift+26)%26 + 'a')
-		} else {
-			result += string(char)
-		}
-	}
-	return result
+func caesarShift(text string, shift int, alphabet *Alphabet) string {
+	return alphabet.transform(text, func(index int) int { return index + shift })
 }
 
 // ============================================================================
@@ -60,48 +54,49 @@ func (v *VigenereCipher) Name() string { return TypeVigenere }
 
 func (v *VigenereCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
 	key := config["key"].(string)
-	return vigenereProcess(plaintext, key, true), nil
+	return vigenereProcess(plaintext, key, true, AlphabetOf(config)), nil
 }
 
 func (v *VigenereCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
 	key := config["key"].(string)
-	return vigenereProcess(ciphertext, key, false), nil
+	return vigenereProcess(ciphertext, key, false, AlphabetOf(config)), nil
 }
 
-func (v *VigenereCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (v *VigenereCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return v.GenerateLocalizedKey(difficulty, Latin, rng)
+}
+
+func (v *VigenereCipher) GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{} {
 	keyLength := 3 + (difficulty / 2)
-	key := ""
-	for i := 0; i < keyLength; i++ {
-		key += string('A' + rune(randInt(26)))
-	}
-	return map[string]interface{}{"key": key}
+	return map[string]interface{}{"key": alphabet.randomKey(rng, keyLength)}
 }
 
-func vigenereProcess(text, key string, encrypt bool) string {
-	result := ""
+func vigenereProcess(text, key string, encrypt bool, alphabet *Alphabet) string {
+	shifts := keyIndices(key, alphabet)
+	if len(shifts) == 0 {
+		return text
+	}
+
 	keyIndex := 0
-	key = strings.ToUpper(key)
+	return alphabet.transform(text, func(index int) int {
+		shift := shifts[keyIndex%len(shifts)]
+		keyIndex++
+		if !encrypt {
+			shift = -shift
+		}
+		return index + shift
+	})
+}
 
-	for _, char := range text {
-		if char >= 'A' && char <= 'Z' {
-			shift := int(key[keyIndex%len(key)] - 'A')
-			if !encrypt {
-				shift = -shift
-			}
-			result += string((int(char-'A')+shift+26)%26 + 'A')
-			keyIndex++
-		} else if char >= 'a' && char <= 'z' {
-			shift := int(key[keyIndex%len(key)] - 'A')
-			if !encrypt {
-				shift = -shift
-			}
-			result += string((int(char-'a')+shift+26)%26 + 'a')
-			keyIndex++
-		} else {
-			result += string(char)
+// keyIndices returns the alphabet positions of a key's letters
+func keyIndices(key string, alphabet *Alphabet) []int {
+	var indices []int
+	for _, char := range alphabet.Fold(key) {
+		if i, ok := alphabet.Index(char); ok {
+			indices = append(indices, i)
 		}
 	}
-	return result
+	return indices
 }
 
 // ============================================================================
@@ -113,7 +108,7 @@ type RailFenceCipher struct{}
 func (r *RailFenceCipher) Name() string { return TypeRailFence }
 
 func (r *RailFenceCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
-	rails := int(config["rails"].(float64))
+	rails := configInt(config, "rails")
 	if rails <= 1 {
 		return plaintext, nil
 	}
@@ -140,16 +135,17 @@ func (r *RailFenceCipher) Encrypt(plaintext string, config map[string]interface{
 }
 
 func (r *RailFenceCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
-	rails := int(config["rails"].(float64))
+	rails := configInt(config, "rails")
 	if rails <= 1 {
 		return ciphertext, nil
 	}
+	runes := []rune(ciphertext)
 
 	// Calculate rail lengths
 	fence := make([][]rune, rails)
 	railLengths := make([]int, rails)
 	rail, direction := 0, 1
-	for i := 0; i < len(ciphertext); i++ {
+	for i := 0; i < len(runes); i++ {
 		railLengths[rail]++
 		rail += direction
 		if rail == 0 || rail == rails-1 {
@@ -160,7 +156,7 @@ func (r *RailFenceCipher) Decrypt(ciphertext string, config map[string]interface
 	// Fill fence with ciphertext
 	idx := 0
 	for i := 0; i < rails; i++ {
-		fence[i] = []rune(ciphertext[idx : idx+railLengths[i]])
+		fence[i] = runes[idx : idx+railLengths[i]]
 		idx += railLengths[i]
 	}
 
@@ -168,7 +164,7 @@ func (r *RailFenceCipher) Decrypt(ciphertext string, config map[string]interface
 	result := ""
 	rail, direction = 0, 1
 	railIdx := make([]int, rails)
-	for i := 0; i < len(ciphertext); i++ {
+	for i := 0; i < len(runes); i++ {
 		result += string(fence[rail][railIdx[rail]])
 		railIdx[rail]++
 		rail += direction
@@ -179,7 +175,7 @@ func (r *RailFenceCipher) Decrypt(ciphertext string, config map[string]interface
 	return result, nil
 }
 
-func (r *RailFenceCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (r *RailFenceCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	rails := 2 + (difficulty / 3)
 	if rails > 7 {
 		rails = 7
@@ -197,94 +193,135 @@ func (p *PlayfairCipher) Name() string { return TypePlayfair }
 
 func (p *PlayfairCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
 	key := config["key"].(string)
-	grid := buildPlayfairGrid(key)
+	grid := buildPlayfairGrid(key, AlphabetOf(config))
 	return playfairProcess(plaintext, grid, true), nil
 }
 
 func (p *PlayfairCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
 	key := config["key"].(string)
-	grid := buildPlayfairGrid(key)
+	grid := buildPlayfairGrid(key, AlphabetOf(config))
 	return playfairProcess(ciphertext, grid, false), nil
 }
 
-func (p *PlayfairCipher) GenerateKey(difficulty int) map[string]interface{} {
-	keyLength := 5 + difficulty
-	key := ""
-	for i := 0; i < keyLength; i++ {
-		key += string('A' + rune(randInt(26)))
-	}
-	return map[string]interface{}{"key": key}
+func (p *PlayfairCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return p.GenerateLocalizedKey(difficulty, Latin, rng)
 }
 
-func buildPlayfairGrid(key string) [5][5]rune {
-	var grid [5][5]rune
-	used := make(map[rune]bool)
-	key = strings.ToUpper(strings.ReplaceAll(key, "J", "I"))
+func (p *PlayfairCipher) GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{} {
+	keyLength := 5 + difficulty
+	return map[string]interface{}{"key": alphabet.randomKey(rng, keyLength)}
+}
+
+// playfairGrid is the key square. Alphabets of up to 26 letters use the
+// classic 5x5 square with J merged into I; larger ones use a 6x6 square whose
+// spare cells hold digits.
+type playfairGrid struct {
+	size     int
+	cells    []rune
+	pos      map[rune]int
+	merge    map[rune]rune
+	alphabet *Alphabet
+}
+
+func buildPlayfairGrid(key string, alphabet *Alphabet) *playfairGrid {
+	grid := &playfairGrid{
+		size:     5,
+		pos:      make(map[rune]int),
+		merge:    make(map[rune]rune),
+		alphabet: alphabet,
+	}
 
-	idx := 0
-	for _, char := range key {
-		if char >= 'A' && char <= 'Z' && !used[char] {
-			grid[idx/5][idx%5] = char
-			used[char] = true
-			idx++
+	var symbols []rune
+	if alphabet.Size() <= 26 {
+		grid.merge['J'] = 'I'
+		for _, letter := range alphabet.Letters() {
+			if letter != 'J' {
+				symbols = append(symbols, letter)
+			}
+		}
+	} else {
+		grid.size = 6
+		symbols = []rune(alphabet.Letters())
+		for digit := '0'; len(symbols) < 36; digit++ {
+			symbols = append(symbols, digit)
 		}
 	}
+	inGrid := make(map[rune]bool)
+	for _, symbol := range symbols {
+		inGrid[symbol] = true
+	}
 
-	for char := 'A'; char <= 'Z' && idx < 25; char++ {
-		if char == 'J' || used[char] {
-			continue
+	add := func(char rune) {
+		if inGrid[char] {
+			if _, used := grid.pos[char]; !used {
+				grid.pos[char] = len(grid.cells)
+				grid.cells = append(grid.cells, char)
+			}
 		}
-		grid[idx/5][idx%5] = char
-		used[char] = true
-		idx++
+	}
+	for _, char := range grid.normalize(key) {
+		add(char)
+	}
+	for _, symbol := range symbols {
+		add(symbol)
 	}
 	return grid
 }
 
-func playfairProcess(text string, grid [5][5]rune, encrypt bool) string {
-	text = strings.ToUpper(strings.ReplaceAll(text, "J", "I"))
-	text = strings.ReplaceAll(text, " ", "")
+// normalize uppercases text and merges letters the square shares a cell for.
+// Folding comes first: ß has no single uppercase letter and folds to ss.
+func (g *playfairGrid) normalize(text string) []rune {
+	var out []rune
+	for _, char := range strings.ToUpper(g.alphabet.Fold(text)) {
+		if merged, ok := g.merge[char]; ok {
+			char = merged
+		}
+		out = append(out, char)
+	}
+	return out
+}
 
-	// Find positions
-	pos := make(map[rune][2]int)
-	for i := 0; i < 5; i++ {
-		for j := 0; j < 5; j++ {
-			pos[grid[i][j]] = [2]int{i, j}
+func (g *playfairGrid) at(row, col int) rune {
+	return g.cells[mod(row, g.size)*g.size+mod(col, g.size)]
+}
+
+func playfairProcess(text string, grid *playfairGrid, encrypt bool) string {
+	// Only characters of the square are encrypted; spaces are dropped
+	var letters []rune
+	for _, char := range grid.normalize(text) {
+		if _, ok := grid.pos[char]; ok {
+			letters = append(letters, char)
 		}
 	}
 
-	result := ""
-	for i := 0; i < len(text); i += 2 {
-		a := rune(text[i])
-		b := rune('X')
-		if i+1 < len(text) {
-			b = rune(text[i+1])
+	step := 1
+	if !encrypt {
+		step = -1
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(letters); i += 2 {
+		a := letters[i]
+		b := grid.alphabet.Filler
+		if i+1 < len(letters) {
+			b = letters[i+1]
 		}
 
-		posA, posB := pos[a], pos[b]
+		rowA, colA := grid.pos[a]/grid.size, grid.pos[a]%grid.size
+		rowB, colB := grid.pos[b]/grid.size, grid.pos[b]%grid.size
 
-		if posA[0] == posB[0] { // Same row
-			if encrypt {
-				result += string(grid[posA[0]][(posA[1]+1)%5])
-				result += string(grid[posB[0]][(posB[1]+1)%5])
-			} else {
-				result += string(grid[posA[0]][(posA[1]+4)%5])
-				result += string(grid[posB[0]][(posB[1]+4)%5])
-			}
-		} else if posA[1] == posB[1] { // Same column
-			if encrypt {
-				result += string(grid[(posA[0]+1)%5][posA[1]])
-				result += string(grid[(posB[0]+1)%5][posB[1]])
-			} else {
-				result += string(grid[(posA[0]+4)%5][posA[1]])
-				result += string(grid[(posB[0]+4)%5][posB[1]])
-			}
+		if rowA == rowB { // Same row
+			result.WriteRune(grid.at(rowA, colA+step))
+			result.WriteRune(grid.at(rowB, colB+step))
+		} else if colA == colB { // Same column
+			result.WriteRune(grid.at(rowA+step, colA))
+			result.WriteRune(grid.at(rowB+step, colB))
 		} else { // Rectangle
-			result += string(grid[posA[0]][posB[1]])
-			result += string(grid[posB[0]][posA[1]])
+			result.WriteRune(grid.at(rowA, colB))
+			result.WriteRune(grid.at(rowB, colA))
 		}
 	}
-	return result
+	return result.String()
 }
 
 // ============================================================================
@@ -296,45 +333,53 @@ type SubstitutionCipher struct{}
 func (s *SubstitutionCipher) Name() string { return TypeSubstitution }
 
 func (s *SubstitutionCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
-	key := config["key"].(string)
-	result := ""
-	for _, char := range plaintext {
-		if char >= 'A' && char <= 'Z' {
-			result += string(key[char-'A'])
-		} else if char >= 'a' && char <= 'z' {
-			result += string(key[char-'a'] + 32)
-		} else {
-			result += string(char)
-		}
+	alphabet := AlphabetOf(config)
+	mapping, err := substitutionMapping(config["key"].(string), alphabet)
+	if err != nil {
+		return "", err
 	}
-	return result, nil
+	return alphabet.transform(plaintext, func(index int) int { return mapping[index] }), nil
 }
 
 func (s *SubstitutionCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
-	key := config["key"].(string)
-	reverseKey := make(map[rune]rune)
-	for i, char := range key {
-		reverseKey[char] = rune('A' + i)
-		reverseKey[char+32] = rune('a' + i)
+	alphabet := AlphabetOf(config)
+	mapping, err := substitutionMapping(config["key"].(string), alphabet)
+	if err != nil {
+		return "", err
 	}
-
-	result := ""
-	for _, char := range ciphertext {
-		if val, ok := reverseKey[char]; ok {
-			result += string(val)
-		} else {
-			result += string(char)
-		}
+	reverse := make([]int, len(mapping))
+	for plain, cipher := range mapping {
+		reverse[cipher] = plain
 	}
-	return result, nil
+	return alphabet.transform(ciphertext, func(index int) int { return reverse[index] }), nil
+}
+
+func (s *SubstitutionCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return s.GenerateLocalizedKey(difficulty, Latin, rng)
 }
 
-func (s *SubstitutionCipher) GenerateKey(difficulty int) map[string]interface{} {
-	alphabet := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	shuffled := shuffleString(alphabet)
+func (s *SubstitutionCipher) GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{} {
+	shuffled := shuffleString(rng, alphabet.Letters())
 	return map[string]interface{}{"key": shuffled}
 }
 
+// substitutionMapping reads a key, the alphabet in substituted order, as the
+// position each plaintext letter maps to
+func substitutionMapping(key string, alphabet *Alphabet) ([]int, error) {
+	mapping := keyIndices(key, alphabet)
+	if len(mapping) != alphabet.Size() {
+		return nil, fmt.Errorf("substitution key must contain all %d letters of the alphabet", alphabet.Size())
+	}
+	seen := make([]bool, alphabet.Size())
+	for _, index := range mapping {
+		if seen[index] {
+			return nil, fmt.Errorf("substitution key repeats the letter %c", alphabet.Letter(index))
+		}
+		seen[index] = true
+	}
+	return mapping, nil
+}
+
 // ============================================================================
 // 6. TRANSPOSITION CIPHER
 // ============================================================================
@@ -345,16 +390,17 @@ func (t *TranspositionCipher) Name() string { return TypeTransposition }
 
 func (t *TranspositionCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
 	key := config["key"].(string)
+	runes := []rune(plaintext)
 	cols := len(key)
-	rows := (len(plaintext) + cols - 1) / cols
+	rows := (len(runes) + cols - 1) / cols
 	grid := make([][]rune, rows)
 
 	idx := 0
 	for i := 0; i < rows; i++ {
 		grid[i] = make([]rune, cols)
 		for j := 0; j < cols; j++ {
-			if idx < len(plaintext) {
-				grid[i][j] = rune(plaintext[idx])
+			if idx < len(runes) {
+				grid[i][j] = runes[idx]
 				idx++
 			} else {
 				grid[i][j] = 'X'
@@ -374,8 +420,9 @@ func (t *TranspositionCipher) Encrypt(plaintext string, config map[string]interf
 
 func (t *TranspositionCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
 	key := config["key"].(string)
+	runes := []rune(ciphertext)
 	cols := len(key)
-	rows := len(ciphertext) / cols
+	rows := len(runes) / cols
 
 	order := getSortedOrder(key)
 	grid := make([][]rune, rows)
@@ -386,7 +433,7 @@ func (t *TranspositionCipher) Decrypt(ciphertext string, config map[string]inter
 	idx := 0
 	for _, col := range order {
 		for row := 0; row < rows; row++ {
-			grid[row][col] = rune(ciphertext[idx])
+			grid[row][col] = runes[idx]
 			idx++
 		}
 	}
@@ -400,13 +447,13 @@ func (t *TranspositionCipher) Decrypt(ciphertext string, config map[string]inter
 	return strings.TrimRight(result, "X"), nil
 }
 
-func (t *TranspositionCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (t *TranspositionCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	length := 3 + (difficulty / 2)
 	key := ""
 	for i := 0; i < length; i++ {
 		key += string('A' + rune(i))
 	}
-	return map[string]interface{}{"key": shuffleString(key)}
+	return map[string]interface{}{"key": shuffleString(rng, key)}
 }
 
 // ============================================================================
@@ -422,16 +469,30 @@ func (x *XORCipher) Encrypt(plaintext string, config map[string]interface{}) (st
 	return xorProcess(plaintext, key), nil
 }
 
+// Decrypt undoes xorProcess: the hex decodes to the XORed characters, and the
+// key position advances by the byte length of each recovered plaintext
+// character, as it did over the plaintext when encrypting
 func (x *XORCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
 	key := config["key"].(string)
-	return xorProcess(ciphertext, key), nil
+	decoded, err := hex.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	offset := 0
+	for _, char := range string(decoded) {
+		plain := char ^ rune(key[offset%len(key)])
+		result.WriteRune(plain)
+		offset += utf8.RuneLen(plain)
+	}
+	return result.String(), nil
 }
 
-func (x *XORCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (x *XORCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	keyLength := 2 + (difficulty / 2)
 	key := ""
 	for i := 0; i < keyLength; i++ {
-		key += string(rune(randInt(94) + 33))
+		key += string(rune(randInt(rng, 94) + 33))
 	}
 	return map[string]interface{}{"key": key}
 }
@@ -464,7 +525,7 @@ func (b *Base64Cipher) Decrypt(ciphertext string, config map[string]interface{})
 	return string(decoded), nil
 }
 
-func (b *Base64Cipher) GenerateKey(difficulty int) map[string]interface{} {
+func (b *Base64Cipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	return map[string]interface{}{}
 }
 
@@ -512,7 +573,7 @@ func (m *MorseCipher) Decrypt(ciphertext string, config map[string]interface{})
 	return result, nil
 }
 
-func (m *MorseCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (m *MorseCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	return map[string]interface{}{}
 }
 
@@ -539,7 +600,7 @@ func (b *BinaryCipher) Decrypt(ciphertext string, config map[string]interface{})
 	}
 	return result, nil
 }
-func (b *BinaryCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (b *BinaryCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	return map[string]interface{}{}
 }
 
@@ -552,41 +613,42 @@ func (h *HexadecimalCipher) Decrypt(ciphertext string, config map[string]interfa
 	decoded, err := hex.DecodeString(ciphertext)
 	return string(decoded), err
 }
-func (h *HexadecimalCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (h *HexadecimalCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	return map[string]interface{}{}
 }
 
 type ROT13Cipher struct{}
 func (r *ROT13Cipher) Name() string { return TypeROT13 }
+// ROT13 rotates by half the alphabet, which is 13 for A-Z
 func (r *ROT13Cipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
-	return caesarShift(plaintext, 13), nil
+	alphabet := AlphabetOf(config)
+	return caesarShift(plaintext, alphabet.Size()/2, alphabet), nil
 }
 func (r *ROT13Cipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
-	return caesarShift(ciphertext, 13), nil
+	alphabet := AlphabetOf(config)
+	return caesarShift(ciphertext, -alphabet.Size()/2, alphabet), nil
 }
-func (r *ROT13Cipher) GenerateKey(difficulty int) map[string]interface{} {
+func (r *ROT13Cipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return map[string]interface{}{}
+}
+func (r *ROT13Cipher) GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{} {
 	return map[string]interface{}{}
 }
 
 type AtbashCipher struct{}
 func (a *AtbashCipher) Name() string { return TypeAtbash }
 func (a *AtbashCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
-	result := ""
-	for _, char := range plaintext {
-		if char >= 'A' && char <= 'Z' {
-			result += string('Z' - (char - 'A'))
-		} else if char >= 'a' && char <= 'z' {
-			result += string('z' - (char - 'a'))
-		} else {
-			result += string(char)
-		}
-	}
-	return result, nil
+	alphabet := AlphabetOf(config)
+	last := alphabet.Size() - 1
+	return alphabet.transform(plaintext, func(index int) int { return last - index }), nil
 }
 func (a *AtbashCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
 	return a.Encrypt(ciphertext, config)
 }
-func (a *AtbashCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (a *AtbashCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return map[string]interface{}{}
+}
+func (a *AtbashCipher) GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{} {
 	return map[string]interface{}{}
 }
 
@@ -616,20 +678,20 @@ func (b *BookCipherImpl) Decrypt(ciphertext string, config map[string]interface{
 	}
 	return result, nil
 }
-func (b *BookCipherImpl) GenerateKey(difficulty int) map[string]interface{} {
+func (b *BookCipherImpl) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	books := []string{
 		"THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG",
 		"CRYPTOGRAPHY IS THE PRACTICE OF SECURE COMMUNICATION",
 		"HELLO WORLD THIS IS A TEST MESSAGE FOR ENCRYPTION",
 	}
-	return map[string]interface{}{"book": books[randInt(len(books))]}
+	return map[string]interface{}{"book": books[randInt(rng, len(books))]}
 }
 
 type RSASimpleCipher struct{}
 func (r *RSASimpleCipher) Name() string { return TypeRSASimple }
 func (r *RSASimpleCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
-	e := int64(config["e"].(float64))
-	n := int64(config["n"].(float64))
+	e := configInt64(config, "e")
+	n := configInt64(config, "n")
 	result := ""
 	for _, char := range plaintext {
 		encrypted := modPow(int64(char), e, n)
@@ -638,8 +700,8 @@ func (r *RSASimpleCipher) Encrypt(plaintext string, config map[string]interface{
 	return strings.TrimSpace(result), nil
 }
 func (r *RSASimpleCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
-	d := int64(config["d"].(float64))
-	n := int64(config["n"].(float64))
+	d := configInt64(config, "d")
+	n := configInt64(config, "n")
 	parts := strings.Split(ciphertext, " ")
 	result := ""
 	for _, part := range parts {
@@ -650,7 +712,7 @@ func (r *RSASimpleCipher) Decrypt(ciphertext string, config map[string]interface
 	}
 	return result, nil
 }
-func (r *RSASimpleCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (r *RSASimpleCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	// Simple RSA with small primes for demonstration
 	p, q := int64(61), int64(53)
 	n := p * q
@@ -664,17 +726,37 @@ func (r *RSASimpleCipher) GenerateKey(difficulty int) map[string]interface{} {
 // HELPER FUNCTIONS
 // ============================================================================
 
-func randInt(max int) int {
-	n, _ := rand.Int(rand.Reader, big.NewInt(int64(max)))
-	return int(n.Int64())
+func randInt(rng *rand.Rand, max int) int {
+	return rng.Intn(max)
+}
+
+// configInt64 reads a numeric config value. Keys fresh from GenerateKey hold
+// ints, while configs that went through JSON (DB, cache) hold float64s.
+func configInt64(config map[string]interface{}, key string) int64 {
+	switch v := config[key].(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+func configInt(config map[string]interface{}, key string) int {
+	return int(configInt64(config, key))
+}
+
+// ConfigInt reads a numeric config value for callers outside this package
+func ConfigInt(config map[string]interface{}, key string) int {
+	return configInt(config, key)
 }
 
-func shuffleString(s string) string {
+func shuffleString(rng *rand.Rand, s string) string {
 	runes := []rune(s)
-	for i := range runes {
-		j := randInt(len(runes))
-		runes[i], runes[j] = runes[j], runes[i]
-	}
+	rng.Shuffle(len(runes), func(i, j int) { runes[i], runes[j] = runes[j], runes[i] })
 	return string(runes)
 }
 
@@ -722,63 +804,42 @@ func modInverse(a, m int64) int64 {
 // ============================================================================
 // 16. AFFINE CIPHER (V2.0)
 // ============================================================================
-// Affine cipher uses: E(x) = (ax + b) mod 26
-// where 'a' must be coprime with 26, 'b' is the shift
+// Affine cipher uses: E(x) = (ax + b) mod m over an alphabet of m letters
+// where 'a' must be coprime with m, 'b' is the shift
 
 type AffineCipher struct{}
 
 func (a *AffineCipher) Name() string { return TypeAffine }
 
 func (a *AffineCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
-	keyA := int(config["a"].(float64))
-	keyB := int(config["b"].(float64))
+	keyA := configInt(config, "a")
+	keyB := configInt(config, "b")
 
-	result := ""
-	for _, char := range plaintext {
-		if char >= 'A' && char <= 'Z' {
-			x := int(char - 'A')
-			encrypted := (keyA*x + keyB) % 26
-			result += string(rune(encrypted + 'A'))
-		} else if char >= 'a' && char <= 'z' {
-			x := int(char - 'a')
-			encrypted := (keyA*x + keyB) % 26
-			result += string(rune(encrypted + 'a'))
-		} else {
-			result += string(char)
-		}
-	}
-	return result, nil
+	alphabet := AlphabetOf(config)
+	return alphabet.transform(plaintext, func(x int) int { return keyA*x + keyB }), nil
 }
 
 func (a *AffineCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
-	keyA := int(config["a"].(float64))
-	keyB := int(config["b"].(float64))
+	keyA := configInt(config, "a")
+	keyB := configInt(config, "b")
 
-	// Find multiplicative inverse of a mod 26
-	aInverse := int(modInverse(int64(keyA), 26))
+	// Find multiplicative inverse of a mod m
+	alphabet := AlphabetOf(config)
+	aInverse := int(modInverse(int64(keyA), int64(alphabet.Size())))
 
-	result := ""
-	for _, char := range ciphertext {
-		if char >= 'A' && char <= 'Z' {
-			y := int(char - 'A')
-			decrypted := (aInverse * (y - keyB + 26)) % 26
-			result += string(rune(decrypted + 'A'))
-		} else if char >= 'a' && char <= 'z' {
-			y := int(char - 'a')
-			decrypted := (aInverse * (y - keyB + 26)) % 26
-			result += string(rune(decrypted + 'a'))
-		} else {
-			result += string(char)
-		}
-	}
-	return result, nil
+	return alphabet.transform(ciphertext, func(y int) int { return aInverse * (y - keyB) }), nil
 }
 
-func (a *AffineCipher) GenerateKey(difficulty int) map[string]interface{} {
-	// Valid values for 'a' that are coprime with 26: 1, 3, 5, 7, 9, 11, 15, 17, 19, 21, 23, 25
-	validA := []int{3, 5, 7, 9, 11, 15, 17, 19, 21, 23, 25}
+func (a *AffineCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return a.GenerateLocalizedKey(difficulty, Latin, rng)
+}
+
+func (a *AffineCipher) GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{} {
+	// Valid values for 'a' are coprime with the alphabet size; for A-Z
+	// that's 3, 5, 7, 9, 11, 15, 17, 19, 21, 23, 25 (1 would be a Caesar shift)
+	validA := affineMultipliers(alphabet.Size())
 	keyA := validA[difficulty%len(validA)]
-	keyB := (difficulty * 5) % 26
+	keyB := (difficulty * 5) % alphabet.Size()
 
 	return map[string]interface{}{
 		"a": keyA,
@@ -786,6 +847,23 @@ func (a *AffineCipher) GenerateKey(difficulty int) map[string]interface{} {
 	}
 }
 
+func affineMultipliers(size int) []int {
+	var valid []int
+	for a := 2; a < size; a++ {
+		if gcd(a, size) == 1 {
+			valid = append(valid, a)
+		}
+	}
+	return valid
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
 // ============================================================================
 // 17. AUTOKEY CIPHER (V2.0)
 // ============================================================================
@@ -796,76 +874,46 @@ type AutokeyCipher struct{}
 func (a *AutokeyCipher) Name() string { return TypeAutokey }
 
 func (a *AutokeyCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
-	primer := strings.ToUpper(config["primer"].(string))
-
-	result := ""
-	keystream := primer
-	keyIndex := 0
-
-	for _, char := range plaintext {
-		if char >= 'A' && char <= 'Z' {
-			if keyIndex >= len(keystream) {
-				keystream += string(char)
-			}
-			shift := int(keystream[keyIndex] - 'A')
-			encrypted := (int(char-'A') + shift) % 26
-			result += string(rune(encrypted + 'A'))
-			keyIndex++
-		} else if char >= 'a' && char <= 'z' {
-			upperChar := char - 'a' + 'A'
-			if keyIndex >= len(keystream) {
-				keystream += string(upperChar)
-			}
-			shift := int(keystream[keyIndex] - 'A')
-			encrypted := (int(upperChar-'A') + shift) % 26
-			result += string(rune(encrypted + 'a'))
-			keyIndex++
-		} else {
-			result += string(char)
-		}
-	}
-	return result, nil
+	primer := config["primer"].(string)
+	return autokeyProcess(plaintext, primer, true, AlphabetOf(config)), nil
 }
 
 func (a *AutokeyCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
-	primer := strings.ToUpper(config["primer"].(string))
-
-	result := ""
-	keystream := primer
-	keyIndex := 0
+	primer := config["primer"].(string)
+	return autokeyProcess(ciphertext, primer, false, AlphabetOf(config)), nil
+}
 
-	for _, char := range ciphertext {
-		if char >= 'A' && char <= 'Z' {
-			shift := int(keystream[keyIndex] - 'A')
-			decrypted := (int(char-'A') - shift + 26) % 26
-			plainChar := rune(decrypted + 'A')
-			result += string(plainChar)
-			keystream += string(plainChar)
-			keyIndex++
-		} else if char >= 'a' && char <= 'z' {
-			shift := int(keystream[keyIndex] - 'A')
-			decrypted := (int(char-'a') - shift + 26) % 26
-			plainChar := rune(decrypted + 'a')
-			result += string(plainChar)
-			keystream += string(rune(decrypted + 'A'))
-			keyIndex++
-		} else {
-			result += string(char)
-		}
-	}
-	return result, nil
+func (a *AutokeyCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return a.GenerateLocalizedKey(difficulty, Latin, rng)
 }
 
-func (a *AutokeyCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (a *AutokeyCipher) GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{} {
 	primerLength := 3 + (difficulty / 3)
-	primer := ""
-	for i := 0; i < primerLength; i++ {
-		primer += string(rune('A' + randInt(26)))
+	return map[string]interface{}{
+		"primer": alphabet.randomKey(rng, primerLength),
 	}
+}
 
-	return map[string]interface{}{
-		"primer": primer,
+// autokeyProcess shifts each letter by the keystream: the primer followed by
+// the plaintext itself
+func autokeyProcess(text, primer string, encrypt bool, alphabet *Alphabet) string {
+	keystream := keyIndices(primer, alphabet)
+	if len(keystream) == 0 {
+		return text
 	}
+
+	keyIndex := 0
+	return alphabet.transform(text, func(index int) int {
+		shift := keystream[keyIndex]
+		keyIndex++
+		if encrypt {
+			keystream = append(keystream, index)
+			return index + shift
+		}
+		plain := mod(index-shift, alphabet.Size())
+		keystream = append(keystream, plain)
+		return plain
+	})
 }
 
 // ============================================================================
@@ -883,9 +931,9 @@ func (e *EnigmaLiteCipher) Encrypt(plaintext string, config map[string]interface
 	rotor3 := config["rotor3"].(string)
 	reflector := config["reflector"].(string)
 
-	pos1 := int(config["pos1"].(float64))
-	pos2 := int(config["pos2"].(float64))
-	pos3 := int(config["pos3"].(float64))
+	pos1 := configInt(config, "pos1")
+	pos2 := configInt(config, "pos2")
+	pos3 := configInt(config, "pos3")
 
 	result := ""
 
@@ -910,7 +958,7 @@ func (e *EnigmaLiteCipher) Decrypt(ciphertext string, config map[string]interfac
 	return e.Encrypt(ciphertext, config)
 }
 
-func (e *EnigmaLiteCipher) GenerateKey(difficulty int) map[string]interface{} {
+func (e *EnigmaLiteCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
 	// Predefined rotor wirings (simplified)
 	rotorWirings := []string{
 		"EKMFLGDQVZNTOWYHXUSPAIBRCJ",
@@ -0,0 +1,201 @@
+package ciphers
+
+import (
+	"math/rand"
+	"strings"
+	"unicode"
+)
+
+// DefaultLanguage is the language of keys without an "alphabet" entry
+const DefaultLanguage = "en"
+
+// Alphabet is the ordered set of letters a language's ciphers work over.
+// Letters are stored uppercase; lowercase input keeps its case.
+type Alphabet struct {
+	Language string
+	Common   rune // Most frequent letter of the language
+	Filler   rune // Pads the last Playfair digraph
+
+	letters []rune
+	index   map[rune]int
+	fold    map[rune]string // Characters rewritten before use, e.g. accents
+}
+
+func newAlphabet(language, letters string, common, filler rune, fold map[rune]string) *Alphabet {
+	a := &Alphabet{
+		Language: language,
+		Common:   common,
+		Filler:   filler,
+		letters:  []rune(letters),
+		index:    make(map[rune]int),
+		fold:     fold,
+	}
+	for i, letter := range a.letters {
+		a.index[letter] = i
+	}
+	return a
+}
+
+const latinLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Supported alphabets. Spanish and German add their own letters; French
+// folds its accents onto the Latin alphabet, as is usual in cryptograms.
+var (
+	Latin = newAlphabet("en", latinLetters, 'E', 'X', nil)
+
+	Spanish = newAlphabet("es", "ABCDEFGHIJKLMNÑOPQRSTUVWXYZ", 'E', 'X', map[rune]string{
+		'Á': "A", 'É': "E", 'Í': "I", 'Ó': "O", 'Ú': "U", 'Ü': "U",
+	})
+
+	German = newAlphabet("de", latinLetters+"ÄÖÜ", 'E', 'X', map[rune]string{
+		'ß': "SS", 'ẞ': "SS",
+	})
+
+	French = newAlphabet("fr", latinLetters, 'E', 'X', map[rune]string{
+		'À': "A", 'Â': "A", 'Æ': "AE", 'Ç': "C", 'É': "E", 'È': "E", 'Ê': "E", 'Ë': "E",
+		'Î': "I", 'Ï': "I", 'Ô': "O", 'Œ': "OE", 'Ù': "U", 'Û': "U", 'Ü': "U", 'Ÿ': "Y",
+	})
+
+	Cyrillic = newAlphabet("ru", "АБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯ", 'О', 'Х', nil)
+)
+
+var alphabets = map[string]*Alphabet{
+	Latin.Language:    Latin,
+	Spanish.Language:  Spanish,
+	German.Language:   German,
+	French.Language:   French,
+	Cyrillic.Language: Cyrillic,
+}
+
+// AlphabetFor returns the alphabet of a language; empty means DefaultLanguage.
+// Unsupported languages report false and get the Latin alphabet.
+func AlphabetFor(language string) (*Alphabet, bool) {
+	if language == "" {
+		return Latin, true
+	}
+	alphabet, ok := alphabets[language]
+	if !ok {
+		return Latin, false
+	}
+	return alphabet, true
+}
+
+// AlphabetOf returns the alphabet a key was generated for
+func AlphabetOf(config map[string]interface{}) *Alphabet {
+	language, _ := config["alphabet"].(string)
+	alphabet, _ := AlphabetFor(language)
+	return alphabet
+}
+
+// Size is the number of letters
+func (a *Alphabet) Size() int { return len(a.letters) }
+
+// Letter returns the uppercase letter at index i
+func (a *Alphabet) Letter(i int) rune { return a.letters[i] }
+
+// Letters returns the whole alphabet in order
+func (a *Alphabet) Letters() string { return string(a.letters) }
+
+// Index returns the position of a letter in either case
+func (a *Alphabet) Index(char rune) (int, bool) {
+	i, ok := a.index[unicode.ToUpper(char)]
+	return i, ok
+}
+
+// Contains reports whether char is a letter of the alphabet in either case
+func (a *Alphabet) Contains(char rune) bool {
+	_, ok := a.Index(char)
+	return ok
+}
+
+// Fold rewrites the characters the alphabet has no letter for (ß, accents)
+// into ones it has, leaving everything else alone
+func (a *Alphabet) Fold(text string) string {
+	if len(a.fold) == 0 {
+		return text
+	}
+	var b strings.Builder
+	for _, char := range text {
+		if folded, ok := a.fold[unicode.ToUpper(char)]; ok {
+			if unicode.IsLower(char) {
+				folded = strings.ToLower(folded)
+			}
+			b.WriteString(folded)
+		} else {
+			b.WriteRune(char)
+		}
+	}
+	return b.String()
+}
+
+// transform replaces every letter of text by the letter at the index f
+// returns, keeping its case. Other characters pass through unchanged.
+func (a *Alphabet) transform(text string, f func(index int) int) string {
+	var b strings.Builder
+	for _, char := range a.Fold(text) {
+		i, ok := a.Index(char)
+		if !ok {
+			b.WriteRune(char)
+			continue
+		}
+		out := a.letters[mod(f(i), len(a.letters))]
+		if unicode.IsLower(char) {
+			out = unicode.ToLower(out)
+		}
+		b.WriteRune(out)
+	}
+	return b.String()
+}
+
+// randomKey draws length random letters of the alphabet
+func (a *Alphabet) randomKey(rng *rand.Rand, length int) string {
+	key := make([]rune, length)
+	for i := range key {
+		key[i] = a.letters[randInt(rng, len(a.letters))]
+	}
+	return string(key)
+}
+
+func mod(a, m int) int {
+	return ((a % m) + m) % m
+}
+
+// Localized ciphers work over the letters of an alphabet and can generate
+// keys for any supported language
+type Localized interface {
+	Cipher
+	GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{}
+}
+
+// latinOnly ciphers depend on tables or machinery that only exist for A-Z
+var latinOnly = map[string]bool{
+	TypeMorse:      true,
+	TypeBookCipher: true,
+	TypeEnigmaLite: true,
+}
+
+// SupportsLanguage reports whether a cipher type can encrypt texts of a
+// language. Ciphers that aren't Localized treat text as opaque characters
+// and work in any language.
+func SupportsLanguage(cipherType, language string) bool {
+	alphabet, ok := AlphabetFor(language)
+	if !ok {
+		return false
+	}
+	return alphabet == Latin || !latinOnly[cipherType]
+}
+
+// GenerateKeyFor generates a key for texts in a language. Non-default keys
+// record their alphabet under "alphabet"; default ones are exactly what
+// GenerateKey returns, so seeded English puzzles are unaffected.
+func GenerateKeyFor(cipher Cipher, difficulty int, language string, rng *rand.Rand) map[string]interface{} {
+	alphabet, _ := AlphabetFor(language)
+	localized, ok := cipher.(Localized)
+	if alphabet == Latin || !ok {
+		return cipher.GenerateKey(difficulty, rng)
+	}
+
+	config := localized.GenerateLocalizedKey(difficulty, alphabet, rng)
+	config["alphabet"] = alphabet.Language
+	return config
+}
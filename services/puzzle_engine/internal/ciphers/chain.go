@@ -0,0 +1,223 @@
+package ciphers
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// ============================================================================
+// 19. CHAINED CIPHER (multi-layer)
+// ============================================================================
+// Stacks 2-4 ciphers on top of each other, e.g. Vigenère -> Rail Fence -> Base64.
+// Layers are stored in application order: layer 1 is applied to the plaintext
+// first, the last layer produces the ciphertext the player sees.
+
+const (
+	MinChainLayers = 2
+	MaxChainLayers = 4
+)
+
+// ChainLayer describes a single cipher applied within a chain
+type ChainLayer struct {
+	CipherType string                 `json:"cipher_type"`
+	Config     map[string]interface{} `json:"config"`
+}
+
+// ChainStep is the text before and after one layer while unwinding a chain
+type ChainStep struct {
+	Layer      int    `json:"layer"` // Position in application order (1 = innermost)
+	CipherType string `json:"cipher_type"`
+	Input      string `json:"input"`
+	Output     string `json:"output"`
+}
+
+// chainLayerCosts is how much of the difficulty budget each layer consumes.
+// Only ciphers that round-trip arbitrary text without loss are allowed.
+var chainLayerCosts = map[string]int{
+	TypeCaesar:       1,
+	TypeROT13:        1,
+	TypeAtbash:       1,
+	TypeAffine:       2,
+	TypeRailFence:    2,
+	TypeVigenere:     3,
+	TypeAutokey:      3,
+	TypeSubstitution: 4,
+	TypeEnigmaLite:   4,
+}
+
+// chainEncodings produce non-alphabetic output, so they may only be used as
+// the outermost layer
+var chainEncodings = map[string]int{
+	TypeBase64:      1,
+	TypeHexadecimal: 1,
+}
+
+type ChainCipher struct{}
+
+func (c *ChainCipher) Name() string { return TypeChain }
+
+func (c *ChainCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
+	layers, err := ParseChainLayers(config)
+	if err != nil {
+		return "", err
+	}
+
+	// Fold once up front: a layer folding ß into SS mid-chain would shift the
+	// positions an earlier transposition layer relies on
+	text := AlphabetOf(config).Fold(plaintext)
+	for i, layer := range layers {
+		text, err = GetCipher(layer.CipherType).Encrypt(text, layer.Config)
+		if err != nil {
+			return "", fmt.Errorf("layer %d (%s): %w", i+1, layer.CipherType, err)
+		}
+	}
+	return text, nil
+}
+
+func (c *ChainCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
+	steps, err := UnwindChain(ciphertext, config)
+	if err != nil {
+		return "", err
+	}
+	return steps[len(steps)-1].Output, nil
+}
+
+func (c *ChainCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return c.GenerateLocalizedKey(difficulty, Latin, rng)
+}
+
+// GenerateLocalizedKey builds every layer for the alphabet, so each layer's
+// config records it like a standalone key would
+func (c *ChainCipher) GenerateLocalizedKey(difficulty int, alphabet *Alphabet, rng *rand.Rand) map[string]interface{} {
+	layerCount := ChainLayerCount(difficulty)
+	budget := difficulty + layerCount
+
+	layers := make([]interface{}, 0, layerCount)
+	previous := ""
+	for i := 0; i < layerCount; i++ {
+		// Reserve at least one point for each layer still to be chosen
+		remaining := layerCount - i - 1
+		cipherType := pickChainLayer(rng, budget-remaining, previous, remaining == 0, alphabet)
+		budget -= chainLayerCost(cipherType)
+
+		layers = append(layers, map[string]interface{}{
+			"cipher_type": cipherType,
+			"config":      GenerateKeyFor(GetCipher(cipherType), difficulty, alphabet.Language, rng),
+		})
+		previous = cipherType
+	}
+
+	return map[string]interface{}{"layers": layers}
+}
+
+// ChainLayerCount returns how many layers a chain of the given difficulty uses
+func ChainLayerCount(difficulty int) int {
+	count := MinChainLayers + difficulty/4
+	if count > MaxChainLayers {
+		count = MaxChainLayers
+	}
+	return count
+}
+
+// ParseChainLayers reads the layer list from a chain config. It accepts both
+// freshly generated configs and configs decoded from JSON.
+func ParseChainLayers(config map[string]interface{}) ([]ChainLayer, error) {
+	var raw []interface{}
+	switch v := config["layers"].(type) {
+	case []interface{}:
+		raw = v
+	case []map[string]interface{}:
+		for _, layer := range v {
+			raw = append(raw, layer)
+		}
+	default:
+		return nil, fmt.Errorf("chain config has no layers")
+	}
+
+	if len(raw) < MinChainLayers || len(raw) > MaxChainLayers {
+		return nil, fmt.Errorf("chain must have between %d and %d layers, got %d", MinChainLayers, MaxChainLayers, len(raw))
+	}
+
+	layers := make([]ChainLayer, 0, len(raw))
+	for i, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("layer %d is malformed", i+1)
+		}
+
+		cipherType, _ := entry["cipher_type"].(string)
+		if chainLayerCost(cipherType) == 0 {
+			return nil, fmt.Errorf("layer %d: cipher %q cannot be chained", i+1, cipherType)
+		}
+		if _, isEncoding := chainEncodings[cipherType]; isEncoding && i != len(raw)-1 {
+			return nil, fmt.Errorf("layer %d: %s may only be the outermost layer", i+1, cipherType)
+		}
+
+		layerConfig, _ := entry["config"].(map[string]interface{})
+		if layerConfig == nil {
+			layerConfig = map[string]interface{}{}
+		}
+		layers = append(layers, ChainLayer{CipherType: cipherType, Config: layerConfig})
+	}
+	return layers, nil
+}
+
+// UnwindChain peels a chain one layer at a time, outermost first. The last
+// step's output is the plaintext.
+func UnwindChain(ciphertext string, config map[string]interface{}) ([]ChainStep, error) {
+	layers, err := ParseChainLayers(config)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]ChainStep, 0, len(layers))
+	text := ciphertext
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := layers[i]
+		output, err := GetCipher(layer.CipherType).Decrypt(text, layer.Config)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d (%s): %w", i+1, layer.CipherType, err)
+		}
+		steps = append(steps, ChainStep{
+			Layer:      i + 1,
+			CipherType: layer.CipherType,
+			Input:      text,
+			Output:     output,
+		})
+		text = output
+	}
+	return steps, nil
+}
+
+func chainLayerCost(cipherType string) int {
+	if cost, ok := chainLayerCosts[cipherType]; ok {
+		return cost
+	}
+	return chainEncodings[cipherType]
+}
+
+// pickChainLayer chooses a random layer that fits the budget and differs from
+// the previous layer (two Caesar shifts in a row are just one Caesar shift)
+func pickChainLayer(rng *rand.Rand, maxCost int, previous string, outermost bool, alphabet *Alphabet) string {
+	candidates := []string{}
+	for _, cipherType := range GetAllCipherTypes() {
+		if !SupportsLanguage(cipherType, alphabet.Language) {
+			continue
+		}
+		cost, ok := chainLayerCosts[cipherType]
+		if !ok && outermost {
+			cost, ok = chainEncodings[cipherType]
+		}
+		if ok && cost <= maxCost && cipherType != previous {
+			candidates = append(candidates, cipherType)
+		}
+	}
+
+	if len(candidates) == 0 {
+		if previous == TypeCaesar {
+			return TypeAtbash
+		}
+		return TypeCaesar
+	}
+	return candidates[randInt(rng, len(candidates))]
+}
@@ -1,10 +1,14 @@
 package ciphers
 
+import "math/rand"
+
 // Cipher represents a cipher algorithm interface
 type Cipher interface {
 	Encrypt(plaintext string, config map[string]interface{}) (string, error)
 	Decrypt(ciphertext string, config map[string]interface{}) (string, error)
-	GenerateKey(difficulty int) map[string]interface{}
+	// GenerateKey draws all key material from rng so a seeded generator
+	// reproduces the same key
+	GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{}
 	Name() string
 }
 
@@ -29,6 +33,8 @@ const (
 	TypeAffine       = "AFFINE"
 	TypeAutokey      = "AUTOKEY"
 	TypeEnigmaLite   = "ENIGMA_LITE"
+	// Composite
+	TypeChain        = "CHAIN"
 )
 
 // GetCipher returns a cipher by type
@@ -70,6 +76,14 @@ func GetCipher(cipherType string) Cipher {
 		return &AutokeyCipher{}
 	case TypeEnigmaLite:
 		return &EnigmaLiteCipher{}
+	case TypeSPN:
+		return &SPNCipher{}
+	case TypeLFSR:
+		return &LFSRCipher{}
+	case TypeRSATextbook:
+		return &RSATextbookCipher{}
+	case TypeChain:
+		return &ChainCipher{}
 	default:
 		return nil
 	}
@@ -0,0 +1,130 @@
+package ciphers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bounds on player-supplied key values, keeping keys small enough that no
+// cipher does unreasonable work with them
+const (
+	maxCustomKeyLength = 64
+	maxCustomKeyNumber = 1000
+)
+
+// customKeyField is one field of a player-chosen key
+type customKeyField struct {
+	name    string
+	numeric bool // Whole number; otherwise a string of letters
+}
+
+// customKeyFields lists, for the ciphers whose key a player may choose, the
+// config fields that make up the key. Other ciphers always get a generated key.
+var customKeyFields = map[string][]customKeyField{
+	TypeCaesar:        {{name: "shift", numeric: true}},
+	TypeVigenere:      {{name: "key"}},
+	TypeRailFence:     {{name: "rails", numeric: true}},
+	TypePlayfair:      {{name: "key"}},
+	TypeSubstitution:  {{name: "key"}},
+	TypeTransposition: {{name: "key"}},
+	TypeAffine:        {{name: "a", numeric: true}, {name: "b", numeric: true}},
+	TypeAutokey:       {{name: "primer"}},
+}
+
+// SupportsCustomKey reports whether players may choose the key of a cipher
+func SupportsCustomKey(cipherType string) bool {
+	_, ok := customKeyFields[cipherType]
+	return ok
+}
+
+// CustomKey turns a player-supplied key into a cipher config for texts in a
+// language. Only the cipher's own key fields are kept, each a string or a
+// whole number of bounded size. Whether the key actually works is left to
+// CheckRoundTrip.
+func CustomKey(cipherType, language string, key map[string]interface{}) (map[string]interface{}, error) {
+	fields, ok := customKeyFields[cipherType]
+	if !ok {
+		return nil, fmt.Errorf("%s does not accept a custom key", cipherType)
+	}
+	alphabet, ok := AlphabetFor(language)
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", language)
+	}
+
+	config := make(map[string]interface{}, len(fields)+1)
+	for _, field := range fields {
+		value, ok := key[field.name]
+		if !ok {
+			return nil, fmt.Errorf("key field %q is required", field.name)
+		}
+
+		if field.numeric {
+			number, ok := value.(float64)
+			if !ok || number != math.Trunc(number) || math.Abs(number) > maxCustomKeyNumber {
+				return nil, fmt.Errorf("key field %q must be a whole number up to %d", field.name, maxCustomKeyNumber)
+			}
+			config[field.name] = int(number)
+			continue
+		}
+
+		text, ok := value.(string)
+		if !ok || text == "" || utf8.RuneCountInString(text) > maxCustomKeyLength {
+			return nil, fmt.Errorf("key field %q must be 1 to %d characters", field.name, maxCustomKeyLength)
+		}
+		config[field.name] = strings.ToUpper(text)
+	}
+
+	if alphabet != Latin {
+		config["alphabet"] = alphabet.Language
+	}
+	return config, nil
+}
+
+// CheckRoundTrip encrypts a plaintext and makes sure decrypting the result
+// gives it back, returning the ciphertext. It is meant for keys that didn't
+// come from GenerateKey, so a key the cipher chokes on is reported as an
+// error rather than a panic.
+func CheckRoundTrip(cipher Cipher, plaintext string, config map[string]interface{}) (ciphertext string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			ciphertext, err = "", fmt.Errorf("%s cannot use this key: %v", cipher.Name(), r)
+		}
+	}()
+
+	ciphertext, err = cipher.Encrypt(plaintext, config)
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := cipher.Decrypt(ciphertext, config)
+	if err != nil {
+		return "", err
+	}
+
+	if !roundTripMatches(cipher.Name(), plaintext, decrypted, AlphabetOf(config)) {
+		return "", fmt.Errorf("%s does not decrypt back to the plaintext with this key", cipher.Name())
+	}
+	return ciphertext, nil
+}
+
+// roundTripMatches compares a decryption with its plaintext. Playfair drops
+// spaces, merges J into I and pads odd lengths with the filler letter, so
+// its output is compared against the plaintext as the square sees it.
+func roundTripMatches(cipherType, plaintext, decrypted string, alphabet *Alphabet) bool {
+	if cipherType != TypePlayfair {
+		return decrypted == plaintext
+	}
+
+	grid := buildPlayfairGrid("", alphabet)
+	var expected []rune
+	for _, char := range grid.normalize(plaintext) {
+		if _, ok := grid.pos[char]; ok {
+			expected = append(expected, char)
+		}
+	}
+	if len(expected)%2 == 1 {
+		expected = append(expected, alphabet.Filler)
+	}
+	return decrypted == string(expected)
+}
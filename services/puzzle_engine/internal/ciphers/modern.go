@@ -0,0 +1,248 @@
+package ciphers
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/swarit-1/cipher-clash/pkg/toycrypto"
+)
+
+// Educational cipher types: scaled-down modern ciphers. Their output is bytes,
+// written as hex or base64, so they are never picked at random or used as
+// chain layers; a puzzle only uses one when asked for it by name.
+const (
+	TypeSPN         = "SPN_BLOCK"
+	TypeLFSR        = "LFSR_STREAM"
+	TypeRSATextbook = "RSA_TEXTBOOK"
+)
+
+// GetEducationalCipherTypes returns the modern cipher types available on request
+func GetEducationalCipherTypes() []string {
+	return []string{TypeSPN, TypeLFSR, TypeRSATextbook}
+}
+
+// spnCBCDifficulty is the lowest difficulty using CBC mode; below it, ECB
+// leaves repeated blocks visible
+const spnCBCDifficulty = 6
+
+// ============================================================================
+// SPN BLOCK CIPHER
+// ============================================================================
+
+type SPNCipher struct{}
+
+func (s *SPNCipher) Name() string { return TypeSPN }
+
+func (s *SPNCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
+	spn, mode, iv, err := spnFromConfig(config)
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := spn.Encrypt([]byte(plaintext), mode, iv)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(encrypted)), nil
+}
+
+func (s *SPNCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
+	spn, mode, iv, err := spnFromConfig(config)
+	if err != nil {
+		return "", err
+	}
+	data, err := hex.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := spn.Decrypt(data, mode, iv)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+func (s *SPNCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	config := map[string]interface{}{
+		"key":  fmt.Sprintf("%08X", rng.Uint32()),
+		"mode": toycrypto.ModeECB,
+	}
+	if difficulty >= spnCBCDifficulty {
+		config["mode"] = toycrypto.ModeCBC
+		config["iv"] = fmt.Sprintf("%04X", rng.Intn(1<<16))
+	}
+	return config
+}
+
+func spnFromConfig(config map[string]interface{}) (*toycrypto.SPN, string, uint16, error) {
+	key, err := configHex(config, "key", 32)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	mode, _ := config["mode"].(string)
+	if mode == "" {
+		mode = toycrypto.ModeECB
+	}
+	var iv uint64
+	if mode == toycrypto.ModeCBC {
+		if iv, err = configHex(config, "iv", 16); err != nil {
+			return nil, "", 0, err
+		}
+	}
+	return toycrypto.NewSPN(uint32(key)), mode, uint16(iv), nil
+}
+
+// ============================================================================
+// LFSR STREAM CIPHER
+// ============================================================================
+
+type LFSRCipher struct{}
+
+func (l *LFSRCipher) Name() string { return TypeLFSR }
+
+func (l *LFSRCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
+	lfsr, err := lfsrFromConfig(config)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(lfsr.XORKeyStream([]byte(plaintext))), nil
+}
+
+func (l *LFSRCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
+	lfsr, err := lfsrFromConfig(config)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(lfsr.XORKeyStream(data)), nil
+}
+
+func (l *LFSRCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	return map[string]interface{}{
+		"seed": fmt.Sprintf("%04X", 1+rng.Intn(1<<16-1)),
+		"taps": fmt.Sprintf("%04X", toycrypto.DefaultLFSRTaps),
+	}
+}
+
+func lfsrFromConfig(config map[string]interface{}) (*toycrypto.LFSR, error) {
+	seed, err := configHex(config, "seed", 16)
+	if err != nil {
+		return nil, err
+	}
+	var taps uint64
+	if _, ok := config["taps"]; ok {
+		if taps, err = configHex(config, "taps", 16); err != nil {
+			return nil, err
+		}
+	}
+	return toycrypto.NewLFSR(uint16(seed), uint16(taps))
+}
+
+// ============================================================================
+// TEXTBOOK RSA
+// ============================================================================
+
+type RSATextbookCipher struct{}
+
+func (r *RSATextbookCipher) Name() string { return TypeRSATextbook }
+
+func (r *RSATextbookCipher) Encrypt(plaintext string, config map[string]interface{}) (string, error) {
+	key, err := rsaFromConfig(config)
+	if err != nil {
+		return "", err
+	}
+	return key.EncodeBlocks(key.Encrypt([]byte(plaintext))), nil
+}
+
+func (r *RSATextbookCipher) Decrypt(ciphertext string, config map[string]interface{}) (string, error) {
+	key, err := rsaFromConfig(config)
+	if err != nil {
+		return "", err
+	}
+	blocks, err := toycrypto.DecodeBlocks(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	decrypted, err := key.Decrypt(blocks)
+	if err != nil {
+		return "", err
+	}
+	return string(decrypted), nil
+}
+
+// GenerateKey builds a modulus of 20 to 56 bits. Every size stays within
+// reach of factoring, which is the only way to solve the puzzle: players get
+// the public key and must recover the private one.
+func (r *RSATextbookCipher) GenerateKey(difficulty int, rng *rand.Rand) map[string]interface{} {
+	bits := rsaTextbookBits(difficulty)
+	key, err := toycrypto.GenerateRSAKey(rng, bits)
+	if err != nil {
+		panic(err) // rsaTextbookBits is always a valid size
+	}
+	return map[string]interface{}{
+		"n":    key.N.String(),
+		"e":    int(key.E.Int64()),
+		"d":    key.D.String(),
+		"p":    key.P.String(),
+		"q":    key.Q.String(),
+		"bits": bits,
+	}
+}
+
+func rsaTextbookBits(difficulty int) int {
+	if difficulty < 1 {
+		difficulty = 1
+	}
+	if difficulty > 10 {
+		difficulty = 10
+	}
+	return 16 + 4*difficulty
+}
+
+// PublicKeyHint states the public half of a textbook RSA key, which players
+// need before they can start factoring
+func PublicKeyHint(config map[string]interface{}) string {
+	return fmt.Sprintf("Public key: n = %s, e = %d. Factor n to work out the private exponent d.",
+		configDecimal(config, "n"), configInt(config, "e"))
+}
+
+func rsaFromConfig(config map[string]interface{}) (*toycrypto.RSAKey, error) {
+	n, ok := new(big.Int).SetString(configDecimal(config, "n"), 10)
+	if !ok || n.BitLen() < toycrypto.MinRSABits {
+		return nil, fmt.Errorf("invalid RSA modulus")
+	}
+	d, ok := new(big.Int).SetString(configDecimal(config, "d"), 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid RSA private exponent")
+	}
+	e := big.NewInt(configInt64(config, "e"))
+	return toycrypto.NewRSAPrivateKey(n, e, d), nil
+}
+
+// ============================================================================
+// HELPER FUNCTIONS
+// ============================================================================
+
+// configHex reads an unsigned value stored as a hex string
+func configHex(config map[string]interface{}, key string, bits int) (uint64, error) {
+	value, _ := config[key].(string)
+	parsed, err := strconv.ParseUint(value, 16, bits)
+	if err != nil {
+		return 0, fmt.Errorf("%q must be a hex number of up to %d bits", key, bits)
+	}
+	return parsed, nil
+}
+
+// configDecimal reads a big number stored as a decimal string, so that it
+// survives JSON without losing precision
+func configDecimal(config map[string]interface{}, key string) string {
+	value, _ := config[key].(string)
+	return value
+}
@@ -0,0 +1,375 @@
+package corpus
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/ciphers"
+)
+
+// Rarity grades how unusual a text's vocabulary is
+type Rarity int
+
+const (
+	RarityCommon   Rarity = 1
+	RarityUncommon Rarity = 2
+	RarityRare     Rarity = 3
+)
+
+// DefaultLanguage is used when a request doesn't ask for a language
+const DefaultLanguage = ciphers.DefaultLanguage
+
+// Text is a single plaintext candidate with its selection tags
+type Text struct {
+	ID       string `json:"id"`
+	Content  string `json:"content"`
+	Language string `json:"language"`
+	Theme    string `json:"theme"`
+	Letters  int    `json:"letters"` // Number of letters of the language's alphabet
+	Rarity   Rarity `json:"rarity"`
+}
+
+// Tags returns the text's tags in the form stored on puzzles.tags
+func (t *Text) Tags() []string {
+	return []string{
+		"corpus:" + t.ID,
+		"lang:" + t.Language,
+		"theme:" + t.Theme,
+		fmt.Sprintf("rarity:%d", t.Rarity),
+		fmt.Sprintf("letters:%d", t.Letters),
+	}
+}
+
+// Source provides plaintexts to the corpus
+type Source interface {
+	Name() string
+	Load(ctx context.Context) ([]Text, error)
+}
+
+// Criteria describes what kind of text a puzzle needs
+type Criteria struct {
+	CipherType string
+	Difficulty int
+	Language   string          // Defaults to DefaultLanguage
+	Theme      string          // Optional
+	Exclude    map[string]bool // Text IDs the player has already seen
+	MinLetters int             // Optional, overrides the cipher's length band
+	MaxLetters int             // Optional, overrides the cipher's length band
+}
+
+// Corpus holds every loaded plaintext and picks texts for puzzles
+type Corpus struct {
+	mu      sync.RWMutex
+	texts   []Text
+	sources []Source
+	log     *logger.Logger
+}
+
+// New creates a corpus backed by the given sources. Call Reload to load them.
+func New(log *logger.Logger, sources ...Source) *Corpus {
+	return &Corpus{
+		sources: sources,
+		log:     log,
+	}
+}
+
+// Reload loads all sources, dropping duplicate texts. A failing source is
+// logged and skipped so one bad source can't take the engine down.
+func (c *Corpus) Reload(ctx context.Context) error {
+	seen := make(map[string]bool)
+	var texts []Text
+
+	for _, source := range c.sources {
+		loaded, err := source.Load(ctx)
+		if err != nil {
+			c.log.Warn("Failed to load corpus source", map[string]interface{}{
+				"source": source.Name(),
+				"error":  err.Error(),
+			})
+			continue
+		}
+
+		for _, text := range loaded {
+			key := text.Language + ":" + text.Content
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			texts = append(texts, text)
+		}
+
+		c.log.Info("Corpus source loaded", map[string]interface{}{
+			"source": source.Name(),
+			"texts":  len(loaded),
+		})
+	}
+
+	if len(texts) == 0 {
+		return fmt.Errorf("corpus is empty")
+	}
+
+	c.mu.Lock()
+	c.texts = texts
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Size returns the number of loaded texts
+func (c *Corpus) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.texts)
+}
+
+// Select picks a random text matching the criteria. Constraints are relaxed
+// one at a time (rarity, length, theme, exclusions) until something matches.
+// The same rng state over the same corpus always picks the same text.
+func (c *Corpus) Select(criteria Criteria, rng *rand.Rand) (*Text, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	language := criteria.Language
+	if language == "" {
+		language = DefaultLanguage
+	}
+
+	var pool []*Text
+	for i := range c.texts {
+		if c.texts[i].Language == language {
+			pool = append(pool, &c.texts[i])
+		}
+	}
+	if len(pool) == 0 {
+		return nil, fmt.Errorf("no texts available for language %q", language)
+	}
+
+	minLetters, maxLetters := LengthBand(criteria.CipherType, criteria.Difficulty)
+	if criteria.MaxLetters > 0 {
+		minLetters, maxLetters = criteria.MinLetters, criteria.MaxLetters
+	}
+	minRarity, maxRarity := rarityBand(criteria.Difficulty)
+
+	filters := []func(*Text) bool{
+		func(t *Text) bool { return !criteria.Exclude[t.ID] },
+		func(t *Text) bool { return criteria.Theme == "" || t.Theme == criteria.Theme },
+		func(t *Text) bool { return t.Letters >= minLetters && t.Letters <= maxLetters },
+		func(t *Text) bool { return t.Rarity >= minRarity && t.Rarity <= maxRarity },
+	}
+
+	for active := len(filters); active >= 0; active-- {
+		candidates := filterTexts(pool, filters[:active])
+		if len(candidates) > 0 {
+			return candidates[rng.Intn(len(candidates))], nil
+		}
+	}
+
+	return pool[rng.Intn(len(pool))], nil
+}
+
+// LengthBand returns the preferred letter count range for a cipher at a
+// difficulty. Transposition gets harder with longer texts, while frequency
+// analysis gets harder with shorter ones.
+func LengthBand(cipherType string, difficulty int) (int, int) {
+	switch cipherType {
+	case ciphers.TypeRailFence, ciphers.TypeTransposition:
+		min := 8 + difficulty*7
+		return min, min + 50
+	case ciphers.TypeCaesar, ciphers.TypeROT13, ciphers.TypeAtbash, ciphers.TypeAffine,
+		ciphers.TypeSubstitution, ciphers.TypeVigenere, ciphers.TypeAutokey,
+		ciphers.TypePlayfair, ciphers.TypeEnigmaLite:
+		max := 120 - difficulty*9
+		min := max - 55
+		if min < 12 {
+			min = 12
+		}
+		return min, max
+	default:
+		// Encodings and composite ciphers blow up the output size
+		return 12, 70
+	}
+}
+
+func rarityBand(difficulty int) (Rarity, Rarity) {
+	switch {
+	case difficulty <= 3:
+		return RarityCommon, RarityCommon
+	case difficulty <= 6:
+		return RarityCommon, RarityUncommon
+	default:
+		return RarityUncommon, RarityRare
+	}
+}
+
+func filterTexts(pool []*Text, filters []func(*Text) bool) []*Text {
+	var result []*Text
+	for _, text := range pool {
+		matches := true
+		for _, filter := range filters {
+			if !filter(text) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			result = append(result, text)
+		}
+	}
+	return result
+}
+
+// NewText normalizes raw content and computes its tags. An empty id derives
+// a stable one from the content.
+func NewText(id, content, language, theme string) Text {
+	alphabet, _ := ciphers.AlphabetFor(language)
+	content = normalizeContent(content, alphabet)
+	if id == "" {
+		sum := sha1.Sum([]byte(language + ":" + content))
+		id = hex.EncodeToString(sum[:8])
+	}
+
+	return Text{
+		ID:       id,
+		Content:  content,
+		Language: language,
+		Theme:    theme,
+		Letters:  countLetters(content, alphabet),
+		Rarity:   gradeRarity(content, language, alphabet),
+	}
+}
+
+// normalizeContent uppercases text, folds letters the alphabet lacks (accents,
+// ß) and keeps only its letters and single spaces
+func normalizeContent(content string, alphabet *ciphers.Alphabet) string {
+	var b strings.Builder
+	for _, word := range strings.Fields(strings.ToUpper(alphabet.Fold(content))) {
+		var w strings.Builder
+		for _, char := range word {
+			if alphabet.Contains(char) {
+				w.WriteRune(char)
+			}
+		}
+		if w.Len() == 0 {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(w.String())
+	}
+	return b.String()
+}
+
+func countLetters(content string, alphabet *ciphers.Alphabet) int {
+	count := 0
+	for _, char := range content {
+		if alphabet.Contains(char) {
+			count++
+		}
+	}
+	return count
+}
+
+// gradeRarity compares a text's vocabulary against the most common words of
+// its language, falling back to average word length for other languages
+func gradeRarity(content, language string, alphabet *ciphers.Alphabet) Rarity {
+	words := strings.Fields(content)
+	if len(words) == 0 {
+		return RarityCommon
+	}
+
+	if common, ok := commonWords[language]; ok {
+		uncommon := 0
+		for _, word := range words {
+			if !common[word] {
+				uncommon++
+			}
+		}
+		ratio := float64(uncommon) / float64(len(words))
+		switch {
+		case ratio < 0.45:
+			return RarityCommon
+		case ratio < 0.65:
+			return RarityUncommon
+		default:
+			return RarityRare
+		}
+	}
+
+	avgLength := float64(countLetters(content, alphabet)) / float64(len(words))
+	switch {
+	case avgLength < 5:
+		return RarityCommon
+	case avgLength < 6.5:
+		return RarityUncommon
+	default:
+		return RarityRare
+	}
+}
+
+// commonWords holds the most frequent words per language
+var commonWords = map[string]map[string]bool{
+	"en": wordSet(`THE OF AND TO A IN IS IT YOU THAT HE WAS FOR ON ARE WITH AS I HIS THEY BE AT ONE
+		HAVE THIS FROM OR HAD BY NOT WORD BUT WHAT SOME WE CAN OUT OTHER WERE ALL THERE WHEN UP USE
+		YOUR HOW SAID AN EACH SHE WHICH DO THEIR TIME IF WILL WAY ABOUT MANY THEN THEM WRITE WOULD
+		LIKE SO THESE HER LONG MAKE THING SEE HIM TWO HAS LOOK MORE DAY COULD GO COME DID NUMBER
+		SOUND NO MOST PEOPLE MY OVER KNOW WATER THAN CALL FIRST WHO MAY DOWN SIDE BEEN NOW FIND
+		ANY NEW WORK PART TAKE GET PLACE MADE LIVE WHERE AFTER BACK LITTLE ONLY ROUND MAN YEAR CAME
+		SHOW EVERY GOOD ME GIVE OUR UNDER NAME VERY THROUGH JUST FORM GREAT THINK SAY HELP LOW LINE
+		BEFORE TURN CAUSE SAME MEAN DIFFER MOVE RIGHT BOY OLD TOO DOES TELL SET THREE WANT AIR WELL
+		ALSO PLAY SMALL END PUT HOME READ HAND PORT LARGE ADD EVEN LAND HERE MUST BIG HIGH SUCH
+		FOLLOW ACT WHY ASK MEN CHANGE WENT LIGHT KIND OFF NEED HOUSE PICTURE TRY US AGAIN ANIMAL
+		POINT MOTHER WORLD NEAR BUILD SELF EARTH FATHER HEAD STAND OWN PAGE SHOULD COUNTRY FOUND
+		ANSWER SCHOOL GROW STUDY STILL LEARN PLANT COVER FOOD SUN FOUR BETWEEN STATE KEEP EYE NEVER
+		LAST LET THOUGHT CITY TREE CROSS FARM HARD START MIGHT STORY SAW FAR SEA DRAW LEFT LATE RUN
+		WHILE PRESS CLOSE NIGHT REAL LIFE FEW NORTH OPEN SEEM TOGETHER NEXT WHITE CHILDREN BEGIN GOT
+		WALK EXAMPLE EASE PAPER GROUP ALWAYS MUSIC THOSE BOTH MARK OFTEN LETTER UNTIL MILE RIVER CAR
+		FEET CARE SECOND BOOK CARRY TOOK SCIENCE EAT ROOM FRIEND BEGAN IDEA FISH MOUNTAIN STOP ONCE
+		BASE HEAR HORSE CUT SURE WATCH COLOR FACE WOOD MAIN ENOUGH PLAIN GIRL USUAL YOUNG READY ABOVE
+		EVER RED LIST THOUGH FEEL TALK BIRD SOON BODY DOG FAMILY DIRECT LEAVE SONG MEASURE DOOR BLACK
+		SHORT CLASS WIND QUESTION HAPPEN COMPLETE SHIP AREA HALF ROCK ORDER FIRE SOUTH PROBLEM PIECE
+		TOLD KNEW PASS SINCE TOP WHOLE KING SPACE HEARD BEST HOUR BETTER TRUE DURING HUNDRED FIVE
+		REMEMBER STEP EARLY HOLD WEST GROUND INTEREST REACH FAST SING LISTEN SIX TABLE TRAVEL LESS
+		MORNING TEN SIMPLE SEVERAL TOWARD WAR LAY AGAINST SLOW LOVE ROAD MAP RAIN RULE PULL COLD
+		NOTICE VOICE FALL POWER TOWN FINE CERTAIN FLY UNIT LEAD CRY DARK MACHINE NOTE WAIT PLAN
+		FIGURE STAR BOX FIELD REST ABLE POUND DONE BEAUTY DRIVE STOOD FRONT TEACH WEEK FINAL GAVE
+		GREEN OH QUICK SHIP SECRET MESSAGE KEY CODE`),
+	// Other languages are listed normalized: uppercase with accents folded
+	"es": wordSet(`DE LA QUE EL EN Y A LOS SE DEL LAS UN POR CON NO UNA SU PARA ES AL LO COMO MAS O PERO SUS
+		LE HA ME SI SIN SOBRE ESTE YA ENTRE CUANDO TODO ESTA SER SON DOS TAMBIEN FUE HABIA ERA MUY
+		HASTA DESDE MI PORQUE SOLO HAN YO HAY VEZ PUEDE TODOS ASI NOS NI PARTE TIENE
+		UNO DONDE BIEN TIEMPO MISMO ESE AHORA CADA E VIDA OTRO DESPUES TE OTROS AUNQUE ESA ESO
+		HACE OTRA GOBIERNO TAN DURANTE SIEMPRE DIA TANTO ELLA TRES DIJO SIDO GRAN PAIS SEGUN
+		MENOS MUNDO AÑO ANTES QUIEN VA BUENO NUNCA MUCHO POCO CASA HOMBRE DIOS NADA`),
+	"de": wordSet(`DER DIE UND IN DEN VON ZU DAS MIT SICH DES AUF FÜR IST IM DEM NICHT EIN EINE ALS AUCH ES
+		AN WERDEN AUS ER HAT DASS SIE NACH WIRD BEI EINER UM AM SIND NOCH WIE EINEM ÜBER EINEN SO ZUM
+		WAR HABEN NUR ODER ABER VOR ZUR BIS MEHR DURCH MAN SEIN WURDE SEI PROZENT HATTE KANN GEGEN
+		VOM KÖNNEN SCHON WENN HABE SEINE IHRE DANN UNTER WIR SOLL ICH EINES JAHR ZWEI JAHREN DIESE
+		WIEDER KEINE IHR DU GUT ALLES WAS WER WO HEUTE MORGEN TAG ZEIT NIE GELD GOLD IMMER`),
+	"fr": wordSet(`DE LA LE ET LES DES EN UN DU UNE QUE EST POUR QUI DANS A PAR PLUS PAS AU SUR NE SE CE IL
+		SONT OU AVEC SON AUX D L C QU N S J Y ELLE NOUS VOUS ILS ON MAIS COMME TOUT
+		SA SES LEUR ETE ETRE FAIT FAIRE PEUT BIEN AUSSI DEUX MEME SANS ENCORE ENTRE TRES SI AVANT
+		APRES TOUS CETTE ONT AVOIR CES DONT RIEN JAMAIS TOUJOURS TEMPS JOUR VIE HOMME MONDE FAUT`),
+	"ru": wordSet(`И В ВО НЕ ЧТО ОН НА Я С СО КАК А ТО ВСЕ ВСЁ ОНА ТАК ЕГО НО ДА ТЫ К У ЖЕ ВЫ ЗА БЫ ПО ТОЛЬКО
+		ЕЕ ЕЁ МНЕ БЫЛО ВОТ ОТ МЕНЯ ЕЩЕ ЕЩЁ НЕТ О ИЗ ЕМУ ТЕПЕРЬ КОГДА ДАЖЕ НУ ВДРУГ ЛИ ЕСЛИ УЖЕ ИЛИ НИ
+		БЫТЬ БЫЛ НЕГО ДО ВАС НИБУДЬ ОПЯТЬ УЖ ВАМ ВЕДЬ ТАМ ПОТОМ СЕБЯ НИЧЕГО ЕЙ МОЖЕТ ОНИ ТУТ ГДЕ ЕСТЬ
+		НАДО НЕЙ ДЛЯ МЫ ТЕБЯ ИХ ЧЕМ БЫЛА САМ ЧТОБ БЕЗ БУДТО ЧЕГО РАЗ ТОЖЕ СЕБЕ ПОД БУДЕТ Ж ТОГДА КТО
+		ЭТОТ ТОГО ПОТОМУ ЭТОГО КАКОЙ СОВСЕМ НИМ ЗДЕСЬ ЭТОМ ОДИН ПОЧТИ МОЙ ТЕМ ЧТОБЫ НЕЕ СЕЙЧАС БЫЛИ
+		КУДА ЗАЧЕМ ВСЕХ НИКОГДА МОЖНО ПРИ НАКОНЕЦ ДВА ОБ ДРУГОЙ ХОТЬ ПОСЛЕ НАД БОЛЬШЕ ТОТ ЧЕРЕЗ ЭТИ
+		НАС ПРО ВСЕГО НИХ КАКАЯ МНОГО РАЗВЕ ТРИ ЭТУ МОЯ ВПРОЧЕМ ХОРОШО СВОЮ ЭТОЙ ПЕРЕД ИНОГДА ЛУЧШЕ
+		ЧУТЬ ТОМ НЕЛЬЗЯ ТАКОЙ ИМ БОЛЕЕ ВСЕГДА КОНЕЧНО ВСЮ МЕЖДУ ВРЕМЯ ДЕЛО ЖИЗНЬ ДЕНЬ ЧАС`),
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
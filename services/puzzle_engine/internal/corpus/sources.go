@@ -0,0 +1,104 @@
+package corpus
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/swarit-1/cipher-clash/pkg/db"
+)
+
+//go:embed data/*.txt
+var embeddedData embed.FS
+
+// EmbeddedSource loads the texts shipped with the binary. Files are named
+// <language>_<theme>.txt and hold one text per line; lines starting with #
+// are comments.
+type EmbeddedSource struct {
+	files fs.FS
+}
+
+// NewEmbeddedSource creates a source over the built-in corpus files
+func NewEmbeddedSource() *EmbeddedSource {
+	return &EmbeddedSource{files: embeddedData}
+}
+
+func (s *EmbeddedSource) Name() string { return "embedded" }
+
+func (s *EmbeddedSource) Load(ctx context.Context) ([]Text, error) {
+	// Glob returns names sorted, keeping text order stable for seeded selection
+	names, err := fs.Glob(s.files, "data/*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	var texts []Text
+	for _, name := range names {
+		language, theme, ok := strings.Cut(strings.TrimSuffix(path.Base(name), ".txt"), "_")
+		if !ok {
+			return nil, fmt.Errorf("corpus file %s must be named <language>_<theme>.txt", name)
+		}
+
+		file, err := s.files.Open(name)
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			texts = append(texts, NewText("", line, language, theme))
+		}
+		file.Close()
+
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+
+	return texts, nil
+}
+
+// DBSource loads texts curated in the plaintext_corpus table
+type DBSource struct {
+	db *db.DB
+}
+
+// NewDBSource creates a source over the plaintext_corpus table
+func NewDBSource(database *db.DB) *DBSource {
+	return &DBSource{db: database}
+}
+
+func (s *DBSource) Name() string { return "database" }
+
+func (s *DBSource) Load(ctx context.Context) ([]Text, error) {
+	query := `
+		SELECT id, content, language, theme
+		FROM plaintext_corpus
+		WHERE is_active = TRUE
+		ORDER BY id
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var texts []Text
+	for rows.Next() {
+		var id, content, language, theme string
+		if err := rows.Scan(&id, &content, &language, &theme); err != nil {
+			return nil, err
+		}
+		texts = append(texts, NewText(id, content, language, theme))
+	}
+
+	return texts, rows.Err()
+}
@@ -2,8 +2,13 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/swarit-1/cipher-clash/pkg/audit"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/service"
@@ -12,24 +17,34 @@ import (
 // PuzzleHandler handles HTTP requests for puzzles
 type PuzzleHandler struct {
 	puzzleService *service.PuzzleService
+	audit         *audit.Log
 	log           *logger.Logger
 }
 
 // NewPuzzleHandler creates a new puzzle handler
-func NewPuzzleHandler(puzzleService *service.PuzzleService, log *logger.Logger) *PuzzleHandler {
+func NewPuzzleHandler(puzzleService *service.PuzzleService, auditLog *audit.Log, log *logger.Logger) *PuzzleHandler {
 	return &PuzzleHandler{
 		puzzleService: puzzleService,
+		audit:         auditLog,
 		log:           log,
 	}
 }
 
-// GeneratePuzzle handles puzzle generation
+// GeneratePuzzle handles puzzle generation. Only services may seed a puzzle:
+// whoever knows the seed can regenerate its key.
 func (h *PuzzleHandler) GeneratePuzzle(w http.ResponseWriter, r *http.Request) {
 	var req service.GeneratePuzzleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
 		return
 	}
+	if req.Seed != nil && !isService(r) {
+		h.respondError(w, errors.NewForbiddenError("Only internal services may seed puzzles"))
+		return
+	}
+	if !h.authorizeUser(w, r, req.UserID) {
+		return
+	}
 
 	puzzle, err := h.puzzleService.GeneratePuzzle(r.Context(), &req)
 	if err != nil {
@@ -40,7 +55,31 @@ func (h *PuzzleHandler) GeneratePuzzle(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusOK, puzzle)
 }
 
-// ValidateSolution handles solution validation
+// GenerateMatchPuzzles creates the ordered puzzle set of a match
+func (h *PuzzleHandler) GenerateMatchPuzzles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.MatchPuzzlesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	result, err := h.puzzleService.GenerateMatchPuzzles(r.Context(), &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+// ValidateSolution handles solution validation for the calling player, or for
+// the player a service names, so hint penalties always apply. The correct
+// answer and the alignment with it are only ever returned to internal
+// services, which show them where the mode allows it.
 func (h *PuzzleHandler) ValidateSolution(w http.ResponseWriter, r *http.Request) {
 	var req service.ValidateSolutionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -48,6 +87,26 @@ func (h *PuzzleHandler) ValidateSolution(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if (req.RevealAnswer || req.IncludeFeedback) && !isService(r) {
+		h.respondError(w, errors.NewForbiddenError("Only internal services may request the answer or feedback"))
+		return
+	}
+	// The mode picks the pass threshold, so players can't choose a lenient
+	// one; only services know the mode a player is really in
+	if !isService(r) {
+		req.Mode = ""
+	}
+	if req.UserID == "" {
+		req.UserID, _ = auth.UserIDFromContext(r.Context())
+	}
+	if req.UserID == "" {
+		h.respondError(w, errors.NewInvalidInputError("User ID is required"))
+		return
+	}
+	if !h.authorizeUser(w, r, req.UserID) {
+		return
+	}
+
 	result, err := h.puzzleService.ValidateSolution(r.Context(), &req)
 	if err != nil {
 		h.respondError(w, err)
@@ -74,6 +133,365 @@ func (h *PuzzleHandler) GetPuzzle(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusOK, puzzle)
 }
 
+// GetDailyPuzzle returns the puzzle of the day (UTC), or of the date given as
+// YYYY-MM-DD
+func (h *PuzzleHandler) GetDailyPuzzle(w http.ResponseWriter, r *http.Request) {
+	date := time.Now().UTC()
+	if raw := r.URL.Query().Get("date"); raw != "" {
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			h.respondError(w, errors.NewInvalidInputError("date must be formatted as YYYY-MM-DD"))
+			return
+		}
+		if parsed.After(date) {
+			h.respondError(w, errors.NewInvalidInputError("Daily puzzles are not available in advance"))
+			return
+		}
+		date = parsed
+	}
+
+	puzzle, err := h.puzzleService.GetDailyPuzzle(r.Context(), date)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, puzzle)
+}
+
+// HintRequest identifies whose hint ladder to climb
+type HintRequest struct {
+	PuzzleID string `json:"puzzle_id"`
+	UserID   string `json:"user_id"`
+}
+
+// RequestHint unlocks the next hint for a user (POST) or lists the hints they
+// have already unlocked (GET, with id and user_id query parameters)
+func (h *PuzzleHandler) RequestHint(w http.ResponseWriter, r *http.Request) {
+	var req HintRequest
+	switch r.Method {
+	case http.MethodGet:
+		req.PuzzleID = r.URL.Query().Get("id")
+		req.UserID = r.URL.Query().Get("user_id")
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+			return
+		}
+	default:
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	if req.PuzzleID == "" {
+		h.respondError(w, errors.NewInvalidInputError("Puzzle ID is required"))
+		return
+	}
+	if !h.authorizeUser(w, r, req.UserID) {
+		return
+	}
+
+	var result *service.HintResponse
+	var err error
+	if r.Method == http.MethodPost {
+		result, err = h.puzzleService.RequestHint(r.Context(), req.PuzzleID, req.UserID)
+	} else {
+		result, err = h.puzzleService.GetHints(r.Context(), req.PuzzleID, req.UserID)
+	}
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+// GetPuzzleStats returns the attempt statistics of one puzzle (id), or of
+// every cipher type or difficulty (group_by)
+func (h *PuzzleHandler) GetPuzzleStats(w http.ResponseWriter, r *http.Request) {
+	if groupBy := r.URL.Query().Get("group_by"); groupBy != "" {
+		groups, err := h.puzzleService.GetGroupedStats(r.Context(), groupBy)
+		if err != nil {
+			h.respondError(w, err)
+			return
+		}
+
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"group_by": groupBy,
+			"groups":   groups,
+		})
+		return
+	}
+
+	puzzleID := r.URL.Query().Get("id")
+	if puzzleID == "" {
+		h.respondError(w, errors.NewInvalidInputError("Puzzle ID or group_by is required"))
+		return
+	}
+
+	stats, err := h.puzzleService.GetPuzzleStats(r.Context(), puzzleID)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, stats)
+}
+
+// CustomPuzzles creates a player's own puzzle (POST), or looks one up by share
+// code (GET ?code=) or lists a player's puzzles to that player (GET ?owner_id=).
+// Unlisted puzzles are otherwise only reachable by share code.
+func (h *PuzzleHandler) CustomPuzzles(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req service.CreateCustomPuzzleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+			return
+		}
+
+		if req.OwnerID == "" {
+			h.respondError(w, errors.NewInvalidInputError("Owner ID is required"))
+			return
+		}
+		if !h.authorizeUser(w, r, req.OwnerID) {
+			return
+		}
+
+		puzzle, err := h.puzzleService.CreateCustomPuzzle(r.Context(), &req)
+		if err != nil {
+			h.respondError(w, err)
+			return
+		}
+
+		h.respondJSON(w, http.StatusCreated, puzzle)
+	case http.MethodGet:
+		if ownerID := r.URL.Query().Get("owner_id"); ownerID != "" {
+			if !h.authorizeUser(w, r, ownerID) {
+				return
+			}
+			puzzles, err := h.puzzleService.ListCustomPuzzles(r.Context(), ownerID)
+			if err != nil {
+				h.respondError(w, err)
+				return
+			}
+
+			h.respondJSON(w, http.StatusOK, map[string]interface{}{
+				"owner_id": ownerID,
+				"puzzles":  puzzles,
+			})
+			return
+		}
+
+		code := r.URL.Query().Get("code")
+		if code == "" {
+			h.respondError(w, errors.NewInvalidInputError("Share code or owner_id is required"))
+			return
+		}
+
+		puzzle, err := h.puzzleService.GetCustomPuzzle(r.Context(), code)
+		if err != nil {
+			h.respondError(w, err)
+			return
+		}
+
+		h.respondJSON(w, http.StatusOK, puzzle)
+	default:
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+	}
+}
+
+// CustomPuzzleRequest identifies a custom puzzle and the player acting on it
+type CustomPuzzleRequest struct {
+	ShareCode string `json:"share_code"`
+	UserID    string `json:"user_id"`
+	Rating    int    `json:"rating,omitempty"` // Rating requests only, 1-5
+}
+
+// PlayCustomPuzzle starts a play of a custom puzzle, counting it
+func (h *PuzzleHandler) PlayCustomPuzzle(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeCustomPuzzleRequest(w, r)
+	if !ok {
+		return
+	}
+
+	puzzle, err := h.puzzleService.PlayCustomPuzzle(r.Context(), req.ShareCode, req.UserID)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, puzzle)
+}
+
+// RateCustomPuzzle records a player's rating of a custom puzzle
+func (h *PuzzleHandler) RateCustomPuzzle(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeCustomPuzzleRequest(w, r)
+	if !ok {
+		return
+	}
+
+	puzzle, err := h.puzzleService.RateCustomPuzzle(r.Context(), req.ShareCode, req.UserID, req.Rating)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, puzzle)
+}
+
+func (h *PuzzleHandler) decodeCustomPuzzleRequest(w http.ResponseWriter, r *http.Request) (*CustomPuzzleRequest, bool) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return nil, false
+	}
+
+	var req CustomPuzzleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return nil, false
+	}
+	if req.ShareCode == "" {
+		h.respondError(w, errors.NewInvalidInputError("Share code is required"))
+		return nil, false
+	}
+	if !h.authorizeUser(w, r, req.UserID) {
+		return nil, false
+	}
+	return &req, true
+}
+
+// Toolkit runs the cryptanalysis toolkit on a ciphertext (POST), or lists the
+// tools a game mode allows (GET ?mode=). Analysis is only run for services,
+// which know the mode a player is really in.
+func (h *PuzzleHandler) Toolkit(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		if !isService(r) {
+			h.respondError(w, errors.NewForbiddenError("The toolkit is only available through the mode being played"))
+			return
+		}
+
+		var req service.AnalyzeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+			return
+		}
+
+		report, err := h.puzzleService.AnalyzeCiphertext(r.Context(), &req)
+		if err != nil {
+			h.respondError(w, err)
+			return
+		}
+
+		h.respondJSON(w, http.StatusOK, report)
+	case http.MethodGet:
+		mode := r.URL.Query().Get("mode")
+		if mode == "" {
+			h.respondError(w, errors.NewInvalidInputError("Game mode is required"))
+			return
+		}
+
+		h.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"mode":  mode,
+			"tools": h.puzzleService.ToolkitTools(mode),
+		})
+	default:
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+	}
+}
+
+// ReportPuzzle records a player's report of a broken or unsuitable puzzle
+func (h *PuzzleHandler) ReportPuzzle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.ReportPuzzleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+	if !h.authorizeUser(w, r, req.UserID) {
+		return
+	}
+	if caller, ok := auth.CallerFromContext(r.Context()); ok {
+		req.Guest = caller.Guest
+	}
+
+	report, err := h.puzzleService.ReportPuzzle(r.Context(), &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, report)
+}
+
+// ModerationQueue lists puzzles waiting for review (GET ?min_rating=&status=&limit=).
+// Moderators and admins.
+func (h *PuzzleHandler) ModerationQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	req := service.ModerationQueueRequest{Status: r.URL.Query().Get("status")}
+	for param, dest := range map[string]*int{"min_rating": &req.MinRating, "limit": &req.Limit} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			h.respondError(w, errors.NewInvalidInputError(fmt.Sprintf("Invalid %s", param)))
+			return
+		}
+		*dest = parsed
+	}
+
+	entries, err := h.puzzleService.ModerationQueue(r.Context(), &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"puzzles": entries,
+	})
+}
+
+// ReviewPuzzle restores, fixes or retires a reported puzzle. Moderators and
+// admins.
+func (h *PuzzleHandler) ReviewPuzzle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.respondError(w, errors.NewInvalidInputError("Method not allowed"))
+		return
+	}
+
+	var req service.ReviewPuzzleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, errors.NewInvalidInputError("Invalid request body"))
+		return
+	}
+
+	result, err := h.puzzleService.ReviewPuzzle(r.Context(), &req)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	h.audit.Record(r.Context(), audit.ActionPuzzleReview, nil, map[string]interface{}{
+		"puzzle_id":      result.PuzzleID,
+		"action":         result.Action,
+		"note":           req.Note,
+		"reports_closed": result.ReportsClosed,
+	})
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
 // Health check endpoint
 func (h *PuzzleHandler) Health(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
@@ -84,6 +502,25 @@ func (h *PuzzleHandler) Health(w http.ResponseWriter, r *http.Request) {
 
 // Helper methods
 
+// authorizeUser checks the caller may act for the user a request names, if
+// any, writing the error response if not
+func (h *PuzzleHandler) authorizeUser(w http.ResponseWriter, r *http.Request, userID string) bool {
+	if userID == "" {
+		return true
+	}
+	if err := auth.AuthorizeUser(r.Context(), userID); err != nil {
+		h.respondError(w, err)
+		return false
+	}
+	return true
+}
+
+// isService reports whether another service made the request
+func isService(r *http.Request) bool {
+	caller, ok := auth.CallerFromContext(r.Context())
+	return ok && caller.HasScope(auth.ScopeService)
+}
+
 func (h *PuzzleHandler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -0,0 +1,304 @@
+package hints
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/ciphers"
+)
+
+// Hint kinds, from least to most revealing
+const (
+	KindFamily    = "cipher_family"
+	KindKey       = "key_fragment"
+	KindLayer     = "layer"
+	KindPlaintext = "plaintext"
+)
+
+// Levels is how many hints a single-cipher puzzle's ladder holds. CHAIN
+// puzzles have one more for every layer under the outermost, see Count.
+const Levels = 4
+
+// Hint is one rung of a puzzle's hint ladder
+type Hint struct {
+	Level int    `json:"level"` // 1 = least revealing
+	Kind  string `json:"kind"`
+	Text  string `json:"text"`
+}
+
+// Ladder builds a puzzle's hints, least revealing first: the cipher family,
+// a fragment of the key, the shape of the message with each word's first
+// letter, and finally every other letter of the message. CHAIN puzzles
+// reveal their inner layers one rung at a time before the message.
+func Ladder(cipherType string, config map[string]interface{}, plaintext string) []Hint {
+	ladder := []Hint{
+		{Kind: KindFamily, Text: familyHint(cipherType, config)},
+		{Kind: KindKey, Text: keyHint(cipherType, config, plaintext)},
+	}
+	if cipherType == ciphers.TypeChain {
+		ladder = append(ladder, layerHints(config)...)
+	}
+	ladder = append(ladder,
+		Hint{Kind: KindPlaintext, Text: "The message reads: " + revealLetters(plaintext, firstOfWord)},
+		Hint{Kind: KindPlaintext, Text: "The message reads: " + revealLetters(plaintext, alternate)},
+	)
+
+	for i := range ladder {
+		ladder[i].Level = i + 1
+	}
+	return ladder
+}
+
+// Count is how many hints a puzzle's ladder holds
+func Count(cipherType string, config map[string]interface{}) int {
+	if cipherType != ciphers.TypeChain {
+		return Levels
+	}
+	return Levels + len(layerHints(config))
+}
+
+// layerNames are the player-facing names of the ciphers a chain can layer
+var layerNames = map[string]string{
+	ciphers.TypeCaesar:       "a Caesar shift",
+	ciphers.TypeROT13:        "ROT13",
+	ciphers.TypeAtbash:       "an Atbash mirror",
+	ciphers.TypeAffine:       "an affine cipher",
+	ciphers.TypeRailFence:    "a rail fence transposition",
+	ciphers.TypeVigenere:     "a Vigenère cipher",
+	ciphers.TypeAutokey:      "an autokey cipher",
+	ciphers.TypeSubstitution: "a monoalphabetic substitution",
+	ciphers.TypeEnigmaLite:   "an Enigma-style rotor machine",
+	ciphers.TypeBase64:       "Base64 encoding",
+	ciphers.TypeHexadecimal:  "hexadecimal encoding",
+}
+
+// LayerHint names one layer of a CHAIN puzzle, stage 1 being the outermost.
+// It is also the layer's puzzle_stages hint text.
+func LayerHint(stage, stages int, cipherType string) string {
+	name, ok := layerNames[cipherType]
+	if !ok {
+		name = cipherType
+	}
+	return fmt.Sprintf("Layer %d of %d (counting from the outside) is %s", stage, stages, name)
+}
+
+// layerHints describe each layer under a chain's outermost, which the family
+// and key hints already cover, with a fragment of its key
+func layerHints(config map[string]interface{}) []Hint {
+	layers, err := ciphers.ParseChainLayers(config)
+	if err != nil {
+		return nil
+	}
+
+	var rungs []Hint
+	for stage := 2; stage <= len(layers); stage++ {
+		layer := layers[len(layers)-stage]
+		rungs = append(rungs, Hint{
+			Kind: KindLayer,
+			Text: LayerHint(stage, len(layers), layer.CipherType) + ". " + keyHint(layer.CipherType, layer.Config, ""),
+		})
+	}
+	return rungs
+}
+
+// Cipher family descriptions, deliberately silent about the key
+const (
+	familyMonoalphabetic = "a monoalphabetic substitution: every letter is always replaced by the same letter"
+	familyPolyalphabetic = "a polyalphabetic substitution: the same letter encrypts differently depending on its position"
+	familyTransposition  = "a transposition: all the original letters are there, just rearranged"
+	familyEncoding       = "an encoding: there is no secret key, only a standard representation of the text"
+)
+
+// families describe how each cipher transforms text
+var families = map[string]string{
+	ciphers.TypeCaesar:        familyMonoalphabetic,
+	ciphers.TypeROT13:         familyMonoalphabetic,
+	ciphers.TypeAtbash:        familyMonoalphabetic,
+	ciphers.TypeAffine:        familyMonoalphabetic,
+	ciphers.TypeSubstitution:  familyMonoalphabetic,
+	ciphers.TypeVigenere:      familyPolyalphabetic,
+	ciphers.TypeAutokey:       familyPolyalphabetic,
+	ciphers.TypeEnigmaLite:    "a rotor machine: the substitution alphabet changes after every letter",
+	ciphers.TypePlayfair:      "a digraph substitution: letters are encrypted in pairs",
+	ciphers.TypeRailFence:     familyTransposition,
+	ciphers.TypeTransposition: familyTransposition,
+	ciphers.TypeXOR:           "a bitwise cipher: characters are combined with a repeating key using XOR",
+	ciphers.TypeBase64:        familyEncoding,
+	ciphers.TypeHexadecimal:   familyEncoding,
+	ciphers.TypeBinary:        familyEncoding,
+	ciphers.TypeMorse:         familyEncoding,
+	ciphers.TypeBookCipher:    "a book cipher: the numbers point into a text both sides share",
+	ciphers.TypeRSASimple:     "a public-key cipher: each character is raised to a power modulo a number",
+	ciphers.TypeSPN:           "a block cipher: 2-byte blocks go through rounds of key mixing, S-boxes and bit permutation",
+	ciphers.TypeLFSR:          "a stream cipher: the bytes are XORed with bits from a linear feedback shift register",
+	ciphers.TypeRSATextbook:   "textbook RSA: blocks of bytes are raised to the public exponent modulo n, with no padding",
+}
+
+func familyHint(cipherType string, config map[string]interface{}) string {
+	if cipherType == ciphers.TypeChain {
+		layers, err := ciphers.ParseChainLayers(config)
+		if err != nil || len(layers) == 0 {
+			return "The message is wrapped in several layers of encryption"
+		}
+		outer := layers[len(layers)-1]
+		return fmt.Sprintf("The message is wrapped in %d layers of encryption. The outermost is %s", len(layers), families[outer.CipherType])
+	}
+
+	family, ok := families[cipherType]
+	if !ok {
+		return "The cipher type is " + cipherType
+	}
+	return "This is " + family
+}
+
+func keyHint(cipherType string, config map[string]interface{}, plaintext string) string {
+	alphabet := ciphers.AlphabetOf(config)
+	size := alphabet.Size()
+
+	switch cipherType {
+	case ciphers.TypeCaesar:
+		shift := ciphers.ConfigInt(config, "shift")
+		common, _ := alphabet.Index(alphabet.Common)
+		return fmt.Sprintf("Plaintext %c becomes %c", alphabet.Common, alphabet.Letter(((common+shift)%size+size)%size))
+	case ciphers.TypeROT13:
+		if size%2 == 1 {
+			return fmt.Sprintf("Every letter moves %d places forward", size/2)
+		}
+		return fmt.Sprintf("Every letter moves %d places, so applying the cipher twice gives back the original", size/2)
+	case ciphers.TypeAtbash:
+		return fmt.Sprintf("The alphabet is mirrored: %c swaps with %c, %c with %c and so on",
+			alphabet.Letter(0), alphabet.Letter(size-1), alphabet.Letter(1), alphabet.Letter(size-2))
+	case ciphers.TypeAffine:
+		return fmt.Sprintf("The multiplier a is %d", ciphers.ConfigInt(config, "a"))
+	case ciphers.TypeSubstitution:
+		key := []rune(configString(config, "key"))
+		letter := mostCommonLetter(plaintext, alphabet)
+		index, ok := alphabet.Index(letter)
+		if len(key) != size || !ok {
+			return fmt.Sprintf("Start with the most frequent ciphertext letter, it is probably %c", alphabet.Common)
+		}
+		return fmt.Sprintf("The most frequent plaintext letter %c is written as %c", letter, key[index])
+	case ciphers.TypeVigenere:
+		key := configString(config, "key")
+		return fmt.Sprintf("The key is %d letters long and starts with %s", utf8.RuneCountInString(key), firstLetter(key))
+	case ciphers.TypeAutokey:
+		primer := configString(config, "primer")
+		return fmt.Sprintf("The %d-letter primer starts with %s; after it, the plaintext itself continues the key", utf8.RuneCountInString(primer), firstLetter(primer))
+	case ciphers.TypeEnigmaLite:
+		return fmt.Sprintf("The first rotor starts at position %c", 'A'+rune(ciphers.ConfigInt(config, "pos1")%26))
+	case ciphers.TypePlayfair:
+		return fmt.Sprintf("The key square is built from a keyword starting with %s", firstLetter(configString(config, "key")))
+	case ciphers.TypeRailFence:
+		return fmt.Sprintf("The text is written across %d rails", ciphers.ConfigInt(config, "rails"))
+	case ciphers.TypeTransposition:
+		return fmt.Sprintf("The text is written into %d columns", len(configString(config, "key")))
+	case ciphers.TypeXOR:
+		key := configString(config, "key")
+		return fmt.Sprintf("The repeating key is %d characters long and starts with %s", len(key), firstLetter(key))
+	case ciphers.TypeBase64:
+		return "Every 4 characters decode to 3 bytes of text; '=' is padding"
+	case ciphers.TypeHexadecimal:
+		return "Every pair of hex digits is one ASCII character"
+	case ciphers.TypeBinary:
+		return "Every group of 8 bits is one ASCII character"
+	case ciphers.TypeMorse:
+		return "Letters are separated by spaces and words by '/'"
+	case ciphers.TypeBookCipher:
+		words := strings.Fields(configString(config, "book"))
+		if len(words) > 3 {
+			words = words[:3]
+		}
+		return fmt.Sprintf("Each number is a position, counted from 0, in a text starting %q", strings.Join(words, " "))
+	case ciphers.TypeRSASimple:
+		return fmt.Sprintf("The public key is e=%d, n=%d", ciphers.ConfigInt(config, "e"), ciphers.ConfigInt(config, "n"))
+	case ciphers.TypeSPN:
+		key := configString(config, "key")
+		mode := configString(config, "mode")
+		if len(key) < 2 {
+			return fmt.Sprintf("The cipher runs in %s mode", mode)
+		}
+		return fmt.Sprintf("The cipher runs in %s mode and the 32-bit key starts with hex %s", mode, key[:2])
+	case ciphers.TypeLFSR:
+		return fmt.Sprintf("The 16-bit register uses taps %s; the ciphertext is base64, and XORing its first bytes with likely plaintext reveals the keystream", configString(config, "taps"))
+	case ciphers.TypeRSATextbook:
+		// A fact about p that narrows the search without handing over the factor
+		p, ok := new(big.Int).SetString(configString(config, "p"), 10)
+		if !ok {
+			return "Factor n: both primes are about half its bit length"
+		}
+		digits := p.String()
+		return fmt.Sprintf("One prime factor of n is %d bits long and ends in the digit %c", p.BitLen(), digits[len(digits)-1])
+	case ciphers.TypeChain:
+		layers, err := ciphers.ParseChainLayers(config)
+		if err != nil || len(layers) == 0 {
+			return "Peel the layers from the outside in"
+		}
+		outer := layers[len(layers)-1]
+		return "Outermost layer: " + keyHint(outer.CipherType, outer.Config, "")
+	default:
+		return "No key fragment is available for this cipher"
+	}
+}
+
+func configString(config map[string]interface{}, key string) string {
+	value, _ := config[key].(string)
+	return value
+}
+
+func firstLetter(key string) string {
+	first, _ := utf8.DecodeRuneInString(key)
+	if key == "" {
+		return "an unknown letter"
+	}
+	return fmt.Sprintf("%q", string(first))
+}
+
+// mostCommonLetter returns the uppercase letter of the alphabet appearing
+// most in text, or 0
+func mostCommonLetter(text string, alphabet *ciphers.Alphabet) rune {
+	counts := make([]int, alphabet.Size())
+	for _, char := range text {
+		if i, ok := alphabet.Index(char); ok {
+			counts[i]++
+		}
+	}
+
+	best := -1
+	for i, count := range counts {
+		if count > 0 && (best < 0 || count > counts[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0
+	}
+	return alphabet.Letter(best)
+}
+
+// revealLetters masks the letters of text, keeping those reveal selects by
+// their position within the word. Spacing and punctuation stay visible.
+func revealLetters(text string, reveal func(position int) bool) string {
+	var b strings.Builder
+	position := 0
+	for _, char := range text {
+		if !unicode.IsLetter(char) {
+			position = 0
+			b.WriteRune(char)
+			continue
+		}
+		if reveal(position) {
+			b.WriteRune(unicode.ToUpper(char))
+		} else {
+			b.WriteRune('_')
+		}
+		position++
+	}
+	return b.String()
+}
+
+func firstOfWord(position int) bool { return position == 0 }
+
+func alternate(position int) bool { return position%2 == 0 }
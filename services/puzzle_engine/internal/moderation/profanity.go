@@ -0,0 +1,71 @@
+// Package moderation screens player-written puzzle content before it is
+// stored and shared
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// leetReplacer undoes the usual digit and symbol swaps used to slip words
+// past a filter
+var leetReplacer = strings.NewReplacer(
+	"0", "O",
+	"1", "I",
+	"3", "E",
+	"4", "A",
+	"5", "S",
+	"7", "T",
+	"@", "A",
+	"$", "S",
+)
+
+// blockedWords must match a whole word. They are short or common enough as
+// parts of harmless words that matching prefixes would flag innocent text.
+var blockedWords = wordSet(`
+	ASS ASSES ARSE DICK DICKS FAG FAGS PISS PRICK PUSSY PUSSIES RETARD RETARDS
+	SLUT SLUTS TITS TWAT WANK
+	COÑO COJONES PUTA PUTAS PUTO
+	ARSCH FICKEN FOTZE HURE
+	CONNE PUTE
+	ХУЙ БЛЯ
+`)
+
+// blockedStems match any word they start, catching inflections and compounds
+var blockedStems = strings.Fields(`
+	ASSHOLE BASTARD BITCH BOLLOCK BULLSHIT CUNT FAGGOT FUCK MOTHERFUCK NIGGA NIGGER
+	SHIT WANKER WHORE
+	CABRÓN GILIPOLL HIJOPUTA JODER MARICÓN PENDEJ
+	ARSCHLOCH SCHEISS SCHEIß WICHSER
+	ENCULÉ ENCULE PUTAIN SALOPE CONNARD MERDE
+	БЛЯД ЕБАТ ЁБАН ЕБАН ПИЗД СУКА ХУЕ ХУЁ МУДАК
+`)
+
+// ContainsProfanity reports whether text contains a blocked word in any of
+// the supported languages. Words are compared case-insensitively after
+// undoing leetspeak, so "Sh1t" is caught along with "SHIT".
+func ContainsProfanity(text string) bool {
+	words := strings.FieldsFunc(leetReplacer.Replace(strings.ToUpper(text)), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	for _, word := range words {
+		if blockedWords[word] {
+			return true
+		}
+		for _, stem := range blockedStems {
+			if strings.HasPrefix(word, stem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/ciphers"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/corpus"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/hints"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/moderation"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/rng"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/solver"
+)
+
+// Custom puzzle limits. Letters are counted in the puzzle's alphabet; the
+// raw length bounds what is normalized in the first place.
+const (
+	customMinLetters     = 10
+	customMaxLetters     = 200
+	customMaxRawLength   = 1000
+	customMaxTitleLength = 100
+	// customDefaultDifficulty sets the strength of generated keys when the
+	// creator doesn't pick one
+	customDefaultDifficulty = 5
+	// customTheme tags custom plaintexts on puzzles.tags
+	customTheme = "custom"
+)
+
+// Share codes avoid characters that are easy to misread (0/O, 1/I/L)
+const (
+	shareCodeAlphabet    = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	shareCodeLength      = 8
+	maxShareCodeAttempts = 5
+)
+
+// CreateCustomPuzzleRequest is a player's own puzzle
+type CreateCustomPuzzleRequest struct {
+	OwnerID    string                 `json:"owner_id"`
+	Plaintext  string                 `json:"plaintext"`
+	CipherType string                 `json:"cipher_type"`
+	Key        map[string]interface{} `json:"key,omitempty"`        // Optional, see ciphers.CustomKey; generated when empty
+	Difficulty int                    `json:"difficulty,omitempty"` // Strength of a generated key, 1-10
+	Language   string                 `json:"language,omitempty"`   // Defaults to English
+	Title      string                 `json:"title,omitempty"`
+}
+
+// CustomPuzzle is a player-made puzzle with its sharing and popularity data.
+// The embedded puzzle never carries the plaintext or key.
+type CustomPuzzle struct {
+	Puzzle
+	ShareCode   string    `json:"share_code"`
+	OwnerID     string    `json:"owner_id"`
+	Title       string    `json:"title,omitempty"`
+	PlayCount   int       `json:"play_count"`
+	RatingCount int       `json:"rating_count"`
+	AvgRating   float64   `json:"avg_rating"`
+	CreatedAt   time.Time `json:"created_at"`
+	// ModerationStatus is OK unless reports took the puzzle out of play
+	ModerationStatus string `json:"moderation_status"`
+}
+
+// CreateCustomPuzzle validates a player's puzzle, encrypts and stores it, and
+// gives it a share code. The text must be within the length limits and pass
+// the profanity filter, and a chosen key must decrypt the text back.
+func (s *PuzzleService) CreateCustomPuzzle(ctx context.Context, req *CreateCustomPuzzleRequest) (*CustomPuzzle, error) {
+	if _, err := uuid.Parse(req.OwnerID); err != nil {
+		return nil, errors.NewInvalidInputError("A valid owner ID is required")
+	}
+
+	language := req.Language
+	if language == "" {
+		language = corpus.DefaultLanguage
+	}
+	if _, ok := ciphers.AlphabetFor(language); !ok {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Unsupported language: %s", language))
+	}
+
+	cipher := ciphers.GetCipher(req.CipherType)
+	if cipher == nil {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Invalid cipher type: %s", req.CipherType))
+	}
+	if !ciphers.SupportsLanguage(req.CipherType, language) {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("%s is not available in language %s", req.CipherType, language))
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if utf8.RuneCountInString(title) > customMaxTitleLength {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Title must be at most %d characters", customMaxTitleLength))
+	}
+	if len(req.Plaintext) > customMaxRawLength {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Plaintext must be at most %d characters", customMaxRawLength))
+	}
+
+	text := corpus.NewText("", req.Plaintext, language, customTheme)
+	if text.Letters < customMinLetters || text.Letters > customMaxLetters {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Plaintext must have between %d and %d letters", customMinLetters, customMaxLetters))
+	}
+	if moderation.ContainsProfanity(req.Plaintext) || moderation.ContainsProfanity(title) {
+		return nil, errors.NewInvalidInputError("Puzzle contains language that isn't allowed")
+	}
+
+	difficulty := req.Difficulty
+	if difficulty == 0 {
+		difficulty = customDefaultDifficulty
+	}
+	if difficulty < 1 || difficulty > 10 {
+		return nil, errors.NewInvalidInputError("Difficulty must be between 1 and 10")
+	}
+
+	random := rng.NewSecure()
+	config, err := customConfig(cipher, req, language, difficulty, random)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedText, err := ciphers.CheckRoundTrip(cipher, text.Content, config)
+	if err != nil {
+		return nil, errors.NewInvalidInputError(err.Error())
+	}
+	if encryptedText == text.Content {
+		return nil, errors.NewInvalidInputError("This key leaves the plaintext unchanged")
+	}
+
+	puzzle := &Puzzle{
+		ID:             uuid.New().String(),
+		CipherType:     req.CipherType,
+		Difficulty:     difficulty,
+		EncryptedText:  encryptedText,
+		Plaintext:      text.Content,
+		Language:       language,
+		Config:         config,
+		HintsAvailable: hints.Count(req.CipherType, config),
+		Tags:           text.Tags(),
+	}
+
+	// A chosen key says nothing about difficulty, so where a solver can
+	// measure it the measurement is used instead
+	if language == corpus.DefaultLanguage {
+		grade := solver.GradePuzzle(req.CipherType, encryptedText, text.Content, random)
+		if grade.Supported {
+			puzzle.EmpiricalDifficulty = grade.Difficulty
+			puzzle.SolverWork = grade.Work
+			puzzle.Difficulty = clampDifficulty(int(math.Round(grade.Difficulty)))
+		}
+	}
+	applyIntroHint(puzzle)
+
+	custom := &CustomPuzzle{
+		Puzzle:           *puzzle,
+		OwnerID:          req.OwnerID,
+		Title:            title,
+		ModerationStatus: ModerationOK,
+	}
+	if err := s.saveCustomPuzzle(ctx, custom, random); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	s.cache.Set(ctx, fmt.Sprintf("puzzle:%s", puzzle.ID), puzzle, cache.TTLPuzzle)
+
+	s.log.Info("Custom puzzle created", map[string]interface{}{
+		"puzzle_id":   puzzle.ID,
+		"share_code":  custom.ShareCode,
+		"owner_id":    req.OwnerID,
+		"cipher_type": req.CipherType,
+		"difficulty":  puzzle.Difficulty,
+		"custom_key":  len(req.Key) > 0,
+	})
+
+	return custom.forClient(), nil
+}
+
+// GetCustomPuzzle looks up a custom puzzle by share code. Quarantined and
+// retired puzzles can't be found until a moderator restores them.
+func (s *PuzzleService) GetCustomPuzzle(ctx context.Context, shareCode string) (*CustomPuzzle, error) {
+	custom, err := s.loadCustomPuzzle(ctx, `WHERE cp.share_code = $1 AND p.moderation_status = 'OK'`, normalizeShareCode(shareCode))
+	if err != nil {
+		return nil, err
+	}
+	if custom == nil {
+		return nil, errors.NewNotFoundError("Custom puzzle not found")
+	}
+	return custom, nil
+}
+
+// PlayCustomPuzzle returns a custom puzzle for a player about to solve it and
+// counts the play. The owner's own plays are not counted.
+func (s *PuzzleService) PlayCustomPuzzle(ctx context.Context, shareCode, userID string) (*CustomPuzzle, error) {
+	custom, err := s.GetCustomPuzzle(ctx, shareCode)
+	if err != nil {
+		return nil, err
+	}
+	if userID == custom.OwnerID {
+		return custom, nil
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE custom_puzzles SET play_count = play_count + 1 WHERE puzzle_id = $1
+	`, custom.ID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	custom.PlayCount++
+
+	s.log.Info("Custom puzzle played", map[string]interface{}{
+		"puzzle_id":  custom.ID,
+		"share_code": custom.ShareCode,
+		"user_id":    userID,
+	})
+	return custom, nil
+}
+
+// RateCustomPuzzle records a player's 1-5 rating of a custom puzzle,
+// replacing any earlier rating of theirs
+func (s *PuzzleService) RateCustomPuzzle(ctx context.Context, shareCode, userID string, rating int) (*CustomPuzzle, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, errors.NewInvalidInputError("A valid user ID is required")
+	}
+	if rating < 1 || rating > 5 {
+		return nil, errors.NewInvalidInputError("Rating must be between 1 and 5")
+	}
+
+	custom, err := s.GetCustomPuzzle(ctx, shareCode)
+	if err != nil {
+		return nil, err
+	}
+	if userID == custom.OwnerID {
+		return nil, errors.NewForbiddenError("You cannot rate your own puzzle")
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO custom_puzzle_ratings (puzzle_id, user_id, rating)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (puzzle_id, user_id)
+		DO UPDATE SET rating = EXCLUDED.rating, updated_at = NOW()
+	`, custom.ID, userID, rating)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return s.GetCustomPuzzle(ctx, custom.ShareCode)
+}
+
+// ListCustomPuzzles returns the puzzles a player has made, newest first
+func (s *PuzzleService) ListCustomPuzzles(ctx context.Context, ownerID string) ([]*CustomPuzzle, error) {
+	if _, err := uuid.Parse(ownerID); err != nil {
+		return nil, errors.NewInvalidInputError("A valid owner ID is required")
+	}
+
+	rows, err := s.db.QueryContext(ctx, customPuzzleQuery+`
+		WHERE cp.owner_id = $1
+		GROUP BY cp.puzzle_id, p.id
+		ORDER BY cp.created_at DESC
+	`, ownerID)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	puzzles := []*CustomPuzzle{}
+	for rows.Next() {
+		custom, err := scanCustomPuzzle(rows)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		puzzles = append(puzzles, custom)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	for i, custom := range puzzles {
+		if puzzles[i], err = s.withPuzzle(ctx, custom); err != nil {
+			return nil, err
+		}
+	}
+	return puzzles, nil
+}
+
+// customConfig builds the key of a custom puzzle: the creator's own key when
+// one was given, a generated one otherwise
+func customConfig(cipher ciphers.Cipher, req *CreateCustomPuzzleRequest, language string, difficulty int, random *rand.Rand) (map[string]interface{}, error) {
+	if len(req.Key) == 0 {
+		return ciphers.GenerateKeyFor(cipher, difficulty, language, random), nil
+	}
+	if !ciphers.SupportsCustomKey(req.CipherType) {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("%s does not accept a custom key", req.CipherType))
+	}
+
+	config, err := ciphers.CustomKey(req.CipherType, language, req.Key)
+	if err != nil {
+		return nil, errors.NewInvalidInputError(err.Error())
+	}
+	return config, nil
+}
+
+// saveCustomPuzzle stores the puzzle and its custom_puzzles row together,
+// drawing a new share code if the first one is taken
+func (s *PuzzleService) saveCustomPuzzle(ctx context.Context, custom *CustomPuzzle, random *rand.Rand) error {
+	var err error
+	for attempt := 0; attempt < maxShareCodeAttempts; attempt++ {
+		custom.ShareCode = newShareCode(random)
+		err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+			if err := insertPuzzle(ctx, tx, &custom.Puzzle); err != nil {
+				return err
+			}
+			return tx.QueryRowContext(ctx, `
+				INSERT INTO custom_puzzles (puzzle_id, owner_id, share_code, title)
+				VALUES ($1, $2, $3, NULLIF($4, ''))
+				RETURNING created_at
+			`, custom.ID, custom.OwnerID, custom.ShareCode, custom.Title).Scan(&custom.CreatedAt)
+		})
+		if !isShareCodeConflict(err) {
+			return err
+		}
+	}
+	return err
+}
+
+const customPuzzleQuery = `
+	SELECT cp.puzzle_id, cp.owner_id, cp.share_code, COALESCE(cp.title, ''), cp.play_count, cp.created_at,
+		COUNT(r.rating), COALESCE(AVG(r.rating), 0), p.moderation_status
+	FROM custom_puzzles cp
+	JOIN puzzles p ON p.id = cp.puzzle_id
+	LEFT JOIN custom_puzzle_ratings r ON r.puzzle_id = cp.puzzle_id
+`
+
+// loadCustomPuzzle returns the custom puzzle matching a WHERE clause, or nil
+func (s *PuzzleService) loadCustomPuzzle(ctx context.Context, where string, args ...interface{}) (*CustomPuzzle, error) {
+	row := s.db.QueryRowContext(ctx, customPuzzleQuery+where+`
+		GROUP BY cp.puzzle_id, p.id
+	`, args...)
+	custom, err := scanCustomPuzzle(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return s.withPuzzle(ctx, custom)
+}
+
+// withPuzzle fills in the puzzle itself, stripped for clients
+func (s *PuzzleService) withPuzzle(ctx context.Context, custom *CustomPuzzle) (*CustomPuzzle, error) {
+	puzzle, err := s.getPuzzle(ctx, custom.ID)
+	if err != nil {
+		return nil, err
+	}
+	custom.Puzzle = *puzzle
+	return custom.forClient(), nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCustomPuzzle(row rowScanner) (*CustomPuzzle, error) {
+	var custom CustomPuzzle
+	err := row.Scan(
+		&custom.ID,
+		&custom.OwnerID,
+		&custom.ShareCode,
+		&custom.Title,
+		&custom.PlayCount,
+		&custom.CreatedAt,
+		&custom.RatingCount,
+		&custom.AvgRating,
+		&custom.ModerationStatus,
+	)
+	if err != nil {
+		return nil, err
+	}
+	custom.AvgRating = math.Round(custom.AvgRating*100) / 100
+	return &custom, nil
+}
+
+// forClient strips the answer and key before the puzzle is returned
+func (c *CustomPuzzle) forClient() *CustomPuzzle {
+	clientPuzzle := *c
+	clientPuzzle.Plaintext = ""
+	clientPuzzle.Config = nil
+	return &clientPuzzle
+}
+
+func newShareCode(random *rand.Rand) string {
+	code := make([]byte, shareCodeLength)
+	for i := range code {
+		code[i] = shareCodeAlphabet[random.Intn(len(shareCodeAlphabet))]
+	}
+	return string(code)
+}
+
+// normalizeShareCode accepts codes typed in lower case or with separators
+func normalizeShareCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+func isShareCodeConflict(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505" && pqErr.Constraint == "custom_puzzles_share_code_key"
+}
+
+func clampDifficulty(difficulty int) int {
+	if difficulty < 1 {
+		return 1
+	}
+	if difficulty > 10 {
+		return 10
+	}
+	return difficulty
+}
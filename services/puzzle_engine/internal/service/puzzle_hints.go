@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/hints"
+)
+
+// hintPenalty is the score multiplier applied per hint taken, matching the
+// practice service's scoring
+const hintPenalty = 0.9
+
+// HintResponse is the part of a puzzle's hint ladder a user has unlocked
+type HintResponse struct {
+	PuzzleID       string       `json:"puzzle_id"`
+	Hints          []hints.Hint `json:"hints"`
+	HintsUsed      int          `json:"hints_used"`
+	HintsRemaining int          `json:"hints_remaining"`
+}
+
+// RequestHint unlocks the next hint of a puzzle for a user. Consumption is
+// recorded before the hint is returned, so a penalty can't be dodged by
+// dropping the response; asking past the last rung returns the full ladder.
+func (s *PuzzleService) RequestHint(ctx context.Context, puzzleID, userID string) (*HintResponse, error) {
+	puzzle, err := s.hintPuzzle(ctx, puzzleID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var used int
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO puzzle_hint_usage (user_id, puzzle_id, hints_used)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (user_id, puzzle_id) DO UPDATE
+		SET hints_used = LEAST(puzzle_hint_usage.hints_used + 1, $3),
+			last_hint_at = NOW()
+		RETURNING hints_used
+	`, userID, puzzleID, hints.Count(puzzle.CipherType, puzzle.Config)).Scan(&used)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+
+	s.log.Info("Hint served", map[string]interface{}{
+		"puzzle_id":  puzzleID,
+		"user_id":    userID,
+		"hints_used": used,
+	})
+
+	return hintResponse(puzzle, used), nil
+}
+
+// GetHints returns the hints a user has already unlocked without spending another
+func (s *PuzzleService) GetHints(ctx context.Context, puzzleID, userID string) (*HintResponse, error) {
+	puzzle, err := s.hintPuzzle(ctx, puzzleID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := s.hintsUsed(ctx, puzzleID, userID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+
+	return hintResponse(puzzle, used), nil
+}
+
+func (s *PuzzleService) hintPuzzle(ctx context.Context, puzzleID, userID string) (*Puzzle, error) {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil, errors.NewInvalidInputError("A valid user ID is required for hints")
+	}
+	return s.getPuzzle(ctx, puzzleID)
+}
+
+// hintsUsed is how many hints a user has taken on a puzzle
+func (s *PuzzleService) hintsUsed(ctx context.Context, puzzleID, userID string) (int, error) {
+	var used int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(hints_used), 0)
+		FROM puzzle_hint_usage
+		WHERE user_id = $1 AND puzzle_id = $2
+	`, userID, puzzleID).Scan(&used)
+	return used, err
+}
+
+func hintResponse(puzzle *Puzzle, used int) *HintResponse {
+	ladder := hints.Ladder(puzzle.CipherType, puzzle.Config, puzzle.Plaintext)
+	if used > len(ladder) {
+		used = len(ladder)
+	}
+	return &HintResponse{
+		PuzzleID:       puzzle.ID,
+		Hints:          ladder[:used],
+		HintsUsed:      used,
+		HintsRemaining: len(ladder) - used,
+	}
+}
+
+// applyHintPenalty reduces a score by hintPenalty for every hint taken
+func applyHintPenalty(score, hintsUsed int) int {
+	return int(float64(score) * math.Pow(hintPenalty, float64(hintsUsed)))
+}
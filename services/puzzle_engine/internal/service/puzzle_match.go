@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/ciphers"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/rng"
+)
+
+const (
+	// maxMatchPuzzles bounds the size of one match's puzzle set
+	maxMatchPuzzles = 10
+	// Every puzzle of a match uses a plaintext within this letter count range,
+	// so no round is won or lost on text length
+	matchMinLetters = 30
+	matchMaxLetters = 60
+)
+
+// MatchPuzzlesRequest asks for the ordered puzzle set of a match
+type MatchPuzzlesRequest struct {
+	Count         int      `json:"count"`
+	MinDifficulty int      `json:"min_difficulty"` // 0 derives the range from AvgPlayerELO
+	MaxDifficulty int      `json:"max_difficulty"`
+	CipherTypes   []string `json:"cipher_types"` // Empty for all types
+	AvgPlayerELO  int      `json:"avg_player_elo"`
+	PlayerIDs     []string `json:"player_ids"` // Plaintexts any of them saw recently are avoided
+	Language      string   `json:"language"`
+	MatchSeed     *int64   `json:"match_seed"` // Reproduces the whole set, ignoring player histories. A secret from rng.NewSeed, never a player's
+}
+
+// MatchPuzzlesResponse is a match's puzzle set, in play order
+type MatchPuzzlesResponse struct {
+	Puzzles []*Puzzle `json:"puzzles"`
+}
+
+// GenerateMatchPuzzles creates a match's puzzle set. Difficulty ramps evenly
+// from the minimum to the maximum, no cipher type is played twice in a row,
+// and every plaintext comes from the same letter count range and is new to
+// all players.
+func (s *PuzzleService) GenerateMatchPuzzles(ctx context.Context, req *MatchPuzzlesRequest) (*MatchPuzzlesResponse, error) {
+	if req.Count < 1 || req.Count > maxMatchPuzzles {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("count must be between 1 and %d", maxMatchPuzzles))
+	}
+	req.CipherTypes = distinct(req.CipherTypes)
+	if req.Count > 1 && len(req.CipherTypes) == 1 {
+		return nil, errors.NewInvalidInputError("At least two cipher types are needed to avoid back to back repeats")
+	}
+	if _, ok := ciphers.AlphabetFor(req.Language); !ok {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Unsupported language: %s", req.Language))
+	}
+	for _, cipherType := range req.CipherTypes {
+		if ciphers.GetCipher(cipherType) == nil {
+			return nil, errors.NewInvalidInputError(fmt.Sprintf("Invalid cipher type: %s", cipherType))
+		}
+		if !ciphers.SupportsLanguage(cipherType, req.Language) {
+			return nil, errors.NewInvalidInputError(fmt.Sprintf("%s is not available in language %s", cipherType, req.Language))
+		}
+	}
+
+	minDiff, maxDiff := req.MinDifficulty, req.MaxDifficulty
+	if minDiff == 0 && maxDiff == 0 {
+		center := 3
+		if req.AvgPlayerELO > 0 {
+			center = s.calculateDifficultyFromELO(req.AvgPlayerELO)
+		}
+		minDiff, maxDiff = max(center-1, 1), min(center+1, 10)
+	}
+	if minDiff < 1 || maxDiff > 10 || minDiff > maxDiff {
+		return nil, errors.NewInvalidInputError("Difficulties must satisfy 1 <= min_difficulty <= max_difficulty <= 10")
+	}
+
+	var userID string
+	var opponents []string
+	if len(req.PlayerIDs) > 0 {
+		userID, opponents = req.PlayerIDs[0], req.PlayerIDs[1:]
+	}
+
+	random := rng.NewSecure()
+	if req.MatchSeed != nil {
+		random = rng.New(rng.Derive(*req.MatchSeed, "cipher_types"))
+	}
+
+	puzzles := make([]*Puzzle, 0, req.Count)
+	previous := ""
+	for i := 0; i < req.Count; i++ {
+		difficulty := rampDifficulty(minDiff, maxDiff, i, req.Count)
+		cipherType, err := matchCipherType(req.CipherTypes, difficulty, req.Language, previous, random)
+		if err != nil {
+			return nil, err
+		}
+		previous = cipherType
+
+		// Each puzzle gets its own seed so one can be replayed on its own
+		var seed *int64
+		if req.MatchSeed != nil {
+			puzzleSeed := rng.Derive(*req.MatchSeed, "puzzle", i)
+			seed = &puzzleSeed
+		}
+
+		// Generated rather than drawn from the pool, which can't honour the
+		// length range or several players' histories
+		puzzle, err := s.createPuzzle(ctx, &GeneratePuzzleRequest{
+			CipherType: cipherType,
+			Difficulty: difficulty,
+			UserID:     userID,
+			Opponents:  opponents,
+			Language:   req.Language,
+			Seed:       seed,
+			MinLetters: matchMinLetters,
+			MaxLetters: matchMaxLetters,
+		}, difficulty, false)
+		if err != nil {
+			return nil, err
+		}
+
+		clientPuzzle := *puzzle
+		clientPuzzle.Plaintext = ""
+		clientPuzzle.Config = nil
+		puzzles = append(puzzles, &clientPuzzle)
+	}
+
+	s.log.Info("Match puzzles generated", map[string]interface{}{
+		"count":          req.Count,
+		"min_difficulty": minDiff,
+		"max_difficulty": maxDiff,
+		"players":        len(req.PlayerIDs),
+		"seeded":         req.MatchSeed != nil,
+	})
+
+	return &MatchPuzzlesResponse{Puzzles: puzzles}, nil
+}
+
+// rampDifficulty spreads count puzzles evenly from minDiff to maxDiff,
+// starting at minDiff and ending at maxDiff
+func rampDifficulty(minDiff, maxDiff, i, count int) int {
+	if count == 1 {
+		return minDiff
+	}
+	// Round to the nearest step
+	return minDiff + ((maxDiff-minDiff)*i*2+(count-1))/((count-1)*2)
+}
+
+// matchCipherType picks a cipher type from allowed (every type available at
+// the difficulty and language when empty), never repeating the previous one
+func matchCipherType(allowed []string, difficulty int, language, previous string, random *rand.Rand) (string, error) {
+	if len(allowed) == 0 {
+		allowed = availableCipherTypes(difficulty, language)
+	}
+
+	choices := make([]string, 0, len(allowed))
+	for _, cipherType := range allowed {
+		if cipherType != previous {
+			choices = append(choices, cipherType)
+		}
+	}
+	if len(choices) == 0 {
+		return "", errors.NewInvalidInputError(fmt.Sprintf("No cipher type other than %s is available at difficulty %d", previous, difficulty))
+	}
+	return choices[random.Intn(len(choices))], nil
+}
+
+// distinct returns values without repeats, keeping the first of each
+func distinct(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,590 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/ciphers"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/corpus"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/moderation"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/rng"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/solver"
+)
+
+// Report reasons
+const (
+	ReportWrongDecryption = "WRONG_DECRYPTION"
+	ReportOffensive       = "OFFENSIVE"
+	ReportUnsolvable      = "UNSOLVABLE"
+	ReportDuplicate       = "DUPLICATE"
+	ReportOther           = "OTHER"
+)
+
+// Moderation statuses of a puzzle. Quarantined and retired puzzles are
+// inactive, so the pool stops serving them.
+const (
+	ModerationOK          = "OK"
+	ModerationQuarantined = "QUARANTINED"
+	ModerationRetired     = "RETIRED"
+)
+
+// Review actions
+const (
+	ReviewRestore = "restore" // Reports were wrong, the puzzle goes back into play
+	ReviewFix     = "fix"     // The puzzle is corrected and goes back into play
+	ReviewRetire  = "retire"  // The puzzle is never served again
+)
+
+const (
+	// reportQuarantineThreshold is the number of players with an open report
+	// that takes a puzzle out of play until it is reviewed
+	reportQuarantineThreshold = 3
+	reportMaxDetailsLength    = 500
+	// moderationDefaultMinRating is the rating above which players are
+	// expected to solve any puzzle
+	moderationDefaultMinRating = 1800
+	// moderationMinStrongAttempts is how many failed attempts by strong
+	// players flag a puzzle nobody has solved
+	moderationMinStrongAttempts = 3
+	moderationDefaultLimit      = 50
+	moderationMaxLimit          = 200
+)
+
+// ReportPuzzleRequest is a player's report of a broken or unsuitable puzzle
+type ReportPuzzleRequest struct {
+	PuzzleID string `json:"puzzle_id"`
+	UserID   string `json:"user_id"`
+	Reason   string `json:"reason"`
+	Details  string `json:"details,omitempty"`
+	Guest    bool   `json:"-"` // Set from the caller's token, never the body
+}
+
+// PuzzleReport is the outcome of a report
+type PuzzleReport struct {
+	ID               int64     `json:"id"`
+	PuzzleID         string    `json:"puzzle_id"`
+	Reason           string    `json:"reason"`
+	CreatedAt        time.Time `json:"created_at"`
+	OpenReports      int       `json:"open_reports"`
+	ModerationStatus string    `json:"moderation_status"`
+}
+
+// ModerationEntry is a puzzle waiting for review, with why it is waiting
+type ModerationEntry struct {
+	PuzzleID         string         `json:"puzzle_id"`
+	CipherType       string         `json:"cipher_type"`
+	Difficulty       int            `json:"difficulty"`
+	ModerationStatus string         `json:"moderation_status"`
+	ModeratedAt      *time.Time     `json:"moderated_at,omitempty"`
+	Note             string         `json:"note,omitempty"`
+	OpenReports      int            `json:"open_reports"`
+	Reasons          map[string]int `json:"reasons"`
+	TimesUsed        int            `json:"times_used"`
+	TimesSolved      int            `json:"times_solved"`
+	StrongAttempts   int            `json:"strong_attempts"`              // Attempts by players at or above the queue's rating
+	BestSolverRating *int           `json:"best_solver_rating,omitempty"` // Highest rating among known solvers
+	// UnsolvedAboveRating flags puzzles strong players keep failing that no
+	// player at or above the rating has solved
+	UnsolvedAboveRating bool `json:"unsolved_above_rating"`
+}
+
+// ModerationQueueRequest filters the moderation queue
+type ModerationQueueRequest struct {
+	MinRating int    // Defaults to moderationDefaultMinRating
+	Status    string // OK or QUARANTINED; empty lists both
+	Limit     int
+}
+
+// ReviewPuzzleRequest is a moderator's decision on a puzzle. A fix gives a
+// corrected plaintext, encrypted again with the puzzle's key, a corrected
+// difficulty, or both.
+type ReviewPuzzleRequest struct {
+	PuzzleID   string `json:"puzzle_id"`
+	Action     string `json:"action"`
+	Note       string `json:"note,omitempty"`
+	Plaintext  string `json:"plaintext,omitempty"`  // Fix only
+	Difficulty int    `json:"difficulty,omitempty"` // Fix only, 1-10
+}
+
+// ReviewResult is the state of a puzzle after review
+type ReviewResult struct {
+	PuzzleID         string `json:"puzzle_id"`
+	Action           string `json:"action"`
+	ModerationStatus string `json:"moderation_status"`
+	ReportsClosed    int    `json:"reports_closed"`
+	EncryptedText    string `json:"encrypted_text,omitempty"` // Fix only
+	Difficulty       int    `json:"difficulty,omitempty"`     // Fix only
+}
+
+// ReportPuzzle records a player's report. Reporting the same puzzle again
+// replaces the player's open report. Once enough players have open reports
+// the puzzle is quarantined until a moderator reviews it.
+func (s *PuzzleService) ReportPuzzle(ctx context.Context, req *ReportPuzzleRequest) (*PuzzleReport, error) {
+	// Guest accounts are free to create, so their reports could quarantine
+	// any puzzle
+	if req.Guest {
+		return nil, errors.NewForbiddenError("Register an account to report puzzles")
+	}
+	if _, err := uuid.Parse(req.UserID); err != nil {
+		return nil, errors.NewInvalidInputError("A valid user ID is required")
+	}
+	if _, err := uuid.Parse(req.PuzzleID); err != nil {
+		return nil, errors.NewInvalidInputError("A valid puzzle ID is required")
+	}
+	if !isReportReason(req.Reason) {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Invalid report reason: %s", req.Reason))
+	}
+	details := strings.TrimSpace(req.Details)
+	if utf8.RuneCountInString(details) > reportMaxDetailsLength {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Details must be at most %d characters", reportMaxDetailsLength))
+	}
+
+	var status string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT moderation_status FROM puzzles WHERE id = $1
+	`, req.PuzzleID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewPuzzleNotFoundError()
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if status == ModerationRetired {
+		return nil, errors.NewInvalidInputError("This puzzle has already been retired")
+	}
+
+	report := &PuzzleReport{
+		PuzzleID:         req.PuzzleID,
+		Reason:           req.Reason,
+		ModerationStatus: status,
+	}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO puzzle_reports (puzzle_id, user_id, reason, details)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (puzzle_id, user_id) WHERE status = 'OPEN'
+		DO UPDATE SET reason = EXCLUDED.reason, details = EXCLUDED.details
+		RETURNING id, created_at
+	`, req.PuzzleID, req.UserID, req.Reason, details).Scan(&report.ID, &report.CreatedAt)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM puzzle_reports WHERE puzzle_id = $1 AND status = 'OPEN'
+	`, req.PuzzleID).Scan(&report.OpenReports)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	if status == ModerationOK && report.OpenReports >= reportQuarantineThreshold {
+		quarantined, err := s.quarantinePuzzle(ctx, req.PuzzleID, report.OpenReports)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		if quarantined {
+			report.ModerationStatus = ModerationQuarantined
+		}
+	}
+
+	s.log.Info("Puzzle reported", map[string]interface{}{
+		"puzzle_id":    req.PuzzleID,
+		"user_id":      req.UserID,
+		"reason":       req.Reason,
+		"open_reports": report.OpenReports,
+	})
+	return report, nil
+}
+
+// quarantinePuzzle takes a puzzle out of play pending review. It reports
+// false when another report got there first.
+func (s *PuzzleService) quarantinePuzzle(ctx context.Context, puzzleID string, reports int) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE puzzles
+		SET moderation_status = 'QUARANTINED', is_active = FALSE, moderated_at = NOW(), moderation_note = $2
+		WHERE id = $1 AND moderation_status = 'OK'
+	`, puzzleID, fmt.Sprintf("Quarantined after %d reports", reports))
+	if err != nil {
+		return false, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return false, nil
+	}
+
+	s.invalidatePuzzle(ctx, puzzleID)
+	s.log.Warn("Puzzle quarantined", map[string]interface{}{
+		"puzzle_id":    puzzleID,
+		"open_reports": reports,
+	})
+	return true, nil
+}
+
+// ModerationQueue lists puzzles needing review: quarantined puzzles, puzzles
+// with open reports, and puzzles strong players keep failing that nobody at
+// or above the minimum rating has solved. Quarantined puzzles come first,
+// then the most reported.
+func (s *PuzzleService) ModerationQueue(ctx context.Context, req *ModerationQueueRequest) ([]*ModerationEntry, error) {
+	minRating := req.MinRating
+	if minRating == 0 {
+		minRating = moderationDefaultMinRating
+	}
+	if minRating < 0 {
+		return nil, errors.NewInvalidInputError("Minimum rating must be positive")
+	}
+	if req.Status != "" && req.Status != ModerationOK && req.Status != ModerationQuarantined {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Invalid moderation status: %s", req.Status))
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = moderationDefaultLimit
+	}
+	if limit > moderationMaxLimit {
+		limit = moderationMaxLimit
+	}
+
+	query := `
+		WITH open_reports AS (
+			SELECT puzzle_id, COUNT(*) AS reports
+			FROM puzzle_reports
+			WHERE status = 'OPEN'
+			GROUP BY puzzle_id
+		), rated_attempts AS (
+			SELECT puzzle_id,
+				MAX(player_rating) FILTER (WHERE solved) AS best_solver,
+				COUNT(*) FILTER (WHERE player_rating >= $1) AS strong_attempts
+			FROM puzzle_solve_log
+			WHERE player_rating IS NOT NULL
+			GROUP BY puzzle_id
+		)
+		SELECT p.id, p.cipher_type, p.difficulty, p.moderation_status, p.moderated_at,
+			COALESCE(p.moderation_note, ''), COALESCE(p.times_used, 0), COALESCE(p.times_solved, 0),
+			COALESCE(r.reports, 0), COALESCE(a.strong_attempts, 0), a.best_solver
+		FROM puzzles p
+		LEFT JOIN open_reports r ON r.puzzle_id = p.id
+		LEFT JOIN rated_attempts a ON a.puzzle_id = p.id
+		WHERE p.moderation_status <> 'RETIRED'
+			AND ($2 = '' OR p.moderation_status = $2)
+			AND (
+				p.moderation_status = 'QUARANTINED'
+				OR r.reports > 0
+				OR (a.strong_attempts >= $3 AND COALESCE(a.best_solver, 0) < $1)
+			)
+		ORDER BY p.moderation_status = 'QUARANTINED' DESC, COALESCE(r.reports, 0) DESC,
+			COALESCE(a.strong_attempts, 0) DESC, p.id
+		LIMIT $4
+	`
+	rows, err := s.db.QueryContext(ctx, query, minRating, req.Status, moderationMinStrongAttempts, limit)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	entries := []*ModerationEntry{}
+	byID := make(map[string]*ModerationEntry)
+	ids := []string{}
+	for rows.Next() {
+		entry := &ModerationEntry{Reasons: map[string]int{}}
+		var moderatedAt sql.NullTime
+		var bestSolver sql.NullInt64
+		err := rows.Scan(
+			&entry.PuzzleID,
+			&entry.CipherType,
+			&entry.Difficulty,
+			&entry.ModerationStatus,
+			&moderatedAt,
+			&entry.Note,
+			&entry.TimesUsed,
+			&entry.TimesSolved,
+			&entry.OpenReports,
+			&entry.StrongAttempts,
+			&bestSolver,
+		)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		if moderatedAt.Valid {
+			entry.ModeratedAt = &moderatedAt.Time
+		}
+		if bestSolver.Valid {
+			rating := int(bestSolver.Int64)
+			entry.BestSolverRating = &rating
+		}
+		entry.UnsolvedAboveRating = entry.StrongAttempts >= moderationMinStrongAttempts &&
+			(entry.BestSolverRating == nil || *entry.BestSolverRating < minRating)
+
+		entries = append(entries, entry)
+		byID[entry.PuzzleID] = entry
+		ids = append(ids, entry.PuzzleID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	reasonRows, err := s.db.QueryContext(ctx, `
+		SELECT puzzle_id, reason, COUNT(*)
+		FROM puzzle_reports
+		WHERE status = 'OPEN' AND puzzle_id = ANY($1::UUID[])
+		GROUP BY puzzle_id, reason
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer reasonRows.Close()
+
+	for reasonRows.Next() {
+		var puzzleID, reason string
+		var count int
+		if err := reasonRows.Scan(&puzzleID, &reason, &count); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		if entry, ok := byID[puzzleID]; ok {
+			entry.Reasons[reason] = count
+		}
+	}
+	if err := reasonRows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return entries, nil
+}
+
+// ReviewPuzzle applies a moderator's decision and closes the puzzle's open
+// reports: restoring dismisses them, fixing or retiring resolves them.
+func (s *PuzzleService) ReviewPuzzle(ctx context.Context, req *ReviewPuzzleRequest) (*ReviewResult, error) {
+	if _, err := uuid.Parse(req.PuzzleID); err != nil {
+		return nil, errors.NewInvalidInputError("A valid puzzle ID is required")
+	}
+
+	result := &ReviewResult{PuzzleID: req.PuzzleID, Action: req.Action}
+	var err error
+	switch req.Action {
+	case ReviewRestore:
+		result.ModerationStatus = ModerationOK
+		err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+			if err := setModerationStatus(ctx, tx, req.PuzzleID, ModerationOK, req.Note); err != nil {
+				return err
+			}
+			result.ReportsClosed, err = closeReports(ctx, tx, req.PuzzleID, "DISMISSED")
+			return err
+		})
+	case ReviewRetire:
+		result.ModerationStatus = ModerationRetired
+		err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+			if err := setModerationStatus(ctx, tx, req.PuzzleID, ModerationRetired, req.Note); err != nil {
+				return err
+			}
+			result.ReportsClosed, err = closeReports(ctx, tx, req.PuzzleID, "RESOLVED")
+			return err
+		})
+	case ReviewFix:
+		err = s.fixPuzzle(ctx, req, result)
+	default:
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Invalid review action: %s", req.Action))
+	}
+	if err == sql.ErrNoRows {
+		return nil, errors.NewPuzzleNotFoundError()
+	}
+	if err != nil {
+		if _, ok := err.(*errors.AppError); ok {
+			return nil, err
+		}
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	s.invalidatePuzzle(ctx, req.PuzzleID)
+	s.log.Info("Puzzle reviewed", map[string]interface{}{
+		"puzzle_id":         req.PuzzleID,
+		"action":            req.Action,
+		"moderation_status": result.ModerationStatus,
+		"reports_closed":    result.ReportsClosed,
+	})
+	return result, nil
+}
+
+// fixPuzzle corrects a puzzle's plaintext or difficulty and puts it back into
+// play. The puzzle's statistics describe the broken version, so they start
+// over along with its calibration.
+func (s *PuzzleService) fixPuzzle(ctx context.Context, req *ReviewPuzzleRequest, result *ReviewResult) error {
+	if req.Plaintext == "" && req.Difficulty == 0 {
+		return errors.NewInvalidInputError("A fix needs a corrected plaintext or difficulty")
+	}
+	if req.Difficulty != 0 && (req.Difficulty < 1 || req.Difficulty > 10) {
+		return errors.NewInvalidInputError("Difficulty must be between 1 and 10")
+	}
+
+	s.invalidatePuzzle(ctx, req.PuzzleID)
+	puzzle, err := s.getPuzzle(ctx, req.PuzzleID)
+	if err != nil {
+		return err
+	}
+
+	if req.Plaintext != "" {
+		err := s.db.QueryRowContext(ctx, `SELECT tags FROM puzzles WHERE id = $1`, puzzle.ID).Scan(pq.Array(&puzzle.Tags))
+		if err != nil {
+			return err
+		}
+		if err := s.replacePlaintext(puzzle, req.Plaintext, rng.NewSecure()); err != nil {
+			return err
+		}
+	}
+	if req.Difficulty != 0 {
+		puzzle.Difficulty = req.Difficulty
+	}
+
+	err = s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE puzzles
+			SET plaintext = $2, encrypted_text = $3, difficulty = $4, tags = $5,
+				empirical_difficulty = $6, solver_work = $7, calibrated_difficulty = NULL,
+				times_used = 0, times_solved = 0, success_rate = 0, avg_solve_time_ms = NULL
+			WHERE id = $1
+		`,
+			puzzle.ID,
+			puzzle.Plaintext,
+			puzzle.EncryptedText,
+			puzzle.Difficulty,
+			pq.Array(puzzle.Tags),
+			sql.NullFloat64{Float64: puzzle.EmpiricalDifficulty, Valid: puzzle.EmpiricalDifficulty > 0},
+			sql.NullInt64{Int64: int64(puzzle.SolverWork), Valid: puzzle.EmpiricalDifficulty > 0},
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM puzzle_solve_log WHERE puzzle_id = $1`, puzzle.ID); err != nil {
+			return err
+		}
+
+		// Stage hints describe the old text, so a chain's stages are rebuilt
+		if puzzle.CipherType == ciphers.TypeChain && req.Plaintext != "" {
+			_, err := tx.ExecContext(ctx, `
+				DELETE FROM puzzle_chains
+				WHERE id IN (SELECT chain_id FROM puzzle_stages WHERE puzzle_id = $1)
+			`, puzzle.ID)
+			if err != nil {
+				return err
+			}
+			if err := saveChainStages(ctx, tx, puzzle); err != nil {
+				return err
+			}
+		}
+
+		if err := setModerationStatus(ctx, tx, puzzle.ID, ModerationOK, req.Note); err != nil {
+			return err
+		}
+		result.ReportsClosed, err = closeReports(ctx, tx, puzzle.ID, "RESOLVED")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	result.ModerationStatus = ModerationOK
+	result.EncryptedText = puzzle.EncryptedText
+	result.Difficulty = puzzle.Difficulty
+	return nil
+}
+
+// replacePlaintext encrypts a corrected plaintext with the puzzle's existing
+// key, screening it like player-written text, and regrades the puzzle
+func (s *PuzzleService) replacePlaintext(puzzle *Puzzle, plaintext string, random *rand.Rand) error {
+	if moderation.ContainsProfanity(plaintext) {
+		return errors.NewInvalidInputError("Plaintext contains language that isn't allowed")
+	}
+	text := corpus.NewText("", plaintext, puzzle.Language, "")
+	if text.Letters == 0 {
+		return errors.NewInvalidInputError("Plaintext has no letters")
+	}
+
+	cipher := ciphers.GetCipher(puzzle.CipherType)
+	if cipher == nil {
+		return errors.NewInvalidInputError(fmt.Sprintf("Invalid cipher type: %s", puzzle.CipherType))
+	}
+	encryptedText, err := ciphers.CheckRoundTrip(cipher, text.Content, puzzle.Config)
+	if err != nil {
+		return errors.NewInvalidInputError(err.Error())
+	}
+
+	puzzle.Plaintext = text.Content
+	puzzle.EncryptedText = encryptedText
+	puzzle.EmpiricalDifficulty = 0
+	puzzle.SolverWork = 0
+	if puzzle.Language == corpus.DefaultLanguage {
+		grade := solver.GradePuzzle(puzzle.CipherType, encryptedText, text.Content, random)
+		if grade.Supported {
+			puzzle.EmpiricalDifficulty = grade.Difficulty
+			puzzle.SolverWork = grade.Work
+		}
+	}
+
+	// The letter count tag is the only one describing the text itself
+	tags := []string{}
+	for _, tag := range puzzle.Tags {
+		if !strings.HasPrefix(tag, "letters:") {
+			tags = append(tags, tag)
+		}
+	}
+	puzzle.Tags = append(tags, fmt.Sprintf("letters:%d", text.Letters))
+	return nil
+}
+
+// setModerationStatus moves a puzzle to a moderation status. Only OK puzzles
+// are active; retired puzzles also leave the pool for good.
+func setModerationStatus(ctx context.Context, tx *sql.Tx, puzzleID, status, note string) error {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE puzzles
+		SET moderation_status = $2,
+			is_active = $2 = 'OK',
+			in_pool = in_pool AND $2 <> 'RETIRED',
+			moderated_at = NOW(),
+			moderation_note = NULLIF($3, '')
+		WHERE id = $1
+	`, puzzleID, status, strings.TrimSpace(note))
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// closeReports closes a puzzle's open reports with the given status
+func closeReports(ctx context.Context, tx *sql.Tx, puzzleID, status string) (int, error) {
+	result, err := tx.ExecContext(ctx, `
+		UPDATE puzzle_reports
+		SET status = $2, resolved_at = NOW()
+		WHERE puzzle_id = $1 AND status = 'OPEN'
+	`, puzzleID, status)
+	if err != nil {
+		return 0, err
+	}
+	closed, _ := result.RowsAffected()
+	return int(closed), nil
+}
+
+// invalidatePuzzle drops the cached copy of a puzzle after it changed
+func (s *PuzzleService) invalidatePuzzle(ctx context.Context, puzzleID string) {
+	if err := s.cache.Delete(ctx, fmt.Sprintf("puzzle:%s", puzzleID)); err != nil {
+		s.log.Warn("Failed to invalidate cached puzzle", map[string]interface{}{
+			"puzzle_id": puzzleID,
+			"error":     err.Error(),
+		})
+	}
+}
+
+func isReportReason(reason string) bool {
+	switch reason {
+	case ReportWrongDecryption, ReportOffensive, ReportUnsolvable, ReportDuplicate, ReportOther:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/corpus"
+)
+
+// errPuzzleNotVetted is returned when a pool candidate fails solver grading
+var errPuzzleNotVetted = errors.New("puzzle failed solver grading")
+
+// Difficulty calibration: players are expected to solve 95% of difficulty 1
+// puzzles, 8 points fewer per level. Observed success rates imply a
+// difficulty on that scale, blended with the nominal difficulty so that a
+// handful of attempts can't swing a puzzle far.
+const (
+	calibrationEasiestSuccess = 0.95
+	calibrationSuccessStep    = 0.08
+	calibrationPriorWeight    = 20 // Attempts the nominal difficulty counts as
+)
+
+// PoolConfig controls how the puzzle pool is stocked
+type PoolConfig struct {
+	TargetPerBucket        int           // Puzzles kept per cipher type and difficulty
+	MaxPerRefill           int           // Generation attempts per refill, vetted or not; bounds CPU spent per cycle
+	RefillInterval         time.Duration // How often the pool is topped up and recalibrated
+	CalibrationMinAttempts int           // Attempts before a puzzle's success rate is trusted
+}
+
+// DefaultPoolConfig returns the production pool settings
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		TargetPerBucket:        20,
+		MaxPerRefill:           200,
+		RefillInterval:         5 * time.Minute,
+		CalibrationMinAttempts: 10,
+	}
+}
+
+// PoolBuilder keeps a stock of vetted puzzles per cipher type and difficulty
+// and feeds observed success rates back into their calibrated difficulty
+type PoolBuilder struct {
+	puzzleService *PuzzleService
+	cfg           PoolConfig
+	log           *logger.Logger
+}
+
+// NewPoolBuilder creates a new pool builder
+func NewPoolBuilder(puzzleService *PuzzleService, cfg PoolConfig, log *logger.Logger) *PoolBuilder {
+	return &PoolBuilder{
+		puzzleService: puzzleService,
+		cfg:           cfg,
+		log:           log,
+	}
+}
+
+// Run refills and recalibrates the pool until the context is cancelled
+func (b *PoolBuilder) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.cfg.RefillInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.Calibrate(ctx); err != nil {
+			b.log.Error("Failed to calibrate puzzle pool", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		if err := b.Refill(ctx); err != nil {
+			b.log.Error("Failed to refill puzzle pool", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refill generates puzzles for every bucket below its target stock
+func (b *PoolBuilder) Refill(ctx context.Context) error {
+	stock, err := b.stock(ctx)
+	if err != nil {
+		return err
+	}
+
+	generated, rejected := 0, 0
+	for difficulty := 1; difficulty <= 10; difficulty++ {
+		for _, cipherType := range availableCipherTypes(difficulty, corpus.DefaultLanguage) {
+			missing := b.cfg.TargetPerBucket - stock[poolBucket(cipherType, difficulty)]
+			for i := 0; i < missing; i++ {
+				// Rejected candidates went through solver grading too, so
+				// they count against the cap
+				if generated+rejected >= b.cfg.MaxPerRefill || ctx.Err() != nil {
+					b.logRefill(generated, rejected)
+					return ctx.Err()
+				}
+
+				_, err := b.puzzleService.createPuzzle(ctx, &GeneratePuzzleRequest{
+					CipherType: cipherType,
+					Difficulty: difficulty,
+				}, difficulty, true)
+				if errors.Is(err, errPuzzleNotVetted) {
+					rejected++
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				generated++
+			}
+		}
+	}
+
+	b.logRefill(generated, rejected)
+	return nil
+}
+
+// Calibrate moves each well-played pool puzzle's calibrated difficulty
+// towards the difficulty implied by its observed success rate
+func (b *PoolBuilder) Calibrate(ctx context.Context) error {
+	query := `
+		UPDATE puzzles
+		SET calibrated_difficulty = (
+			difficulty * $2 +
+			LEAST(10, GREATEST(1, 1 + ($3 - times_solved::FLOAT / times_used) / $4)) * times_used
+		) / ($2 + times_used)
+		WHERE in_pool = TRUE AND times_used >= $1
+	`
+	result, err := b.puzzleService.db.ExecContext(ctx, query,
+		b.cfg.CalibrationMinAttempts,
+		calibrationPriorWeight,
+		calibrationEasiestSuccess,
+		calibrationSuccessStep,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, _ := result.RowsAffected()
+	b.log.Debug("Puzzle pool calibrated", map[string]interface{}{
+		"puzzles": updated,
+	})
+	return nil
+}
+
+func (b *PoolBuilder) stock(ctx context.Context) (map[string]int, error) {
+	query := `
+		SELECT cipher_type, difficulty, COUNT(*)
+		FROM puzzles
+		WHERE in_pool = TRUE AND is_active = TRUE
+		GROUP BY cipher_type, difficulty
+	`
+	rows, err := b.puzzleService.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := make(map[string]int)
+	for rows.Next() {
+		var cipherType string
+		var difficulty, count int
+		if err := rows.Scan(&cipherType, &difficulty, &count); err != nil {
+			return nil, err
+		}
+		stock[poolBucket(cipherType, difficulty)] = count
+	}
+	return stock, rows.Err()
+}
+
+func (b *PoolBuilder) logRefill(generated, rejected int) {
+	if generated == 0 && rejected == 0 {
+		return
+	}
+	b.log.Info("Puzzle pool refilled", map[string]interface{}{
+		"generated": generated,
+		"rejected":  rejected,
+	})
+}
+
+// drawFromPool picks a pool puzzle matching the request by calibrated
+// difficulty that the user hasn't seen yet. It returns nil when the pool has
+// nothing suitable.
+func (s *PuzzleService) drawFromPool(ctx context.Context, req *GeneratePuzzleRequest, difficulty int) (*Puzzle, error) {
+	language := req.Language
+	if language == "" {
+		language = corpus.DefaultLanguage
+	}
+	tags := []string{"lang:" + language}
+	if req.Theme != "" {
+		tags = append(tags, "theme:"+req.Theme)
+	}
+
+	userID := sql.NullString{String: req.UserID, Valid: req.UserID != ""}
+
+	query := `
+		SELECT p.id
+		FROM puzzles p
+		WHERE p.in_pool = TRUE AND p.is_active = TRUE
+			AND ($1 = '' OR p.cipher_type = $1)
+			AND ROUND(COALESCE(p.calibrated_difficulty, p.difficulty)) = $2
+			AND p.tags @> $3
+			AND ($4::UUID IS NULL OR NOT EXISTS (
+				SELECT 1 FROM user_seen_puzzles s
+				WHERE s.user_id = $4::UUID AND s.puzzle_id = p.id
+			))
+		ORDER BY random()
+		LIMIT 1
+	`
+	var puzzleID string
+	err := s.db.QueryRowContext(ctx, query, req.CipherType, difficulty, pq.Array(tags), userID).Scan(&puzzleID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		_, err := s.db.ExecContext(ctx, `
+			INSERT INTO user_seen_puzzles (user_id, puzzle_id)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		`, userID.String, puzzleID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.log.Info("Puzzle served from pool", map[string]interface{}{
+		"puzzle_id":  puzzleID,
+		"difficulty": difficulty,
+	})
+
+	return s.GetPuzzle(ctx, puzzleID)
+}
+
+func poolBucket(cipherType string, difficulty int) string {
+	return fmt.Sprintf("%s:%d", cipherType, difficulty)
+}
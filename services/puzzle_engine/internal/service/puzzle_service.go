@@ -2,43 +2,78 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/pkg/grading"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/ciphers"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/corpus"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/hints"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/rng"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/solver"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/toolkit"
 )
 
 // PuzzleService handles puzzle generation and validation
 type PuzzleService struct {
-	db    *db.DB
-	cache *cache.Cache
-	log   *logger.Logger
+	db         *db.DB
+	cache      *cache.Cache
+	corpus     *corpus.Corpus
+	seedSecret string // Keeps daily puzzle seeds unguessable
+	thresholds grading.Thresholds
+	toolkit    toolkit.Policy // Cryptanalysis tools allowed per game mode
+	log        *logger.Logger
 }
 
 // NewPuzzleService creates a new puzzle service
-func NewPuzzleService(database *db.DB, cacheClient *cache.Cache, log *logger.Logger) *PuzzleService {
+func NewPuzzleService(database *db.DB, cacheClient *cache.Cache, textCorpus *corpus.Corpus, seedSecret string, thresholds grading.Thresholds, toolkitPolicy toolkit.Policy, log *logger.Logger) *PuzzleService {
 	return &PuzzleService{
-		db:    database,
-		cache: cacheClient,
-		log:   log,
+		db:         database,
+		cache:      cacheClient,
+		corpus:     textCorpus,
+		seedSecret: seedSecret,
+		thresholds: thresholds,
+		toolkit:    toolkitPolicy,
+		log:        log,
 	}
 }
 
 // Puzzle represents a puzzle
 type Puzzle struct {
-	ID            string                 `json:"id"`
-	CipherType    string                 `json:"cipher_type"`
-	Difficulty    int                    `json:"difficulty"`
-	EncryptedText string                 `json:"encrypted_text"`
-	Plaintext     string                 `json:"plaintext,omitempty"` // Only for server-side
-	Config        map[string]interface{} `json:"config,omitempty"`
-	Hint          string                 `json:"hint,omitempty"`
+	ID             string                 `json:"id"`
+	CipherType     string                 `json:"cipher_type"`
+	Difficulty     int                    `json:"difficulty"`
+	EncryptedText  string                 `json:"encrypted_text"`
+	Plaintext      string                 `json:"plaintext,omitempty"` // Only for server-side
+	Language       string                 `json:"language"`
+	Config         map[string]interface{} `json:"config,omitempty"`
+	Hint           string                 `json:"hint,omitempty"`
+	Layers         int                    `json:"layers,omitempty"` // Number of layers for CHAIN puzzles
+	HintsAvailable int                    `json:"hints_available"`  // Rungs on the hint ladder, served by RequestHint
+	Tags           []string               `json:"-"`                // Corpus tags of the plaintext, stored on puzzles.tags
+
+	// Solver grading (zero for cipher types without a solver)
+	EmpiricalDifficulty float64 `json:"empirical_difficulty,omitempty"`
+	SolverWork          int     `json:"-"`
+
+	// Seed reproduces the puzzle exactly; nil for unseeded puzzles. Never sent
+	// to clients since it reveals the key.
+	Seed *int64 `json:"-"`
+
+	// Pooled puzzles were pre-generated by the pool builder and are reused
+	// across players
+	Pooled bool `json:"-"`
 }
 
 // GeneratePuzzleRequest represents puzzle generation input
@@ -46,6 +81,16 @@ type GeneratePuzzleRequest struct {
 	CipherType string `json:"cipher_type"` // Empty for random
 	Difficulty int    `json:"difficulty"`  // 1-10
 	PlayerELO  int    `json:"player_elo"`  // For auto-difficulty
+	UserID     string `json:"user_id"`     // Optional, avoids repeating recently seen plaintexts
+	Theme      string `json:"theme"`       // Optional corpus theme
+	Language   string `json:"language"`    // Optional, defaults to English; see ciphers.AlphabetFor
+	Seed       *int64 `json:"seed"`        // Optional, makes generation reproducible. Services only, see PuzzleHandler.GeneratePuzzle
+
+	// Set by match generation: the other players whose recent plaintexts are
+	// avoided too, and a letter count range overriding the cipher's own
+	Opponents  []string `json:"-"`
+	MinLetters int      `json:"-"`
+	MaxLetters int      `json:"-"`
 }
 
 // ValidateSolutionRequest represents solution validation input
@@ -53,34 +98,67 @@ type ValidateSolutionRequest struct {
 	PuzzleID  string `json:"puzzle_id"`
 	Solution  string `json:"solution"`
 	SolveTime int    `json:"solve_time_ms"`
+	Stage     int    `json:"stage,omitempty"`   // CHAIN only: number of layers peeled (0 = final plaintext)
+	UserID    string `json:"user_id,omitempty"` // Whose attempt this is, for the penalty for hints they took
+	Mode      string `json:"mode,omitempty"`    // Game mode, selects the pass threshold. Services only.
+
+	// RevealAnswer returns the expected text with the result, and
+	// IncludeFeedback the per-character and per-word alignment with it. Only
+	// internal callers may set them, see PuzzleHandler.ValidateSolution.
+	RevealAnswer    bool `json:"reveal_answer,omitempty"`
+	IncludeFeedback bool `json:"include_feedback,omitempty"`
 }
 
 // ValidateSolutionResponse represents validation result
 type ValidateSolutionResponse struct {
-	IsCorrect bool    `json:"is_correct"`
-	Score     int     `json:"score"`
-	Accuracy  float64 `json:"accuracy"`
+	IsCorrect       bool    `json:"is_correct"`
+	Score           int     `json:"score"`
+	Accuracy        float64 `json:"accuracy"`
+	LayersRemaining int     `json:"layers_remaining,omitempty"`
+	HintsUsed       int     `json:"hints_used"`
+
+	Feedback      *grading.Result `json:"feedback,omitempty"`       // Only for IncludeFeedback requests
+	CorrectAnswer string          `json:"correct_answer,omitempty"` // Only for RevealAnswer requests
 }
 
-// Sample plaintexts for puzzle generation
-var sampleTexts = []string{
-	"THE QUICK BROWN FOX JUMPS OVER THE LAZY DOG",
-	"CRYPTOGRAPHY IS THE ART OF SECURE COMMUNICATION",
-	"HELLO WORLD THIS IS A SECRET MESSAGE",
-	"NEVER GONNA GIVE YOU UP NEVER GONNA LET YOU DOWN",
-	"TO BE OR NOT TO BE THAT IS THE QUESTION",
-	"ALL YOUR BASE ARE BELONG TO US",
-	"THE CAKE IS A LIE",
-	"MAY THE FORCE BE WITH YOU",
-	"I SEE DEAD PEOPLE",
-	"SHOW ME THE MONEY",
-	"WINTER IS COMING",
-	"I AM YOUR FATHER",
-	"ELEMENTARY MY DEAR WATSON",
-	"HOUSTON WE HAVE A PROBLEM",
-	"ET PHONE HOME",
+// ChainHint describes one layer of a CHAIN puzzle, stored as its puzzle_stages
+// hint text. Players unlock the same descriptions, with a key fragment, as
+// layer rungs of the hint ladder (RequestHint).
+type ChainHint struct {
+	Stage      int    `json:"stage"` // 1 = outermost layer
+	CipherType string `json:"-"`
+	Hint       string `json:"hint"`
 }
 
+// dailyPuzzleTTL outlives the day so late timezones still find the puzzle cached
+const dailyPuzzleTTL = 48 * time.Hour
+
+// chainMinDifficulty is the lowest difficulty at which random selection may
+// produce a multi-layer puzzle
+const chainMinDifficulty = 7
+
+// recentTextLimit is how many plaintexts per user are remembered to avoid repeats
+const recentTextLimit = 50
+
+const (
+	// validateAttemptLimit caps the submissions one user may grade against one
+	// puzzle per validateAttemptWindow, so accuracy can't be used to search
+	// for the answer
+	validateAttemptLimit  = 30
+	validateAttemptWindow = time.Hour
+)
+
+const (
+	// maxGradingAttempts is how many candidates are generated before settling
+	// for the one whose empirical difficulty is closest to the nominal one
+	maxGradingAttempts = 3
+	// gradeTolerance is how far below its nominal difficulty a solved puzzle may grade
+	gradeTolerance = 4.0
+	// unsolvableCeiling is the highest nominal difficulty at which a puzzle the
+	// solvers can't break is rejected as accidentally impossible
+	unsolvableCeiling = 5
+)
+
 // GeneratePuzzle creates a new puzzle
 func (s *PuzzleService) GeneratePuzzle(ctx context.Context, req *GeneratePuzzleRequest) (*Puzzle, error) {
 	// Auto-adjust difficulty based on ELO if difficulty is 0
@@ -94,12 +172,51 @@ func (s *PuzzleService) GeneratePuzzle(ctx context.Context, req *GeneratePuzzleR
 	if difficulty > 10 {
 		difficulty = 10
 	}
+	if _, ok := ciphers.AlphabetFor(req.Language); !ok {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("Unsupported language: %s", req.Language))
+	}
+
+	// Unseeded requests are served from the vetted pool when it has a puzzle
+	// the user hasn't seen; seeded ones must be generated to be reproducible
+	if req.Seed == nil {
+		puzzle, err := s.drawFromPool(ctx, req, difficulty)
+		if err != nil {
+			s.log.Warn("Failed to draw from puzzle pool", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+		if puzzle != nil {
+			return puzzle, nil
+		}
+	}
+
+	puzzle, err := s.createPuzzle(ctx, req, difficulty, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return puzzle without plaintext for client
+	clientPuzzle := *puzzle
+	clientPuzzle.Plaintext = "" // Don't send plaintext to client!
+	clientPuzzle.Config = nil   // Don't send config to client!
+
+	return &clientPuzzle, nil
+}
+
+// createPuzzle generates, saves and caches a new puzzle. Pool puzzles must
+// pass solver grading and be saved, otherwise errPuzzleNotVetted or the save
+// error is returned.
+func (s *PuzzleService) createPuzzle(ctx context.Context, req *GeneratePuzzleRequest, difficulty int, pooled bool) (*Puzzle, error) {
+	// Seeded requests are reproducible; everything else draws from crypto/rand
+	random := rng.NewSecure()
+	if req.Seed != nil {
+		random = rng.New(*req.Seed)
+	}
 
 	// Select cipher type (random if not specified)
 	cipherType := req.CipherType
 	if cipherType == "" {
-		allTypes := ciphers.GetAllCipherTypes()
-		cipherType = allTypes[rand.Intn(len(allTypes))]
+		cipherType = selectCipherType(difficulty, req.Language, random)
 	}
 
 	// Get cipher implementation
@@ -107,32 +224,42 @@ func (s *PuzzleService) GeneratePuzzle(ctx context.Context, req *GeneratePuzzleR
 	if cipher == nil {
 		return nil, errors.NewInvalidInputError(fmt.Sprintf("Invalid cipher type: %s", cipherType))
 	}
+	if !ciphers.SupportsLanguage(cipherType, req.Language) {
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("%s is not available in language %s", cipherType, req.Language))
+	}
 
-	// Generate key based on difficulty
-	config := cipher.GenerateKey(difficulty)
-
-	// Select random plaintext
-	plaintext := sampleTexts[rand.Intn(len(sampleTexts))]
+	// Generate candidates until the solvers agree one fits its difficulty
+	var puzzle *Puzzle
+	var text *corpus.Text
+	for attempt := 0; attempt < maxGradingAttempts; attempt++ {
+		candidate, candidateText, err := s.buildCandidate(ctx, req, cipher, cipherType, difficulty, random)
+		if err != nil {
+			return nil, err
+		}
 
-	// Encrypt
-	encryptedText, err := cipher.Encrypt(plaintext, config)
-	if err != nil {
-		return nil, errors.NewInternalServerError(err)
+		if puzzle == nil || gradeDeviation(candidate) < gradeDeviation(puzzle) {
+			puzzle, text = candidate, candidateText
+		}
+		if gradeAcceptable(candidate) {
+			break
+		}
 	}
 
-	// Create puzzle
-	puzzleID := uuid.New().String()
-	puzzle := &Puzzle{
-		ID:            puzzleID,
-		CipherType:    cipherType,
-		Difficulty:    difficulty,
-		EncryptedText: encryptedText,
-		Plaintext:     plaintext,
-		Config:        config,
+	if pooled && !gradeAcceptable(puzzle) {
+		return nil, errPuzzleNotVetted
 	}
 
+	puzzleID := puzzle.ID
+	puzzle.Seed = req.Seed
+	puzzle.Pooled = pooled
+	puzzle.HintsAvailable = hints.Count(puzzle.CipherType, puzzle.Config)
+	applyIntroHint(puzzle)
+
 	// Save to database
 	if err := s.savePuzzle(ctx, puzzle); err != nil {
+		if pooled {
+			return nil, err
+		}
 		s.log.Error("Failed to save puzzle", map[string]interface{}{
 			"error": err.Error(),
 		})
@@ -143,66 +270,64 @@ func (s *PuzzleService) GeneratePuzzle(ctx context.Context, req *GeneratePuzzleR
 	cacheKey := fmt.Sprintf("puzzle:%s", puzzleID)
 	s.cache.Set(ctx, cacheKey, puzzle, cache.TTLPuzzle)
 
+	s.rememberText(ctx, req.UserID, text.ID)
+	for _, opponent := range req.Opponents {
+		s.rememberText(ctx, opponent, text.ID)
+	}
+
 	s.log.Info("Puzzle generated", map[string]interface{}{
-		"puzzle_id":   puzzleID,
-		"cipher_type": cipherType,
-		"difficulty":  difficulty,
+		"puzzle_id":            puzzleID,
+		"cipher_type":          cipherType,
+		"difficulty":           difficulty,
+		"text_id":              text.ID,
+		"empirical_difficulty": puzzle.EmpiricalDifficulty,
+		"pooled":               pooled,
 	})
 
-	// Return puzzle without plaintext for client
-	clientPuzzle := *puzzle
-	clientPuzzle.Plaintext = "" // Don't send plaintext to client!
-	clientPuzzle.Config = nil   // Don't send config to client!
-
-	return &clientPuzzle, nil
-}
-
-// GenerateMultiplePuzzles creates multiple puzzles for a match
-func (s *PuzzleService) GenerateMultiplePuzzles(ctx context.Context, count, minDiff, maxDiff, avgELO int, cipherTypes []string) ([]*Puzzle, error) {
-	puzzles := make([]*Puzzle, 0, count)
-
-	for i := 0; i < count; i++ {
-		// Progressive difficulty
-		difficulty := minDiff + ((maxDiff - minDiff) * i / count)
-
-		// Select cipher type
-		cipherType := ""
-		if len(cipherTypes) > 0 {
-			cipherType = cipherTypes[rand.Intn(len(cipherTypes))]
-		}
-
-		puzzle, err := s.GeneratePuzzle(ctx, &GeneratePuzzleRequest{
-			CipherType: cipherType,
-			Difficulty: difficulty,
-			PlayerELO:  avgELO,
-		})
-		if err != nil {
-			return nil, err
-		}
-
-		puzzles = append(puzzles, puzzle)
-	}
-
-	return puzzles, nil
+	return puzzle, nil
 }
 
 // ValidateSolution validates a puzzle solution
 func (s *PuzzleService) ValidateSolution(ctx context.Context, req *ValidateSolutionRequest) (*ValidateSolutionResponse, error) {
+	if req.UserID == "" {
+		return nil, errors.NewInvalidInputError("User ID is required")
+	}
+
 	// Get puzzle from cache or database
 	puzzle, err := s.getPuzzle(ctx, req.PuzzleID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Compare solutions (case-insensitive, trimmed)
-	submittedSolution := normalizeText(req.Solution)
-	correctSolution := normalizeText(puzzle.Plaintext)
+	key := fmt.Sprintf("puzzle:validate:%s:%s", req.PuzzleID, req.UserID)
+	allowed, err := s.cache.RateLimitCheck(ctx, key, validateAttemptLimit, validateAttemptWindow)
+	if err != nil {
+		s.log.Error("Rate limit check failed", map[string]interface{}{"error": err.Error()})
+	}
+	if !allowed {
+		return nil, errors.NewRateLimitError()
+	}
 
-	isCorrect := submittedSolution == correctSolution
+	// CHAIN puzzles can be checked layer by layer
+	expected := puzzle.Plaintext
+	layersRemaining := 0
+	if req.Stage > 0 {
+		expected, layersRemaining, err = chainStageText(puzzle, req.Stage)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// Calculate score based on difficulty and solve time
+	// Align the solution with the answer, ignoring case and whitespace. Accents
+	// the alphabet folds away in plaintexts are folded in the solution too.
+	solution := ciphers.AlphabetOf(puzzle.Config).Fold(req.Solution)
+	result := grading.Grade(solution, expected, s.thresholds.For(req.Mode))
+	isCorrect := result.Passed
+
+	// Calculate score based on difficulty and solve time, scaled down for
+	// partial credit. Intermediate layers of a chain are checkpoints and don't score
 	score := 0
-	if isCorrect {
+	if isCorrect && layersRemaining == 0 {
 		baseScore := 100 * puzzle.Difficulty
 		// Bonus for fast solving (max 2x multiplier)
 		timeBonus := 1.0
@@ -211,27 +336,45 @@ func (s *PuzzleService) ValidateSolution(ctx context.Context, req *ValidateSolut
 		} else if req.SolveTime < 60000 { // Under 1 minute
 			timeBonus = 1.5
 		}
-		score = int(float64(baseScore) * timeBonus)
+		score = int(float64(baseScore) * timeBonus * result.Accuracy)
 	}
 
-	// Calculate accuracy (simple character match percentage)
-	accuracy := calculateAccuracy(submittedSolution, correctSolution)
+	// Hint usage comes from the server-side record, never from the client
+	hintsUsed, err := s.hintsUsed(ctx, req.PuzzleID, req.UserID)
+	if err != nil {
+		return nil, errors.NewInternalServerError(err)
+	}
+	score = applyHintPenalty(score, hintsUsed)
 
 	// Update puzzle statistics
-	go s.updatePuzzleStats(context.Background(), req.PuzzleID, isCorrect, req.SolveTime)
+	if layersRemaining == 0 {
+		go s.updatePuzzleStats(context.Background(), req.PuzzleID, req.UserID, isCorrect, req.SolveTime)
+	}
 
 	s.log.Info("Solution validated", map[string]interface{}{
 		"puzzle_id":  req.PuzzleID,
 		"is_correct": isCorrect,
 		"score":      score,
 		"solve_time": req.SolveTime,
+		"stage":      req.Stage,
+		"hints_used": hintsUsed,
 	})
 
-	return &ValidateSolutionResponse{
-		IsCorrect: isCorrect,
-		Score:     score,
-		Accuracy:  accuracy,
-	}, nil
+	response := &ValidateSolutionResponse{
+		IsCorrect:       isCorrect,
+		Score:           score,
+		Accuracy:        result.Accuracy,
+		LayersRemaining: layersRemaining,
+		HintsUsed:       hintsUsed,
+	}
+	if req.IncludeFeedback {
+		response.Feedback = result
+	}
+	if req.RevealAnswer {
+		response.CorrectAnswer = expected
+	}
+
+	return response, nil
 }
 
 // GetPuzzle retrieves a puzzle by ID
@@ -249,8 +392,93 @@ func (s *PuzzleService) GetPuzzle(ctx context.Context, puzzleID string) (*Puzzle
 	return &clientPuzzle, nil
 }
 
+// GetDailyPuzzle returns the daily puzzle for a UTC date. It is generated from
+// a seed derived from the date, so every instance serves the same puzzle even
+// if the cached copy is lost.
+func (s *PuzzleService) GetDailyPuzzle(ctx context.Context, date time.Time) (*Puzzle, error) {
+	day := date.UTC().Format("2006-01-02")
+	cacheKey := fmt.Sprintf("puzzle:daily:%s", day)
+
+	var puzzleID string
+	if err := s.cache.Get(ctx, cacheKey, &puzzleID); err == nil {
+		if puzzle, err := s.GetPuzzle(ctx, puzzleID); err == nil {
+			return puzzle, nil
+		}
+	}
+
+	seed := rng.DailySeed(s.seedSecret, date)
+	puzzle, err := s.GeneratePuzzle(ctx, &GeneratePuzzleRequest{
+		Difficulty: dailyDifficulty(date),
+		Seed:       &seed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Concurrent first requests generate identical puzzles; keep whichever
+	// registered first so everyone shares one puzzle ID
+	if ok, err := s.cache.SetNX(ctx, cacheKey, puzzle.ID, dailyPuzzleTTL); err == nil && !ok {
+		if err := s.cache.Get(ctx, cacheKey, &puzzleID); err == nil {
+			if existing, err := s.GetPuzzle(ctx, puzzleID); err == nil {
+				return existing, nil
+			}
+		}
+	}
+
+	return puzzle, nil
+}
+
+// dailyDifficulty ramps from 3 on Monday to 9 on Sunday
+func dailyDifficulty(date time.Time) int {
+	weekday := int(date.UTC().Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	return weekday + 2
+}
+
 // Helper functions
 
+func chainIntroHint(layers int) string {
+	return fmt.Sprintf("This message is protected by %d layers of encryption. Peel them from the outside in.", layers)
+}
+
+// applyIntroHint sets the hint a puzzle is shown with from the start: the
+// layer count of a chain, or the public key of textbook RSA, which can't be
+// attempted without it
+func applyIntroHint(puzzle *Puzzle) {
+	switch puzzle.CipherType {
+	case ciphers.TypeChain:
+		if layers, err := ciphers.ParseChainLayers(puzzle.Config); err == nil {
+			puzzle.Layers = len(layers)
+			puzzle.Hint = chainIntroHint(puzzle.Layers)
+		}
+	case ciphers.TypeRSATextbook:
+		puzzle.Hint = ciphers.PublicKeyHint(puzzle.Config)
+	}
+}
+
+// selectCipherType picks a random cipher, mixing in multi-layer chains at high difficulty
+func selectCipherType(difficulty int, language string, random *rand.Rand) string {
+	allTypes := availableCipherTypes(difficulty, language)
+	return allTypes[random.Intn(len(allTypes))]
+}
+
+// availableCipherTypes lists the cipher types used at a difficulty for texts
+// in a language
+func availableCipherTypes(difficulty int, language string) []string {
+	var types []string
+	for _, cipherType := range ciphers.GetAllCipherTypes() {
+		if ciphers.SupportsLanguage(cipherType, language) {
+			types = append(types, cipherType)
+		}
+	}
+	if difficulty >= chainMinDifficulty {
+		types = append(types, ciphers.TypeChain)
+	}
+	return types
+}
+
 func (s *PuzzleService) calculateDifficultyFromELO(elo int) int {
 	// ELO to difficulty mapping
 	// 1200 (starting) -> difficulty 3
@@ -267,25 +495,235 @@ func (s *PuzzleService) calculateDifficultyFromELO(elo int) int {
 	return difficulty
 }
 
+// buildCandidate generates, encrypts and grades one puzzle candidate
+func (s *PuzzleService) buildCandidate(ctx context.Context, req *GeneratePuzzleRequest, cipher ciphers.Cipher, cipherType string, difficulty int, random *rand.Rand) (*Puzzle, *corpus.Text, error) {
+	// Generate key based on difficulty, over the alphabet of the language
+	config := ciphers.GenerateKeyFor(cipher, difficulty, req.Language, random)
+
+	// Select a plaintext suited to the cipher and difficulty
+	text, err := s.selectText(ctx, req, cipherType, difficulty, random)
+	if err != nil {
+		return nil, nil, errors.NewInternalServerError(err)
+	}
+
+	// Encrypt
+	encryptedText, err := cipher.Encrypt(text.Content, config)
+	if err != nil {
+		return nil, nil, errors.NewInternalServerError(err)
+	}
+
+	// The solvers score candidates by English letter statistics
+	var grade solver.Grade
+	if text.Language == corpus.DefaultLanguage {
+		grade = solver.GradePuzzle(cipherType, encryptedText, text.Content, random)
+	}
+
+	puzzle := &Puzzle{
+		ID:            uuid.New().String(),
+		CipherType:    cipherType,
+		Difficulty:    difficulty,
+		EncryptedText: encryptedText,
+		Plaintext:     text.Content,
+		Language:      text.Language,
+		Config:        config,
+		Tags:          text.Tags(),
+	}
+	if grade.Supported {
+		puzzle.EmpiricalDifficulty = grade.Difficulty
+		puzzle.SolverWork = grade.Work
+	}
+	return puzzle, text, nil
+}
+
+// gradeAcceptable rejects puzzles the solvers break far too easily for their
+// nominal difficulty, and easy puzzles they can't break at all
+func gradeAcceptable(puzzle *Puzzle) bool {
+	if puzzle.EmpiricalDifficulty == 0 {
+		return true // No solver for this cipher
+	}
+	if puzzle.EmpiricalDifficulty >= solver.MaxDifficulty {
+		return puzzle.Difficulty > unsolvableCeiling
+	}
+	return puzzle.EmpiricalDifficulty >= float64(puzzle.Difficulty)-gradeTolerance
+}
+
+func gradeDeviation(puzzle *Puzzle) float64 {
+	if puzzle.EmpiricalDifficulty == 0 {
+		return 0
+	}
+	return math.Abs(puzzle.EmpiricalDifficulty - float64(puzzle.Difficulty))
+}
+
+// selectText picks a corpus text for the puzzle, skipping ones the user has
+// seen recently. Seeded puzzles must come out the same for everyone, so they
+// ignore the user's history.
+func (s *PuzzleService) selectText(ctx context.Context, req *GeneratePuzzleRequest, cipherType string, difficulty int, random *rand.Rand) (*corpus.Text, error) {
+	exclude := make(map[string]bool)
+	if req.Seed == nil {
+		for _, userID := range append([]string{req.UserID}, req.Opponents...) {
+			for _, id := range s.recentTexts(ctx, userID) {
+				exclude[id] = true
+			}
+		}
+	}
+
+	return s.corpus.Select(corpus.Criteria{
+		CipherType: cipherType,
+		Difficulty: difficulty,
+		Language:   req.Language,
+		Theme:      req.Theme,
+		Exclude:    exclude,
+		MinLetters: req.MinLetters,
+		MaxLetters: req.MaxLetters,
+	}, random)
+}
+
+func (s *PuzzleService) recentTexts(ctx context.Context, userID string) []string {
+	if userID == "" {
+		return nil
+	}
+
+	var recent []string
+	if err := s.cache.Get(ctx, fmt.Sprintf("puzzle:recent_texts:%s", userID), &recent); err != nil {
+		return nil
+	}
+	return recent
+}
+
+// rememberText records a text as seen by the user for the rest of the session
+func (s *PuzzleService) rememberText(ctx context.Context, userID, textID string) {
+	if userID == "" {
+		return
+	}
+
+	recent := append(s.recentTexts(ctx, userID), textID)
+	if len(recent) > recentTextLimit {
+		recent = recent[len(recent)-recentTextLimit:]
+	}
+
+	if err := s.cache.Set(ctx, fmt.Sprintf("puzzle:recent_texts:%s", userID), recent, cache.TTLSession); err != nil {
+		s.log.Warn("Failed to record recent text", map[string]interface{}{
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+	}
+}
+
 func (s *PuzzleService) savePuzzle(ctx context.Context, puzzle *Puzzle) error {
+	return s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		return insertPuzzle(ctx, tx, puzzle)
+	})
+}
+
+// insertPuzzle writes a puzzle, and the stages of a CHAIN puzzle, within tx
+func insertPuzzle(ctx context.Context, tx *sql.Tx, puzzle *Puzzle) error {
 	configJSON, err := json.Marshal(puzzle.Config)
 	if err != nil {
 		return err
 	}
 
 	query := `
-		INSERT INTO puzzles (id, cipher_type, difficulty, encrypted_text, plaintext, config)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO puzzles (id, cipher_type, difficulty, encrypted_text, plaintext, config, tags,
+			empirical_difficulty, solver_work, generation_seed, in_pool)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
-	_, err = s.db.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		puzzle.ID,
 		puzzle.CipherType,
 		puzzle.Difficulty,
 		puzzle.EncryptedText,
 		puzzle.Plaintext,
 		configJSON,
+		pq.Array(puzzle.Tags),
+		sql.NullFloat64{Float64: puzzle.EmpiricalDifficulty, Valid: puzzle.EmpiricalDifficulty > 0},
+		sql.NullInt64{Int64: int64(puzzle.SolverWork), Valid: puzzle.EmpiricalDifficulty > 0},
+		puzzle.Seed,
+		puzzle.Pooled,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if puzzle.CipherType == ciphers.TypeChain {
+		return saveChainStages(ctx, tx, puzzle)
+	}
+	return nil
+}
+
+// saveChainStages records a CHAIN puzzle's layers as a puzzle_chains row with
+// one puzzle_stages row per layer, outermost layer first
+func saveChainStages(ctx context.Context, tx *sql.Tx, puzzle *Puzzle) error {
+	hints, err := buildChainHints(puzzle)
+	if err != nil {
+		return err
+	}
+
+	layerTypes := make([]string, len(hints))
+	for i, hint := range hints {
+		layerTypes[len(hints)-1-i] = hint.CipherType
+	}
+
+	var chainID string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO puzzle_chains (name, description, total_stages, difficulty, category)
+		VALUES ($1, $2, $3, $4, 'layered')
+		RETURNING id
+	`,
+		fmt.Sprintf("Layered cipher (%d layers)", len(hints)),
+		strings.Join(layerTypes, " -> "),
+		len(hints),
+		puzzle.Difficulty,
+	).Scan(&chainID)
+	if err != nil {
+		return err
+	}
+
+	for _, hint := range hints {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO puzzle_stages (chain_id, stage_number, puzzle_id, hint_text)
+			VALUES ($1, $2, $3, $4)
+		`, chainID, hint.Stage, puzzle.ID, hint.Hint)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildChainHints describes each layer of a CHAIN puzzle in peeling order
+func buildChainHints(puzzle *Puzzle) ([]ChainHint, error) {
+	steps, err := ciphers.UnwindChain(puzzle.EncryptedText, puzzle.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	layerHints := make([]ChainHint, len(steps))
+	for i, step := range steps {
+		layerHints[i] = ChainHint{
+			Stage:      i + 1,
+			CipherType: step.CipherType,
+			Hint:       hints.LayerHint(i+1, len(steps), step.CipherType),
+		}
+	}
+	return layerHints, nil
+}
+
+// chainStageText returns the expected text after peeling `stage` layers off a
+// CHAIN puzzle, along with how many layers remain underneath
+func chainStageText(puzzle *Puzzle, stage int) (string, int, error) {
+	if puzzle.CipherType != ciphers.TypeChain {
+		return "", 0, errors.NewInvalidInputError("Stages are only available for CHAIN puzzles")
+	}
+
+	steps, err := ciphers.UnwindChain(puzzle.EncryptedText, puzzle.Config)
+	if err != nil {
+		return "", 0, errors.NewInternalServerError(err)
+	}
+	if stage > len(steps) {
+		return "", 0, errors.NewInvalidInputError(fmt.Sprintf("Puzzle only has %d layers", len(steps)))
+	}
+
+	return steps[stage-1].Output, len(steps) - stage, nil
 }
 
 func (s *PuzzleService) getPuzzle(ctx context.Context, puzzleID string) (*Puzzle, error) {
@@ -299,9 +737,12 @@ func (s *PuzzleService) getPuzzle(ctx context.Context, puzzleID string) (*Puzzle
 	// Fetch from database
 	var puzzle Puzzle
 	var configJSON []byte
+	var empiricalDifficulty sql.NullFloat64
+	var solverWork sql.NullInt64
 
 	query := `
-		SELECT id, cipher_type, difficulty, encrypted_text, plaintext, config
+		SELECT id, cipher_type, difficulty, encrypted_text, plaintext, config,
+			empirical_difficulty, solver_work
 		FROM puzzles
 		WHERE id = $1
 	`
@@ -312,10 +753,14 @@ func (s *PuzzleService) getPuzzle(ctx context.Context, puzzleID string) (*Puzzle
 		&puzzle.EncryptedText,
 		&puzzle.Plaintext,
 		&configJSON,
+		&empiricalDifficulty,
+		&solverWork,
 	)
 	if err != nil {
 		return nil, errors.NewPuzzleNotFoundError()
 	}
+	puzzle.EmpiricalDifficulty = empiricalDifficulty.Float64
+	puzzle.SolverWork = int(solverWork.Int64)
 
 	// Parse config
 	if err := json.Unmarshal(configJSON, &puzzle.Config); err != nil {
@@ -323,6 +768,9 @@ func (s *PuzzleService) getPuzzle(ctx context.Context, puzzleID string) (*Puzzle
 			"error": err.Error(),
 		})
 	}
+	puzzle.Language = ciphers.AlphabetOf(puzzle.Config).Language
+	puzzle.HintsAvailable = hints.Count(puzzle.CipherType, puzzle.Config)
+	applyIntroHint(&puzzle)
 
 	// Cache for future requests
 	s.cache.Set(ctx, cacheKey, &puzzle, cache.TTLPuzzle)
@@ -330,60 +778,10 @@ func (s *PuzzleService) getPuzzle(ctx context.Context, puzzleID string) (*Puzzle
 	return &puzzle, nil
 }
 
-func (s *PuzzleService) updatePuzzleStats(ctx context.Context, puzzleID string, solved bool, solveTime int) {
-	query := `
-		UPDATE puzzles
-		SET
-			times_used = times_used + 1,
-			times_solved = times_solved + CASE WHEN $2 THEN 1 ELSE 0 END,
-			avg_solve_time_ms = CASE
-				WHEN times_solved = 0 THEN $3
-				ELSE (avg_solve_time_ms * times_solved + $3) / (times_solved + 1)
-			END,
-			success_rate = CASE
-				WHEN times_used = 0 THEN CASE WHEN $2 THEN 1.0 ELSE 0.0 END
-				ELSE (times_solved::FLOAT + CASE WHEN $2 THEN 1 ELSE 0 END) / (times_used + 1)
-			END
-		WHERE id = $1
-	`
-	if _, err := s.db.ExecContext(ctx, query, puzzleID, solved, solveTime); err != nil {
+func (s *PuzzleService) updatePuzzleStats(ctx context.Context, puzzleID, userID string, solved bool, solveTime int) {
+	if err := s.recordAttempt(ctx, puzzleID, userID, solved, solveTime); err != nil {
 		s.log.Error("Failed to update puzzle stats", map[string]interface{}{
 			"error": err.Error(),
 		})
 	}
 }
-
-func normalizeText(text string) string {
-	// Remove spaces, convert to uppercase
-	result := ""
-	for _, char := range text {
-		if char != ' ' && char != '\n' && char != '\r' && char != '\t' {
-			if char >= 'a' && char <= 'z' {
-				result += string(char - 32)
-			} else {
-				result += string(char)
-			}
-		}
-	}
-	return result
-}
-
-func calculateAccuracy(submitted, correct string) float64 {
-	if len(correct) == 0 {
-		return 0.0
-	}
-
-	matches := 0
-	maxLen := len(submitted)
-	if len(correct) < maxLen {
-		maxLen = len(correct)
-	}
-
-	for i := 0; i < maxLen; i++ {
-		if submitted[i] == correct[i] {
-			matches++
-		}
-	}
-
-	return float64(matches) / float64(len(correct))
-}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// Stats groupings
+const (
+	GroupByCipherType = "cipher_type"
+	GroupByDifficulty = "difficulty"
+)
+
+// PuzzleStats aggregates attempts on one puzzle or a group of puzzles. Solve
+// times only cover successful attempts.
+type PuzzleStats struct {
+	PuzzleID       string  `json:"puzzle_id,omitempty"`
+	CipherType     string  `json:"cipher_type,omitempty"`
+	Difficulty     int     `json:"difficulty,omitempty"`
+	Puzzles        int     `json:"puzzles,omitempty"` // Groups only
+	TimesUsed      int     `json:"times_used"`
+	TimesSolved    int     `json:"times_solved"`
+	SuccessRate    float64 `json:"success_rate"`
+	AvgSolveTimeMs int     `json:"avg_solve_time_ms"`
+	P50SolveTimeMs int     `json:"p50_solve_time_ms"`
+	P90SolveTimeMs int     `json:"p90_solve_time_ms"`
+}
+
+// recordAttempt updates a puzzle's running counters and keeps the attempt for
+// percentile queries, along with the player's current rating, in one
+// transaction so the counters always match the attempt log. Only attempts by
+// a known player count. The average solve time moves on solves only.
+func (s *PuzzleService) recordAttempt(ctx context.Context, puzzleID, userID string, solved bool, solveTime int) error {
+	if _, err := uuid.Parse(userID); err != nil {
+		return nil
+	}
+
+	return s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE puzzles
+			SET
+				times_used = times_used + 1,
+				times_solved = times_solved + CASE WHEN $2 THEN 1 ELSE 0 END,
+				avg_solve_time_ms = CASE
+					WHEN NOT $2 THEN avg_solve_time_ms
+					WHEN times_solved = 0 OR avg_solve_time_ms IS NULL THEN $3
+					ELSE (avg_solve_time_ms::BIGINT * times_solved + $3) / (times_solved + 1)
+				END,
+				success_rate = (times_solved + CASE WHEN $2 THEN 1 ELSE 0 END)::FLOAT / (times_used + 1)
+			WHERE id = $1
+		`, puzzleID, solved, solveTime)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO puzzle_solve_log (puzzle_id, solved, solve_time_ms, user_id, player_rating)
+			VALUES ($1, $2, $3, $4::UUID, (SELECT elo_rating FROM users WHERE id = $4::UUID))
+		`, puzzleID, solved, solveTime, userID)
+		return err
+	})
+}
+
+// GetPuzzleStats returns the attempt statistics of one puzzle
+func (s *PuzzleService) GetPuzzleStats(ctx context.Context, puzzleID string) (*PuzzleStats, error) {
+	stats := &PuzzleStats{PuzzleID: puzzleID}
+	var avgSolveTime sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT cipher_type, difficulty, COALESCE(times_used, 0), COALESCE(times_solved, 0),
+			COALESCE(success_rate, 0), avg_solve_time_ms
+		FROM puzzles
+		WHERE id = $1
+	`, puzzleID).Scan(
+		&stats.CipherType,
+		&stats.Difficulty,
+		&stats.TimesUsed,
+		&stats.TimesSolved,
+		&stats.SuccessRate,
+		&avgSolveTime,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewPuzzleNotFoundError()
+	}
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	stats.AvgSolveTimeMs = int(avgSolveTime.Int64)
+
+	var p50, p90 sql.NullFloat64
+	err = s.db.QueryRowContext(ctx, `
+		SELECT
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY solve_time_ms),
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY solve_time_ms)
+		FROM puzzle_solve_log
+		WHERE puzzle_id = $1 AND solved
+	`, puzzleID).Scan(&p50, &p90)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	stats.P50SolveTimeMs = int(p50.Float64)
+	stats.P90SolveTimeMs = int(p90.Float64)
+
+	return stats, nil
+}
+
+// GetGroupedStats aggregates attempts per cipher type or per difficulty,
+// the data needed to tune difficulty curves
+func (s *PuzzleService) GetGroupedStats(ctx context.Context, groupBy string) ([]*PuzzleStats, error) {
+	var column string
+	switch groupBy {
+	case GroupByCipherType:
+		column = "p.cipher_type"
+	case GroupByDifficulty:
+		column = "p.difficulty"
+	default:
+		return nil, errors.NewInvalidInputError(fmt.Sprintf("group_by must be %s or %s", GroupByCipherType, GroupByDifficulty))
+	}
+
+	// Counts come from the attempt log so every figure covers the same attempts
+	query := fmt.Sprintf(`
+		SELECT
+			%[1]s,
+			COUNT(DISTINCT a.puzzle_id),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE a.solved),
+			AVG(a.solve_time_ms) FILTER (WHERE a.solved),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY a.solve_time_ms) FILTER (WHERE a.solved),
+			percentile_cont(0.9) WITHIN GROUP (ORDER BY a.solve_time_ms) FILTER (WHERE a.solved)
+		FROM puzzle_solve_log a
+		JOIN puzzles p ON p.id = a.puzzle_id
+		GROUP BY %[1]s
+		ORDER BY %[1]s
+	`, column)
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	defer rows.Close()
+
+	var groups []*PuzzleStats
+	for rows.Next() {
+		stats := &PuzzleStats{}
+		var avg, p50, p90 sql.NullFloat64
+		var key interface{} = &stats.CipherType
+		if groupBy == GroupByDifficulty {
+			key = &stats.Difficulty
+		}
+		if err := rows.Scan(key, &stats.Puzzles, &stats.TimesUsed, &stats.TimesSolved, &avg, &p50, &p90); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		if stats.TimesUsed > 0 {
+			stats.SuccessRate = float64(stats.TimesSolved) / float64(stats.TimesUsed)
+		}
+		stats.AvgSolveTimeMs = int(avg.Float64)
+		stats.P50SolveTimeMs = int(p50.Float64)
+		stats.P90SolveTimeMs = int(p90.Float64)
+		groups = append(groups, stats)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return groups, nil
+}
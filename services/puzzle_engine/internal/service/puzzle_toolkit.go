@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/ciphers"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/toolkit"
+)
+
+// AnalyzeRequest asks for cryptanalysis of a ciphertext. Nothing is stored;
+// the game mode only decides which tools may run.
+type AnalyzeRequest struct {
+	Ciphertext   string   `json:"ciphertext"`
+	Mode         string   `json:"mode"`
+	Language     string   `json:"language,omitempty"`
+	Tools        []string `json:"tools,omitempty"` // Defaults to every tool the mode allows
+	Candidates   []string `json:"candidates,omitempty"`
+	MaxKeyLength int      `json:"max_key_length,omitempty"`
+}
+
+// ToolkitTools returns the cryptanalysis tools allowed in a game mode
+func (s *PuzzleService) ToolkitTools(mode string) []string {
+	tools := s.toolkit.For(mode)
+	if tools == nil {
+		return []string{}
+	}
+	return tools
+}
+
+// AnalyzeCiphertext runs the cryptanalysis toolkit on a ciphertext, refusing
+// tools the game mode doesn't allow
+func (s *PuzzleService) AnalyzeCiphertext(ctx context.Context, req *AnalyzeRequest) (*toolkit.Report, error) {
+	if req.Mode == "" {
+		return nil, errors.NewInvalidInputError("Game mode is required")
+	}
+	allowed := s.toolkit.For(req.Mode)
+	if len(allowed) == 0 {
+		return nil, errors.NewForbiddenError(fmt.Sprintf("The cryptanalysis toolkit is disabled in %s", req.Mode))
+	}
+
+	tools := req.Tools
+	if len(tools) == 0 {
+		// N-gram scoring is English-only, so it is left out by default elsewhere
+		english := req.Language == "" || req.Language == ciphers.DefaultLanguage
+		for _, tool := range allowed {
+			if tool != toolkit.ToolNGram || english {
+				tools = append(tools, tool)
+			}
+		}
+		if len(tools) == 0 {
+			return nil, errors.NewForbiddenError(fmt.Sprintf("No toolkit tool for %s texts is enabled in %s", req.Language, req.Mode))
+		}
+	}
+	for _, tool := range tools {
+		if !s.toolkit.Allows(req.Mode, tool) {
+			return nil, errors.NewForbiddenError(fmt.Sprintf("The %s tool is disabled in %s", tool, req.Mode))
+		}
+	}
+
+	report, err := toolkit.Analyze(req.Ciphertext, tools, toolkit.Options{
+		Language:     req.Language,
+		MaxKeyLength: req.MaxKeyLength,
+		Candidates:   req.Candidates,
+	})
+	if err != nil {
+		return nil, errors.NewInvalidInputError(err.Error())
+	}
+	return report, nil
+}
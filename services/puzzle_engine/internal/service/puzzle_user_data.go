@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
+)
+
+// DeleteUserData erases what the puzzle engine keeps about a user who
+// deleted their account: their seen puzzles, hint usage, ratings, reports
+// and custom puzzle listings. Their solves stay, unattributed, since puzzle
+// calibration is built on them.
+func (s *PuzzleService) DeleteUserData(ctx context.Context, userID uuid.UUID) error {
+	err := s.db.WithTransaction(ctx, func(tx *sql.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM user_seen_puzzles WHERE user_id = $1`,
+			`DELETE FROM puzzle_hint_usage WHERE user_id = $1`,
+			`DELETE FROM custom_puzzle_ratings WHERE user_id = $1`,
+			`DELETE FROM puzzle_reports WHERE user_id = $1`,
+			`DELETE FROM custom_puzzles WHERE owner_id = $1`,
+		} {
+			if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.NewInternalServerError(err)
+	}
+
+	// Solves are also unattributed when the account row goes, so failing
+	// here mustn't undo or retry the erase above
+	if _, err := s.db.ExecContext(ctx, `UPDATE puzzle_solve_log SET user_id = NULL WHERE user_id = $1`, userID); err != nil {
+		s.log.Warn("Failed to unattribute puzzle solves", map[string]interface{}{
+			"user_id": userID.String(),
+			"error":   err.Error(),
+		})
+	}
+	return nil
+}
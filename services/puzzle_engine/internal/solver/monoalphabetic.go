@@ -0,0 +1,83 @@
+package solver
+
+import (
+	"math"
+	"math/rand"
+)
+
+// ============================================================================
+// Brute-force solvers for ciphers with tiny key spaces
+// ============================================================================
+
+// CaesarSolver tries all 26 shifts
+type CaesarSolver struct{}
+
+func (c *CaesarSolver) Solve(ciphertext string, rng *rand.Rand) Attempt {
+	text := letters(ciphertext)
+	scorer := DefaultScorer()
+
+	best, bestScore := text, math.Inf(-1)
+	for shift := 0; shift < 26; shift++ {
+		candidate := mapLetters(text, func(x int) int { return (x - shift + 26) % 26 })
+		if score := scorer.Score(candidate); score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return Attempt{Plaintext: restore(ciphertext, best), Work: 26}
+}
+
+// ROT13Solver has a single key to try
+type ROT13Solver struct{}
+
+func (r *ROT13Solver) Solve(ciphertext string, rng *rand.Rand) Attempt {
+	plain := mapLetters(letters(ciphertext), func(x int) int { return (x + 13) % 26 })
+	return Attempt{Plaintext: restore(ciphertext, plain), Work: 1}
+}
+
+// AtbashSolver has a single key to try
+type AtbashSolver struct{}
+
+func (a *AtbashSolver) Solve(ciphertext string, rng *rand.Rand) Attempt {
+	plain := mapLetters(letters(ciphertext), func(x int) int { return 25 - x })
+	return Attempt{Plaintext: restore(ciphertext, plain), Work: 1}
+}
+
+// AffineSolver tries every (a, b) pair with a coprime to 26
+type AffineSolver struct{}
+
+var affineMultipliers = []int{1, 3, 5, 7, 9, 11, 15, 17, 19, 21, 23, 25}
+
+func (a *AffineSolver) Solve(ciphertext string, rng *rand.Rand) Attempt {
+	text := letters(ciphertext)
+	scorer := DefaultScorer()
+
+	best, bestScore, work := text, math.Inf(-1), 0
+	for _, keyA := range affineMultipliers {
+		inverse := modInverse26(keyA)
+		for keyB := 0; keyB < 26; keyB++ {
+			candidate := mapLetters(text, func(y int) int { return inverse * (y - keyB + 26) % 26 })
+			if score := scorer.Score(candidate); score > bestScore {
+				best, bestScore = candidate, score
+			}
+			work++
+		}
+	}
+	return Attempt{Plaintext: restore(ciphertext, best), Work: work}
+}
+
+func mapLetters(text []int, f func(int) int) []int {
+	result := make([]int, len(text))
+	for i, x := range text {
+		result[i] = f(x)
+	}
+	return result
+}
+
+func modInverse26(a int) int {
+	for x := 1; x < 26; x++ {
+		if a*x%26 == 1 {
+			return x
+		}
+	}
+	return 1
+}
@@ -0,0 +1,131 @@
+package solver
+
+import (
+	_ "embed"
+	"math"
+	"sync"
+)
+
+//go:embed data/english.txt
+var trainingText string
+
+// englishFrequencies are the relative letter frequencies of English, A-Z
+var englishFrequencies = [26]float64{
+	0.08167, 0.01492, 0.02782, 0.04253, 0.12702, 0.02228, 0.02015,
+	0.06094, 0.06966, 0.00153, 0.00772, 0.04025, 0.02406, 0.06749,
+	0.07507, 0.01929, 0.00095, 0.05987, 0.06327, 0.09056, 0.02758,
+	0.00978, 0.02360, 0.00150, 0.01974, 0.00074,
+}
+
+// Scorer rates how English-like a run of letters is using quadgram
+// log-probabilities. Quadgrams never seen in training back off to the
+// trailing trigram, then bigram, each step paying a fixed penalty.
+type Scorer struct {
+	quadgrams []float64 // Indexed by a*26^3 + b*26^2 + c*26 + d
+	trigrams  []float64 // Indexed by a*26^2 + b*26 + c
+	bigrams   []float64 // Indexed by a*26 + b
+	floor     float64
+}
+
+// backoffPenalty is the log10 cost of each backoff step
+const backoffPenalty = 1.0
+
+var (
+	defaultScorer     *Scorer
+	defaultScorerOnce sync.Once
+)
+
+// DefaultScorer returns a scorer trained on the embedded English sample
+func DefaultScorer() *Scorer {
+	defaultScorerOnce.Do(func() {
+		defaultScorer = NewScorer(trainingText)
+	})
+	return defaultScorer
+}
+
+// NewScorer trains a scorer on a sample of English text
+func NewScorer(training string) *Scorer {
+	text := letters(training)
+
+	quadTotal := math.Max(float64(len(text)-3), 1)
+	return &Scorer{
+		quadgrams: ngramLogProbs(text, 4),
+		trigrams:  ngramLogProbs(text, 3),
+		bigrams:   ngramLogProbs(text, 2),
+		floor:     math.Log10(0.01 / quadTotal),
+	}
+}
+
+// ngramLogProbs returns log10 probabilities of every n-gram, -Inf if unseen
+func ngramLogProbs(text []int, n int) []float64 {
+	size := int(math.Pow(26, float64(n)))
+	counts := make([]float64, size)
+	total := 0.0
+	for i := 0; i+n <= len(text); i++ {
+		index := 0
+		for _, x := range text[i : i+n] {
+			index = index*26 + x
+		}
+		counts[index]++
+		total++
+	}
+
+	probs := make([]float64, size)
+	for i, count := range counts {
+		probs[i] = math.Inf(-1)
+		if count > 0 {
+			probs[i] = math.Log10(count / total)
+		}
+	}
+	return probs
+}
+
+// Score returns the log-likelihood of the letters (0-25) being English.
+// Higher is better; only comparable between texts of the same length.
+func (s *Scorer) Score(text []int) float64 {
+	score := 0.0
+	for i := 0; i+3 < len(text); i++ {
+		p := s.quadgrams[text[i]*17576+text[i+1]*676+text[i+2]*26+text[i+3]]
+		if math.IsInf(p, -1) {
+			p = s.trigrams[text[i+1]*676+text[i+2]*26+text[i+3]] - backoffPenalty
+		}
+		if math.IsInf(p, -1) {
+			p = s.bigrams[text[i+2]*26+text[i+3]] - 2*backoffPenalty
+		}
+		if p < s.floor {
+			p = s.floor
+		}
+		score += p
+	}
+	return score
+}
+
+// ScoreText rates how English-like the letters of text are as the mean
+// quadgram log-probability, so texts of different lengths can be compared.
+// ok is false when text has fewer than four letters.
+func (s *Scorer) ScoreText(text string) (score float64, ok bool) {
+	plain := letters(text)
+	if len(plain) < 4 {
+		return 0, false
+	}
+	return s.Score(plain) / float64(len(plain)-3), true
+}
+
+// EnglishFrequencies returns the relative letter frequencies of English, A-Z
+func EnglishFrequencies() [26]float64 {
+	return englishFrequencies
+}
+
+// chiSquared measures how far a letter distribution is from English; lower is better
+func chiSquared(counts [26]int, total int) float64 {
+	if total == 0 {
+		return math.Inf(1)
+	}
+	chi := 0.0
+	for i, count := range counts {
+		expected := englishFrequencies[i] * float64(total)
+		diff := float64(count) - expected
+		chi += diff * diff / expected
+	}
+	return chi
+}
@@ -0,0 +1,136 @@
+package solver
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/ciphers"
+)
+
+// MaxDifficulty is the empirical difficulty given to puzzles the solvers fail to break
+const MaxDifficulty = 10.0
+
+// solvedAccuracy is the share of letters a solver must recover for a puzzle to
+// count as broken. Hill climbing often leaves rare letters like J/Q/Z swapped.
+const solvedAccuracy = 0.9
+
+// Attempt is a solver's best guess at a plaintext
+type Attempt struct {
+	Plaintext string
+	Work      int // Number of candidate keys scored
+}
+
+// Solver breaks ciphertexts of a single cipher type without knowing the key.
+// Randomised solvers draw only from rng, so grading is reproducible.
+type Solver interface {
+	Solve(ciphertext string, rng *rand.Rand) Attempt
+}
+
+// Grade is the outcome of attacking a generated puzzle
+type Grade struct {
+	Supported  bool    `json:"supported"`
+	Solved     bool    `json:"solved"`
+	Accuracy   float64 `json:"accuracy"`
+	Work       int     `json:"work"`
+	Difficulty float64 `json:"difficulty"` // Empirical difficulty, 1-10
+}
+
+var solvers = map[string]Solver{
+	ciphers.TypeCaesar:       &CaesarSolver{},
+	ciphers.TypeROT13:        &ROT13Solver{},
+	ciphers.TypeAtbash:       &AtbashSolver{},
+	ciphers.TypeAffine:       &AffineSolver{},
+	ciphers.TypeVigenere:     &VigenereSolver{},
+	ciphers.TypeSubstitution: &SubstitutionSolver{},
+}
+
+// GetSolver returns the solver for a cipher type, or nil if there is none
+func GetSolver(cipherType string) Solver {
+	return solvers[cipherType]
+}
+
+// GradePuzzle attacks a puzzle and scores how much work breaking it took.
+// Cipher types without a solver come back unsupported.
+func GradePuzzle(cipherType, ciphertext, plaintext string, rng *rand.Rand) Grade {
+	s := GetSolver(cipherType)
+	if s == nil {
+		return Grade{}
+	}
+
+	attempt := s.Solve(ciphertext, rng)
+	accuracy := letterAccuracy(attempt.Plaintext, plaintext)
+
+	grade := Grade{
+		Supported:  true,
+		Solved:     accuracy >= solvedAccuracy,
+		Accuracy:   accuracy,
+		Work:       attempt.Work,
+		Difficulty: MaxDifficulty,
+	}
+	if grade.Solved {
+		grade.Difficulty = workToDifficulty(attempt.Work)
+	}
+	return grade
+}
+
+// workToDifficulty maps solver work onto the 1-10 scale logarithmically:
+// ~25 keys (Caesar) is about 3, ~300 (Affine) about 5, ~10k (substitution) about 7.5
+func workToDifficulty(work int) float64 {
+	if work < 1 {
+		work = 1
+	}
+	difficulty := 1 + 1.6*math.Log10(float64(work))
+	if difficulty > MaxDifficulty {
+		difficulty = MaxDifficulty
+	}
+	return math.Round(difficulty*100) / 100
+}
+
+// letterAccuracy compares the letters of a guess against the expected text
+func letterAccuracy(guess, expected string) float64 {
+	g := letters(guess)
+	e := letters(expected)
+	if len(e) == 0 {
+		return 0
+	}
+
+	matches := 0
+	for i := 0; i < len(g) && i < len(e); i++ {
+		if g[i] == e[i] {
+			matches++
+		}
+	}
+	return float64(matches) / float64(len(e))
+}
+
+// letters returns the alphabetic characters of text as 0-25
+func letters(text string) []int {
+	result := make([]int, 0, len(text))
+	for _, char := range strings.ToUpper(text) {
+		if char >= 'A' && char <= 'Z' {
+			result = append(result, int(char-'A'))
+		}
+	}
+	return result
+}
+
+// restore writes decrypted letters back into the ciphertext's layout,
+// keeping spaces and punctuation in place
+func restore(ciphertext string, plain []int) string {
+	var b strings.Builder
+	i := 0
+	for _, char := range ciphertext {
+		switch {
+		case char >= 'A' && char <= 'Z' && i < len(plain):
+			b.WriteRune(rune('A' + plain[i]))
+			i++
+		case char >= 'a' && char <= 'z' && i < len(plain):
+			b.WriteRune(rune('a' + plain[i]))
+			i++
+		default:
+			b.WriteRune(char)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package solver
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// ============================================================================
+// Substitution solver (hill climbing with quadgram scoring)
+// ============================================================================
+
+const (
+	substitutionRestarts   = 25
+	substitutionStaleLimit = 1000 // Swaps without improvement before a climb gives up
+	substitutionKicks      = 4    // Random swaps applied to the best key before each restart
+	substitutionConverged  = 3    // Climbs in a row ending on the best score before stopping early
+)
+
+// englishOrder is the alphabet sorted by descending English frequency
+var englishOrder = []int{4, 19, 0, 14, 8, 13, 18, 7, 17, 3, 11, 2, 20, 12, 22, 5, 6, 24, 15, 1, 21, 10, 9, 23, 16, 25}
+
+// SubstitutionSolver climbs from a frequency-analysis key by swapping pairs
+// of letters. Each restart kicks the best key so far with a few random swaps
+// to escape local maxima, stopping early once climbs keep landing on the
+// same best score.
+type SubstitutionSolver struct{}
+
+func (s *SubstitutionSolver) Solve(ciphertext string, rng *rand.Rand) Attempt {
+	text := letters(ciphertext)
+	scorer := DefaultScorer()
+	work := 0
+
+	bestKey, bestScore := frequencyKey(text), math.Inf(-1)
+	converged := 0
+	for restart := 0; restart < substitutionRestarts && converged < substitutionConverged; restart++ {
+		key := append([]int(nil), bestKey...)
+		if restart > 0 {
+			for kick := 0; kick < substitutionKicks; kick++ {
+				i, j := rng.Intn(26), rng.Intn(26)
+				key[i], key[j] = key[j], key[i]
+			}
+		}
+
+		score := scorer.Score(applyKey(text, key))
+		work++
+		for stale := 0; stale < substitutionStaleLimit; stale++ {
+			i, j := rng.Intn(26), rng.Intn(26)
+			if i == j {
+				continue
+			}
+			key[i], key[j] = key[j], key[i]
+			candidate := scorer.Score(applyKey(text, key))
+			work++
+			if candidate > score {
+				score = candidate
+				stale = 0
+			} else {
+				key[i], key[j] = key[j], key[i]
+			}
+		}
+
+		switch {
+		case score > bestScore+1e-9:
+			bestKey, bestScore = key, score
+			converged = 0
+		case math.Abs(score-bestScore) < 1e-9:
+			converged++
+		}
+	}
+
+	return Attempt{Plaintext: restore(ciphertext, applyKey(text, bestKey)), Work: work}
+}
+
+// frequencyKey maps cipher letters to plain letters by frequency rank
+func frequencyKey(text []int) []int {
+	var counts [26]int
+	for _, x := range text {
+		counts[x]++
+	}
+
+	order := make([]int, 26)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+
+	key := make([]int, 26)
+	for rank, cipherLetter := range order {
+		key[cipherLetter] = englishOrder[rank]
+	}
+	return key
+}
+
+func applyKey(text, key []int) []int {
+	result := make([]int, len(text))
+	for i, x := range text {
+		result[i] = key[x]
+	}
+	return result
+}
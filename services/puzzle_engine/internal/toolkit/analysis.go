@@ -0,0 +1,423 @@
+package toolkit
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/ciphers"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/solver"
+)
+
+// Limits keeping a single request cheap
+const (
+	MaxTextLength     = 10000 // Characters of ciphertext or of a candidate
+	MaxCandidates     = 20
+	DefaultKeyLength  = 12
+	MaxKeyLength      = 30
+	maxRepeats        = 25 // Repeated sequences reported by the Kasiski tool
+	maxRepeatLength   = 8
+	minRepeatLength   = 3
+	caesarPreviewSize = 60
+)
+
+// englishIoC is the normalized index of coincidence of English text; random
+// text of any alphabet scores 1
+const englishIoC = 1.73
+
+// Options tune the analyses
+type Options struct {
+	Language     string   // Alphabet of the ciphertext; empty means English
+	MaxKeyLength int      // Longest key the IoC and Kasiski tools consider
+	Candidates   []string // Plaintexts for n-gram scoring; none scores the ciphertext itself
+}
+
+// Report holds the result of every tool that ran
+type Report struct {
+	Language  string           `json:"language"`
+	Tools     []string         `json:"tools"`
+	Frequency *FrequencyReport `json:"frequency,omitempty"`
+	IoC       *IoCReport       `json:"ioc,omitempty"`
+	Kasiski   *KasiskiReport   `json:"kasiski,omitempty"`
+	Caesar    []CaesarShift    `json:"caesar,omitempty"`
+	NGram     []NGramScore     `json:"ngram,omitempty"`
+}
+
+// FrequencyReport is a letter frequency histogram
+type FrequencyReport struct {
+	Total   int           `json:"total"`
+	Letters []LetterCount `json:"letters"` // In alphabet order
+	Ranked  string        `json:"ranked"`  // Letters that appear, most frequent first
+}
+
+// LetterCount is one bar of the histogram
+type LetterCount struct {
+	Letter   string  `json:"letter"`
+	Count    int     `json:"count"`
+	Percent  float64 `json:"percent"`
+	Expected float64 `json:"expected,omitempty"` // Percent in typical English text
+}
+
+// IoCReport is the index of coincidence of the whole text and of the text
+// split into columns for each candidate key length
+type IoCReport struct {
+	Value      float64     `json:"value"`
+	Normalized float64     `json:"normalized"` // Value times the alphabet size; random text is 1
+	English    float64     `json:"english,omitempty"`
+	Periods    []PeriodIoC `json:"periods"`
+}
+
+// PeriodIoC is the average index of coincidence of the columns a key of this
+// length would produce. The true key length stands out close to the
+// language's value.
+type PeriodIoC struct {
+	KeyLength  int     `json:"key_length"`
+	Normalized float64 `json:"normalized"`
+}
+
+// KasiskiReport lists repeated letter sequences and the key lengths their
+// distances point to
+type KasiskiReport struct {
+	Repeats    []RepeatedSequence `json:"repeats"`
+	KeyLengths []KeyLengthVote    `json:"key_lengths"` // Most votes first
+}
+
+// RepeatedSequence is a run of letters occurring more than once. Positions
+// count letters only, from 0.
+type RepeatedSequence struct {
+	Sequence  string `json:"sequence"`
+	Positions []int  `json:"positions"`
+	Distances []int  `json:"distances"`
+}
+
+// KeyLengthVote counts the repeat distances a key length divides
+type KeyLengthVote struct {
+	KeyLength int `json:"key_length"`
+	Votes     int `json:"votes"`
+}
+
+// CaesarShift is the ciphertext decrypted with one shift
+type CaesarShift struct {
+	Shift   int     `json:"shift"`
+	Preview string  `json:"preview"`
+	Score   float64 `json:"score,omitempty"` // English n-gram score, higher is better
+}
+
+// NGramScore rates one candidate plaintext
+type NGramScore struct {
+	Text  string   `json:"text"`
+	Score *float64 `json:"score"` // Mean quadgram log10 probability, higher is more English-like; nil under four letters
+	Rank  int      `json:"rank"`
+}
+
+// Analyze runs tools on a ciphertext. Without tools it runs every one that
+// applies: n-gram scoring needs English text.
+func Analyze(ciphertext string, tools []string, opts Options) (*Report, error) {
+	alphabet, ok := ciphers.AlphabetFor(opts.Language)
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", opts.Language)
+	}
+	english := alphabet == ciphers.Latin
+	if strings.TrimSpace(ciphertext) == "" {
+		return nil, fmt.Errorf("ciphertext is required")
+	}
+	if utf8.RuneCountInString(ciphertext) > MaxTextLength {
+		return nil, fmt.Errorf("ciphertext must be at most %d characters", MaxTextLength)
+	}
+	if len(opts.Candidates) > MaxCandidates {
+		return nil, fmt.Errorf("at most %d candidates can be scored", MaxCandidates)
+	}
+	for _, candidate := range opts.Candidates {
+		if utf8.RuneCountInString(candidate) > MaxTextLength {
+			return nil, fmt.Errorf("candidates must be at most %d characters", MaxTextLength)
+		}
+	}
+	if opts.MaxKeyLength == 0 {
+		opts.MaxKeyLength = DefaultKeyLength
+	}
+	if opts.MaxKeyLength < 2 || opts.MaxKeyLength > MaxKeyLength {
+		return nil, fmt.Errorf("max key length must be from 2 to %d", MaxKeyLength)
+	}
+
+	if len(tools) == 0 {
+		for _, tool := range AllTools {
+			if tool != ToolNGram || english {
+				tools = append(tools, tool)
+			}
+		}
+	}
+
+	text := letterIndexes(ciphertext, alphabet)
+	report := &Report{Language: alphabet.Language, Tools: tools}
+	for _, tool := range tools {
+		switch tool {
+		case ToolFrequency:
+			report.Frequency = frequency(text, alphabet)
+		case ToolIoC:
+			report.IoC = indexOfCoincidence(text, alphabet, opts.MaxKeyLength)
+		case ToolKasiski:
+			report.Kasiski = kasiski(text, alphabet, opts.MaxKeyLength)
+		case ToolCaesar:
+			report.Caesar = caesarShifts(ciphertext, alphabet)
+		case ToolNGram:
+			if !english {
+				return nil, fmt.Errorf("n-gram scoring is only available for English")
+			}
+			report.NGram = ngramScores(ciphertext, opts.Candidates)
+		default:
+			return nil, fmt.Errorf("unknown tool: %s", tool)
+		}
+	}
+	return report, nil
+}
+
+// letterIndexes returns the letters of text as alphabet indexes
+func letterIndexes(text string, alphabet *ciphers.Alphabet) []int {
+	var indexes []int
+	for _, char := range alphabet.Fold(text) {
+		if i, ok := alphabet.Index(char); ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+func frequency(text []int, alphabet *ciphers.Alphabet) *FrequencyReport {
+	counts := make([]int, alphabet.Size())
+	for _, i := range text {
+		counts[i]++
+	}
+
+	var expected [26]float64
+	if alphabet == ciphers.Latin {
+		expected = solver.EnglishFrequencies()
+	}
+
+	report := &FrequencyReport{Total: len(text), Letters: make([]LetterCount, alphabet.Size())}
+	order := make([]int, 0, alphabet.Size())
+	for i, count := range counts {
+		report.Letters[i] = LetterCount{Letter: string(alphabet.Letter(i)), Count: count}
+		if len(text) > 0 {
+			report.Letters[i].Percent = round(100 * float64(count) / float64(len(text)))
+		}
+		if i < len(expected) {
+			report.Letters[i].Expected = round(100 * expected[i])
+		}
+		if count > 0 {
+			order = append(order, i)
+		}
+	}
+
+	sort.SliceStable(order, func(a, b int) bool { return counts[order[a]] > counts[order[b]] })
+	var ranked strings.Builder
+	for _, i := range order {
+		ranked.WriteRune(alphabet.Letter(i))
+	}
+	report.Ranked = ranked.String()
+	return report
+}
+
+func indexOfCoincidence(text []int, alphabet *ciphers.Alphabet, maxKeyLength int) *IoCReport {
+	size := float64(alphabet.Size())
+	value := columnIoC(text, alphabet.Size(), 1)
+	report := &IoCReport{
+		Value:      round4(value),
+		Normalized: round(value * size),
+	}
+	if alphabet == ciphers.Latin {
+		report.English = englishIoC
+	}
+	for length := 1; length <= maxKeyLength; length++ {
+		report.Periods = append(report.Periods, PeriodIoC{
+			KeyLength:  length,
+			Normalized: round(columnIoC(text, alphabet.Size(), length) * size),
+		})
+	}
+	return report
+}
+
+// columnIoC is the mean index of coincidence of the text's columns when
+// written in rows of length letters
+func columnIoC(text []int, size, length int) float64 {
+	total, columns := 0.0, 0
+	for column := 0; column < length; column++ {
+		counts := make([]int, size)
+		n := 0
+		for i := column; i < len(text); i += length {
+			counts[text[i]]++
+			n++
+		}
+		if n < 2 {
+			continue
+		}
+		sum := 0
+		for _, count := range counts {
+			sum += count * (count - 1)
+		}
+		total += float64(sum) / float64(n*(n-1))
+		columns++
+	}
+	if columns == 0 {
+		return 0
+	}
+	return total / float64(columns)
+}
+
+// kasiski finds repeated sequences, longest first, skipping ones that only
+// occur inside a longer repeat already found
+func kasiski(text []int, alphabet *ciphers.Alphabet, maxKeyLength int) *KasiskiReport {
+	// ends[i] is the furthest end of a reported occurrence starting at i
+	ends := make([]int, len(text))
+	reach := make([]int, len(text))
+
+	report := &KasiskiReport{}
+	votes := make(map[int]int)
+	for length := maxRepeatLength; length >= minRepeatLength; length-- {
+		// reach[i] is the furthest end of a longer repeat starting at or before i
+		furthest := 0
+		for i := range text {
+			furthest = max(furthest, ends[i])
+			reach[i] = furthest
+		}
+
+		positions := make(map[string][]int)
+		var order []string
+		for i := 0; i+length <= len(text); i++ {
+			key := sequence(text[i:i+length], alphabet)
+			if positions[key] == nil {
+				order = append(order, key)
+			}
+			positions[key] = append(positions[key], i)
+		}
+
+		for _, key := range order {
+			seen := positions[key]
+			if len(seen) < 2 {
+				continue
+			}
+			covered := true
+			for _, start := range seen {
+				if reach[start] < start+length {
+					covered = false
+					break
+				}
+			}
+			if covered {
+				continue
+			}
+
+			repeat := RepeatedSequence{Sequence: key, Positions: seen}
+			for i := 1; i < len(seen); i++ {
+				distance := seen[i] - seen[i-1]
+				repeat.Distances = append(repeat.Distances, distance)
+				for keyLength := 2; keyLength <= maxKeyLength; keyLength++ {
+					if distance%keyLength == 0 {
+						votes[keyLength]++
+					}
+				}
+			}
+			for _, start := range seen {
+				ends[start] = max(ends[start], start+length)
+			}
+			if len(report.Repeats) < maxRepeats {
+				report.Repeats = append(report.Repeats, repeat)
+			}
+		}
+	}
+
+	for keyLength, count := range votes {
+		report.KeyLengths = append(report.KeyLengths, KeyLengthVote{KeyLength: keyLength, Votes: count})
+	}
+	sort.Slice(report.KeyLengths, func(i, j int) bool {
+		a, b := report.KeyLengths[i], report.KeyLengths[j]
+		if a.Votes != b.Votes {
+			return a.Votes > b.Votes
+		}
+		return a.KeyLength < b.KeyLength
+	})
+	return report
+}
+
+func sequence(indexes []int, alphabet *ciphers.Alphabet) string {
+	var b strings.Builder
+	for _, i := range indexes {
+		b.WriteRune(alphabet.Letter(i))
+	}
+	return b.String()
+}
+
+// caesarShifts decrypts the ciphertext with every shift. English results are
+// ranked by n-gram score; others stay in shift order.
+func caesarShifts(ciphertext string, alphabet *ciphers.Alphabet) []CaesarShift {
+	caesar := ciphers.GetCipher(ciphers.TypeCaesar)
+	scorer := solver.DefaultScorer()
+	english := alphabet == ciphers.Latin
+
+	shifts := make([]CaesarShift, 0, alphabet.Size())
+	for shift := 0; shift < alphabet.Size(); shift++ {
+		config := map[string]interface{}{"shift": shift}
+		if !english {
+			config["alphabet"] = alphabet.Language
+		}
+		decrypted, _ := caesar.Decrypt(ciphertext, config)
+
+		result := CaesarShift{Shift: shift, Preview: preview(decrypted)}
+		if english {
+			if score, ok := scorer.ScoreText(decrypted); ok {
+				result.Score = round(score)
+			}
+		}
+		shifts = append(shifts, result)
+	}
+
+	if english {
+		sort.SliceStable(shifts, func(i, j int) bool { return shifts[i].Score > shifts[j].Score })
+	}
+	return shifts
+}
+
+// ngramScores scores the candidates, or the ciphertext when there are none,
+// ranking the most English-like first
+func ngramScores(ciphertext string, candidates []string) []NGramScore {
+	if len(candidates) == 0 {
+		candidates = []string{ciphertext}
+	}
+
+	scorer := solver.DefaultScorer()
+	scores := make([]NGramScore, len(candidates))
+	for i, candidate := range candidates {
+		scores[i] = NGramScore{Text: candidate}
+		if score, ok := scorer.ScoreText(candidate); ok {
+			score = round(score)
+			scores[i].Score = &score
+		}
+	}
+
+	// Candidates too short to score rank last
+	sort.SliceStable(scores, func(i, j int) bool {
+		a, b := scores[i].Score, scores[j].Score
+		return a != nil && (b == nil || *a > *b)
+	})
+	for i := range scores {
+		scores[i].Rank = i + 1
+	}
+	return scores
+}
+
+func preview(text string) string {
+	runes := []rune(text)
+	if len(runes) <= caesarPreviewSize {
+		return text
+	}
+	return string(runes[:caesarPreviewSize]) + "…"
+}
+
+func round(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+func round4(x float64) float64 {
+	return math.Round(x*10000) / 10000
+}
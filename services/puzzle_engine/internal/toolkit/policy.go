@@ -0,0 +1,95 @@
+// Package toolkit runs the cryptanalysis tools players can use on a
+// ciphertext: letter frequencies, index of coincidence, Kasiski examination,
+// Caesar brute force and n-gram scoring of candidate plaintexts
+package toolkit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tools
+const (
+	ToolFrequency = "frequency"
+	ToolIoC       = "ioc"
+	ToolKasiski   = "kasiski"
+	ToolCaesar    = "caesar"
+	ToolNGram     = "ngram"
+)
+
+// AllTools lists every tool in the order reports present them
+var AllTools = []string{ToolFrequency, ToolIoC, ToolKasiski, ToolCaesar, ToolNGram}
+
+// Policy maps a game mode to the tools allowed in it. Modes without an entry
+// get no tools, so new competitive modes start out without assistance.
+type Policy map[string][]string
+
+// DefaultPolicy enables the whole toolkit in the practice modes
+func DefaultPolicy() Policy {
+	return Policy{
+		"UNTIMED":   AllTools,
+		"TIMED":     AllTools,
+		"ACCURACY":  AllTools,
+		"SPEED_RUN": AllTools,
+	}
+}
+
+// ParsePolicy overlays "MODE=tool+tool,OTHER=all,RANKED_1V1=none" style
+// overrides on the defaults
+func ParsePolicy(raw string) (Policy, error) {
+	policy := DefaultPolicy()
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		mode, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("toolkit policy %q is not MODE=TOOLS", entry)
+		}
+		mode = strings.TrimSpace(mode)
+
+		switch value = strings.TrimSpace(value); value {
+		case "all":
+			policy[mode] = AllTools
+		case "none":
+			policy[mode] = nil
+		default:
+			var tools []string
+			for _, tool := range strings.Split(value, "+") {
+				tool = strings.TrimSpace(tool)
+				if !isTool(tool) {
+					return nil, fmt.Errorf("unknown toolkit tool %q for %s", tool, mode)
+				}
+				tools = append(tools, tool)
+			}
+			policy[mode] = tools
+		}
+	}
+	return policy, nil
+}
+
+// For returns the tools allowed in a game mode
+func (p Policy) For(mode string) []string {
+	return p[mode]
+}
+
+// Allows reports whether a tool may be used in a game mode
+func (p Policy) Allows(mode, tool string) bool {
+	for _, allowed := range p[mode] {
+		if allowed == tool {
+			return true
+		}
+	}
+	return false
+}
+
+func isTool(name string) bool {
+	for _, tool := range AllTools {
+		if tool == name {
+			return true
+		}
+	}
+	return false
+}
@@ -9,12 +9,18 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/swarit-1/cipher-clash/pkg/audit"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
+	"github.com/swarit-1/cipher-clash/pkg/grading"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/corpus"
 	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/handler"
 	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/service"
+	"github.com/swarit-1/cipher-clash/services/puzzle_engine/internal/toolkit"
 )
 
 func main() {
@@ -60,20 +66,99 @@ func main() {
 	}
 	defer cacheClient.Close()
 
+	// Load plaintext corpus (embedded texts plus curated texts from the database)
+	textCorpus := corpus.New(log, corpus.NewEmbeddedSource(), corpus.NewDBSource(database))
+	if err := textCorpus.Reload(context.Background()); err != nil {
+		log.Fatal("Failed to load plaintext corpus", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	log.Info("Plaintext corpus loaded", map[string]interface{}{
+		"texts": textCorpus.Size(),
+	})
+
+	// Daily puzzle seeds must match across instances, so the secret is shared config
+	seedSecret := os.Getenv("PUZZLE_SEED_SECRET")
+	if seedSecret == "" {
+		seedSecret = "dev-puzzle-seed-change-in-production"
+		log.Warn("PUZZLE_SEED_SECRET not set, daily puzzles are predictable")
+	}
+
+	if cfg.Internal.ServiceToken == "" {
+		log.Warn("INTERNAL_SERVICE_TOKEN not set, internal-only API fields are disabled")
+	}
+
+	thresholds, err := grading.ParseThresholds(cfg.Grading.PassThresholds)
+	if err != nil {
+		log.Fatal("Invalid GRADING_PASS_THRESHOLDS", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	toolkitPolicy, err := toolkit.ParsePolicy(cfg.Toolkit.ModeTools)
+	if err != nil {
+		log.Fatal("Invalid TOOLKIT_MODE_TOOLS", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
 	// Initialize services
-	puzzleService := service.NewPuzzleService(database, cacheClient, log)
+	puzzleService := service.NewPuzzleService(database, cacheClient, textCorpus, seedSecret, thresholds, toolkitPolicy, log)
+
+	// Keep the puzzle pool stocked in the background
+	poolCtx, stopPool := context.WithCancel(context.Background())
+	defer stopPool()
+	poolBuilder := service.NewPoolBuilder(puzzleService, service.DefaultPoolConfig(), log)
+	go poolBuilder.Run(poolCtx)
+
+	// Erase what this service keeps about users who delete their account
+	// (optional - warn but continue if RabbitMQ unavailable)
+	userDeletions, err := messaging.ListenUserDeleted(cfg.RabbitMQ, "puzzle_engine", puzzleService.DeleteUserData, log)
+	if err != nil {
+		log.Warn("RabbitMQ unavailable - deleted users' puzzle history won't be erased", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else {
+		defer userDeletions.Close()
+	}
 
 	// Initialize handlers
-	puzzleHandler := handler.NewPuzzleHandler(puzzleService, log)
+	puzzleHandler := handler.NewPuzzleHandler(puzzleService, audit.NewLog(database, log), log)
+
+	// Initialize JWT verifier, which checks tokens against the auth service's keys
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT verifier", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, log)
 
 	// Setup HTTP router
 	mux := http.NewServeMux()
 
-	// Public routes
+	// Public routes. Requests naming a user_id must come from that user or
+	// another service.
 	mux.HandleFunc("/health", puzzleHandler.Health)
-	mux.HandleFunc("/api/v1/puzzle/generate", puzzleHandler.GeneratePuzzle)
-	mux.HandleFunc("/api/v1/puzzle/validate", puzzleHandler.ValidateSolution)
+	mux.HandleFunc("/api/v1/puzzle/generate", authGuard.OptionalAuth(puzzleHandler.GeneratePuzzle))
 	mux.HandleFunc("/api/v1/puzzle/get", puzzleHandler.GetPuzzle)
+	mux.HandleFunc("/api/v1/puzzle/daily", puzzleHandler.GetDailyPuzzle)
+	mux.HandleFunc("/api/v1/puzzle/hint", authGuard.OptionalAuth(puzzleHandler.RequestHint))
+	mux.HandleFunc("/api/v1/puzzle/stats", puzzleHandler.GetPuzzleStats)
+	mux.HandleFunc("/api/v1/puzzle/custom", authGuard.OptionalAuth(puzzleHandler.CustomPuzzles))
+	mux.HandleFunc("/api/v1/puzzle/toolkit", authGuard.OptionalAuth(puzzleHandler.Toolkit))
+
+	// Protected routes
+	mux.HandleFunc("/api/v1/puzzle/validate", authGuard.RequireAuth(puzzleHandler.ValidateSolution))
+	mux.HandleFunc("/api/v1/puzzle/custom/play", authGuard.RequireAuth(puzzleHandler.PlayCustomPuzzle))
+	mux.HandleFunc("/api/v1/puzzle/custom/rate", authGuard.RequireAuth(puzzleHandler.RateCustomPuzzle))
+	mux.HandleFunc("/api/v1/puzzle/report", authGuard.RequireAuth(puzzleHandler.ReportPuzzle))
+	mux.HandleFunc("/api/v1/puzzle/admin/moderation", authGuard.RequireScope(auth.ScopeModerator, puzzleHandler.ModerationQueue))
+	mux.HandleFunc("/api/v1/puzzle/admin/review", authGuard.RequireScope(auth.ScopeModerator, puzzleHandler.ReviewPuzzle))
+
+	// Internal routes. Match sets name their players, so only the services
+	// that run matches may ask for one.
+	mux.HandleFunc("/api/v1/puzzle/match", authGuard.RequireScope(auth.ScopeService, puzzleHandler.GenerateMatchPuzzles))
 
 	// Create HTTP server
 	addr := "0.0.0.0:" + port
@@ -103,6 +188,7 @@ func main() {
 	<-quit
 
 	log.Info("Shutting down server...")
+	stopPool()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
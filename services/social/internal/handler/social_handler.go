@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
 	"github.com/swarit-1/cipher-clash/services/social/internal/service"
@@ -32,6 +33,11 @@ func (h *SocialHandler) GetFriends(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	friends, err := h.socialService.GetFriends(r.Context(), userID)
 	if err != nil {
 		h.respondError(w, err)
@@ -56,6 +62,11 @@ func (h *SocialHandler) SendFriendRequest(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.FromUserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	friendship, err := h.socialService.SendFriendRequest(r.Context(), req.FromUserID, req.ToUserID)
 	if err != nil {
 		h.respondError(w, err)
@@ -80,6 +91,11 @@ func (h *SocialHandler) AcceptFriendRequest(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	friendship, err := h.socialService.AcceptFriendRequest(r.Context(), req.UserID, req.FriendID)
 	if err != nil {
 		h.respondError(w, err)
@@ -104,6 +120,11 @@ func (h *SocialHandler) RejectFriendRequest(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	if err := h.socialService.RejectFriendRequest(r.Context(), req.UserID, req.FriendID); err != nil {
 		h.respondError(w, err)
 		return
@@ -126,6 +147,11 @@ func (h *SocialHandler) RemoveFriend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	if err := h.socialService.RemoveFriend(r.Context(), req.UserID, req.FriendID); err != nil {
 		h.respondError(w, err)
 		return
@@ -145,6 +171,11 @@ func (h *SocialHandler) GetPendingRequests(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	requests, err := h.socialService.GetPendingRequests(r.Context(), userID)
 	if err != nil {
 		h.respondError(w, err)
@@ -163,6 +194,7 @@ func (h *SocialHandler) SendMatchInvite(w http.ResponseWriter, r *http.Request)
 		FromUserID uuid.UUID `json:"from_user_id"`
 		ToUserID   uuid.UUID `json:"to_user_id"`
 		GameMode   string    `json:"game_mode"`
+		ShareCode  string    `json:"share_code"` // Optional custom puzzle
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -170,7 +202,12 @@ func (h *SocialHandler) SendMatchInvite(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	invite, err := h.socialService.SendMatchInvite(r.Context(), req.FromUserID, req.ToUserID, req.GameMode)
+	if err := auth.AuthorizeUser(r.Context(), req.FromUserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	invite, err := h.socialService.SendMatchInvite(r.Context(), req.FromUserID, req.ToUserID, req.GameMode, req.ShareCode)
 	if err != nil {
 		h.respondError(w, err)
 		return
@@ -193,7 +230,13 @@ func (h *SocialHandler) AcceptMatchInvite(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	invite, err := h.socialService.AcceptMatchInvite(r.Context(), req.InviteID)
+	userID, err := h.callerID(r)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	invite, err := h.socialService.AcceptMatchInvite(r.Context(), userID, req.InviteID)
 	if err != nil {
 		h.respondError(w, err)
 		return
@@ -216,7 +259,13 @@ func (h *SocialHandler) RejectMatchInvite(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := h.socialService.RejectMatchInvite(r.Context(), req.InviteID); err != nil {
+	userID, err := h.callerID(r)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	if err := h.socialService.RejectMatchInvite(r.Context(), userID, req.InviteID); err != nil {
 		h.respondError(w, err)
 		return
 	}
@@ -235,6 +284,11 @@ func (h *SocialHandler) GetMatchInvites(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), userID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	invites, err := h.socialService.GetMatchInvites(r.Context(), userID)
 	if err != nil {
 		h.respondError(w, err)
@@ -259,6 +313,11 @@ func (h *SocialHandler) JoinAsSpectator(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if err := auth.AuthorizeUser(r.Context(), req.UserID.String()); err != nil {
+		h.respondError(w, err)
+		return
+	}
+
 	session, err := h.socialService.JoinAsSpectator(r.Context(), req.UserID, req.MatchID)
 	if err != nil {
 		h.respondError(w, err)
@@ -282,7 +341,13 @@ func (h *SocialHandler) LeaveSpectatorMode(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := h.socialService.LeaveSpectatorMode(r.Context(), req.SessionID); err != nil {
+	userID, err := h.callerID(r)
+	if err != nil {
+		h.respondError(w, err)
+		return
+	}
+
+	if err := h.socialService.LeaveSpectatorMode(r.Context(), userID, req.SessionID); err != nil {
 		h.respondError(w, err)
 		return
 	}
@@ -314,6 +379,20 @@ func (h *SocialHandler) GetSpectators(w http.ResponseWriter, r *http.Request) {
 }
 
 // Helper methods
+
+// callerID returns the ID of the player making the request
+func (h *SocialHandler) callerID(r *http.Request) (uuid.UUID, error) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		return uuid.Nil, errors.NewForbiddenError("Only players can do this")
+	}
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, errors.NewUnauthorizedError("Invalid user ID")
+	}
+	return id, nil
+}
+
 func (h *SocialHandler) respondJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -22,6 +22,7 @@ type MatchInvite struct {
 	FromUserID uuid.UUID `json:"from_user_id"`
 	ToUserID   uuid.UUID `json:"to_user_id"`
 	GameMode   string    `json:"game_mode"`
+	ShareCode  string    `json:"share_code,omitempty"` // Custom puzzle to play, if any
 	Status     string    `json:"status"`
 	CreatedAt  time.Time `json:"created_at"`
 	ExpiresAt  time.Time `json:"expires_at"`
@@ -20,6 +20,7 @@ type FriendsRepository interface {
 	GetPendingRequests(ctx context.Context, userID uuid.UUID) ([]*models.Friendship, error)
 	UpdateFriendship(ctx context.Context, friendship *models.Friendship) error
 	DeleteFriendship(ctx context.Context, friendshipID uuid.UUID) error
+	DeleteUserFriendships(ctx context.Context, userID uuid.UUID) error
 }
 
 type friendsRepository struct {
@@ -133,6 +134,12 @@ func (r *friendsRepository) DeleteFriendship(ctx context.Context, friendshipID u
 	return err
 }
 
+func (r *friendsRepository) DeleteUserFriendships(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM friendships WHERE user1_id = $1 OR user2_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
 // ============================================================================
 // INVITES REPOSITORY
 // ============================================================================
@@ -142,6 +149,7 @@ type InvitesRepository interface {
 	GetInvite(ctx context.Context, inviteID uuid.UUID) (*models.MatchInvite, error)
 	GetUserInvites(ctx context.Context, userID uuid.UUID) ([]*models.MatchInvite, error)
 	UpdateInvite(ctx context.Context, invite *models.MatchInvite) error
+	DeleteUserInvites(ctx context.Context, userID uuid.UUID) error
 }
 
 type invitesRepository struct {
@@ -154,11 +162,11 @@ func NewInvitesRepository(db *sql.DB) InvitesRepository {
 
 func (r *invitesRepository) CreateInvite(ctx context.Context, invite *models.MatchInvite) error {
 	query := `
-		INSERT INTO match_invitations (id, from_user_id, to_user_id, game_mode, status, created_at, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO match_invitations (id, from_user_id, to_user_id, game_mode, share_code, status, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8)
 	`
 	_, err := r.db.ExecContext(ctx, query,
-		invite.ID, invite.FromUserID, invite.ToUserID, invite.GameMode,
+		invite.ID, invite.FromUserID, invite.ToUserID, invite.GameMode, invite.ShareCode,
 		invite.Status, invite.CreatedAt, invite.ExpiresAt,
 	)
 	return err
@@ -166,13 +174,13 @@ func (r *invitesRepository) CreateInvite(ctx context.Context, invite *models.Mat
 
 func (r *invitesRepository) GetInvite(ctx context.Context, inviteID uuid.UUID) (*models.MatchInvite, error) {
 	query := `
-		SELECT id, from_user_id, to_user_id, game_mode, status, created_at, expires_at
+		SELECT id, from_user_id, to_user_id, game_mode, COALESCE(share_code, ''), status, created_at, expires_at
 		FROM match_invitations
 		WHERE id = $1
 	`
 	invite := &models.MatchInvite{}
 	err := r.db.QueryRowContext(ctx, query, inviteID).Scan(
-		&invite.ID, &invite.FromUserID, &invite.ToUserID, &invite.GameMode,
+		&invite.ID, &invite.FromUserID, &invite.ToUserID, &invite.GameMode, &invite.ShareCode,
 		&invite.Status, &invite.CreatedAt, &invite.ExpiresAt,
 	)
 	if err == sql.ErrNoRows {
@@ -183,7 +191,7 @@ func (r *invitesRepository) GetInvite(ctx context.Context, inviteID uuid.UUID) (
 
 func (r *invitesRepository) GetUserInvites(ctx context.Context, userID uuid.UUID) ([]*models.MatchInvite, error) {
 	query := `
-		SELECT id, from_user_id, to_user_id, game_mode, status, created_at, expires_at
+		SELECT id, from_user_id, to_user_id, game_mode, COALESCE(share_code, ''), status, created_at, expires_at
 		FROM match_invitations
 		WHERE to_user_id = $1 AND status = 'pending' AND expires_at > NOW()
 		ORDER BY created_at DESC
@@ -197,7 +205,7 @@ func (r *invitesRepository) GetUserInvites(ctx context.Context, userID uuid.UUID
 	var invites []*models.MatchInvite
 	for rows.Next() {
 		invite := &models.MatchInvite{}
-		if err := rows.Scan(&invite.ID, &invite.FromUserID, &invite.ToUserID, &invite.GameMode, &invite.Status, &invite.CreatedAt, &invite.ExpiresAt); err != nil {
+		if err := rows.Scan(&invite.ID, &invite.FromUserID, &invite.ToUserID, &invite.GameMode, &invite.ShareCode, &invite.Status, &invite.CreatedAt, &invite.ExpiresAt); err != nil {
 			return nil, err
 		}
 		invites = append(invites, invite)
@@ -211,6 +219,12 @@ func (r *invitesRepository) UpdateInvite(ctx context.Context, invite *models.Mat
 	return err
 }
 
+func (r *invitesRepository) DeleteUserInvites(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM match_invitations WHERE from_user_id = $1 OR to_user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
+	return err
+}
+
 // ============================================================================
 // SPECTATOR REPOSITORY
 // ============================================================================
@@ -218,7 +232,8 @@ func (r *invitesRepository) UpdateInvite(ctx context.Context, invite *models.Mat
 type SpectatorRepository interface {
 	CreateSession(ctx context.Context, session *models.SpectatorSession) error
 	GetMatchSpectators(ctx context.Context, matchID uuid.UUID) ([]*models.SpectatorSession, error)
-	EndSession(ctx context.Context, sessionID uuid.UUID) error
+	EndSession(ctx context.Context, userID, sessionID uuid.UUID) (bool, error)
+	DeleteUserSessions(ctx context.Context, userID uuid.UUID) error
 }
 
 type spectatorRepository struct {
@@ -266,8 +281,18 @@ func (r *spectatorRepository) GetMatchSpectators(ctx context.Context, matchID uu
 	return spectators, rows.Err()
 }
 
-func (r *spectatorRepository) EndSession(ctx context.Context, sessionID uuid.UUID) error {
-	query := `UPDATE spectator_sessions SET left_at = $1 WHERE id = $2`
-	_, err := r.db.ExecContext(ctx, query, time.Now(), sessionID)
+func (r *spectatorRepository) EndSession(ctx context.Context, userID, sessionID uuid.UUID) (bool, error) {
+	query := `UPDATE spectator_sessions SET left_at = $1 WHERE id = $2 AND user_id = $3 AND left_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, time.Now(), sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+func (r *spectatorRepository) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	query := `DELETE FROM spectator_sessions WHERE user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID)
 	return err
 }
@@ -2,11 +2,14 @@ package service
 
 import (
 	"context"
+	stderrors "errors"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/puzzleclient"
 	"github.com/swarit-1/cipher-clash/services/social/internal/models"
 	"github.com/swarit-1/cipher-clash/services/social/internal/repository"
 )
@@ -15,6 +18,7 @@ type SocialService struct {
 	friendsRepo   repository.FriendsRepository
 	invitesRepo   repository.InvitesRepository
 	spectatorRepo repository.SpectatorRepository
+	puzzles       *puzzleclient.Client
 	log           *logger.Logger
 }
 
@@ -22,12 +26,14 @@ func NewSocialService(
 	friendsRepo repository.FriendsRepository,
 	invitesRepo repository.InvitesRepository,
 	spectatorRepo repository.SpectatorRepository,
+	puzzles *puzzleclient.Client,
 	log *logger.Logger,
 ) *SocialService {
 	return &SocialService{
 		friendsRepo:   friendsRepo,
 		invitesRepo:   invitesRepo,
 		spectatorRepo: spectatorRepo,
+		puzzles:       puzzles,
 		log:           log,
 	}
 }
@@ -71,10 +77,11 @@ func (s *SocialService) SendFriendRequest(ctx context.Context, fromUserID, toUse
 	return friendship, nil
 }
 
-// AcceptFriendRequest accepts a friend request
+// AcceptFriendRequest accepts a friend request sent to userID. GetFriendship
+// matches either direction, so the sender is ruled out explicitly.
 func (s *SocialService) AcceptFriendRequest(ctx context.Context, userID, friendID uuid.UUID) (*models.Friendship, error) {
 	friendship, err := s.friendsRepo.GetFriendship(ctx, friendID, userID)
-	if err != nil || friendship == nil {
+	if err != nil || friendship == nil || friendship.User2ID != userID {
 		return nil, errors.NewNotFoundError("Friend request not found")
 	}
 
@@ -94,10 +101,11 @@ func (s *SocialService) AcceptFriendRequest(ctx context.Context, userID, friendI
 	return friendship, nil
 }
 
-// RejectFriendRequest rejects a friend request
+// RejectFriendRequest rejects a pending friend request sent to userID.
+// Accepted friendships are ended with RemoveFriend instead.
 func (s *SocialService) RejectFriendRequest(ctx context.Context, userID, friendID uuid.UUID) error {
 	friendship, err := s.friendsRepo.GetFriendship(ctx, friendID, userID)
-	if err != nil || friendship == nil {
+	if err != nil || friendship == nil || friendship.User2ID != userID || friendship.Status != "pending" {
 		return errors.NewNotFoundError("Friend request not found")
 	}
 
@@ -134,8 +142,9 @@ func (s *SocialService) GetPendingRequests(ctx context.Context, userID uuid.UUID
 	return requests, nil
 }
 
-// SendMatchInvite sends a match invite
-func (s *SocialService) SendMatchInvite(ctx context.Context, fromUserID, toUserID uuid.UUID, gameMode string) (*models.MatchInvite, error) {
+// SendMatchInvite sends a match invite. A share code makes it a friend match
+// on that custom puzzle instead of a generated one.
+func (s *SocialService) SendMatchInvite(ctx context.Context, fromUserID, toUserID uuid.UUID, gameMode, shareCode string) (*models.MatchInvite, error) {
 	invite := &models.MatchInvite{
 		ID:         uuid.New(),
 		FromUserID: fromUserID,
@@ -146,19 +155,32 @@ func (s *SocialService) SendMatchInvite(ctx context.Context, fromUserID, toUserI
 		ExpiresAt:  time.Now().Add(5 * time.Minute),
 	}
 
+	if shareCode != "" {
+		puzzle, err := s.puzzles.GetCustomPuzzle(ctx, shareCode)
+		if err != nil {
+			var engineErr *puzzleclient.Error
+			if stderrors.As(err, &engineErr) && engineErr.StatusCode == http.StatusNotFound {
+				return nil, errors.NewNotFoundError("Custom puzzle not found")
+			}
+			s.log.LogError("Failed to look up custom puzzle", "share_code", shareCode, "error", err)
+			return nil, errors.NewInternalError("Failed to send match invite")
+		}
+		invite.ShareCode = puzzle.ShareCode
+	}
+
 	if err := s.invitesRepo.CreateInvite(ctx, invite); err != nil {
 		s.log.LogError("Failed to create invite", "error", err)
 		return nil, errors.NewInternalError("Failed to send match invite")
 	}
 
-	s.log.LogInfo("Match invite sent", "from", fromUserID, "to", toUserID, "mode", gameMode)
+	s.log.LogInfo("Match invite sent", "from", fromUserID, "to", toUserID, "mode", gameMode, "share_code", invite.ShareCode)
 	return invite, nil
 }
 
 // AcceptMatchInvite accepts a match invite
-func (s *SocialService) AcceptMatchInvite(ctx context.Context, inviteID uuid.UUID) (*models.MatchInvite, error) {
+func (s *SocialService) AcceptMatchInvite(ctx context.Context, userID, inviteID uuid.UUID) (*models.MatchInvite, error) {
 	invite, err := s.invitesRepo.GetInvite(ctx, inviteID)
-	if err != nil || invite == nil {
+	if err != nil || invite == nil || invite.ToUserID != userID {
 		return nil, errors.NewNotFoundError("Match invite not found")
 	}
 
@@ -180,9 +202,9 @@ func (s *SocialService) AcceptMatchInvite(ctx context.Context, inviteID uuid.UUI
 }
 
 // RejectMatchInvite rejects a match invite
-func (s *SocialService) RejectMatchInvite(ctx context.Context, inviteID uuid.UUID) error {
+func (s *SocialService) RejectMatchInvite(ctx context.Context, userID, inviteID uuid.UUID) error {
 	invite, err := s.invitesRepo.GetInvite(ctx, inviteID)
-	if err != nil || invite == nil {
+	if err != nil || invite == nil || invite.ToUserID != userID {
 		return errors.NewNotFoundError("Match invite not found")
 	}
 
@@ -224,10 +246,14 @@ func (s *SocialService) JoinAsSpectator(ctx context.Context, userID, matchID uui
 }
 
 // LeaveSpectatorMode removes a user from spectator mode
-func (s *SocialService) LeaveSpectatorMode(ctx context.Context, sessionID uuid.UUID) error {
-	if err := s.spectatorRepo.EndSession(ctx, sessionID); err != nil {
+func (s *SocialService) LeaveSpectatorMode(ctx context.Context, userID, sessionID uuid.UUID) error {
+	ended, err := s.spectatorRepo.EndSession(ctx, userID, sessionID)
+	if err != nil {
 		return errors.NewInternalError("Failed to leave spectator mode")
 	}
+	if !ended {
+		return errors.NewNotFoundError("Spectator session not found")
+	}
 
 	s.log.LogInfo("User left spectator mode", "session_id", sessionID)
 	return nil
@@ -242,3 +268,22 @@ func (s *SocialService) GetSpectators(ctx context.Context, matchID uuid.UUID) ([
 	}
 	return spectators, nil
 }
+
+// DeleteUserData erases the friendships, match invites and spectating
+// history of a user who deleted their account
+func (s *SocialService) DeleteUserData(ctx context.Context, userID uuid.UUID) error {
+	if err := s.friendsRepo.DeleteUserFriendships(ctx, userID); err != nil {
+		s.log.LogError("Failed to delete friendships", "user_id", userID, "error", err)
+		return errors.NewInternalError("Failed to delete friendships")
+	}
+	if err := s.invitesRepo.DeleteUserInvites(ctx, userID); err != nil {
+		s.log.LogError("Failed to delete match invites", "user_id", userID, "error", err)
+		return errors.NewInternalError("Failed to delete match invites")
+	}
+	if err := s.spectatorRepo.DeleteUserSessions(ctx, userID); err != nil {
+		s.log.LogError("Failed to delete spectator sessions", "user_id", userID, "error", err)
+		return errors.NewInternalError("Failed to delete spectator sessions")
+	}
+
+	return nil
+}
@@ -10,9 +10,13 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
+	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
+	"github.com/swarit-1/cipher-clash/pkg/puzzleclient"
 	"github.com/swarit-1/cipher-clash/services/social/internal/handler"
 	"github.com/swarit-1/cipher-clash/services/social/internal/repository"
 	"github.com/swarit-1/cipher-clash/services/social/internal/service"
@@ -38,19 +42,47 @@ func main() {
 
 	log.LogInfo("Connected to database successfully")
 
+	// Initialize cache
+	cacheClient, err := cache.New(cfg.Redis, log)
+	if err != nil {
+		log.Fatal("Failed to connect to Redis", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	defer cacheClient.Close()
+
 	// Initialize repositories
 	friendsRepo := repository.NewFriendsRepository(database.DB)
 	invitesRepo := repository.NewInvitesRepository(database.DB)
 	spectatorRepo := repository.NewSpectatorRepository(database.DB)
 
+	// Puzzle engine client, for friend matches on custom puzzles
+	puzzles := puzzleclient.New(cfg.Internal.PuzzleEngineURL, cfg.Internal.ServiceToken)
+
 	// Initialize service
-	socialService := service.NewSocialService(friendsRepo, invitesRepo, spectatorRepo, log)
+	socialService := service.NewSocialService(friendsRepo, invitesRepo, spectatorRepo, puzzles, log)
+
+	// Erase what this service keeps about users who delete their account
+	// (optional - warn but continue if RabbitMQ unavailable)
+	userDeletions, err := messaging.ListenUserDeleted(cfg.RabbitMQ, "social", socialService.DeleteUserData, log)
+	if err != nil {
+		log.LogWarn("RabbitMQ unavailable - deleted users' friendships and invites won't be erased", "error", err)
+	} else {
+		defer userDeletions.Close()
+	}
 
 	// Initialize handler
 	socialHandler := handler.NewSocialHandler(socialService, log)
 
+	// Tokens are checked against the auth service's published keys
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT verifier", map[string]interface{}{"error": err})
+	}
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, log)
+
 	// Setup router
-	router := setupRouter(socialHandler)
+	router := setupRouter(socialHandler, authGuard)
 
 	// Setup CORS
 	corsHandler := cors.New(cors.Options{
@@ -96,28 +128,28 @@ func main() {
 	log.LogInfo("Social Service stopped")
 }
 
-func setupRouter(h *handler.SocialHandler) *mux.Router {
+func setupRouter(h *handler.SocialHandler, authGuard *auth.Middleware) *mux.Router {
 	r := mux.NewRouter()
 	api := r.PathPrefix("/api/v1").Subrouter()
 
 	// Friends
-	api.HandleFunc("/friends/{user_id}", h.GetFriends).Methods("GET")
-	api.HandleFunc("/friends/request", h.SendFriendRequest).Methods("POST")
-	api.HandleFunc("/friends/accept", h.AcceptFriendRequest).Methods("POST")
-	api.HandleFunc("/friends/reject", h.RejectFriendRequest).Methods("POST")
-	api.HandleFunc("/friends/remove", h.RemoveFriend).Methods("DELETE")
-	api.HandleFunc("/friends/pending/{user_id}", h.GetPendingRequests).Methods("GET")
+	api.HandleFunc("/friends/{user_id}", authGuard.RequireAuth(h.GetFriends)).Methods("GET")
+	api.HandleFunc("/friends/request", authGuard.RequireAuth(h.SendFriendRequest)).Methods("POST")
+	api.HandleFunc("/friends/accept", authGuard.RequireAuth(h.AcceptFriendRequest)).Methods("POST")
+	api.HandleFunc("/friends/reject", authGuard.RequireAuth(h.RejectFriendRequest)).Methods("POST")
+	api.HandleFunc("/friends/remove", authGuard.RequireAuth(h.RemoveFriend)).Methods("DELETE")
+	api.HandleFunc("/friends/pending/{user_id}", authGuard.RequireAuth(h.GetPendingRequests)).Methods("GET")
 
 	// Match invites
-	api.HandleFunc("/invites/send", h.SendMatchInvite).Methods("POST")
-	api.HandleFunc("/invites/accept", h.AcceptMatchInvite).Methods("POST")
-	api.HandleFunc("/invites/reject", h.RejectMatchInvite).Methods("POST")
-	api.HandleFunc("/invites/{user_id}", h.GetMatchInvites).Methods("GET")
+	api.HandleFunc("/invites/send", authGuard.RequireAuth(h.SendMatchInvite)).Methods("POST")
+	api.HandleFunc("/invites/accept", authGuard.RequireScope(auth.ScopePlayer, h.AcceptMatchInvite)).Methods("POST")
+	api.HandleFunc("/invites/reject", authGuard.RequireScope(auth.ScopePlayer, h.RejectMatchInvite)).Methods("POST")
+	api.HandleFunc("/invites/{user_id}", authGuard.RequireAuth(h.GetMatchInvites)).Methods("GET")
 
 	// Spectator
-	api.HandleFunc("/spectator/join", h.JoinAsSpectator).Methods("POST")
-	api.HandleFunc("/spectator/leave", h.LeaveSpectatorMode).Methods("POST")
-	api.HandleFunc("/spectator/match/{match_id}", h.GetSpectators).Methods("GET")
+	api.HandleFunc("/spectator/join", authGuard.RequireAuth(h.JoinAsSpectator)).Methods("POST")
+	api.HandleFunc("/spectator/leave", authGuard.RequireScope(auth.ScopePlayer, h.LeaveSpectatorMode)).Methods("POST")
+	api.HandleFunc("/spectator/match/{match_id}", authGuard.RequireAuth(h.GetSpectators)).Methods("GET")
 
 	// Health
 	r.HandleFunc("/health", healthCheck).Methods("GET")
@@ -4,9 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
+	"github.com/swarit-1/cipher-clash/pkg/errors"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/puzzleclient"
 	"github.com/swarit-1/cipher-clash/services/tutorial/internal"
 	"github.com/swarit-1/cipher-clash/services/tutorial/internal/service"
 )
@@ -14,20 +19,23 @@ import (
 type TutorialHandler struct {
 	tutorialService   service.TutorialService
 	visualizerService service.VisualizerService
-	jwtManager        *auth.JWTManager
+	puzzles           *puzzleclient.Client
+	cache             *cache.Cache
 	log               *logger.Logger
 }
 
 func NewTutorialHandler(
 	tutorialService service.TutorialService,
 	visualizerService service.VisualizerService,
-	jwtManager *auth.JWTManager,
+	puzzles *puzzleclient.Client,
+	cacheClient *cache.Cache,
 	log *logger.Logger,
 ) *TutorialHandler {
 	return &TutorialHandler{
 		tutorialService:   tutorialService,
 		visualizerService: visualizerService,
-		jwtManager:        jwtManager,
+		puzzles:           puzzles,
+		cache:             cacheClient,
 		log:               log,
 	}
 }
@@ -51,6 +59,10 @@ func (h *TutorialHandler) GetTutorialSteps(w http.ResponseWriter, r *http.Reques
 	userID := r.URL.Query().Get("user_id")
 
 	if userID != "" {
+		if !h.authorize(w, r, userID) {
+			return
+		}
+
 		// Return steps with progress
 		stepsWithProgress, err := h.tutorialService.GetStepsWithProgress(r.Context(), userID)
 		if err != nil {
@@ -98,6 +110,9 @@ func (h *TutorialHandler) GetUserProgress(w http.ResponseWriter, r *http.Request
 		http.Error(w, `{"error":"user_id is required"}`, http.StatusBadRequest)
 		return
 	}
+	if !h.authorize(w, r, userID) {
+		return
+	}
 
 	progress, err := h.tutorialService.GetUserProgress(r.Context(), userID)
 	if err != nil {
@@ -142,6 +157,9 @@ func (h *TutorialHandler) UpdateProgress(w http.ResponseWriter, r *http.Request)
 		http.Error(w, `{"error":"user_id and step_id are required"}`, http.StatusBadRequest)
 		return
 	}
+	if !h.authorize(w, r, req.UserID) {
+		return
+	}
 
 	err := h.tutorialService.UpdateProgress(r.Context(), req.UserID, req.StepID, req.TimeSpentSecs, req.Score)
 	if err != nil {
@@ -176,6 +194,9 @@ func (h *TutorialHandler) CompleteStep(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"user_id and step_id are required"}`, http.StatusBadRequest)
 		return
 	}
+	if !h.authorize(w, r, req.UserID) {
+		return
+	}
 
 	err := h.tutorialService.CompleteStep(r.Context(), req.UserID, req.StepID, req.TimeSpentSecs, req.Score)
 	if err != nil {
@@ -221,6 +242,9 @@ func (h *TutorialHandler) SkipTutorial(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"user_id is required"}`, http.StatusBadRequest)
 		return
 	}
+	if !h.authorize(w, r, req.UserID) {
+		return
+	}
 
 	err := h.tutorialService.SkipTutorial(r.Context(), req.UserID)
 	if err != nil {
@@ -304,6 +328,19 @@ func (h *TutorialHandler) GetAvailableVisualizers(w http.ResponseWriter, r *http
 	})
 }
 
+// botBattleTTL is how long a started bot battle can still be submitted
+const botBattleTTL = time.Hour
+
+func botBattleKey(battleID string) string {
+	return "tutorial:bot_battle:" + battleID
+}
+
+// botSolveSeconds is how long the tutorial bot takes on a puzzle; beating it
+// wins the battle
+func botSolveSeconds(difficulty int) int {
+	return 30 + 15*difficulty
+}
+
 func (h *TutorialHandler) StartBotBattle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
@@ -320,17 +357,51 @@ func (h *TutorialHandler) StartBotBattle(w http.ResponseWriter, r *http.Request)
 		http.Error(w, `{"error":"user_id and cipher_type are required"}`, http.StatusBadRequest)
 		return
 	}
+	if !h.authorize(w, r, req.UserID) {
+		return
+	}
 
-	// This is a simplified implementation
-	// In a real scenario, you would create a bot battle in the database
-	// and possibly call the puzzle engine service to generate a puzzle
+	puzzle, err := h.puzzles.Generate(r.Context(), &puzzleclient.GenerateRequest{
+		CipherType: req.CipherType,
+		Difficulty: req.Difficulty,
+		UserID:     req.UserID,
+	})
+	if err != nil {
+		h.log.Error("Failed to generate bot battle puzzle", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": req.UserID,
+		})
+		http.Error(w, `{"error":"Failed to start bot battle"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// The battle is kept server-side so submissions, which get per-letter
+	// feedback, only ever grade the puzzle generated for this user
+	battle := internal.BotBattle{
+		ID:         uuid.New().String(),
+		UserID:     req.UserID,
+		PuzzleID:   puzzle.ID,
+		CipherType: puzzle.CipherType,
+		Difficulty: puzzle.Difficulty,
+		StartedAt:  time.Now(),
+	}
+	if err := h.cache.Set(r.Context(), botBattleKey(battle.ID), battle, botBattleTTL); err != nil {
+		h.log.Error("Failed to save bot battle", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": req.UserID,
+		})
+		http.Error(w, `{"error":"Failed to start bot battle"}`, http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":     "Bot battle started",
-		"battle_id":   "bot-battle-123",
-		"cipher_type": req.CipherType,
-		"difficulty":  req.Difficulty,
+		"message":        "Bot battle started",
+		"battle_id":      battle.ID,
+		"cipher_type":    puzzle.CipherType,
+		"difficulty":     puzzle.Difficulty,
+		"encrypted_text": puzzle.EncryptedText,
+		"bot_solve_time": botSolveSeconds(puzzle.Difficulty),
 	})
 }
 
@@ -350,15 +421,60 @@ func (h *TutorialHandler) SubmitBotBattleSolution(w http.ResponseWriter, r *http
 		http.Error(w, `{"error":"battle_id, user_id, and solution are required"}`, http.StatusBadRequest)
 		return
 	}
+	if !h.authorize(w, r, req.UserID) {
+		return
+	}
+
+	var battle internal.BotBattle
+	if err := h.cache.Get(r.Context(), botBattleKey(req.BattleID), &battle); err != nil || battle.UserID != req.UserID {
+		http.Error(w, `{"error":"Bot battle not found"}`, http.StatusNotFound)
+		return
+	}
 
-	// This is a simplified implementation
-	// In a real scenario, you would validate the solution and update the battle
+	result, err := h.puzzles.Validate(r.Context(), &puzzleclient.ValidateRequest{
+		PuzzleID:        battle.PuzzleID,
+		Solution:        req.Solution,
+		SolveTimeMs:     int64(req.SolveTimeSec) * 1000,
+		UserID:          req.UserID,
+		IncludeFeedback: true,
+	})
+	if err != nil {
+		h.log.Error("Failed to validate bot battle solution", map[string]interface{}{
+			"error":     err.Error(),
+			"battle_id": req.BattleID,
+		})
+		http.Error(w, `{"error":"Failed to submit solution"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// A battle ends with its first correct solution
+	if result.IsCorrect {
+		h.cache.Delete(r.Context(), botBattleKey(battle.ID))
+	}
+
+	botSolveTime := botSolveSeconds(battle.Difficulty)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Solution submitted",
-		"correct": true,
-		"won":     true,
-		"score":   100,
+		"message":        "Solution submitted",
+		"correct":        result.IsCorrect,
+		"won":            result.IsCorrect && req.SolveTimeSec <= botSolveTime,
+		"score":          result.Score,
+		"bot_solve_time": botSolveTime,
+		"feedback":       result.Feedback,
 	})
 }
+
+// authorize checks the caller may act for userID, writing the error response
+// if not
+func (h *TutorialHandler) authorize(w http.ResponseWriter, r *http.Request, userID string) bool {
+	if err := auth.AuthorizeUser(r.Context(), userID); err != nil {
+		appErr, ok := err.(*errors.AppError)
+		if !ok {
+			appErr = errors.NewInternalServerError(err)
+		}
+		http.Error(w, `{"error":"`+appErr.Message+`"}`, appErr.HTTPStatus)
+		return false
+	}
+	return true
+}
@@ -18,6 +18,7 @@ type ProgressRepository interface {
 	CompleteStep(ctx context.Context, userID, stepID string, timeSpentSecs int, score *int) error
 	GetUserStats(ctx context.Context, userID string) (*internal.UserTutorialStats, error)
 	MarkAllStepsSkipped(ctx context.Context, userID string) error
+	DeleteUserProgress(ctx context.Context, userID string) error
 }
 
 type progressRepository struct {
@@ -302,3 +303,13 @@ func (r *progressRepository) MarkAllStepsSkipped(ctx context.Context, userID str
 
 	return nil
 }
+
+func (r *progressRepository) DeleteUserProgress(ctx context.Context, userID string) error {
+	query := `DELETE FROM user_tutorial_progress WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to delete progress: %w", err)
+	}
+
+	return nil
+}
@@ -18,6 +18,7 @@ type TutorialService interface {
 	CompleteStep(ctx context.Context, userID, stepID string, timeSpentSecs int, score *int) error
 	SkipTutorial(ctx context.Context, userID string) error
 	GetUserStats(ctx context.Context, userID string) (*internal.UserTutorialStats, error)
+	DeleteUserData(ctx context.Context, userID string) error
 }
 
 type tutorialService struct {
@@ -205,3 +206,17 @@ func (s *tutorialService) GetUserStats(ctx context.Context, userID string) (*int
 
 	return stats, nil
 }
+
+// DeleteUserData erases the tutorial progress of a user who deleted their
+// account
+func (s *tutorialService) DeleteUserData(ctx context.Context, userID string) error {
+	if err := s.progressRepo.DeleteUserProgress(ctx, userID); err != nil {
+		s.log.Error("Failed to delete tutorial progress", map[string]interface{}{
+			"error":   err.Error(),
+			"user_id": userID,
+		})
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/swarit-1/cipher-clash/pkg/toycrypto"
+	"github.com/swarit-1/cipher-clash/services/tutorial/internal"
+)
+
+// Defaults for the modern cipher visualizers when no key is given
+const (
+	defaultSPNKey  = "3A94D63F"
+	defaultSPNIV   = "5A5A"
+	defaultLFSRKey = "ACE1"
+	defaultRSABits = 32
+	defaultRSASeed = 1
+
+	// factorSteps bounds the Pollard rho demonstration
+	factorSteps = 1 << 20
+	// tracedKeystreamBytes is how many keystream bytes are shown bit by bit
+	tracedKeystreamBytes = 3
+)
+
+// visualizeSPN walks through the toy block cipher. The key is the 32-bit key
+// in hex, optionally followed by the mode and a hex IV: "3A94D63F:CBC:5A5A".
+func (s *visualizerService) visualizeSPN(input string, key string) (*internal.CipherVisualization, error) {
+	if key == "" {
+		key = defaultSPNKey
+	}
+	parts := strings.Split(key, ":")
+	keyValue, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("SPN key must be up to 8 hex digits")
+	}
+	mode := toycrypto.ModeECB
+	if len(parts) > 1 {
+		mode = strings.ToUpper(parts[1])
+	}
+	ivText := defaultSPNIV
+	if len(parts) > 2 {
+		ivText = parts[2]
+	}
+	iv, err := strconv.ParseUint(ivText, 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("SPN IV must be up to 4 hex digits")
+	}
+
+	spn := toycrypto.NewSPN(uint32(keyValue))
+	ciphertext, err := spn.Encrypt([]byte(input), mode, uint16(iv))
+	if err != nil {
+		return nil, err
+	}
+	output := strings.ToUpper(hex.EncodeToString(ciphertext))
+
+	subkeys := make([]string, 0, toycrypto.SPNRounds+1)
+	for i, subkey := range spn.Subkeys() {
+		subkeys = append(subkeys, fmt.Sprintf("K%d=%04X", i+1, subkey))
+	}
+
+	// The first block the cipher sees, after CBC has mixed in the IV
+	padded := toycrypto.Pad([]byte(input))
+	first := uint16(padded[0])<<8 | uint16(padded[1])
+	if mode == toycrypto.ModeCBC {
+		first ^= uint16(iv)
+	}
+
+	steps := []internal.VisualizationStep{
+		{
+			StepNumber:  1,
+			Title:       "Original Text",
+			Description: "The plaintext as bytes",
+			Input:       input,
+			Output:      strings.ToUpper(hex.EncodeToString([]byte(input))),
+			Explanation: "Block ciphers work on bytes, not letters. Every character becomes one or more bytes, shown here in hex",
+		},
+		{
+			StepNumber:  2,
+			Title:       "Key Schedule",
+			Description: "The 32-bit key is cut into five 16-bit round keys",
+			Input:       fmt.Sprintf("%08X", keyValue),
+			Output:      strings.Join(subkeys, " "),
+			Explanation: "Round key i is the 16 bits starting 4(i-1) bits into the key, so neighbouring round keys overlap",
+		},
+		{
+			StepNumber:  3,
+			Title:       "Padding",
+			Description: "The text is padded to whole 2-byte blocks",
+			Input:       strings.ToUpper(hex.EncodeToString([]byte(input))),
+			Output:      strings.ToUpper(hex.EncodeToString(padded)),
+			Explanation: "PKCS#7 padding adds n bytes of value n, always at least one, so the receiver knows how many to strip",
+		},
+	}
+
+	for _, round := range spn.TraceBlock(first) {
+		explanation := "XOR with the round key, pass each 4-bit nibble through the S-box, then transpose the bits so every S-box feeds all four S-boxes of the next round"
+		if round.Round == toycrypto.SPNRounds {
+			explanation = "The last round skips the permutation and XORs in the fifth round key instead, so every step depends on the key"
+		}
+		steps = append(steps, internal.VisualizationStep{
+			StepNumber:  len(steps) + 1,
+			Title:       fmt.Sprintf("Round %d (first block)", round.Round),
+			Description: fmt.Sprintf("Key mixing with K%d, substitution and permutation", round.Round),
+			Input:       fmt.Sprintf("%016b", round.Input),
+			Output:      fmt.Sprintf("%016b", round.Output),
+			Explanation: explanation,
+			Metadata: map[string]interface{}{
+				"subkey":      fmt.Sprintf("%016b", round.Subkey),
+				"key_mixed":   fmt.Sprintf("%016b", round.KeyMixed),
+				"substituted": fmt.Sprintf("%016b", round.Substituted),
+			},
+		})
+	}
+
+	modeExplanation := "In ECB mode every block is encrypted on its own, so identical plaintext blocks give identical ciphertext blocks and patterns show through"
+	if mode == toycrypto.ModeCBC {
+		modeExplanation = fmt.Sprintf("In CBC mode each block is XORed with the previous ciphertext block (the IV %04X for the first) before encryption, so repeated plaintext no longer shows", iv)
+	}
+	steps = append(steps, internal.VisualizationStep{
+		StepNumber:  len(steps) + 1,
+		Title:       fmt.Sprintf("%s Mode", mode),
+		Description: "Every block goes through the same four rounds",
+		Input:       strings.ToUpper(hex.EncodeToString([]byte(input))),
+		Output:      output,
+		Explanation: modeExplanation,
+	})
+
+	return &internal.CipherVisualization{
+		CipherType:  "SPN_BLOCK",
+		Steps:       steps,
+		Interactive: true,
+		Example: internal.CipherExample{
+			PlainText:  input,
+			CipherText: output,
+			Key:        key,
+			Difficulty: 8,
+		},
+		Metadata: map[string]interface{}{
+			"mode":       mode,
+			"block_bits": toycrypto.SPNBlockSize * 8,
+			"rounds":     toycrypto.SPNRounds,
+			"algorithm":  "Substitution-Permutation Network",
+		},
+	}, nil
+}
+
+// visualizeLFSR walks through the stream cipher. The key is the 16-bit seed
+// in hex, optionally followed by the feedback taps: "ACE1:B400".
+func (s *visualizerService) visualizeLFSR(input string, key string) (*internal.CipherVisualization, error) {
+	if key == "" {
+		key = defaultLFSRKey
+	}
+	seedText, tapsText, _ := strings.Cut(key, ":")
+	seed, err := strconv.ParseUint(seedText, 16, 16)
+	if err != nil {
+		return nil, fmt.Errorf("LFSR seed must be up to 4 hex digits")
+	}
+	var taps uint64
+	if tapsText != "" {
+		if taps, err = strconv.ParseUint(tapsText, 16, 16); err != nil {
+			return nil, fmt.Errorf("LFSR taps must be up to 4 hex digits")
+		}
+	}
+
+	register, err := toycrypto.NewLFSR(uint16(seed), uint16(taps))
+	if err != nil {
+		return nil, err
+	}
+	if taps == 0 {
+		taps = uint64(toycrypto.DefaultLFSRTaps)
+	}
+	period := register.Period()
+
+	plain := []byte(input)
+	keystream := register.Keystream(len(plain))
+	streamBytes := make([]byte, len(keystream))
+	cipherBytes := make([]byte, len(plain))
+	for i, step := range keystream {
+		streamBytes[i] = step.Byte
+		cipherBytes[i] = plain[i] ^ step.Byte
+	}
+	output := base64.StdEncoding.EncodeToString(cipherBytes)
+
+	steps := []internal.VisualizationStep{
+		{
+			StepNumber:  1,
+			Title:       "Original Text",
+			Description: "The plaintext as bytes",
+			Input:       input,
+			Output:      strings.ToUpper(hex.EncodeToString(plain)),
+			Explanation: "The stream cipher encrypts bytes, shown here in hex",
+		},
+		{
+			StepNumber:  2,
+			Title:       "Load the Register",
+			Description: "The 16-bit shift register starts from the seed",
+			Input:       fmt.Sprintf("%04X", seed),
+			Output:      fmt.Sprintf("%016b", seed),
+			Explanation: fmt.Sprintf("With taps %04X the register runs through %d states before repeating. The seed can't be zero: a zero register stays zero forever", taps, period),
+			Metadata: map[string]interface{}{
+				"taps":   fmt.Sprintf("%016b", taps),
+				"period": period,
+			},
+		},
+	}
+
+	for i, step := range keystream {
+		if i == tracedKeystreamBytes {
+			break
+		}
+		states := make([]string, len(step.States))
+		for j, state := range step.States {
+			states[j] = fmt.Sprintf("%016b", state)
+		}
+		steps = append(steps, internal.VisualizationStep{
+			StepNumber:  len(steps) + 1,
+			Title:       fmt.Sprintf("Keystream Byte %d", i+1),
+			Description: "Shift eight times, collecting the bit that falls off the end",
+			Input:       states[0],
+			Output:      fmt.Sprintf("%08b = %02X", step.Byte, step.Byte),
+			Explanation: "Each shift outputs the lowest bit; when it is 1, the taps are XORed into the register. This feedback is linear, which is exactly what makes LFSRs breakable",
+			Metadata: map[string]interface{}{
+				"states": states,
+				"bits":   step.Bits,
+			},
+		})
+	}
+
+	steps = append(steps,
+		internal.VisualizationStep{
+			StepNumber:  len(steps) + 1,
+			Title:       "XOR with the Keystream",
+			Description: "Each plaintext byte is XORed with one keystream byte",
+			Input:       strings.ToUpper(hex.EncodeToString(plain)),
+			Output:      strings.ToUpper(hex.EncodeToString(cipherBytes)),
+			Explanation: "Decrypting is the same operation: regenerate the keystream from the seed and XOR again",
+			Metadata: map[string]interface{}{
+				"keystream": strings.ToUpper(hex.EncodeToString(streamBytes)),
+			},
+		},
+		internal.VisualizationStep{
+			StepNumber:  len(steps) + 2,
+			Title:       "Encode as Base64",
+			Description: "The ciphertext bytes are written as printable text",
+			Input:       strings.ToUpper(hex.EncodeToString(cipherBytes)),
+			Output:      output,
+			Explanation: "Knowing 2 bytes of plaintext reveals 16 keystream bits, a complete register state, and from there the whole keystream",
+		},
+	)
+
+	return &internal.CipherVisualization{
+		CipherType:  "LFSR_STREAM",
+		Steps:       steps,
+		Interactive: true,
+		Example: internal.CipherExample{
+			PlainText:  input,
+			CipherText: output,
+			Key:        key,
+			Difficulty: 7,
+		},
+		Metadata: map[string]interface{}{
+			"register_bits": 16,
+			"period":        period,
+			"algorithm":     "Linear Feedback Shift Register",
+		},
+	}, nil
+}
+
+// visualizeRSA walks through textbook RSA. The key is the two primes, "p,q";
+// without one a 32-bit key is generated.
+func (s *visualizerService) visualizeRSA(input string, key string) (*internal.CipherVisualization, error) {
+	var rsaKey *toycrypto.RSAKey
+	var err error
+	if key == "" {
+		rsaKey, err = toycrypto.GenerateRSAKey(rand.New(rand.NewSource(defaultRSASeed)), defaultRSABits)
+	} else {
+		pText, qText, _ := strings.Cut(key, ",")
+		p, okP := new(big.Int).SetString(strings.TrimSpace(pText), 10)
+		q, okQ := new(big.Int).SetString(strings.TrimSpace(qText), 10)
+		if !okP || !okQ {
+			return nil, fmt.Errorf("RSA key must be two primes, \"p,q\"")
+		}
+		rsaKey, err = toycrypto.NewRSAKey(p, q)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := rsaKey.Encrypt([]byte(input))
+	output := rsaKey.EncodeBlocks(blocks)
+
+	messages := make([]string, len(blocks))
+	ciphers := make([]string, len(blocks))
+	for i, block := range blocks {
+		messages[i] = block.M.String()
+		ciphers[i] = block.C.String()
+	}
+
+	steps := []internal.VisualizationStep{
+		{
+			StepNumber:  1,
+			Title:       "Choose Two Primes",
+			Description: "Key generation starts from two secret primes p and q",
+			Input:       fmt.Sprintf("p = %s, q = %s", rsaKey.P, rsaKey.Q),
+			Output:      fmt.Sprintf("n = %s (%d bits)", rsaKey.N, rsaKey.Bits),
+			Explanation: "n = p × q is public. Multiplying is easy; getting p and q back from n is the hard problem RSA relies on",
+		},
+		{
+			StepNumber:  2,
+			Title:       "Compute φ(n)",
+			Description: "Count the numbers below n that share no factor with it",
+			Input:       "(p - 1) × (q - 1)",
+			Output:      fmt.Sprintf("φ(n) = %s", rsaKey.Phi),
+			Explanation: "Only someone who knows p and q can compute φ(n)",
+		},
+		{
+			StepNumber:  3,
+			Title:       "Pick the Exponents",
+			Description: "e is public; d is its inverse modulo φ(n)",
+			Input:       fmt.Sprintf("e = %s", rsaKey.E),
+			Output:      fmt.Sprintf("d = %s", rsaKey.D),
+			Explanation: "e must share no factor with φ(n). Then e × d ≡ 1 (mod φ(n)), so raising to e and then to d gives back the original number",
+		},
+		{
+			StepNumber:  4,
+			Title:       "Split into Blocks",
+			Description: fmt.Sprintf("The message is cut into %d-byte blocks, each read as a number", rsaKey.BlockSize()),
+			Input:       input,
+			Output:      strings.Join(messages, " "),
+			Explanation: "Each block must be smaller than n. Textbook RSA adds no padding or randomness, so equal blocks always encrypt the same way",
+		},
+		{
+			StepNumber:  5,
+			Title:       "Encrypt: c = m^e mod n",
+			Description: "Each block is raised to the public exponent",
+			Input:       strings.Join(messages, " "),
+			Output:      strings.Join(ciphers, " "),
+			Explanation: "Anyone can do this with the public key (n, e); undoing it needs d",
+		},
+		{
+			StepNumber:  6,
+			Title:       "Encode as Hex",
+			Description: "Each ciphertext block is written in hex, padded to the width of n",
+			Input:       strings.Join(ciphers, " "),
+			Output:      output,
+			Explanation: "Decryption computes m = c^d mod n for each block",
+		},
+	}
+
+	challenge := rsaKey.Bits <= toycrypto.FactoringChallengeMaxBits
+	if challenge {
+		step := internal.VisualizationStep{
+			StepNumber:  len(steps) + 1,
+			Title:       "Factoring Challenge",
+			Description: "Break the key by factoring n",
+			Input:       fmt.Sprintf("n = %s", rsaKey.N),
+			Explanation: fmt.Sprintf("A %d-bit n falls to Pollard's rho in moments. With p and q, an attacker computes φ(n) and d just like the key owner did; real keys use 2048 bits or more", rsaKey.Bits),
+		}
+		if p, q, ok := toycrypto.Factor(rsaKey.N, factorSteps); ok {
+			step.Output = fmt.Sprintf("n = %s × %s", p, q)
+		} else {
+			step.Output = "n resisted this many steps of Pollard's rho; try again with more"
+		}
+		steps = append(steps, step)
+	}
+
+	return &internal.CipherVisualization{
+		CipherType:  "RSA_TEXTBOOK",
+		Steps:       steps,
+		Interactive: true,
+		Example: internal.CipherExample{
+			PlainText:  input,
+			CipherText: output,
+			Key:        fmt.Sprintf("%s,%s", rsaKey.P, rsaKey.Q),
+			Difficulty: 9,
+		},
+		Metadata: map[string]interface{}{
+			"n":                   rsaKey.N.String(),
+			"e":                   rsaKey.E.String(),
+			"bits":                rsaKey.Bits,
+			"factoring_challenge": challenge,
+			"algorithm":           "Textbook RSA",
+		},
+	}, nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"strings"
 
@@ -39,6 +40,10 @@ func (s *visualizerService) GetAvailableVisualizers(ctx context.Context) ([]stri
 		"HEXADECIMAL",
 		"ROT13",
 		"ATBASH",
+		"CHAIN",
+		"SPN_BLOCK",
+		"LFSR_STREAM",
+		"RSA_TEXTBOOK",
 	}, nil
 }
 
@@ -58,6 +63,14 @@ func (s *visualizerService) GetCipherVisualization(ctx context.Context, cipherTy
 		return s.visualizeAtbash(input), nil
 	case "BASE64":
 		return s.visualizeBase64(input), nil
+	case "CHAIN":
+		return s.visualizeChain(input, key)
+	case "SPN_BLOCK":
+		return s.visualizeSPN(input, key)
+	case "LFSR_STREAM":
+		return s.visualizeLFSR(input, key)
+	case "RSA_TEXTBOOK":
+		return s.visualizeRSA(input, key)
 	default:
 		return nil, fmt.Errorf("visualizer not implemented for cipher type: %s", cipherType)
 	}
@@ -350,8 +363,124 @@ func (s *visualizerService) visualizeBase64(input string) *internal.CipherVisual
 	}
 }
 
+// visualizeChain walks through a multi-layer cipher. The key lists the layers
+// in application order, e.g. "VIGENERE:KEY,RAIL_FENCE:3,BASE64".
+func (s *visualizerService) visualizeChain(input string, key string) (*internal.CipherVisualization, error) {
+	if key == "" {
+		key = "VIGENERE:KEY,RAIL_FENCE:3,BASE64"
+	}
+
+	layers := strings.Split(key, ",")
+	if len(layers) < 2 || len(layers) > 4 {
+		return nil, fmt.Errorf("a chain needs between 2 and 4 layers, got %d", len(layers))
+	}
+
+	steps := []internal.VisualizationStep{
+		{
+			StepNumber:  1,
+			Title:       "Original Text",
+			Description: "The plaintext message",
+			Input:       input,
+			Output:      input,
+			Explanation: "This is the original message before any layer is applied",
+		},
+	}
+
+	layerNames := make([]string, 0, len(layers))
+	text := input
+	for i, layer := range layers {
+		cipherType, layerKey, _ := strings.Cut(strings.TrimSpace(layer), ":")
+		cipherType = strings.ToUpper(cipherType)
+
+		output, err := applyChainLayer(cipherType, text, layerKey)
+		if err != nil {
+			return nil, fmt.Errorf("layer %d: %w", i+1, err)
+		}
+
+		steps = append(steps, internal.VisualizationStep{
+			StepNumber:  len(steps) + 1,
+			Title:       fmt.Sprintf("Layer %d: %s", i+1, cipherType),
+			Description: fmt.Sprintf("Apply %s to the output of the previous step", cipherType),
+			Input:       text,
+			Output:      output,
+			Explanation: "Each layer encrypts whatever the previous layer produced",
+			Metadata: map[string]interface{}{
+				"layer":       i + 1,
+				"cipher_type": cipherType,
+				"key":         layerKey,
+			},
+		})
+		layerNames = append(layerNames, cipherType)
+		text = output
+	}
+
+	steps = append(steps, internal.VisualizationStep{
+		StepNumber:  len(steps) + 1,
+		Title:       "Unwinding the Chain",
+		Description: "Decrypt the layers in reverse order",
+		Input:       text,
+		Output:      input,
+		Explanation: fmt.Sprintf("To solve, peel the layers from the outside in: %s", strings.Join(reverseStrings(layerNames), " -> ")),
+	})
+
+	return &internal.CipherVisualization{
+		CipherType:  "CHAIN",
+		Steps:       steps,
+		Interactive: true,
+		Example: internal.CipherExample{
+			PlainText:  input,
+			CipherText: text,
+			Key:        key,
+			Difficulty: 7,
+		},
+		Metadata: map[string]interface{}{
+			"layers":    layerNames,
+			"algorithm": "Chained Cipher",
+		},
+	}, nil
+}
+
+// applyChainLayer encrypts text with a single chain layer
+func applyChainLayer(cipherType string, text string, key string) (string, error) {
+	switch cipherType {
+	case "CAESAR":
+		shift := 3
+		if key != "" {
+			fmt.Sscanf(key, "%d", &shift)
+		}
+		return caesarCipher(text, shift), nil
+	case "ROT13":
+		return caesarCipher(text, 13), nil
+	case "ATBASH":
+		return atbashCipher(text), nil
+	case "VIGENERE":
+		if key == "" {
+			key = "KEY"
+		}
+		return vigenereCipher(text, key), nil
+	case "RAIL_FENCE":
+		rails := 3
+		if key != "" {
+			fmt.Sscanf(key, "%d", &rails)
+		}
+		return railFenceCipher(text, rails), nil
+	case "BASE64":
+		return base64.StdEncoding.EncodeToString([]byte(text)), nil
+	default:
+		return "", fmt.Errorf("cipher type %s cannot be used as a chain layer", cipherType)
+	}
+}
+
 // Helper functions for actual cipher implementations
 
+func reverseStrings(values []string) []string {
+	reversed := make([]string, len(values))
+	for i, v := range values {
+		reversed[len(values)-1-i] = v
+	}
+	return reversed
+}
+
 func caesarCipher(text string, shift int) string {
 	result := strings.Builder{}
 	for _, ch := range text {
@@ -8,11 +8,15 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/swarit-1/cipher-clash/pkg/auth"
+	"github.com/swarit-1/cipher-clash/pkg/cache"
 	"github.com/swarit-1/cipher-clash/pkg/config"
 	"github.com/swarit-1/cipher-clash/pkg/db"
 	"github.com/swarit-1/cipher-clash/pkg/logger"
+	"github.com/swarit-1/cipher-clash/pkg/messaging"
+	"github.com/swarit-1/cipher-clash/pkg/puzzleclient"
 	"github.com/swarit-1/cipher-clash/services/tutorial/internal/handler"
 	"github.com/swarit-1/cipher-clash/services/tutorial/internal/repository"
 	"github.com/swarit-1/cipher-clash/services/tutorial/internal/service"
@@ -54,8 +58,22 @@ func main() {
 	}
 	defer database.Close()
 
-	// Initialize JWT manager
-	jwtManager := auth.NewJWTManager(cfg.JWT)
+	// Initialize cache
+	cacheClient, err := cache.New(cfg.Redis, log)
+	if err != nil {
+		log.Fatal("Failed to connect to Redis", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	defer cacheClient.Close()
+
+	// Initialize JWT verifier, which checks tokens against the auth service's keys
+	jwtManager, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT verifier", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
 
 	// Initialize repositories
 	tutorialRepo := repository.NewTutorialRepository(database.DB)
@@ -65,8 +83,25 @@ func main() {
 	tutorialService := service.NewTutorialService(tutorialRepo, progressRepo, log)
 	visualizerService := service.NewVisualizerService(log)
 
+	// Erase what this service keeps about users who delete their account
+	// (optional - warn but continue if RabbitMQ unavailable)
+	userDeletions, err := messaging.ListenUserDeleted(cfg.RabbitMQ, "tutorial", func(ctx context.Context, userID uuid.UUID) error {
+		return tutorialService.DeleteUserData(ctx, userID.String())
+	}, log)
+	if err != nil {
+		log.Warn("RabbitMQ unavailable - deleted users' tutorial progress won't be erased", map[string]interface{}{
+			"error": err.Error(),
+		})
+	} else {
+		defer userDeletions.Close()
+	}
+
 	// Initialize handlers
-	tutorialHandler := handler.NewTutorialHandler(tutorialService, visualizerService, jwtManager, log)
+	puzzles := puzzleclient.New(cfg.Internal.PuzzleEngineURL, cfg.Internal.ServiceToken)
+	tutorialHandler := handler.NewTutorialHandler(tutorialService, visualizerService, puzzles, cacheClient, log)
+
+	// Progress routes act for the user_id they name, which must be the caller's
+	authGuard := auth.NewMiddleware(jwtManager, auth.NewDenylist(cacheClient, cfg.JWT.AccessTTL), cfg.Internal.ServiceToken, log)
 
 	// Setup HTTP router
 	mux := http.NewServeMux()
@@ -75,8 +110,8 @@ func main() {
 	mux.HandleFunc("/health", tutorialHandler.Health)
 
 	// Tutorial routes
-	mux.HandleFunc("/api/v1/tutorial/steps", tutorialHandler.GetTutorialSteps)
-	mux.HandleFunc("/api/v1/tutorial/progress", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/api/v1/tutorial/steps", authGuard.OptionalAuth(tutorialHandler.GetTutorialSteps))
+	mux.HandleFunc("/api/v1/tutorial/progress", authGuard.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			tutorialHandler.GetUserProgress(w, r)
 		} else if r.Method == http.MethodPost {
@@ -84,17 +119,17 @@ func main() {
 		} else {
 			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
 		}
-	})
-	mux.HandleFunc("/api/v1/tutorial/complete", tutorialHandler.CompleteStep)
-	mux.HandleFunc("/api/v1/tutorial/skip", tutorialHandler.SkipTutorial)
+	}))
+	mux.HandleFunc("/api/v1/tutorial/complete", authGuard.RequireAuth(tutorialHandler.CompleteStep))
+	mux.HandleFunc("/api/v1/tutorial/skip", authGuard.RequireAuth(tutorialHandler.SkipTutorial))
 
 	// Visualizer routes
 	mux.HandleFunc("/api/v1/tutorial/visualize/", tutorialHandler.GetCipherVisualization)
 	mux.HandleFunc("/api/v1/tutorial/visualizers", tutorialHandler.GetAvailableVisualizers)
 
 	// Bot battle routes
-	mux.HandleFunc("/api/v1/tutorial/bot-battle/start", tutorialHandler.StartBotBattle)
-	mux.HandleFunc("/api/v1/tutorial/bot-battle/submit", tutorialHandler.SubmitBotBattleSolution)
+	mux.HandleFunc("/api/v1/tutorial/bot-battle/start", authGuard.RequireAuth(tutorialHandler.StartBotBattle))
+	mux.HandleFunc("/api/v1/tutorial/bot-battle/submit", authGuard.RequireAuth(tutorialHandler.SubmitBotBattleSolution))
 
 	// Create HTTP server
 	addr := "0.0.0.0:" + port